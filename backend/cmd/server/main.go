@@ -127,7 +127,9 @@ func main() {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	if err := server.Shutdown(ctx); err != nil {
+	err = server.Shutdown(ctx)
+	bootstrap.StopModuleBackgroundTasks()
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -105,4 +105,41 @@ func SetDefaults(v *viper.Viper) {
 	v.SetDefault("cloud.sync_concurrency", 5)
 	v.SetDefault("cloud.sync_timeout", 300)
 	v.SetDefault("cloud.default_regions", "ap-guangzhou,ap-shanghai,ap-beijing")
+
+	// 通知渠道默认配置（为空则不注册对应渠道）
+	v.SetDefault("notification.feishu_webhook", "")
+	v.SetDefault("notification.feishu_secret", "")
+	v.SetDefault("notification.dingtalk_webhook", "")
+	v.SetDefault("notification.dingtalk_secret", "")
+	v.SetDefault("notification.wecom_webhook", "")
+	v.SetDefault("notification.slack_webhook", "")
+	v.SetDefault("notification.teams_webhook", "")
+	v.SetDefault("notification.webhook.url", "")
+	v.SetDefault("notification.webhook.method", "POST")
+	v.SetDefault("notification.webhook.body_template", "")
+	v.SetDefault("notification.outbox.enabled", true)
+	v.SetDefault("notification.outbox.workers", 4)
+	v.SetDefault("notification.outbox.max_attempts", 5)
+	v.SetDefault("notification.outbox.base_backoff", 10)
+	v.SetDefault("notification.outbox.max_backoff", 600)
+	v.SetDefault("notification.outbox.poll_interval", 5)
+
+	// 告警规则评估默认配置
+	v.SetDefault("alert.evaluation_interval", 30)
+	v.SetDefault("alert.group_by", []string{"alertname", "cluster"})
+	v.SetDefault("alert.group_wait", 30)
+	v.SetDefault("alert.group_interval", 300)
+	v.SetDefault("alert.repeat_interval", 14400)
+	v.SetDefault("alert.escalation_interval", 30)
+
+	// CI/CD 运行记录同步
+	v.SetDefault("cicd.run_sync_interval", 300)
+	v.SetDefault("cicd.pipeline_interval", 10)
+
+	// Harbor 漏洞扫描部署策略
+	v.SetDefault("harbor.scan_policy.max_critical", 0)
+	v.SetDefault("harbor.scan_policy.max_high", -1)
+	v.SetDefault("harbor.scan_policy.require_scan", true)
+	v.SetDefault("harbor.retention_interval", 86400)
+	v.SetDefault("harbor.promotion_require_approval", false)
 }
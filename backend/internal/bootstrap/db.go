@@ -2,6 +2,7 @@ package bootstrap
 
 import (
 	"devops-platform/config"
+	alertModel "devops-platform/internal/modules/alert/model"
 	cicdModel "devops-platform/internal/modules/cicd/model"
 	cmdbModel "devops-platform/internal/modules/cmdb/model"
 	harborModel "devops-platform/internal/modules/harbor/model"
@@ -99,6 +100,7 @@ func InitDB() error {
 		&nfModel.Template{},
 		&nfModel.ChannelConfig{},
 		&nfModel.SendLog{},
+		&nfModel.OutboxMessage{},
 		&wfModel.ChangeOrder{},
 		&wfModel.Approval{},
 		&toolModel.Tool{},
@@ -109,10 +111,26 @@ func InitDB() error {
 		&sqlAuditModel.SqlRecord{},
 		&harborModel.HarborConfig{},
 		&monitorModel.PrometheusConfig{},
-		&cicdModel.JenkinsConfig{},
+		&monitorModel.Dashboard{},
+		&alertModel.Rule{},
+		&alertModel.Silence{},
+		&alertModel.InhibitRule{},
+		&alertModel.NotificationChannel{},
+		&alertModel.History{},
+		&alertModel.AlertmanagerConfig{},
+		&alertModel.NotificationGroup{},
+		&alertModel.OnCallSchedule{},
+		&alertModel.EscalationPolicy{},
+		&cicdModel.CIProviderConfig{},
 		&cicdModel.Pipeline{},
 		&cicdModel.PipelineRun{},
+		&cicdModel.PipelineSpec{},
+		&cicdModel.PipelineExecution{},
+		&harborModel.RetentionPolicy{},
+		&harborModel.RetentionRun{},
+		&harborModel.Promotion{},
 		&logModel.LogSource{},
+		&logModel.SavedQuery{},
 		&kbModel.Category{},
 		&kbModel.Article{},
 	)
@@ -131,6 +149,11 @@ func InitDB() error {
 		return err
 	}
 
+	// 旧 Jenkins 配置迁移到通用 CI 配置（非致命）
+	if err := migrateJenkinsConfigs(db); err != nil {
+		logger.Log.Warn("Jenkins 配置迁移失败（非致命）", zap.Error(err))
+	}
+
 	// 迁移 users.department_id -> user_departments（在 AutoMigrate 之后、种子数据之前）
 	if err := migrateUserDepartments(db); err != nil {
 		logger.Log.Warn("用户部门迁移失败（非致命）", zap.Error(err))
@@ -185,6 +208,47 @@ func ensureDefaultTenantAndBackfill(db *gorm.DB) error {
 	return nil
 }
 
+// migrateJenkinsConfigs copies legacy cicd_jenkins_configs rows into
+// cicd_provider_configs with their IDs unchanged, then points pipelines at them.
+// Rows already copied are skipped, so it is safe to run on every start.
+func migrateJenkinsConfigs(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&cicdModel.JenkinsConfig{}) {
+		return nil
+	}
+	var legacy []cicdModel.JenkinsConfig
+	if err := db.Find(&legacy).Error; err != nil {
+		return err
+	}
+	for _, old := range legacy {
+		var count int64
+		db.Unscoped().Model(&cicdModel.CIProviderConfig{}).Where("id = ?", old.ID).Count(&count)
+		if count > 0 {
+			continue
+		}
+		cfg := cicdModel.CIProviderConfig{
+			ID:        old.ID,
+			Name:      old.Name,
+			Type:      cicdModel.ProviderJenkins,
+			URL:       old.URL,
+			Username:  old.Username,
+			Token:     old.APIToken,
+			Status:    old.Status,
+			CreatedAt: old.CreatedAt,
+			UpdatedAt: old.UpdatedAt,
+		}
+		if err := db.Create(&cfg).Error; err != nil {
+			return fmt.Errorf("migrate jenkins config %d failed: %w", old.ID, err)
+		}
+	}
+	if db.Migrator().HasColumn(&cicdModel.Pipeline{}, "jenkins_config_id") {
+		if err := db.Exec("UPDATE cicd_pipelines SET provider_config_id = jenkins_config_id " +
+			"WHERE (provider_config_id IS NULL OR provider_config_id = 0) AND jenkins_config_id > 0").Error; err != nil {
+			return fmt.Errorf("backfill cicd_pipelines.provider_config_id failed: %w", err)
+		}
+	}
+	return nil
+}
+
 func ensureKeywordIndexes(db *gorm.DB) error {
 	if db.Dialector == nil || db.Dialector.Name() != "mysql" {
 		return nil
@@ -363,6 +427,9 @@ func seedPermissions(db *gorm.DB) error {
 		{Name: "创建告警规则", Type: userModel.PermissionTypeAPI, Resource: "alert", Action: "create"},
 		{Name: "更新告警规则", Type: userModel.PermissionTypeAPI, Resource: "alert", Action: "update"},
 		{Name: "删除告警规则", Type: userModel.PermissionTypeAPI, Resource: "alert", Action: "delete"},
+		// 通知管理权限
+		{Name: "查看通知模板", Type: userModel.PermissionTypeAPI, Resource: "notification", Action: "list"},
+		{Name: "管理通知模板", Type: userModel.PermissionTypeAPI, Resource: "notification", Action: "update"},
 		// 日志管理权限
 		{Name: "查看日志", Type: userModel.PermissionTypeAPI, Resource: "log", Action: "list"},
 		// 监控管理权限
@@ -2,19 +2,36 @@ package bootstrap
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 
 	"devops-platform/config"
 	"devops-platform/internal/middleware"
 	"devops-platform/internal/pkg/logger"
+	alertAPI "devops-platform/internal/modules/alert/api"
 	alertService "devops-platform/internal/modules/alert/service"
+	appAPI "devops-platform/internal/modules/app/api"
+	appService "devops-platform/internal/modules/app/service"
 	cicdAPI "devops-platform/internal/modules/cicd/api"
+	cicdModel "devops-platform/internal/modules/cicd/model"
+	cicdService "devops-platform/internal/modules/cicd/service"
 	sqlAuditAPI "devops-platform/internal/modules/sqlaudit/api"
 	sqlAuditService "devops-platform/internal/modules/sqlaudit/service"
 	cmdbAPI "devops-platform/internal/modules/cmdb/api"
+	cmdbService "devops-platform/internal/modules/cmdb/service"
 	harborAPI "devops-platform/internal/modules/harbor/api"
+	harborModel "devops-platform/internal/modules/harbor/model"
+	harborService "devops-platform/internal/modules/harbor/service"
 	k8sAPI "devops-platform/internal/modules/k8s/api"
+	k8sService "devops-platform/internal/modules/k8s/service"
 	logAPI "devops-platform/internal/modules/log/api"
+	logService "devops-platform/internal/modules/log/service"
 	monitorAPI "devops-platform/internal/modules/monitor/api"
+	monitorRepo "devops-platform/internal/modules/monitor/repository"
+	monitorService "devops-platform/internal/modules/monitor/service"
+	notifAPI "devops-platform/internal/modules/notification/api"
 	notifModel "devops-platform/internal/modules/notification/model"
 	notifService "devops-platform/internal/modules/notification/service"
 	taskAPI "devops-platform/internal/modules/task/api"
@@ -24,9 +41,11 @@ import (
 	toolService "devops-platform/internal/modules/tool/service"
 	userAPI "devops-platform/internal/modules/user/api"
 	kbAPI "devops-platform/internal/modules/knowledge/api"
+	userModel "devops-platform/internal/modules/user/model"
 	"devops-platform/internal/modules/user/repository"
 	"devops-platform/internal/modules/user/service"
 	workflowAPI "devops-platform/internal/modules/workflow/api"
+	workflowModel "devops-platform/internal/modules/workflow/model"
 	workflowService "devops-platform/internal/modules/workflow/service"
 
 	"go.uber.org/zap"
@@ -44,13 +63,56 @@ func InitModules(db *gorm.DB) {
 	k8sAPI.SetK8sDB(db, K8sFactory)
 
 	// Harbor module
-	harborAPI.SetHarborDB(db)
+	harborSvc := harborService.NewHarborService(db)
+	harborSvc.SetScanPolicy(harborModel.ScanPolicy{
+		MaxCritical: config.Cfg.GetInt("harbor.scan_policy.max_critical"),
+		MaxHigh:     config.Cfg.GetInt("harbor.scan_policy.max_high"),
+		RequireScan: config.Cfg.GetBool("harbor.scan_policy.require_scan"),
+	})
+	harborSvc.SetRunningImages(k8sService.NewK8sService(k8sService.NewClusterService(db), K8sFactory).RunningImages)
+	harborSvc.SetAuditFunc(func(operator, operation, target, detail string, err error) {
+		params, _ := json.Marshal(map[string]string{"target": target, "detail": detail})
+		method := "POST"
+		if strings.Contains(operation, "删除") {
+			method = "DELETE"
+		}
+		entry := &userModel.AuditLog{
+			Username:  operator,
+			Operation: operation,
+			Method:    method,
+			Path:      target,
+			Params:    string(params),
+			Result:    "{}",
+			Status:    200,
+			RequestAt: time.Now(),
+		}
+		if err != nil {
+			entry.Status = 500
+			entry.ErrorMessage = err.Error()
+		}
+		if createErr := repository.NewAuditRepo(db).Create(entry); createErr != nil {
+			logger.Log.Warn("记录 Harbor 审计日志失败", zap.Error(createErr))
+		}
+	})
+	addBackgroundTask(func() {
+		harborSvc.StartRetentionExecutor(time.Duration(config.Cfg.GetInt("harbor.retention_interval")) * time.Second)
+	}, harborSvc.StopRetentionExecutor)
+	harborAPI.InitHarborService(harborSvc)
 
 	// Monitor module
 	monitorAPI.SetMonitorDB(db)
+	monitorAPI.SetDashboardLogAggregator(logService.NewLogService(db))
+	monitorAPI.SetDashboardVariableSources(dashboardVariableSources(db))
+
+	// Alert module
+	alertAPI.SetAlertDB(db)
 
 	// CI/CD module
-	cicdAPI.SetCICDDB(db)
+	cicdSvc := cicdService.NewCICDService(db)
+	addBackgroundTask(func() {
+		cicdSvc.StartRunSync(time.Duration(config.Cfg.GetInt("cicd.run_sync_interval")) * time.Second)
+	}, cicdSvc.StopRunSync)
+	cicdAPI.InitCICDService(cicdSvc)
 
 	// CMDB module
 	cmdbAPI.SetDB(db)
@@ -93,15 +155,70 @@ func InitModules(db *gorm.DB) {
 	ns := notifService.NewNotificationService(db)
 	feishuURL := config.Cfg.GetString("notification.feishu_webhook")
 	if feishuURL != "" {
-		ns.RegisterNotifier(notifModel.ChannelFeishu, notifService.NewFeishuNotifier(feishuURL))
+		ns.RegisterNotifier(notifModel.ChannelFeishu, notifService.NewSignedFeishuNotifier(feishuURL, config.Cfg.GetString("notification.feishu_secret")))
 	}
 	dingtalkURL := config.Cfg.GetString("notification.dingtalk_webhook")
 	if dingtalkURL != "" {
-		ns.RegisterNotifier(notifModel.ChannelDingTalk, notifService.NewDingTalkNotifier(dingtalkURL))
+		ns.RegisterNotifier(notifModel.ChannelDingTalk, notifService.NewSignedDingTalkNotifier(dingtalkURL, config.Cfg.GetString("notification.dingtalk_secret")))
+	}
+	if wecomURL := config.Cfg.GetString("notification.wecom_webhook"); wecomURL != "" {
+		ns.RegisterNotifier(notifModel.ChannelWeCom, notifService.NewWeComNotifier(wecomURL))
+	}
+	if slackURL := config.Cfg.GetString("notification.slack_webhook"); slackURL != "" {
+		ns.RegisterNotifier(notifModel.ChannelSlack, notifService.NewSlackNotifier(slackURL))
+	}
+	if teamsURL := config.Cfg.GetString("notification.teams_webhook"); teamsURL != "" {
+		ns.RegisterNotifier(notifModel.ChannelTeams, notifService.NewTeamsNotifier(teamsURL))
+	}
+	if webhookURL := config.Cfg.GetString("notification.webhook.url"); webhookURL != "" {
+		webhookNotifier, err := notifService.NewWebhookNotifier(
+			webhookURL,
+			config.Cfg.GetString("notification.webhook.method"),
+			config.Cfg.GetStringMapString("notification.webhook.headers"),
+			config.Cfg.GetString("notification.webhook.body_template"),
+		)
+		if err != nil {
+			logger.Log.Error("通用 Webhook 通知配置无效", zap.Error(err))
+		} else {
+			ns.RegisterNotifier(notifModel.ChannelWebhook, webhookNotifier)
+		}
 	}
 
-	// Alert-notification bridge
+	if config.Cfg.GetBool("notification.outbox.enabled") {
+		ns.EnableOutbox(notifService.OutboxPolicy{
+			Workers:     config.Cfg.GetInt("notification.outbox.workers"),
+			MaxAttempts: config.Cfg.GetInt("notification.outbox.max_attempts"),
+			BaseBackoff: time.Duration(config.Cfg.GetInt("notification.outbox.base_backoff")) * time.Second,
+			MaxBackoff:  time.Duration(config.Cfg.GetInt("notification.outbox.max_backoff")) * time.Second,
+		})
+		addBackgroundTask(func() {
+			ns.StartOutbox(time.Duration(config.Cfg.GetInt("notification.outbox.poll_interval")) * time.Second)
+		}, ns.StopOutbox)
+	}
+	notifAPI.InitNotificationService(ns)
+
+	// Alert-notification bridge + built-in rule evaluator
 	alertBridge := alertService.NewAlertNotificationBridge(ns)
+	alertGrouper := alertService.NewAlertGrouper(db, alertBridge, alertService.GroupPolicy{
+		GroupBy:        config.Cfg.GetStringSlice("alert.group_by"),
+		GroupWait:      time.Duration(config.Cfg.GetInt("alert.group_wait")) * time.Second,
+		GroupInterval:  time.Duration(config.Cfg.GetInt("alert.group_interval")) * time.Second,
+		RepeatInterval: time.Duration(config.Cfg.GetInt("alert.repeat_interval")) * time.Second,
+	})
+	alertBridge.SetGrouper(alertGrouper)
+	addBackgroundTask(func() { alertGrouper.Start(5 * time.Second) }, alertGrouper.Stop)
+	alertAPI.SetAlertNotificationBridge(alertBridge)
+	alertEvaluator := alertService.NewRuleEvaluator(db, monitorRepo.NewMonitorRepo(db), alertBridge)
+	alertLogSvc := logService.NewLogService(db)
+	alertEvaluator.SetLogCounter(alertLogSvc)
+	alertAPI.SetSavedLogQueries(alertLogSvc)
+	addBackgroundTask(func() {
+		alertEvaluator.Start(time.Duration(config.Cfg.GetInt("alert.evaluation_interval")) * time.Second)
+	}, alertEvaluator.Stop)
+	alertEscalator := alertService.NewEscalator(db, ns)
+	addBackgroundTask(func() {
+		alertEscalator.Start(time.Duration(config.Cfg.GetInt("alert.escalation_interval")) * time.Second)
+	}, alertEscalator.Stop)
 
 	// Workflow engine: service + callback executor
 	ws := workflowService.NewWorkflowService(db)
@@ -118,9 +235,86 @@ func InitModules(db *gorm.DB) {
 			return ns.Send(tenantID, ch, []string{recipients}, subject, body)
 		},
 	})
+	callbackExecutor.Register("harbor", &workflowService.PromotionCallback{
+		ExecutePromotion: harborSvc.ExecutePromotion,
+	})
 	ws.SetCallbackExecutor(callbackExecutor)
 	workflowAPI.InitWorkflowService(ws)
 
+	// Harbor promotions wait for a change order when approval is required
+	harborSvc.SetPromotionApproval(harborService.PromotionApproval{
+		Request: func(tenantID, userID uint, title, description string, levels int, promotionID uint) (uint, error) {
+			order, err := ws.CreateCallbackOrder(tenantID, userID, title, description, "image_promotion", levels,
+				"harbor", "promote", map[string]uint{"promotionId": promotionID})
+			if err != nil {
+				return 0, err
+			}
+			return order.ID, ws.SubmitForReview(order.ID, tenantID)
+		},
+		Rejected: func(tenantID, orderID uint) (bool, error) {
+			order, err := ws.GetOrder(orderID, tenantID)
+			if err != nil {
+				return false, err
+			}
+			return order.Status == workflowModel.StatusRejected, nil
+		},
+	}, config.Cfg.GetBool("harbor.promotion_require_approval"))
+
+	// Multi-stage pipelines: stages run through harbor, workflow and app
+	apps := appAPI.SharedAppService()
+	cicdSvc.SetStageHooks(cicdService.StageHooks{
+		CheckArtifact: func(harborConfigID uint, project, repository, reference string, enforceScanPolicy bool) error {
+			if !enforceScanPolicy {
+				_, err := harborSvc.GetArtifact(harborConfigID, project, repository, reference)
+				return err
+			}
+			decision, err := harborSvc.CheckScanPolicy(harborConfigID, project, repository, reference)
+			if err != nil {
+				return err
+			}
+			if !decision.Allowed {
+				return fmt.Errorf("blocked by scan policy: %s", strings.Join(decision.Reasons, "; "))
+			}
+			return nil
+		},
+		RequestApproval: func(tenantID, userID uint, title, description string, levels int) (uint, error) {
+			order, err := ws.CreateOrder(tenantID, userID, title, description, "pipeline", levels)
+			if err != nil {
+				return 0, err
+			}
+			return order.ID, ws.SubmitForReview(order.ID, tenantID)
+		},
+		ApprovalStatus: func(tenantID, orderID uint) (string, error) {
+			order, err := ws.GetOrder(orderID, tenantID)
+			if err != nil {
+				return "", err
+			}
+			switch order.Status {
+			case workflowModel.StatusApproved, workflowModel.StatusExecuting, workflowModel.StatusCompleted:
+				return cicdService.ApprovalApproved, nil
+			case workflowModel.StatusRejected, workflowModel.StatusFailed:
+				return cicdService.ApprovalRejected, nil
+			}
+			return cicdService.ApprovalPending, nil
+		},
+		Deploy: func(tenantID, appID uint, stage cicdModel.DeployStage, version, operator string) (uint, error) {
+			d, err := apps.DeployInTenant(tenantID, appService.DeployRequest{
+				AppID:       appID,
+				TemplateID:  stage.TemplateID,
+				Cluster:     stage.Cluster,
+				Environment: stage.Environment,
+				Namespace:   stage.Namespace,
+				Version:     version,
+				Operator:    operator,
+				Variables:   stage.Variables,
+			})
+			return d.ID, err
+		},
+	})
+	addBackgroundTask(func() {
+		cicdSvc.StartPipelineRunner(time.Duration(config.Cfg.GetInt("cicd.pipeline_interval")) * time.Second)
+	}, cicdSvc.StopPipelineRunner)
+
 	// Tool marketplace: service + builtin scripts seed
 	toolSvc := toolService.NewToolService(db)
 	if err := toolSvc.SeedBuiltinScripts(); err != nil {
@@ -146,13 +340,86 @@ func InitModules(db *gorm.DB) {
 	sqlAuditAPI.InitSqlAuditService(sqlAuditSvc)
 
 	// Store references for background tasks
-	_ = alertBridge
 	_ = toolSvc
 	_ = sqlAuditSvc
 }
 
+// backgroundTask is a module loop wired by InitModules, started by
+// StartModuleBackgroundTasks and stopped by StopModuleBackgroundTasks.
+type backgroundTask struct {
+	start func()
+	stop  func()
+}
+
+var backgroundTasks []backgroundTask
+
+func addBackgroundTask(start, stop func()) {
+	backgroundTasks = append(backgroundTasks, backgroundTask{start: start, stop: stop})
+}
+
 // StartModuleBackgroundTasks starts background services (schedulers, cleanup, etc.)
 func StartModuleBackgroundTasks() {
 	cmdbAPI.StartCloudSync()
 	cmdbAPI.StartRecordingCleanup()
+	for _, t := range backgroundTasks {
+		t.start()
+	}
+}
+
+// StopModuleBackgroundTasks stops the module loops in reverse start order, so
+// none of them is still working when the server exits.
+func StopModuleBackgroundTasks() {
+	for i := len(backgroundTasks) - 1; i >= 0; i-- {
+		backgroundTasks[i].stop()
+	}
+}
+
+// dashboardVariableSources lists k8s clusters and namespaces and cmdb host
+// groups for dashboard variables.
+func dashboardVariableSources(db *gorm.DB) monitorService.VariableSources {
+	clusterSvc := k8sService.NewClusterService(db)
+	k8sSvc := k8sService.NewK8sService(clusterSvc, K8sFactory)
+	groupSvc := cmdbService.NewGroupService(db)
+	return monitorService.VariableSources{
+		Clusters: func(tenantID uint) ([]string, error) {
+			clusters, _, err := clusterSvc.ListInTenant(tenantID, 1, 100, "", "")
+			if err != nil {
+				return nil, err
+			}
+			names := make([]string, 0, len(clusters))
+			for _, c := range clusters {
+				names = append(names, c.Name)
+			}
+			return names, nil
+		},
+		Namespaces: func(tenantID uint, cluster string) ([]string, error) {
+			if _, err := clusterSvc.GetByExactNameInTenant(tenantID, cluster); err != nil {
+				return nil, err
+			}
+			resp, err := k8sSvc.ListNamespaces(cluster, "", 1, 1000)
+			if err != nil {
+				return nil, err
+			}
+			names := make([]string, 0, len(resp.Items))
+			for _, ns := range resp.Items {
+				names = append(names, ns.Name)
+			}
+			return names, nil
+		},
+		HostGroups: func(tenantID uint) ([]string, error) {
+			groups, err := groupSvc.ListInTenant(tenantID)
+			if err != nil {
+				return nil, err
+			}
+			names := make([]string, 0, len(groups))
+			seen := map[string]bool{}
+			for _, g := range groups {
+				if !seen[g.Name] {
+					seen[g.Name] = true
+					names = append(names, g.Name)
+				}
+			}
+			return names, nil
+		},
+	}
 }
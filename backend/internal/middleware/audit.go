@@ -169,15 +169,25 @@ type auditResponseWriter struct {
 }
 
 func (w *auditResponseWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	if !isStreamingResponse(w) {
+		w.body.Write(b)
+	}
 	return w.ResponseWriter.Write(b)
 }
 
 func (w *auditResponseWriter) WriteString(s string) (int, error) {
-	w.body.WriteString(s)
+	if !isStreamingResponse(w) {
+		w.body.WriteString(s)
+	}
 	return w.ResponseWriter.WriteString(s)
 }
 
+// isStreamingResponse reports a server-sent event stream, whose body is not
+// kept: it can run for as long as the client stays connected.
+func isStreamingResponse(w gin.ResponseWriter) bool {
+	return strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream")
+}
+
 // Audit 审计日志中间件
 func Audit() gin.HandlerFunc {
 	return func(c *gin.Context) {
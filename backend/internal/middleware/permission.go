@@ -111,3 +111,19 @@ func RequirePermission(resource, action string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// HasPermission 判断当前用户是否拥有某项权限，供需要按数据内容鉴权的处理函数使用，
+// 校验顺序与 RequirePermission 一致：优先 Casbin，未注入时降级到自建权限校验
+func HasPermission(c *gin.Context, resource, action string) (bool, error) {
+	userID, tenantID := c.GetUint("userID"), c.GetUint("tenantID")
+	if userID == 0 || tenantID == 0 {
+		return false, nil
+	}
+	if casbinEnforcer != nil {
+		return casbinEnforcer.Enforce(fmt.Sprintf("%d", userID), fmt.Sprintf("%d", tenantID), resource, action)
+	}
+	if db == nil {
+		return false, fmt.Errorf("数据库连接未初始化")
+	}
+	return service.NewUserService(db).CheckPermission(c.Request.Context(), tenantID, userID, resource, action)
+}
@@ -17,7 +17,9 @@ type bodyWriter struct {
 }
 
 func (w bodyWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	if !isStreamingResponse(w) {
+		w.body.Write(b)
+	}
 	return w.ResponseWriter.Write(b)
 }
 
@@ -9,9 +9,25 @@ import (
 	"devops-platform/internal/pkg/obserr"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-var alertService = service.NewAlertService()
+var alertService *service.AlertService
+
+// SetAlertDB initializes the alert service with a DB connection
+func SetAlertDB(db *gorm.DB) {
+	alertService = service.NewAlertService(db)
+}
+
+// SetAlertNotificationBridge routes ingested alerts to the notification hub
+func SetAlertNotificationBridge(bridge *service.AlertNotificationBridge) {
+	alertService.SetNotificationBridge(bridge)
+}
+
+// SetSavedLogQueries lets log rules start from a saved log query
+func SetSavedLogQueries(queries service.SavedLogQueries) {
+	alertService.SetSavedLogQueries(queries)
+}
 
 // ListAlertRules godoc
 // @Summary 获取告警规则列表
@@ -23,10 +39,50 @@ var alertService = service.NewAlertService()
 // @Success 200 {object} map[string]interface{} "成功"
 // @Router /alert/rules [get]
 func ListAlertRules(c *gin.Context) {
+	data, err := alertService.ListRules(c.GetUint("tenantID"), c.Query("keyword"))
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// UpsertAlertRule godoc
+// @Summary 保存告警规则
+// @Description 创建或更新告警规则
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body map[string]interface{} true "告警规则"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "规则不存在"
+// @Router /alert/rule/upsert [post]
+func UpsertAlertRule(c *gin.Context) {
+	var req service.RuleUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeObservableError(c, http.StatusBadRequest, obserr.Wrap("ALERT_INVALID_REQUEST", "alert.UpsertAlertRule", "参数错误", err))
+		return
+	}
+	req.UserID = c.GetUint("userID")
+	data, err := alertService.UpsertRule(c.GetUint("tenantID"), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if alertService.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		writeObservableError(c, status, err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "success",
-		"data":    alertService.ListRules(c.Query("keyword")),
+		"data":    data,
 	})
 }
 
@@ -44,10 +100,15 @@ func ListAlertRules(c *gin.Context) {
 func ListAlertHistory(c *gin.Context) {
 	start, _ := time.Parse(time.RFC3339, c.Query("start"))
 	end, _ := time.Parse(time.RFC3339, c.Query("end"))
+	data, err := alertService.ListHistory(c.GetUint("tenantID"), c.Query("status"), start, end)
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "success",
-		"data":    alertService.ListHistory(c.Query("status"), start, end),
+		"data":    data,
 	})
 }
 
@@ -73,7 +134,7 @@ func ToggleAlertRule(c *gin.Context) {
 		writeObservableError(c, http.StatusBadRequest, obserr.New("ALERT_RULE_ID_REQUIRED", "alert.ToggleAlertRule", "rule id 不能为空"))
 		return
 	}
-	rule, err := alertService.SetRuleEnabled(req)
+	rule, err := alertService.SetRuleEnabled(c.GetUint("tenantID"), req)
 	if err != nil {
 		status := http.StatusBadRequest
 		if alertService.IsNotFound(err) {
@@ -100,10 +161,15 @@ func ToggleAlertRule(c *gin.Context) {
 // @Router /alert/silences [get]
 func ListAlertSilences(c *gin.Context) {
 	ruleID, _ := strconv.ParseUint(c.Query("ruleId"), 10, 64)
+	data, err := alertService.ListSilences(c.GetUint("tenantID"), uint(ruleID))
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "success",
-		"data":    alertService.ListSilences(uint(ruleID)),
+		"data":    data,
 	})
 }
 
@@ -124,7 +190,40 @@ func UpsertAlertSilence(c *gin.Context) {
 		writeObservableError(c, http.StatusBadRequest, obserr.Wrap("ALERT_INVALID_REQUEST", "alert.UpsertAlertSilence", "参数错误", err))
 		return
 	}
-	data, err := alertService.UpsertSilence(req)
+	data, err := alertService.UpsertSilence(c.GetUint("tenantID"), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if alertService.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		writeObservableError(c, status, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// PreviewAlertSilence godoc
+// @Summary 预览告警静默命中
+// @Description 在保存前预览静默匹配规则命中的未恢复告警
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body map[string]interface{} true "静默配置"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /alert/silence/preview [post]
+func PreviewAlertSilence(c *gin.Context) {
+	var req service.SilenceUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeObservableError(c, http.StatusBadRequest, obserr.Wrap("ALERT_INVALID_REQUEST", "alert.PreviewAlertSilence", "参数错误", err))
+		return
+	}
+	data, err := alertService.PreviewSilence(c.GetUint("tenantID"), req)
 	if err != nil {
 		writeObservableError(c, http.StatusBadRequest, err)
 		return
@@ -136,6 +235,61 @@ func UpsertAlertSilence(c *gin.Context) {
 	})
 }
 
+// ListAlertInhibitRules godoc
+// @Summary 获取告警抑制规则
+// @Description 获取当前租户的告警抑制规则
+// @Tags 告警管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /alert/inhibitions [get]
+func ListAlertInhibitRules(c *gin.Context) {
+	data, err := alertService.ListInhibitRules(c.GetUint("tenantID"))
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// UpsertAlertInhibitRule godoc
+// @Summary 保存告警抑制规则
+// @Description 创建或更新告警抑制规则，源告警触发时抑制匹配的目标告警
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body map[string]interface{} true "抑制规则配置"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "抑制规则不存在"
+// @Router /alert/inhibition/upsert [post]
+func UpsertAlertInhibitRule(c *gin.Context) {
+	var req service.InhibitRuleUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeObservableError(c, http.StatusBadRequest, obserr.Wrap("ALERT_INVALID_REQUEST", "alert.UpsertAlertInhibitRule", "参数错误", err))
+		return
+	}
+	data, err := alertService.UpsertInhibitRule(c.GetUint("tenantID"), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if alertService.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		writeObservableError(c, status, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    data,
+	})
+}
+
 // ListAlertChannels godoc
 // @Summary 获取通知渠道列表
 // @Description 按类型筛选告警通知渠道
@@ -146,10 +300,15 @@ func UpsertAlertSilence(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "成功"
 // @Router /alert/channels [get]
 func ListAlertChannels(c *gin.Context) {
+	data, err := alertService.ListChannels(c.GetUint("tenantID"), c.Query("type"))
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "success",
-		"data":    alertService.ListChannels(c.Query("type")),
+		"data":    data,
 	})
 }
 
@@ -170,9 +329,13 @@ func UpsertAlertChannel(c *gin.Context) {
 		writeObservableError(c, http.StatusBadRequest, obserr.Wrap("ALERT_INVALID_REQUEST", "alert.UpsertAlertChannel", "参数错误", err))
 		return
 	}
-	data, err := alertService.UpsertChannel(req)
+	data, err := alertService.UpsertChannel(c.GetUint("tenantID"), req)
 	if err != nil {
-		writeObservableError(c, http.StatusBadRequest, err)
+		status := http.StatusBadRequest
+		if alertService.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		writeObservableError(c, status, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -191,10 +354,15 @@ func UpsertAlertChannel(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "成功"
 // @Router /alert/config [get]
 func GetAlertmanagerConfig(c *gin.Context) {
+	data, err := alertService.GetConfig(c.GetUint("tenantID"))
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "success",
-		"data":    alertService.GetConfig(),
+		"data":    data,
 	})
 }
 
@@ -215,7 +383,7 @@ func SaveAlertmanagerConfig(c *gin.Context) {
 		writeObservableError(c, http.StatusBadRequest, obserr.Wrap("ALERT_INVALID_REQUEST", "alert.SaveAlertmanagerConfig", "参数错误", err))
 		return
 	}
-	data, err := alertService.SaveConfig(req)
+	data, err := alertService.SaveConfig(c.GetUint("tenantID"), req)
 	if err != nil {
 		writeObservableError(c, http.StatusBadRequest, err)
 		return
@@ -227,6 +395,41 @@ func SaveAlertmanagerConfig(c *gin.Context) {
 	})
 }
 
+// ReceiveAlertmanagerWebhook godoc
+// @Summary 接收Alertmanager Webhook
+// @Description 接收Alertmanager标准webhook(v4)并按fingerprint写入告警历史
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body map[string]interface{} true "Alertmanager webhook payload"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 500 {object} map[string]interface{} "服务器错误"
+// @Router /alert/webhook/alertmanager [post]
+func ReceiveAlertmanagerWebhook(c *gin.Context) {
+	var req service.AlertmanagerWebhook
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeObservableError(c, http.StatusBadRequest, obserr.Wrap("ALERT_INVALID_REQUEST", "alert.ReceiveAlertmanagerWebhook", "参数错误", err))
+		return
+	}
+	data, err := alertService.IngestAlertmanagerWebhook(c.GetUint("tenantID"), req)
+	if err != nil {
+		// Alertmanager retries 5xx but drops 4xx, so only a bad payload is a 400.
+		status := http.StatusInternalServerError
+		if obserr.Details(err)["code"] == "ALERT_WEBHOOK_EMPTY" {
+			status = http.StatusBadRequest
+		}
+		writeObservableError(c, status, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    data,
+	})
+}
+
 func writeObservableError(c *gin.Context, status int, err error) {
 	details := obserr.Details(err)
 	msg, _ := details["message"].(string)
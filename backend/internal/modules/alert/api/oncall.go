@@ -0,0 +1,217 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	"devops-platform/internal/modules/alert/service"
+	"devops-platform/internal/pkg/obserr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListOnCallSchedules godoc
+// @Summary 获取值班表列表
+// @Description 获取当前租户的值班表
+// @Tags 告警管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /alert/oncall/schedules [get]
+func ListOnCallSchedules(c *gin.Context) {
+	data, err := alertService.ListSchedules(c.GetUint("tenantID"))
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// UpsertOnCallSchedule godoc
+// @Summary 保存值班表
+// @Description 创建或更新值班表，支持按天/按周轮值层与临时替班
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body map[string]interface{} true "值班表配置"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "值班表不存在"
+// @Router /alert/oncall/schedule/upsert [post]
+func UpsertOnCallSchedule(c *gin.Context) {
+	var req service.ScheduleUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeObservableError(c, http.StatusBadRequest, obserr.Wrap("ALERT_INVALID_REQUEST", "alert.UpsertOnCallSchedule", "参数错误", err))
+		return
+	}
+	data, err := alertService.UpsertSchedule(c.GetUint("tenantID"), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if alertService.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		writeObservableError(c, status, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// GetCurrentOnCall godoc
+// @Summary 查询当前值班人
+// @Description 查询值班表在指定时间(默认当前)的值班人员
+// @Tags 告警管理
+// @Produce json
+// @Security BearerAuth
+// @Param scheduleId query int true "值班表ID"
+// @Param at query string false "时间(RFC3339)"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "值班表不存在"
+// @Router /alert/oncall/current [get]
+func GetCurrentOnCall(c *gin.Context) {
+	scheduleID, _ := strconv.ParseUint(c.Query("scheduleId"), 10, 64)
+	if scheduleID == 0 {
+		writeObservableError(c, http.StatusBadRequest, obserr.New("ALERT_SCHEDULE_ID_REQUIRED", "alert.GetCurrentOnCall", "scheduleId 不能为空"))
+		return
+	}
+	at := time.Now()
+	if raw := c.Query("at"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeObservableError(c, http.StatusBadRequest, obserr.Wrap("ALERT_INVALID_REQUEST", "alert.GetCurrentOnCall", "时间格式无效", err))
+			return
+		}
+		at = parsed
+	}
+	data, err := alertService.CurrentOnCall(c.GetUint("tenantID"), uint(scheduleID), at)
+	if err != nil {
+		status := http.StatusBadRequest
+		if alertService.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		writeObservableError(c, status, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// ListEscalationPolicies godoc
+// @Summary 获取升级策略列表
+// @Description 获取当前租户的告警升级策略
+// @Tags 告警管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /alert/escalation/policies [get]
+func ListEscalationPolicies(c *gin.Context) {
+	data, err := alertService.ListEscalationPolicies(c.GetUint("tenantID"))
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// UpsertEscalationPolicy godoc
+// @Summary 保存升级策略
+// @Description 创建或更新告警升级策略，未确认的告警按层级逐级通知
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body map[string]interface{} true "升级策略配置"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "升级策略不存在"
+// @Router /alert/escalation/policy/upsert [post]
+func UpsertEscalationPolicy(c *gin.Context) {
+	var req service.EscalationPolicyUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeObservableError(c, http.StatusBadRequest, obserr.Wrap("ALERT_INVALID_REQUEST", "alert.UpsertEscalationPolicy", "参数错误", err))
+		return
+	}
+	data, err := alertService.UpsertEscalationPolicy(c.GetUint("tenantID"), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if alertService.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		writeObservableError(c, status, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// AckAlertHistory godoc
+// @Summary 确认告警
+// @Description 确认未恢复的告警并停止升级，记录确认人和时间
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body map[string]interface{} true "告警记录ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "告警记录不存在"
+// @Router /alert/history/ack [post]
+func AckAlertHistory(c *gin.Context) {
+	handleHistoryAction(c, "alert.AckAlertHistory", alertService.AckHistory)
+}
+
+// ResolveAlertHistory godoc
+// @Summary 手动恢复告警
+// @Description 将未恢复的告警标记为已恢复，记录处理人和时间
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body map[string]interface{} true "告警记录ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "告警记录不存在"
+// @Router /alert/history/resolve [post]
+func ResolveAlertHistory(c *gin.Context) {
+	handleHistoryAction(c, "alert.ResolveAlertHistory", alertService.ResolveHistory)
+}
+
+func handleHistoryAction(c *gin.Context, op string, action func(tenantID, id uint, actor string) (model.History, error)) {
+	var req service.HistoryActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+		writeObservableError(c, http.StatusBadRequest, obserr.New("ALERT_HISTORY_ID_REQUIRED", op, "告警记录 id 不能为空"))
+		return
+	}
+	data, err := action(c.GetUint("tenantID"), req.ID, c.GetString("username"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if alertService.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		writeObservableError(c, status, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    data,
+	})
+}
@@ -1,59 +1,227 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	logModel "devops-platform/internal/modules/log/model"
+
+	"gorm.io/gorm"
+)
+
+// Alert lifecycle states tracked by the rule evaluator and stored on History.
+const (
+	StatePending   = "pending"
+	StateFiring    = "firing"
+	StateSilenced  = "silenced"
+	StateInhibited = "inhibited"
+	StateResolved  = "resolved"
+)
+
+// History sources: rows the rule evaluator raised itself, and rows ingested
+// from the Alertmanager webhook.
+const (
+	HistorySourceRule    = "rule"
+	HistorySourceWebhook = "webhook"
+)
+
+// OpenStates are the History statuses of an alert that has not resolved yet.
+var OpenStates = []string{StateFiring, StateSilenced, StateInhibited}
+
+// Matcher operators, following Alertmanager semantics.
+const (
+	MatchEqual     = "="
+	MatchNotEqual  = "!="
+	MatchRegexp    = "=~"
+	MatchNotRegexp = "!~"
+)
+
+// Matcher is a single label matcher on a silence.
+type Matcher struct {
+	Name  string `json:"name"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+// Rule types. A Prometheus rule alerts on every series its PromQL expression
+// returns; a log rule alerts when its log search matches more than Threshold
+// entries within LogWindow.
+const (
+	RuleTypePrometheus = "prometheus"
+	RuleTypeLog        = "log"
+)
 
 type Rule struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Expr        string    `json:"expr"`
-	Severity    string    `json:"severity"`
-	Enabled     bool      `json:"enabled"`
-	Cluster     string    `json:"cluster"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	Description string    `json:"description"`
+	ID                 uint                    `gorm:"primaryKey" json:"id"`
+	TenantID           uint                    `gorm:"index;not null" json:"tenantId"`
+	Name               string                  `gorm:"size:128;not null" json:"name"`
+	Type               string                  `gorm:"size:16;default:'prometheus'" json:"type"`
+	Expr               string                  `gorm:"type:text;not null" json:"expr"`
+	Severity           string                  `gorm:"size:32;not null" json:"severity"`
+	Enabled            bool                    `json:"enabled"`
+	Cluster            string                  `gorm:"size:128" json:"cluster"`
+	Description        string                  `gorm:"size:512" json:"description"`
+	PrometheusConfigID uint                    `gorm:"index" json:"prometheusConfigId"`
+	SavedQueryID       uint                    `gorm:"index" json:"savedQueryId"` // the saved log query a log rule was created from
+	LogSourceID        uint                    `gorm:"index" json:"logSourceId"`
+	LogQuery           *logModel.SearchRequest `gorm:"type:text;serializer:json" json:"logQuery,omitempty"` // filters only; source, paging and time range are ignored
+	LogWindow          string                  `gorm:"size:32" json:"logWindow"`                            // e.g. "5m", the span a log rule counts over
+	Threshold          int64                   `json:"threshold"`                                           // a log rule fires above this many matches
+	EscalationPolicyID uint                    `gorm:"index" json:"escalationPolicyId"`
+	For                string                  `gorm:"column:for_duration;size:32" json:"for"` // e.g. "5m", empty fires immediately
+	CreatedAt          time.Time               `json:"createdAt"`
+	UpdatedAt          time.Time               `json:"updatedAt"`
+	DeletedAt          gorm.DeletedAt          `gorm:"index" json:"-"`
 }
 
+func (Rule) TableName() string { return "alert_rules" }
+
 type Silence struct {
-	ID        uint      `json:"id"`
-	RuleID    uint      `json:"ruleId"`
-	Reason    string    `json:"reason"`
-	StartsAt  time.Time `json:"startsAt"`
-	EndsAt    time.Time `json:"endsAt"`
-	CreatedBy string    `json:"createdBy"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	TenantID  uint           `gorm:"index;not null" json:"tenantId"`
+	RuleID    uint           `gorm:"index" json:"ruleId"`
+	Matchers  []Matcher      `gorm:"type:text;serializer:json" json:"matchers"`
+	Reason    string         `gorm:"size:512" json:"reason"`
+	StartsAt  time.Time      `json:"startsAt"`
+	EndsAt    time.Time      `json:"endsAt"`
+	CreatedBy string         `gorm:"size:128" json:"createdBy"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (Silence) TableName() string { return "alert_silences" }
+
+// InhibitEqual requires the Source label of the inhibiting alert and the
+// Target label of the inhibited alert to carry the same value. Target
+// defaults to Source.
+type InhibitEqual struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// InhibitRule suppresses alerts matching TargetMatchers while an alert
+// matching SourceMatchers is open with the same Equal label values.
+type InhibitRule struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	TenantID       uint           `gorm:"index;not null" json:"tenantId"`
+	Name           string         `gorm:"size:128;not null" json:"name"`
+	SourceMatchers []Matcher      `gorm:"type:text;serializer:json" json:"sourceMatchers"`
+	TargetMatchers []Matcher      `gorm:"type:text;serializer:json" json:"targetMatchers"`
+	Equal          []InhibitEqual `gorm:"type:text;serializer:json" json:"equal"`
+	Enabled        bool           `json:"enabled"`
+	Description    string         `gorm:"size:512" json:"description"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+func (InhibitRule) TableName() string { return "alert_inhibit_rules" }
+
 type NotificationChannel struct {
-	ID        uint      `json:"id"`
-	Name      string    `json:"name"`
-	Type      string    `json:"type"`
-	Target    string    `json:"target"`
-	Enabled   bool      `json:"enabled"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	TenantID  uint           `gorm:"index;not null" json:"tenantId"`
+	Name      string         `gorm:"size:128;not null" json:"name"`
+	Type      string         `gorm:"size:32;not null" json:"type"`
+	Target    string         `gorm:"size:512;not null" json:"target"`
+	Enabled   bool           `json:"enabled"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+func (NotificationChannel) TableName() string { return "alert_channels" }
+
+// History is one alert occurrence. EscalationLevel counts the escalation
+// levels paged so far; AckedAt stops further escalation.
 type History struct {
-	ID          uint      `json:"id"`
-	RuleID      uint      `json:"ruleId"`
-	RuleName    string    `json:"ruleName"`
-	Status      string    `json:"status"`
-	Severity    string    `json:"severity"`
-	Summary     string    `json:"summary"`
-	StartsAt    time.Time `json:"startsAt"`
-	EndsAt      time.Time `json:"endsAt"`
-	Cluster     string    `json:"cluster"`
-	Namespace   string    `json:"namespace"`
-	Instance    string    `json:"instance"`
-	Fingerprint string    `json:"fingerprint"`
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	TenantID           uint           `gorm:"index;not null" json:"tenantId"`
+	RuleID             uint           `gorm:"index" json:"ruleId"`
+	RuleName           string         `gorm:"size:128" json:"ruleName"`
+	Status             string         `gorm:"size:32;index" json:"status"`
+	Severity           string         `gorm:"size:32" json:"severity"`
+	Summary            string         `gorm:"size:1024" json:"summary"`
+	StartsAt           time.Time      `gorm:"index" json:"startsAt"`
+	EndsAt             time.Time      `json:"endsAt"`
+	Cluster            string         `gorm:"size:128" json:"cluster"`
+	Namespace          string         `gorm:"size:128" json:"namespace"`
+	Instance           string         `gorm:"size:255" json:"instance"`
+	Fingerprint        string         `gorm:"size:64;index" json:"fingerprint"`
+	Source             string         `gorm:"size:16;index;default:'rule'" json:"source"`
+	Labels             string         `gorm:"type:text" json:"labels"`
+	EscalationPolicyID uint           `gorm:"index" json:"escalationPolicyId"`
+	EscalationLevel    int            `json:"escalationLevel"`
+	LastEscalatedAt    *time.Time     `json:"lastEscalatedAt"`
+	AckedBy            string         `gorm:"size:128" json:"ackedBy"`
+	AckedAt            *time.Time     `json:"ackedAt"`
+	ResolvedBy         string         `gorm:"size:128" json:"resolvedBy"`
+	ResolvedAt         *time.Time     `json:"resolvedAt"`
+	CreatedAt          time.Time      `json:"createdAt"`
+	UpdatedAt          time.Time      `json:"updatedAt"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (History) TableName() string { return "alert_histories" }
+
+// LabelMap decodes the JSON-encoded Labels column.
+func (h History) LabelMap() map[string]string {
+	labels := map[string]string{}
+	if h.Labels != "" {
+		_ = json.Unmarshal([]byte(h.Labels), &labels)
+	}
+	return labels
+}
+
+// EncodeLabels serializes a label set for the History.Labels column.
+func EncodeLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(labels)
+	return string(data)
 }
 
 type AlertmanagerConfig struct {
-	Endpoint              string    `json:"endpoint"`
-	APIPath               string    `json:"apiPath"`
-	TimeoutSeconds        int       `json:"timeoutSeconds"`
-	Username              string    `json:"username"`
-	Password              string    `json:"password"`
-	BearerToken           string    `json:"bearerToken"`
-	TLSInsecureSkipVerify bool      `json:"tlsInsecureSkipVerify"`
-	UpdatedAt             time.Time `json:"updatedAt"`
+	ID                    uint           `gorm:"primaryKey" json:"id"`
+	TenantID              uint           `gorm:"uniqueIndex;not null" json:"tenantId"`
+	Endpoint              string         `gorm:"size:512;not null" json:"endpoint"`
+	APIPath               string         `gorm:"size:255" json:"apiPath"`
+	TimeoutSeconds        int            `gorm:"default:10" json:"timeoutSeconds"`
+	Username              string         `gorm:"size:128" json:"username"`
+	Password              string         `gorm:"size:256" json:"password"`
+	BearerToken           string         `gorm:"size:1024" json:"bearerToken"`
+	TLSInsecureSkipVerify bool           `json:"tlsInsecureSkipVerify"`
+	CreatedAt             time.Time      `json:"createdAt"`
+	UpdatedAt             time.Time      `json:"updatedAt"`
+	DeletedAt             gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (AlertmanagerConfig) TableName() string { return "alert_alertmanager_configs" }
+
+// GroupedAlert is one alert tracked inside a NotificationGroup.
+type GroupedAlert struct {
+	Fingerprint string            `json:"fingerprint"`
+	RuleName    string            `json:"ruleName"`
+	Severity    string            `json:"severity"`
+	Status      string            `json:"status"`
+	Summary     string            `json:"summary"`
+	Labels      map[string]string `json:"labels"`
 }
+
+// NotificationGroup is the persisted notification state of one alert group,
+// so a restart neither drops queued alerts nor re-pages groups already sent.
+type NotificationGroup struct {
+	ID             uint              `gorm:"primaryKey" json:"id"`
+	TenantID       uint              `gorm:"uniqueIndex:idx_alert_group_key;not null" json:"tenantId"`
+	GroupKey       string            `gorm:"size:64;uniqueIndex:idx_alert_group_key;not null" json:"groupKey"`
+	GroupLabels    map[string]string `gorm:"type:text;serializer:json" json:"groupLabels"`
+	Alerts         []GroupedAlert    `gorm:"type:text;serializer:json" json:"alerts"`
+	Changed        bool              `json:"changed"`
+	FirstSeenAt    time.Time         `json:"firstSeenAt"`
+	LastNotifiedAt *time.Time        `json:"lastNotifiedAt"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	UpdatedAt      time.Time         `json:"updatedAt"`
+}
+
+func (NotificationGroup) TableName() string { return "alert_notification_groups" }
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// On-call rotation lengths.
+const (
+	RotationDaily  = "daily"
+	RotationWeekly = "weekly"
+)
+
+// OnCallLayer rotates through UserIDs, handing off every day or week at the
+// time of day of StartAt. Later layers take precedence over earlier ones
+// while they are active.
+type OnCallLayer struct {
+	Name     string     `json:"name"`
+	Rotation string     `json:"rotation"`
+	UserIDs  []uint     `json:"userIds"`
+	StartAt  time.Time  `json:"startAt"`
+	EndAt    *time.Time `json:"endAt,omitempty"`
+}
+
+// OnCallOverride puts a user on call for a fixed window, above all layers.
+type OnCallOverride struct {
+	UserID   uint      `json:"userId"`
+	StartsAt time.Time `json:"startsAt"`
+	EndsAt   time.Time `json:"endsAt"`
+}
+
+type OnCallSchedule struct {
+	ID          uint             `gorm:"primaryKey" json:"id"`
+	TenantID    uint             `gorm:"index;not null" json:"tenantId"`
+	Name        string           `gorm:"size:128;not null" json:"name"`
+	Description string           `gorm:"size:512" json:"description"`
+	Layers      []OnCallLayer    `gorm:"type:text;serializer:json" json:"layers"`
+	Overrides   []OnCallOverride `gorm:"type:text;serializer:json" json:"overrides"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt   `gorm:"index" json:"-"`
+}
+
+func (OnCallSchedule) TableName() string { return "alert_oncall_schedules" }
+
+// EscalationLevel pages the on-call user of ScheduleID plus UserIDs.
+// DelayMinutes is how long the previous level has to acknowledge before this
+// level is paged; it is ignored on the first level, which pages immediately.
+type EscalationLevel struct {
+	DelayMinutes int    `json:"delayMinutes"`
+	ScheduleID   uint   `json:"scheduleId"`
+	UserIDs      []uint `json:"userIds"`
+}
+
+type EscalationPolicy struct {
+	ID          uint              `gorm:"primaryKey" json:"id"`
+	TenantID    uint              `gorm:"index;not null" json:"tenantId"`
+	Name        string            `gorm:"size:128;not null" json:"name"`
+	Description string            `gorm:"size:512" json:"description"`
+	Levels      []EscalationLevel `gorm:"type:text;serializer:json" json:"levels"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt    `gorm:"index" json:"-"`
+}
+
+func (EscalationPolicy) TableName() string { return "alert_escalation_policies" }
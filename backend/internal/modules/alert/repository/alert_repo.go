@@ -3,223 +3,521 @@ package repository
 import (
 	"errors"
 	"strings"
-	"sync"
 	"time"
 
 	"devops-platform/internal/modules/alert/model"
+	userModel "devops-platform/internal/modules/user/model"
+	"devops-platform/internal/pkg/obserr"
+
+	"gorm.io/gorm"
 )
 
+const op = "alert/repository"
+
+const (
+	defaultAlertmanagerEndpoint = "http://alertmanager.monitoring.svc:9093"
+	defaultAlertmanagerAPIPath  = "/api/v2/alerts"
+)
+
+// AlertRepo persists alert rules, silences, channels, history and the
+// Alertmanager config per tenant.
 type AlertRepo struct {
-	mu       sync.RWMutex
-	nextID   uint
-	rules    []model.Rule
-	silences []model.Silence
-	channels []model.NotificationChannel
-	config   model.AlertmanagerConfig
-}
-
-func NewAlertRepo() *AlertRepo {
-	now := time.Now()
-	return &AlertRepo{
-		nextID: 100,
-		rules: []model.Rule{
-			{
-				ID:          1,
-				Name:        "PodCrashLooping",
-				Expr:        `sum(rate(kube_pod_container_status_restarts_total[5m])) > 0`,
-				Severity:    "warning",
-				Enabled:     true,
-				Cluster:     "default",
-				UpdatedAt:   now.Add(-30 * time.Minute),
-				Description: "容器重启频繁",
-			},
-			{
-				ID:          2,
-				Name:        "NodeMemoryHigh",
-				Expr:        `(1 - (node_memory_MemAvailable_bytes / node_memory_MemTotal_bytes)) > 0.9`,
-				Severity:    "critical",
-				Enabled:     true,
-				Cluster:     "prod-sh",
-				UpdatedAt:   now.Add(-2 * time.Hour),
-				Description: "节点内存使用率过高",
-			},
-			{
-				ID:          3,
-				Name:        "ApiServerLatencyP99",
-				Expr:        `histogram_quantile(0.99, sum(rate(apiserver_request_duration_seconds_bucket[5m])) by (le)) > 1`,
-				Severity:    "warning",
-				Enabled:     false,
-				Cluster:     "prod-bj",
-				UpdatedAt:   now.Add(-24 * time.Hour),
-				Description: "apiserver 延迟过高",
-			},
-		},
-		silences: []model.Silence{
-			{
-				ID:        11,
-				RuleID:    2,
-				Reason:    "生产变更窗口",
-				StartsAt:  now.Add(-10 * time.Minute),
-				EndsAt:    now.Add(50 * time.Minute),
-				CreatedBy: "sre",
-				UpdatedAt: now.Add(-10 * time.Minute),
-			},
-		},
-		channels: []model.NotificationChannel{
-			{
-				ID:        21,
-				Name:      "值班邮箱",
-				Type:      "email",
-				Target:    "sre@example.com",
-				Enabled:   true,
-				UpdatedAt: now.Add(-2 * time.Hour),
-			},
-			{
-				ID:        22,
-				Name:      "报警机器人",
-				Type:      "slack",
-				Target:    "#prod-alert",
-				Enabled:   true,
-				UpdatedAt: now.Add(-30 * time.Minute),
-			},
-		},
-		config: model.AlertmanagerConfig{
-			Endpoint:       "http://alertmanager.monitoring.svc:9093",
-			APIPath:        "/api/v2/alerts",
-			TimeoutSeconds: 10,
-			UpdatedAt:      now,
-		},
+	db *gorm.DB
+}
+
+func NewAlertRepo(db *gorm.DB) *AlertRepo {
+	return &AlertRepo{db: db}
+}
+
+// --- Rules ---
+
+func (r *AlertRepo) ListRules(tenantID uint) ([]model.Rule, error) {
+	var rules []model.Rule
+	if err := r.db.Where("tenant_id = ?", tenantID).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list alert rules failed", err)
 	}
+	return rules, nil
 }
 
-func (r *AlertRepo) ListRules() []model.Rule {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return append([]model.Rule(nil), r.rules...)
+// ListEnabledRules returns enabled rules across all tenants for the evaluator.
+func (r *AlertRepo) ListEnabledRules() ([]model.Rule, error) {
+	var rules []model.Rule
+	if err := r.db.Where("enabled = ?", true).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list enabled alert rules failed", err)
+	}
+	return rules, nil
 }
 
-func (r *AlertRepo) SetRuleEnabled(id uint, enabled bool) (model.Rule, bool) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	for i := range r.rules {
-		if r.rules[i].ID != id {
-			continue
-		}
-		r.rules[i].Enabled = enabled
-		r.rules[i].UpdatedAt = time.Now()
-		return r.rules[i], true
+func (r *AlertRepo) GetRule(tenantID, id uint) (model.Rule, bool, error) {
+	var rule model.Rule
+	err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.Rule{}, false, nil
+	}
+	if err != nil {
+		return model.Rule{}, false, obserr.Wrap("DB_ERROR", op, "get alert rule failed", err)
+	}
+	return rule, true, nil
+}
+
+// FindRuleByName looks up a tenant's rule by name, used to link externally
+// ingested alerts back to a local rule.
+func (r *AlertRepo) FindRuleByName(tenantID uint, name string) (model.Rule, bool, error) {
+	var rule model.Rule
+	err := r.db.Where("tenant_id = ? AND name = ?", tenantID, name).First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.Rule{}, false, nil
+	}
+	if err != nil {
+		return model.Rule{}, false, obserr.Wrap("DB_ERROR", op, "find alert rule failed", err)
+	}
+	return rule, true, nil
+}
+
+func (r *AlertRepo) SaveRule(rule *model.Rule) error {
+	if err := r.db.Save(rule).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "save alert rule failed", err)
+	}
+	return nil
+}
+
+func (r *AlertRepo) SetRuleEnabled(tenantID, id uint, enabled bool) (model.Rule, bool, error) {
+	rule, ok, err := r.GetRule(tenantID, id)
+	if err != nil || !ok {
+		return model.Rule{}, ok, err
 	}
-	return model.Rule{}, false
+	if err := r.db.Model(&rule).Update("enabled", enabled).Error; err != nil {
+		return model.Rule{}, false, obserr.Wrap("DB_ERROR", op, "update alert rule failed", err)
+	}
+	rule.Enabled = enabled
+	return rule, true, nil
 }
 
-func (r *AlertRepo) ListSilences() []model.Silence {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return append([]model.Silence(nil), r.silences...)
+// --- Silences ---
+
+func (r *AlertRepo) ListSilences(tenantID uint) ([]model.Silence, error) {
+	var silences []model.Silence
+	if err := r.db.Where("tenant_id = ?", tenantID).Order("id ASC").Find(&silences).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list alert silences failed", err)
+	}
+	return silences, nil
 }
 
-func (r *AlertRepo) UpsertSilence(silence model.Silence) model.Silence {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	silence.UpdatedAt = time.Now()
+// ListActiveSilences returns the given tenants' silences whose window covers at.
+func (r *AlertRepo) ListActiveSilences(tenantIDs []uint, at time.Time) ([]model.Silence, error) {
+	var silences []model.Silence
+	if len(tenantIDs) == 0 {
+		return silences, nil
+	}
+	if err := r.db.Where("tenant_id IN ? AND starts_at <= ? AND ends_at > ?", tenantIDs, at, at).Find(&silences).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list active alert silences failed", err)
+	}
+	return silences, nil
+}
+
+// UpsertSilence creates the silence, or updates it when it has an ID; an ID
+// not found within the tenant is an error.
+func (r *AlertRepo) UpsertSilence(silence model.Silence) (model.Silence, error) {
 	if silence.ID > 0 {
-		for i := range r.silences {
-			if r.silences[i].ID == silence.ID {
-				r.silences[i] = silence
-				return silence
-			}
+		var existing model.Silence
+		err := r.db.Where("id = ? AND tenant_id = ?", silence.ID, silence.TenantID).First(&existing).Error
+		switch {
+		case err == nil:
+			silence.CreatedAt = existing.CreatedAt
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return model.Silence{}, obserr.New("ALERT_SILENCE_NOT_FOUND", op, "告警静默不存在")
+		default:
+			return model.Silence{}, obserr.Wrap("DB_ERROR", op, "get alert silence failed", err)
 		}
 	}
-	r.nextID++
-	silence.ID = r.nextID
-	r.silences = append(r.silences, silence)
-	return silence
+	if err := r.db.Save(&silence).Error; err != nil {
+		return model.Silence{}, obserr.Wrap("DB_ERROR", op, "save alert silence failed", err)
+	}
+	return silence, nil
 }
 
-func (r *AlertRepo) ListChannels() []model.NotificationChannel {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return append([]model.NotificationChannel(nil), r.channels...)
+// --- Inhibit rules ---
+
+func (r *AlertRepo) ListInhibitRules(tenantID uint) ([]model.InhibitRule, error) {
+	var rules []model.InhibitRule
+	if err := r.db.Where("tenant_id = ?", tenantID).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list alert inhibit rules failed", err)
+	}
+	return rules, nil
 }
 
-func (r *AlertRepo) UpsertChannel(channel model.NotificationChannel) model.NotificationChannel {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	channel.UpdatedAt = time.Now()
+// ListEnabledInhibitRules returns enabled inhibit rules across all tenants.
+func (r *AlertRepo) ListEnabledInhibitRules() ([]model.InhibitRule, error) {
+	var rules []model.InhibitRule
+	if err := r.db.Where("enabled = ?", true).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list enabled alert inhibit rules failed", err)
+	}
+	return rules, nil
+}
+
+// UpsertInhibitRule creates the rule, or updates it when it has an ID; an ID
+// not found within the tenant is an error.
+func (r *AlertRepo) UpsertInhibitRule(rule model.InhibitRule) (model.InhibitRule, error) {
+	if rule.ID > 0 {
+		var existing model.InhibitRule
+		err := r.db.Where("id = ? AND tenant_id = ?", rule.ID, rule.TenantID).First(&existing).Error
+		switch {
+		case err == nil:
+			rule.CreatedAt = existing.CreatedAt
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return model.InhibitRule{}, obserr.New("ALERT_INHIBIT_NOT_FOUND", op, "抑制规则不存在")
+		default:
+			return model.InhibitRule{}, obserr.Wrap("DB_ERROR", op, "get alert inhibit rule failed", err)
+		}
+	}
+	if err := r.db.Save(&rule).Error; err != nil {
+		return model.InhibitRule{}, obserr.Wrap("DB_ERROR", op, "save alert inhibit rule failed", err)
+	}
+	return rule, nil
+}
+
+// --- Channels ---
+
+func (r *AlertRepo) ListChannels(tenantID uint) ([]model.NotificationChannel, error) {
+	var channels []model.NotificationChannel
+	if err := r.db.Where("tenant_id = ?", tenantID).Order("id ASC").Find(&channels).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list alert channels failed", err)
+	}
+	return channels, nil
+}
+
+// UpsertChannel creates the channel, or updates it when it has an ID; an ID
+// not found within the tenant is an error.
+func (r *AlertRepo) UpsertChannel(channel model.NotificationChannel) (model.NotificationChannel, error) {
 	if channel.ID > 0 {
-		for i := range r.channels {
-			if r.channels[i].ID == channel.ID {
-				r.channels[i] = channel
-				return channel
-			}
+		var existing model.NotificationChannel
+		err := r.db.Where("id = ? AND tenant_id = ?", channel.ID, channel.TenantID).First(&existing).Error
+		switch {
+		case err == nil:
+			channel.CreatedAt = existing.CreatedAt
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return model.NotificationChannel{}, obserr.New("ALERT_CHANNEL_NOT_FOUND", op, "通知渠道不存在")
+		default:
+			return model.NotificationChannel{}, obserr.Wrap("DB_ERROR", op, "get alert channel failed", err)
+		}
+	}
+	if err := r.db.Save(&channel).Error; err != nil {
+		return model.NotificationChannel{}, obserr.Wrap("DB_ERROR", op, "save alert channel failed", err)
+	}
+	return channel, nil
+}
+
+// --- History ---
+
+// ListHistory returns the tenant's alert history, newest first. A zero start
+// or end leaves that side of the range open.
+func (r *AlertRepo) ListHistory(tenantID uint, status string, start, end time.Time) ([]model.History, error) {
+	q := r.db.Where("tenant_id = ?", tenantID)
+	if status != "" {
+		q = q.Where("LOWER(status) = ?", status)
+	}
+	if !start.IsZero() {
+		q = q.Where("starts_at >= ?", start)
+	}
+	if !end.IsZero() {
+		q = q.Where("starts_at <= ?", end)
+	}
+	var history []model.History
+	if err := q.Order("starts_at DESC").Find(&history).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list alert history failed", err)
+	}
+	return history, nil
+}
+
+// ListOpenHistory returns unresolved history rows across all tenants,
+// limited to one source unless source is empty.
+func (r *AlertRepo) ListOpenHistory(source string) ([]model.History, error) {
+	var history []model.History
+	q := r.db.Where("status IN ?", model.OpenStates)
+	if source != "" {
+		q = q.Where("source = ?", source)
+	}
+	if err := q.Find(&history).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list open alert history failed", err)
+	}
+	return history, nil
+}
+
+// ListTenantOpenHistory returns a tenant's unresolved history rows, newest first.
+func (r *AlertRepo) ListTenantOpenHistory(tenantID uint) ([]model.History, error) {
+	var history []model.History
+	if err := r.db.Where("tenant_id = ? AND status IN ?", tenantID, model.OpenStates).
+		Order("starts_at DESC").Find(&history).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list open alert history failed", err)
+	}
+	return history, nil
+}
+
+// FindLatestHistory returns the latest history row for a fingerprint,
+// resolved or not.
+func (r *AlertRepo) FindLatestHistory(tenantID uint, fingerprint string) (model.History, bool, error) {
+	var item model.History
+	err := r.db.Where("tenant_id = ? AND fingerprint = ?", tenantID, fingerprint).
+		Order("id DESC").First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.History{}, false, nil
+	}
+	if err != nil {
+		return model.History{}, false, obserr.Wrap("DB_ERROR", op, "find alert history failed", err)
+	}
+	return item, true, nil
+}
+
+func (r *AlertRepo) CreateHistory(item *model.History) error {
+	if err := r.db.Create(item).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "create alert history failed", err)
+	}
+	return nil
+}
+
+func (r *AlertRepo) SaveHistory(item *model.History) error {
+	if err := r.db.Save(item).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "save alert history failed", err)
+	}
+	return nil
+}
+
+func (r *AlertRepo) GetHistory(id uint) (model.History, error) {
+	var item model.History
+	if err := r.db.First(&item, id).Error; err != nil {
+		return model.History{}, obserr.Wrap("DB_ERROR", op, "get alert history failed", err)
+	}
+	return item, nil
+}
+
+// GetTenantHistory returns a history row only if it belongs to the tenant.
+func (r *AlertRepo) GetTenantHistory(tenantID, id uint) (model.History, bool, error) {
+	var item model.History
+	err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.History{}, false, nil
+	}
+	if err != nil {
+		return model.History{}, false, obserr.Wrap("DB_ERROR", op, "get alert history failed", err)
+	}
+	return item, true, nil
+}
+
+// AckHistory acknowledges an open, unacknowledged history row. It reports
+// false when the row was resolved or acknowledged in the meantime.
+func (r *AlertRepo) AckHistory(tenantID, id uint, actor string, at time.Time) (bool, error) {
+	res := r.db.Model(&model.History{}).
+		Where("id = ? AND tenant_id = ? AND status IN ? AND acked_at IS NULL", id, tenantID, model.OpenStates).
+		Updates(map[string]interface{}{"acked_by": actor, "acked_at": at})
+	if res.Error != nil {
+		return false, obserr.Wrap("DB_ERROR", op, "ack alert history failed", res.Error)
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// ResolveHistory resolves an open history row by hand. It reports false when
+// the row was resolved in the meantime.
+func (r *AlertRepo) ResolveHistory(tenantID, id uint, actor string, at time.Time) (bool, error) {
+	res := r.db.Model(&model.History{}).
+		Where("id = ? AND tenant_id = ? AND status IN ?", id, tenantID, model.OpenStates).
+		Updates(map[string]interface{}{"status": model.StateResolved, "ends_at": at, "resolved_by": actor, "resolved_at": at})
+	if res.Error != nil {
+		return false, obserr.Wrap("DB_ERROR", op, "resolve alert history failed", res.Error)
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// AdvanceEscalation records that the given level of a history row was paged.
+// It reports false when the row was acknowledged, stopped firing or was
+// escalated by someone else since it was loaded.
+func (r *AlertRepo) AdvanceEscalation(id uint, level int, at time.Time) (bool, error) {
+	res := r.db.Model(&model.History{}).
+		Where("id = ? AND status = ? AND acked_at IS NULL AND escalation_level = ?", id, model.StateFiring, level-1).
+		Updates(map[string]interface{}{"escalation_level": level, "last_escalated_at": at})
+	if res.Error != nil {
+		return false, obserr.Wrap("DB_ERROR", op, "update alert escalation failed", res.Error)
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// ListEscalatingHistory returns firing, unacknowledged history rows that have
+// an escalation policy, across all tenants.
+func (r *AlertRepo) ListEscalatingHistory() ([]model.History, error) {
+	var history []model.History
+	if err := r.db.Where("status = ? AND acked_at IS NULL AND escalation_policy_id > 0", model.StateFiring).
+		Order("id ASC").Find(&history).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list escalating alert history failed", err)
+	}
+	return history, nil
+}
+
+// --- On-call schedules ---
+
+func (r *AlertRepo) ListSchedules(tenantID uint) ([]model.OnCallSchedule, error) {
+	var schedules []model.OnCallSchedule
+	if err := r.db.Where("tenant_id = ?", tenantID).Order("id ASC").Find(&schedules).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list oncall schedules failed", err)
+	}
+	return schedules, nil
+}
+
+func (r *AlertRepo) GetSchedule(tenantID, id uint) (model.OnCallSchedule, bool, error) {
+	var schedule model.OnCallSchedule
+	err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&schedule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.OnCallSchedule{}, false, nil
+	}
+	if err != nil {
+		return model.OnCallSchedule{}, false, obserr.Wrap("DB_ERROR", op, "get oncall schedule failed", err)
+	}
+	return schedule, true, nil
+}
+
+// UpsertSchedule creates the schedule, or updates it when it has an ID; an ID
+// not found within the tenant is an error.
+func (r *AlertRepo) UpsertSchedule(schedule model.OnCallSchedule) (model.OnCallSchedule, error) {
+	if schedule.ID > 0 {
+		existing, ok, err := r.GetSchedule(schedule.TenantID, schedule.ID)
+		if err != nil {
+			return model.OnCallSchedule{}, err
 		}
+		if !ok {
+			return model.OnCallSchedule{}, obserr.New("ALERT_SCHEDULE_NOT_FOUND", op, "值班表不存在")
+		}
+		schedule.CreatedAt = existing.CreatedAt
+	}
+	if err := r.db.Save(&schedule).Error; err != nil {
+		return model.OnCallSchedule{}, obserr.Wrap("DB_ERROR", op, "save oncall schedule failed", err)
+	}
+	return schedule, nil
+}
+
+// --- Escalation policies ---
+
+func (r *AlertRepo) ListEscalationPolicies(tenantID uint) ([]model.EscalationPolicy, error) {
+	var policies []model.EscalationPolicy
+	if err := r.db.Where("tenant_id = ?", tenantID).Order("id ASC").Find(&policies).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list escalation policies failed", err)
+	}
+	return policies, nil
+}
+
+func (r *AlertRepo) GetEscalationPolicy(tenantID, id uint) (model.EscalationPolicy, bool, error) {
+	var policy model.EscalationPolicy
+	err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.EscalationPolicy{}, false, nil
+	}
+	if err != nil {
+		return model.EscalationPolicy{}, false, obserr.Wrap("DB_ERROR", op, "get escalation policy failed", err)
+	}
+	return policy, true, nil
+}
+
+// UpsertEscalationPolicy creates the policy, or updates it when it has an ID;
+// an ID not found within the tenant is an error.
+func (r *AlertRepo) UpsertEscalationPolicy(policy model.EscalationPolicy) (model.EscalationPolicy, error) {
+	if policy.ID > 0 {
+		existing, ok, err := r.GetEscalationPolicy(policy.TenantID, policy.ID)
+		if err != nil {
+			return model.EscalationPolicy{}, err
+		}
+		if !ok {
+			return model.EscalationPolicy{}, obserr.New("ALERT_ESCALATION_NOT_FOUND", op, "升级策略不存在")
+		}
+		policy.CreatedAt = existing.CreatedAt
+	}
+	if err := r.db.Save(&policy).Error; err != nil {
+		return model.EscalationPolicy{}, obserr.Wrap("DB_ERROR", op, "save escalation policy failed", err)
+	}
+	return policy, nil
+}
+
+// ListUsers returns the tenant's users with the given IDs.
+func (r *AlertRepo) ListUsers(tenantID uint, ids []uint) ([]userModel.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var users []userModel.User
+	if err := r.db.Where("id IN ? AND tenant_id = ?", ids, tenantID).Find(&users).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list oncall users failed", err)
+	}
+	return users, nil
+}
+
+// --- Notification groups ---
+
+func (r *AlertRepo) ListNotificationGroups() ([]model.NotificationGroup, error) {
+	var groups []model.NotificationGroup
+	if err := r.db.Order("id ASC").Find(&groups).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list alert notification groups failed", err)
+	}
+	return groups, nil
+}
+
+func (r *AlertRepo) FindNotificationGroup(tenantID uint, groupKey string) (model.NotificationGroup, bool, error) {
+	var group model.NotificationGroup
+	err := r.db.Where("tenant_id = ? AND group_key = ?", tenantID, groupKey).First(&group).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.NotificationGroup{}, false, nil
+	}
+	if err != nil {
+		return model.NotificationGroup{}, false, obserr.Wrap("DB_ERROR", op, "find alert notification group failed", err)
+	}
+	return group, true, nil
+}
+
+func (r *AlertRepo) SaveNotificationGroup(group *model.NotificationGroup) error {
+	if err := r.db.Save(group).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "save alert notification group failed", err)
+	}
+	return nil
+}
+
+func (r *AlertRepo) DeleteNotificationGroup(id uint) error {
+	if err := r.db.Delete(&model.NotificationGroup{}, id).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "delete alert notification group failed", err)
+	}
+	return nil
+}
+
+// --- Alertmanager config ---
+
+// GetConfig returns the tenant's Alertmanager config, falling back to the
+// in-cluster default when none has been saved yet.
+func (r *AlertRepo) GetConfig(tenantID uint) (model.AlertmanagerConfig, error) {
+	var cfg model.AlertmanagerConfig
+	err := r.db.Where("tenant_id = ?", tenantID).First(&cfg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.AlertmanagerConfig{
+			TenantID:       tenantID,
+			Endpoint:       defaultAlertmanagerEndpoint,
+			APIPath:        defaultAlertmanagerAPIPath,
+			TimeoutSeconds: 10,
+		}, nil
+	}
+	if err != nil {
+		return model.AlertmanagerConfig{}, obserr.Wrap("DB_ERROR", op, "get alertmanager config failed", err)
+	}
+	return cfg, nil
+}
+
+func (r *AlertRepo) SaveConfig(cfg model.AlertmanagerConfig) (model.AlertmanagerConfig, error) {
+	var existing model.AlertmanagerConfig
+	err := r.db.Where("tenant_id = ?", cfg.TenantID).First(&existing).Error
+	switch {
+	case err == nil:
+		cfg.ID = existing.ID
+		cfg.CreatedAt = existing.CreatedAt
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		cfg.ID = 0
+	default:
+		return model.AlertmanagerConfig{}, obserr.Wrap("DB_ERROR", op, "get alertmanager config failed", err)
+	}
+	if err := r.db.Save(&cfg).Error; err != nil {
+		return model.AlertmanagerConfig{}, obserr.Wrap("DB_ERROR", op, "save alertmanager config failed", err)
 	}
-	r.nextID++
-	channel.ID = r.nextID
-	r.channels = append(r.channels, channel)
-	return channel
-}
-
-func (r *AlertRepo) ListHistory() []model.History {
-	now := time.Now()
-	return []model.History{
-		{
-			ID:          1001,
-			RuleID:      2,
-			RuleName:    "NodeMemoryHigh",
-			Status:      "firing",
-			Severity:    "critical",
-			Summary:     "node-1 内存使用率达到 92%",
-			StartsAt:    now.Add(-20 * time.Minute),
-			EndsAt:      time.Time{},
-			Cluster:     "prod-sh",
-			Namespace:   "kube-system",
-			Instance:    "node-1",
-			Fingerprint: "hist-1001",
-		},
-		{
-			ID:          1002,
-			RuleID:      1,
-			RuleName:    "PodCrashLooping",
-			Status:      "resolved",
-			Severity:    "warning",
-			Summary:     "payments-api 重启已恢复",
-			StartsAt:    now.Add(-3 * time.Hour),
-			EndsAt:      now.Add(-2 * time.Hour),
-			Cluster:     "default",
-			Namespace:   "payments",
-			Instance:    "payments-api-6f4f7d47f9-abcde",
-			Fingerprint: "hist-1002",
-		},
-		{
-			ID:          1003,
-			RuleID:      3,
-			RuleName:    "ApiServerLatencyP99",
-			Status:      "resolved",
-			Severity:    "warning",
-			Summary:     "apiserver P99 延迟恢复到 800ms",
-			StartsAt:    now.Add(-8 * time.Hour),
-			EndsAt:      now.Add(-7 * time.Hour),
-			Cluster:     "prod-bj",
-			Namespace:   "kube-system",
-			Instance:    "apiserver-1",
-			Fingerprint: "hist-1003",
-		},
-	}
-}
-
-func (r *AlertRepo) GetConfig() model.AlertmanagerConfig {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return r.config
-}
-
-func (r *AlertRepo) SaveConfig(cfg model.AlertmanagerConfig) model.AlertmanagerConfig {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	cfg.UpdatedAt = time.Now()
-	r.config = cfg
-	return r.config
+	return cfg, nil
 }
 
 func (r *AlertRepo) ValidateConfigConnection(cfg model.AlertmanagerConfig) error {
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -12,6 +13,7 @@ import (
 // AlertNotificationBridge wires alert module events to the notification hub.
 type AlertNotificationBridge struct {
 	notificationService *notifService.NotificationService
+	grouper             *AlertGrouper
 }
 
 func NewAlertNotificationBridge(ns *notifService.NotificationService) *AlertNotificationBridge {
@@ -29,13 +31,54 @@ type AlertInfo struct {
 	Labels      map[string]string
 }
 
+// SetGrouper routes SendAlert through the grouping stage instead of sending
+// one notification per alert.
+func (b *AlertNotificationBridge) SetGrouper(grouper *AlertGrouper) {
+	b.grouper = grouper
+}
+
+// Template names the bridge renders with when the tenant has defined them.
+// Their data is keyed like the notification module's alert and alert_group
+// preview samples.
+const (
+	alertTemplateName      = notifService.SampleAlert
+	alertGroupTemplateName = notifService.SampleAlertGroup
+)
+
 // SendAlert sends an alert via the notification hub.
-// It formats the alert body using the template engine and dispatches to all configured channels with fallback.
+// It renders the tenant's "alert" template, or the built-in format when there
+// is none, and dispatches to all configured channels with fallback.
+// When a grouper is set the alert is queued into its group and sent on the next group flush.
 func (b *AlertNotificationBridge) SendAlert(tenantID uint, alert AlertInfo) error {
+	if b.grouper != nil {
+		return b.grouper.Enqueue(tenantID, alert)
+	}
 	subject := fmt.Sprintf("[%s] %s - %s", strings.ToUpper(alert.Severity), alert.Status, alert.RuleName)
 	body := b.formatAlertBody(alert)
 
-	return b.notificationService.SendWithFallback(tenantID, nil, subject, body)
+	return b.send(tenantID, alertTemplateName, alertTemplateData(alert), subject, body)
+}
+
+// send renders and sends the tenant's template of the given name, falling
+// back to the pre-formatted subject and body when the tenant has none.
+func (b *AlertNotificationBridge) send(tenantID uint, templateName string, data map[string]interface{}, subject, body string) error {
+	err := b.notificationService.SendTemplate(tenantID, templateName, nil, data)
+	if errors.Is(err, notifService.ErrTemplateNotFound) {
+		return b.notificationService.SendWithFallback(tenantID, nil, subject, body)
+	}
+	return err
+}
+
+func alertTemplateData(alert AlertInfo) map[string]interface{} {
+	return map[string]interface{}{
+		"status":      alert.Status,
+		"rule_name":   alert.RuleName,
+		"severity":    alert.Severity,
+		"cluster":     alert.Cluster,
+		"expr":        alert.Expr,
+		"description": alert.Description,
+		"labels":      alert.Labels,
+	}
 }
 
 func (b *AlertNotificationBridge) formatAlertBody(alert AlertInfo) string {
@@ -61,8 +104,9 @@ func (b *AlertNotificationBridge) formatAlertBody(alert AlertInfo) string {
 	return sb.String()
 }
 
-// HandleAlertRuleTriggered is called when an alert rule fires.
-func (b *AlertNotificationBridge) HandleAlertRuleTriggered(tenantID uint, rule model.Rule, status string) error {
+// HandleAlertRuleTriggered is called when an evaluated rule's alert fires or
+// resolves; labels identify the series that triggered it.
+func (b *AlertNotificationBridge) HandleAlertRuleTriggered(tenantID uint, rule model.Rule, status string, labels map[string]string) error {
 	return b.SendAlert(tenantID, AlertInfo{
 		RuleName:    rule.Name,
 		Severity:    rule.Severity,
@@ -70,6 +114,7 @@ func (b *AlertNotificationBridge) HandleAlertRuleTriggered(tenantID uint, rule m
 		Expr:        rule.Expr,
 		Status:      status,
 		Description: rule.Description,
+		Labels:      labels,
 	})
 }
 
@@ -84,6 +129,12 @@ func NotifierAdapter(alertChannelType string) notifModel.ChannelType {
 		return notifModel.ChannelWeCom
 	case "email":
 		return notifModel.ChannelEmail
+	case "webhook":
+		return notifModel.ChannelWebhook
+	case "slack":
+		return notifModel.ChannelSlack
+	case "teams":
+		return notifModel.ChannelTeams
 	default:
 		return notifModel.ChannelFeishu
 	}
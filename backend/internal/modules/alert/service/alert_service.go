@@ -7,12 +7,17 @@ import (
 
 	"devops-platform/internal/modules/alert/model"
 	"devops-platform/internal/modules/alert/repository"
+	logModel "devops-platform/internal/modules/log/model"
 	"devops-platform/internal/pkg/obserr"
 	queryutil "devops-platform/internal/pkg/query"
+
+	"gorm.io/gorm"
 )
 
 type AlertService struct {
-	repo *repository.AlertRepo
+	repo         *repository.AlertRepo
+	bridge       *AlertNotificationBridge
+	savedQueries SavedLogQueries
 }
 
 type ListRulesResponse struct {
@@ -40,13 +45,34 @@ type RuleEnableRequest struct {
 	Enabled bool `json:"enabled"`
 }
 
+type RuleUpsertRequest struct {
+	ID                 uint                    `json:"id"`
+	Name               string                  `json:"name"`
+	Type               string                  `json:"type"` // prometheus (default) or log; log rules derive Expr from the log fields
+	Expr               string                  `json:"expr"`
+	Severity           string                  `json:"severity"`
+	Enabled            bool                    `json:"enabled"`
+	Cluster            string                  `json:"cluster"`
+	Description        string                  `json:"description"`
+	PrometheusConfigID uint                    `json:"prometheusConfigId"`
+	SavedQueryID       uint                    `json:"savedQueryId"` // log rules: take source and filters from a saved log query
+	LogSourceID        uint                    `json:"logSourceId"`
+	LogQuery           *logModel.SearchRequest `json:"logQuery"`
+	LogWindow          string                  `json:"logWindow"`
+	Threshold          int64                   `json:"threshold"`
+	EscalationPolicyID uint                    `json:"escalationPolicyId"`
+	For                string                  `json:"for"`
+	UserID             uint                    `json:"-"`
+}
+
 type SilenceUpsertRequest struct {
-	ID        uint      `json:"id"`
-	RuleID    uint      `json:"ruleId"`
-	Reason    string    `json:"reason"`
-	StartsAt  time.Time `json:"startsAt"`
-	EndsAt    time.Time `json:"endsAt"`
-	CreatedBy string    `json:"createdBy"`
+	ID        uint            `json:"id"`
+	RuleID    uint            `json:"ruleId"`
+	Matchers  []model.Matcher `json:"matchers"`
+	Reason    string          `json:"reason"`
+	StartsAt  time.Time       `json:"startsAt"`
+	EndsAt    time.Time       `json:"endsAt"`
+	CreatedBy string          `json:"createdBy"`
 }
 
 type ChannelUpsertRequest struct {
@@ -67,12 +93,15 @@ type SaveAlertmanagerConfigRequest struct {
 	TLSInsecureSkipVerify bool   `json:"tlsInsecureSkipVerify"`
 }
 
-func NewAlertService() *AlertService {
-	return &AlertService{repo: repository.NewAlertRepo()}
+func NewAlertService(db *gorm.DB) *AlertService {
+	return &AlertService{repo: repository.NewAlertRepo(db)}
 }
 
-func (s *AlertService) ListRules(keyword string) ListRulesResponse {
-	rules := s.repo.ListRules()
+func (s *AlertService) ListRules(tenantID uint, keyword string) (ListRulesResponse, error) {
+	rules, err := s.repo.ListRules(tenantID)
+	if err != nil {
+		return ListRulesResponse{}, err
+	}
 	items := make([]model.Rule, 0, len(rules))
 	for _, rule := range rules {
 		if !queryutil.MatchKeywordAny(keyword, rule.Name, rule.Expr, rule.Cluster, rule.Severity, rule.Description) {
@@ -80,40 +109,109 @@ func (s *AlertService) ListRules(keyword string) ListRulesResponse {
 		}
 		items = append(items, rule)
 	}
-	return ListRulesResponse{Total: len(items), Items: items}
+	return ListRulesResponse{Total: len(items), Items: items}, nil
 }
 
-func (s *AlertService) ListHistory(status string, start, end time.Time) ListHistoryResponse {
-	status = strings.TrimSpace(strings.ToLower(status))
-	history := s.repo.ListHistory()
-	items := make([]model.History, 0, len(history))
-	for _, item := range history {
-		if status != "" && strings.ToLower(item.Status) != status {
-			continue
+func (s *AlertService) UpsertRule(tenantID uint, req RuleUpsertRequest) (model.Rule, error) {
+	ruleType := strings.TrimSpace(strings.ToLower(req.Type))
+	switch ruleType {
+	case "":
+		ruleType = model.RuleTypePrometheus
+	case model.RuleTypePrometheus, model.RuleTypeLog:
+	default:
+		return model.Rule{}, obserr.New("ALERT_RULE_INVALID", "alert.UpsertRule", "不支持的规则类型")
+	}
+	if strings.TrimSpace(req.Name) == "" || (ruleType == model.RuleTypePrometheus && strings.TrimSpace(req.Expr) == "") {
+		return model.Rule{}, obserr.New("ALERT_RULE_INVALID", "alert.UpsertRule", "规则名称和表达式不能为空")
+	}
+	forDuration := strings.TrimSpace(req.For)
+	if forDuration != "" {
+		if d, err := time.ParseDuration(forDuration); err != nil || d < 0 {
+			return model.Rule{}, obserr.New("ALERT_RULE_FOR_INVALID", "alert.UpsertRule", "for 持续时间格式无效")
 		}
-		if !start.IsZero() && item.StartsAt.Before(start) {
-			continue
+	}
+	severity := strings.TrimSpace(strings.ToLower(req.Severity))
+	if severity == "" {
+		severity = "warning"
+	}
+	if req.EscalationPolicyID > 0 {
+		if _, ok, err := s.repo.GetEscalationPolicy(tenantID, req.EscalationPolicyID); err != nil {
+			return model.Rule{}, err
+		} else if !ok {
+			return model.Rule{}, obserr.New("ALERT_ESCALATION_NOT_FOUND", "alert.UpsertRule", "升级策略不存在")
 		}
-		if !end.IsZero() && item.StartsAt.After(end) {
-			continue
+	}
+	rule := model.Rule{
+		TenantID:           tenantID,
+		Name:               strings.TrimSpace(req.Name),
+		Expr:               strings.TrimSpace(req.Expr),
+		Severity:           severity,
+		Enabled:            req.Enabled,
+		Cluster:            strings.TrimSpace(req.Cluster),
+		Description:        req.Description,
+		PrometheusConfigID: req.PrometheusConfigID,
+		EscalationPolicyID: req.EscalationPolicyID,
+		For:                forDuration,
+		Type:               ruleType,
+	}
+	if ruleType == model.RuleTypeLog {
+		rule.LogSourceID = req.LogSourceID
+		rule.LogQuery = req.LogQuery
+		rule.LogWindow = strings.TrimSpace(req.LogWindow)
+		rule.Threshold = req.Threshold
+		if req.SavedQueryID > 0 {
+			if err := s.applySavedQuery(&rule, req.UserID, req.SavedQueryID); err != nil {
+				return model.Rule{}, err
+			}
+		}
+		if err := validateLogRule(&rule); err != nil {
+			return model.Rule{}, err
 		}
-		items = append(items, item)
 	}
-	return ListHistoryResponse{Total: len(items), Items: items}
+	if req.ID > 0 {
+		existing, ok, err := s.repo.GetRule(tenantID, req.ID)
+		if err != nil {
+			return model.Rule{}, err
+		}
+		if !ok {
+			return model.Rule{}, obserr.New("ALERT_RULE_NOT_FOUND", "alert.UpsertRule", "告警规则不存在")
+		}
+		rule.ID = existing.ID
+		rule.CreatedAt = existing.CreatedAt
+	}
+	if err := s.repo.SaveRule(&rule); err != nil {
+		return model.Rule{}, err
+	}
+	return rule, nil
+}
+
+func (s *AlertService) ListHistory(tenantID uint, status string, start, end time.Time) (ListHistoryResponse, error) {
+	status = strings.TrimSpace(strings.ToLower(status))
+	items, err := s.repo.ListHistory(tenantID, status, start, end)
+	if err != nil {
+		return ListHistoryResponse{}, err
+	}
+	return ListHistoryResponse{Total: len(items), Items: items}, nil
 }
 
-func (s *AlertService) SetRuleEnabled(req RuleEnableRequest) (model.Rule, error) {
-	rule, ok := s.repo.SetRuleEnabled(req.ID, req.Enabled)
+func (s *AlertService) SetRuleEnabled(tenantID uint, req RuleEnableRequest) (model.Rule, error) {
+	rule, ok, err := s.repo.SetRuleEnabled(tenantID, req.ID, req.Enabled)
+	if err != nil {
+		return model.Rule{}, err
+	}
 	if !ok {
 		return model.Rule{}, obserr.New("ALERT_RULE_NOT_FOUND", "alert.SetRuleEnabled", "告警规则不存在")
 	}
 	return rule, nil
 }
 
-func (s *AlertService) ListSilences(ruleID uint) ListSilenceResponse {
-	silences := s.repo.ListSilences()
+func (s *AlertService) ListSilences(tenantID, ruleID uint) (ListSilenceResponse, error) {
+	silences, err := s.repo.ListSilences(tenantID)
+	if err != nil {
+		return ListSilenceResponse{}, err
+	}
 	if ruleID == 0 {
-		return ListSilenceResponse{Total: len(silences), Items: silences}
+		return ListSilenceResponse{Total: len(silences), Items: silences}, nil
 	}
 	items := make([]model.Silence, 0, len(silences))
 	for _, silence := range silences {
@@ -121,31 +219,25 @@ func (s *AlertService) ListSilences(ruleID uint) ListSilenceResponse {
 			items = append(items, silence)
 		}
 	}
-	return ListSilenceResponse{Total: len(items), Items: items}
+	return ListSilenceResponse{Total: len(items), Items: items}, nil
 }
 
-func (s *AlertService) UpsertSilence(req SilenceUpsertRequest) (model.Silence, error) {
-	if req.RuleID == 0 {
-		return model.Silence{}, obserr.New("ALERT_SILENCE_RULE_REQUIRED", "alert.UpsertSilence", "ruleId 不能为空")
+func (s *AlertService) UpsertSilence(tenantID uint, req SilenceUpsertRequest) (model.Silence, error) {
+	silence, err := s.buildSilence(tenantID, req, "alert.UpsertSilence")
+	if err != nil {
+		return model.Silence{}, err
 	}
-	if req.StartsAt.IsZero() || req.EndsAt.IsZero() || !req.EndsAt.After(req.StartsAt) {
-		return model.Silence{}, obserr.New("ALERT_SILENCE_RANGE_INVALID", "alert.UpsertSilence", "静默时间范围无效")
-	}
-	return s.repo.UpsertSilence(model.Silence{
-		ID:        req.ID,
-		RuleID:    req.RuleID,
-		Reason:    req.Reason,
-		StartsAt:  req.StartsAt,
-		EndsAt:    req.EndsAt,
-		CreatedBy: req.CreatedBy,
-	}), nil
+	return s.repo.UpsertSilence(silence)
 }
 
-func (s *AlertService) ListChannels(channelType string) ListChannelResponse {
+func (s *AlertService) ListChannels(tenantID uint, channelType string) (ListChannelResponse, error) {
 	channelType = strings.TrimSpace(strings.ToLower(channelType))
-	channels := s.repo.ListChannels()
+	channels, err := s.repo.ListChannels(tenantID)
+	if err != nil {
+		return ListChannelResponse{}, err
+	}
 	if channelType == "" {
-		return ListChannelResponse{Total: len(channels), Items: channels}
+		return ListChannelResponse{Total: len(channels), Items: channels}, nil
 	}
 	items := make([]model.NotificationChannel, 0, len(channels))
 	for _, channel := range channels {
@@ -153,27 +245,28 @@ func (s *AlertService) ListChannels(channelType string) ListChannelResponse {
 			items = append(items, channel)
 		}
 	}
-	return ListChannelResponse{Total: len(items), Items: items}
+	return ListChannelResponse{Total: len(items), Items: items}, nil
 }
 
-func (s *AlertService) UpsertChannel(req ChannelUpsertRequest) (model.NotificationChannel, error) {
+func (s *AlertService) UpsertChannel(tenantID uint, req ChannelUpsertRequest) (model.NotificationChannel, error) {
 	if strings.TrimSpace(req.Name) == "" || strings.TrimSpace(req.Type) == "" || strings.TrimSpace(req.Target) == "" {
 		return model.NotificationChannel{}, obserr.New("ALERT_CHANNEL_INVALID", "alert.UpsertChannel", "通知渠道参数不完整")
 	}
 	return s.repo.UpsertChannel(model.NotificationChannel{
-		ID:      req.ID,
-		Name:    req.Name,
-		Type:    req.Type,
-		Target:  req.Target,
-		Enabled: req.Enabled,
-	}), nil
+		ID:       req.ID,
+		TenantID: tenantID,
+		Name:     req.Name,
+		Type:     req.Type,
+		Target:   req.Target,
+		Enabled:  req.Enabled,
+	})
 }
 
-func (s *AlertService) GetConfig() model.AlertmanagerConfig {
-	return s.repo.GetConfig()
+func (s *AlertService) GetConfig(tenantID uint) (model.AlertmanagerConfig, error) {
+	return s.repo.GetConfig(tenantID)
 }
 
-func (s *AlertService) SaveConfig(req SaveAlertmanagerConfigRequest) (model.AlertmanagerConfig, error) {
+func (s *AlertService) SaveConfig(tenantID uint, req SaveAlertmanagerConfigRequest) (model.AlertmanagerConfig, error) {
 	endpoint := strings.TrimSpace(req.Endpoint)
 	if endpoint == "" {
 		return model.AlertmanagerConfig{}, obserr.New("ALERTMANAGER_ENDPOINT_REQUIRED", "alert.SaveConfig", "Alertmanager endpoint 不能为空")
@@ -187,6 +280,7 @@ func (s *AlertService) SaveConfig(req SaveAlertmanagerConfigRequest) (model.Aler
 		timeout = 10
 	}
 	config := model.AlertmanagerConfig{
+		TenantID:              tenantID,
 		Endpoint:              endpoint,
 		APIPath:               apiPath,
 		TimeoutSeconds:        timeout,
@@ -198,12 +292,14 @@ func (s *AlertService) SaveConfig(req SaveAlertmanagerConfigRequest) (model.Aler
 	if err := s.repo.ValidateConfigConnection(config); err != nil {
 		return model.AlertmanagerConfig{}, obserr.Wrap("ALERTMANAGER_CONNECT_FAILED", "alert.SaveConfig", "Alertmanager 配置连接失败", err)
 	}
-	saved := s.repo.SaveConfig(config)
-	return saved, nil
+	return s.repo.SaveConfig(config)
 }
 
-func (s *AlertService) ValidateCurrentConfig() error {
-	config := s.repo.GetConfig()
+func (s *AlertService) ValidateCurrentConfig(tenantID uint) error {
+	config, err := s.repo.GetConfig(tenantID)
+	if err != nil {
+		return err
+	}
 	if err := s.repo.ValidateConfigConnection(config); err != nil {
 		return obserr.Wrap("ALERTMANAGER_CONNECT_FAILED", "alert.ValidateCurrentConfig", "Alertmanager 配置连接失败", err)
 	}
@@ -212,5 +308,14 @@ func (s *AlertService) ValidateCurrentConfig() error {
 
 func (s *AlertService) IsNotFound(err error) bool {
 	var observable *obserr.ObservableError
-	return errors.As(err, &observable) && observable.Code == "ALERT_RULE_NOT_FOUND"
+	if !errors.As(err, &observable) {
+		return false
+	}
+	switch observable.Code {
+	case "ALERT_RULE_NOT_FOUND", "ALERT_HISTORY_NOT_FOUND", "ALERT_SCHEDULE_NOT_FOUND",
+		"ALERT_SILENCE_NOT_FOUND", "ALERT_CHANNEL_NOT_FOUND", "ALERT_ESCALATION_NOT_FOUND",
+		"ALERT_INHIBIT_NOT_FOUND":
+		return true
+	}
+	return false
 }
@@ -4,11 +4,68 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"devops-platform/internal/modules/alert/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
 )
 
+const testTenantID uint = 1
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&model.Rule{},
+		&model.Silence{},
+		&model.InhibitRule{},
+		&model.NotificationChannel{},
+		&model.History{},
+		&model.AlertmanagerConfig{},
+		&model.NotificationGroup{},
+	); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// newTestAlertService returns a service backed by SQLite and seeded with the
+// fixture rules, silence and history the tests below rely on.
+func newTestAlertService(t *testing.T) *AlertService {
+	db := setupTestDB(t)
+	seedAlertFixtures(t, db)
+	return NewAlertService(db)
+}
+
+func seedAlertFixtures(t *testing.T, db *gorm.DB) {
+	now := time.Now()
+	rules := []model.Rule{
+		{ID: 1, TenantID: testTenantID, Name: "PodCrashLooping", Expr: `sum(rate(kube_pod_container_status_restarts_total[5m])) > 0`, Severity: "warning", Enabled: true, Cluster: "default", Description: "容器重启频繁"},
+		{ID: 2, TenantID: testTenantID, Name: "NodeMemoryHigh", Expr: `(1 - (node_memory_MemAvailable_bytes / node_memory_MemTotal_bytes)) > 0.9`, Severity: "critical", Enabled: true, Cluster: "prod-sh", Description: "节点内存使用率过高"},
+		{ID: 3, TenantID: testTenantID, Name: "ApiServerLatencyP99", Expr: `histogram_quantile(0.99, sum(rate(apiserver_request_duration_seconds_bucket[5m])) by (le)) > 1`, Severity: "warning", Enabled: false, Cluster: "prod-bj", Description: "apiserver 延迟过高"},
+	}
+	if err := db.Create(&rules).Error; err != nil {
+		t.Fatalf("seed rules failed: %v", err)
+	}
+	if err := db.Create(&model.Silence{TenantID: testTenantID, RuleID: 2, Reason: "生产变更窗口", StartsAt: now.Add(-10 * time.Minute), EndsAt: now.Add(50 * time.Minute), CreatedBy: "sre"}).Error; err != nil {
+		t.Fatalf("seed silence failed: %v", err)
+	}
+	history := []model.History{
+		{TenantID: testTenantID, RuleID: 2, RuleName: "NodeMemoryHigh", Status: "firing", Severity: "critical", StartsAt: now.Add(-20 * time.Minute), Cluster: "prod-sh", Instance: "node-1", Fingerprint: "hist-1001"},
+		{TenantID: testTenantID, RuleID: 1, RuleName: "PodCrashLooping", Status: "resolved", Severity: "warning", StartsAt: now.Add(-3 * time.Hour), EndsAt: now.Add(-2 * time.Hour), Cluster: "default", Fingerprint: "hist-1002"},
+		{TenantID: 2, RuleID: 9, RuleName: "OtherTenant", Status: "firing", Severity: "critical", StartsAt: now.Add(-5 * time.Minute), Fingerprint: "hist-2001"},
+	}
+	if err := db.Create(&history).Error; err != nil {
+		t.Fatalf("seed history failed: %v", err)
+	}
+}
+
 func TestAlertServiceListRules_FilterKeyword(t *testing.T) {
-	svc := NewAlertService()
-	result := svc.ListRules("memory")
+	svc := newTestAlertService(t)
+	result, _ := svc.ListRules(testTenantID, "memory")
 	if result.Total == 0 {
 		t.Fatalf("expected matched rules")
 	}
@@ -19,22 +76,25 @@ func TestAlertServiceListRules_FilterKeyword(t *testing.T) {
 		}
 	}
 
-	shortKeyword := svc.ListRules("me")
+	shortKeyword, _ := svc.ListRules(testTenantID, "me")
 	if shortKeyword.Total != 3 {
 		t.Fatalf("expected short keyword ignored, got %d", shortKeyword.Total)
 	}
 
-	clusterKeyword := svc.ListRules("prod-sh")
+	clusterKeyword, _ := svc.ListRules(testTenantID, "prod-sh")
 	if clusterKeyword.Total != 1 {
 		t.Fatalf("expected cluster field keyword matched one rule, got %d", clusterKeyword.Total)
 	}
 }
 
 func TestAlertServiceListHistory_FilterStatusAndRange(t *testing.T) {
-	svc := NewAlertService()
+	svc := newTestAlertService(t)
 	start := time.Now().Add(-time.Hour)
 	end := time.Now()
-	result := svc.ListHistory("firing", start, end)
+	result, err := svc.ListHistory(testTenantID, "firing", start, end)
+	if err != nil {
+		t.Fatalf("list history failed: %v", err)
+	}
 	if result.Total != 1 {
 		t.Fatalf("expected 1 firing alert in range, got %d", result.Total)
 	}
@@ -44,8 +104,8 @@ func TestAlertServiceListHistory_FilterStatusAndRange(t *testing.T) {
 }
 
 func TestAlertServiceSetRuleEnabled(t *testing.T) {
-	svc := NewAlertService()
-	rule, err := svc.SetRuleEnabled(RuleEnableRequest{ID: 3, Enabled: true})
+	svc := newTestAlertService(t)
+	rule, err := svc.SetRuleEnabled(testTenantID, RuleEnableRequest{ID: 3, Enabled: true})
 	if err != nil {
 		t.Fatalf("expected rule to be found: %v", err)
 	}
@@ -55,10 +115,10 @@ func TestAlertServiceSetRuleEnabled(t *testing.T) {
 }
 
 func TestAlertServiceUpsertSilenceAndFilter(t *testing.T) {
-	svc := NewAlertService()
+	svc := newTestAlertService(t)
 	start := time.Now().Add(10 * time.Minute)
 	end := time.Now().Add(40 * time.Minute)
-	created, err := svc.UpsertSilence(SilenceUpsertRequest{
+	created, err := svc.UpsertSilence(testTenantID, SilenceUpsertRequest{
 		RuleID:    1,
 		Reason:    "压测期间降噪",
 		StartsAt:  start,
@@ -71,35 +131,154 @@ func TestAlertServiceUpsertSilenceAndFilter(t *testing.T) {
 	if created.ID == 0 {
 		t.Fatalf("expected silence id")
 	}
-	filtered := svc.ListSilences(1)
+	filtered, _ := svc.ListSilences(testTenantID, 1)
 	if filtered.Total == 0 {
 		t.Fatalf("expected silence for rule 1")
 	}
+	update := SilenceUpsertRequest{ID: created.ID, RuleID: 1, Reason: "延长", StartsAt: start, EndsAt: end.Add(time.Hour)}
+	if _, err := svc.UpsertSilence(2, update); !svc.IsNotFound(err) {
+		t.Fatalf("expected not found updating another tenant's silence, got %v", err)
+	}
+	update.ID = 9999
+	if _, err := svc.UpsertSilence(testTenantID, update); !svc.IsNotFound(err) {
+		t.Fatalf("expected not found updating a missing silence, got %v", err)
+	}
+	if after, _ := svc.ListSilences(testTenantID, 1); after.Total != filtered.Total {
+		t.Fatalf("failed updates must not create silences, got %d", after.Total)
+	}
 }
 
 func TestAlertServiceUpsertChannelAndFilterByType(t *testing.T) {
-	svc := NewAlertService()
-	_, err := svc.UpsertChannel(ChannelUpsertRequest{
+	svc := newTestAlertService(t)
+	req := ChannelUpsertRequest{
 		Name:    "钉钉机器人",
 		Type:    "dingtalk",
 		Target:  "https://oapi.dingtalk.com/robot/send?access_token=fake",
 		Enabled: true,
-	})
+	}
+	created, err := svc.UpsertChannel(testTenantID, req)
 	if err != nil {
 		t.Fatalf("upsert channel failed: %v", err)
 	}
-	result := svc.ListChannels("dingtalk")
+	result, _ := svc.ListChannels(testTenantID, "dingtalk")
 	if result.Total != 1 {
 		t.Fatalf("expected one dingtalk channel, got %d", result.Total)
 	}
+	req.ID = created.ID
+	if _, err := svc.UpsertChannel(2, req); !svc.IsNotFound(err) {
+		t.Fatalf("expected not found updating another tenant's channel, got %v", err)
+	}
+	if result, _ := svc.ListChannels(2, "dingtalk"); result.Total != 0 {
+		t.Fatalf("failed update must not create a channel, got %d", result.Total)
+	}
 }
 
 func TestAlertServiceSaveConfig_InvalidEndpoint(t *testing.T) {
-	svc := NewAlertService()
-	_, err := svc.SaveConfig(SaveAlertmanagerConfigRequest{
+	svc := newTestAlertService(t)
+	_, err := svc.SaveConfig(testTenantID, SaveAlertmanagerConfigRequest{
 		Endpoint: "http://invalid-alertmanager",
 	})
 	if err == nil {
 		t.Fatalf("expected invalid endpoint error")
 	}
 }
+
+func TestAlertServiceTenantIsolation(t *testing.T) {
+	svc := newTestAlertService(t)
+	rules, err := svc.ListRules(2, "")
+	if err != nil {
+		t.Fatalf("list rules failed: %v", err)
+	}
+	if rules.Total != 0 {
+		t.Fatalf("expected no rules for other tenant, got %d", rules.Total)
+	}
+	if _, err := svc.SetRuleEnabled(2, RuleEnableRequest{ID: 1, Enabled: false}); !svc.IsNotFound(err) {
+		t.Fatalf("expected not found toggling another tenant's rule, got %v", err)
+	}
+	now := time.Now()
+	silences, err := svc.repo.ListActiveSilences([]uint{2}, now)
+	if err != nil || len(silences) != 0 {
+		t.Fatalf("expected no active silences loaded for other tenant, got %v %v", silences, err)
+	}
+	if silences, _ = svc.repo.ListActiveSilences([]uint{testTenantID, 2}, now); len(silences) != 1 {
+		t.Fatalf("expected the tenant's active silence, got %v", silences)
+	}
+}
+
+func TestAlertServiceUpsertRuleAndSaveConfigPersist(t *testing.T) {
+	svc := newTestAlertService(t)
+	created, err := svc.UpsertRule(testTenantID, RuleUpsertRequest{
+		Name:    "DiskAlmostFull",
+		Expr:    `node_filesystem_avail_bytes / node_filesystem_size_bytes < 0.1`,
+		Enabled: false,
+	})
+	if err != nil {
+		t.Fatalf("upsert rule failed: %v", err)
+	}
+	if created.ID == 0 || created.Severity != "warning" || created.Enabled {
+		t.Fatalf("unexpected created rule: %+v", created)
+	}
+
+	cfg, err := svc.GetConfig(testTenantID)
+	if err != nil || cfg.ID != 0 || cfg.APIPath != "/api/v2/alerts" {
+		t.Fatalf("expected unsaved default config, got %+v err=%v", cfg, err)
+	}
+	if _, err := svc.SaveConfig(testTenantID, SaveAlertmanagerConfigRequest{Endpoint: "http://am:9093"}); err != nil {
+		t.Fatalf("save config failed: %v", err)
+	}
+	if _, err := svc.SaveConfig(testTenantID, SaveAlertmanagerConfigRequest{Endpoint: "http://am-2:9093"}); err != nil {
+		t.Fatalf("update config failed: %v", err)
+	}
+	cfg, err = svc.GetConfig(testTenantID)
+	if err != nil || cfg.ID == 0 || cfg.Endpoint != "http://am-2:9093" {
+		t.Fatalf("expected persisted config, got %+v err=%v", cfg, err)
+	}
+}
+
+func TestAlertServiceSilenceMatchersPreview(t *testing.T) {
+	db := setupTestDB(t)
+	svc := NewAlertService(db)
+	now := time.Now()
+	db.Create(&[]model.History{
+		{TenantID: testTenantID, RuleName: "PodCrashLooping", Status: model.StateFiring, StartsAt: now.Add(-time.Minute), Fingerprint: "a",
+			Labels: model.EncodeLabels(map[string]string{"alertname": "PodCrashLooping", "cluster": "prod-bj", "namespace": "batch"})},
+		{TenantID: testTenantID, RuleName: "PodCrashLooping", Status: model.StateFiring, StartsAt: now.Add(-time.Minute), Fingerprint: "b",
+			Labels: model.EncodeLabels(map[string]string{"alertname": "PodCrashLooping", "cluster": "prod-bj", "namespace": "payments"})},
+		{TenantID: testTenantID, RuleName: "NodeDown", Status: model.StateFiring, StartsAt: now.Add(-time.Minute), Fingerprint: "c", Cluster: "prod-sh"},
+		{TenantID: testTenantID, RuleName: "NodeDown", Status: model.StateResolved, StartsAt: now.Add(-time.Hour), Fingerprint: "d", Cluster: "prod-bj"},
+	})
+
+	req := SilenceUpsertRequest{
+		Matchers: []model.Matcher{
+			{Name: "cluster", Op: "=", Value: "prod-bj"},
+			{Name: "namespace", Op: "=~", Value: "batch|cron"},
+		},
+		StartsAt: now.Add(-time.Minute),
+		EndsAt:   now.Add(time.Hour),
+	}
+	preview, err := svc.PreviewSilence(testTenantID, req)
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	if preview.Total != 1 || preview.Items[0].Fingerprint != "a" {
+		t.Fatalf("expected only batch alert matched, got %+v", preview.Items)
+	}
+
+	req.Matchers = []model.Matcher{{Name: "cluster", Op: "!~", Value: "prod-.*"}}
+	if preview, _ = svc.PreviewSilence(testTenantID, req); preview.Total != 0 {
+		t.Fatalf("expected negative regex to match nothing, got %d", preview.Total)
+	}
+	req.Matchers = []model.Matcher{{Name: "alertname", Op: "!=", Value: "NodeDown"}}
+	if preview, _ = svc.PreviewSilence(testTenantID, req); preview.Total != 2 {
+		t.Fatalf("expected not-equal matcher to match two alerts, got %d", preview.Total)
+	}
+
+	req.Matchers = []model.Matcher{{Name: "cluster", Op: "=~", Value: "("}}
+	if _, err := svc.UpsertSilence(testTenantID, req); err == nil {
+		t.Fatalf("expected invalid regex rejected")
+	}
+	req.Matchers = nil
+	if _, err := svc.UpsertSilence(testTenantID, req); err == nil {
+		t.Fatalf("expected silence without rule or matchers rejected")
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	"devops-platform/internal/pkg/obserr"
+
+	"go.uber.org/zap"
+)
+
+// AlertmanagerWebhook is the standard Alertmanager webhook payload (version 4).
+type AlertmanagerWebhook struct {
+	Version           string              `json:"version"`
+	GroupKey          string              `json:"groupKey"`
+	TruncatedAlerts   int                 `json:"truncatedAlerts"`
+	Status            string              `json:"status"`
+	Receiver          string              `json:"receiver"`
+	GroupLabels       map[string]string   `json:"groupLabels"`
+	CommonLabels      map[string]string   `json:"commonLabels"`
+	CommonAnnotations map[string]string   `json:"commonAnnotations"`
+	ExternalURL       string              `json:"externalURL"`
+	Alerts            []AlertmanagerAlert `json:"alerts"`
+}
+
+// AlertmanagerAlert is a single alert inside an Alertmanager webhook payload.
+type AlertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+type WebhookIngestResult struct {
+	Received int `json:"received"`
+	Created  int `json:"created"`
+	Updated  int `json:"updated"`
+	Resolved int `json:"resolved"`
+}
+
+// SetNotificationBridge sets the bridge used to route ingested alerts to the notification hub.
+func (s *AlertService) SetNotificationBridge(bridge *AlertNotificationBridge) {
+	s.bridge = bridge
+}
+
+// IngestAlertmanagerWebhook upserts History by fingerprint for every alert in
+// the payload and notifies on new firing alerts and on resolution. Alerts
+// matched by an active silence or inhibited by another open alert are recorded
+// as silenced or inhibited and not notified. Alerts resolved by hand stay
+// closed, and resolutions of alerts with no open row are ignored.
+func (s *AlertService) IngestAlertmanagerWebhook(tenantID uint, payload AlertmanagerWebhook) (WebhookIngestResult, error) {
+	result := WebhookIngestResult{Received: len(payload.Alerts)}
+	if len(payload.Alerts) == 0 {
+		return result, obserr.New("ALERT_WEBHOOK_EMPTY", "alert.IngestAlertmanagerWebhook", "webhook 未包含告警")
+	}
+	now := time.Now()
+	silences := s.activeSilences(tenantID, now)
+	inhibitions := s.activeInhibitRules(tenantID)
+	var sources []inhibitSource
+	if len(inhibitions) > 0 {
+		sources = s.webhookInhibitSources(tenantID, payload)
+	}
+	for _, alert := range payload.Alerts {
+		status := webhookAlertStatus(payload, alert)
+		if status != model.StateFiring && status != model.StateResolved {
+			continue
+		}
+		labels := mergeLabels(payload.CommonLabels, alert.Labels)
+		annotations := mergeLabels(payload.CommonAnnotations, alert.Annotations)
+		fingerprint := webhookFingerprint(alert, labels)
+
+		existing, found, err := s.repo.FindLatestHistory(tenantID, fingerprint)
+		if err != nil {
+			return result, err
+		}
+		if found && existing.Status == model.StateResolved {
+			if resolvedByHand(existing, alert) {
+				continue
+			}
+			found = false
+		}
+		if !found && status == model.StateResolved {
+			// Nothing open to resolve.
+			continue
+		}
+		item := existing
+		if !found {
+			item = model.History{TenantID: tenantID, Fingerprint: fingerprint, Source: model.HistorySourceWebhook}
+		}
+		previous := item.Status
+		item.RuleName = labels["alertname"]
+		item.Severity = labels["severity"]
+		item.Summary = firstNonEmpty(annotations["summary"], annotations["description"], annotations["message"])
+		item.Cluster = labels["cluster"]
+		item.Namespace = labels["namespace"]
+		item.Instance = labels["instance"]
+		item.Labels = model.EncodeLabels(labels)
+		if !alert.StartsAt.IsZero() {
+			item.StartsAt = alert.StartsAt
+		} else if item.StartsAt.IsZero() {
+			item.StartsAt = now
+		}
+		if status == model.StateResolved {
+			item.EndsAt = alert.EndsAt
+			if item.EndsAt.IsZero() {
+				item.EndsAt = now
+			}
+		}
+		if item.RuleID == 0 && item.RuleName != "" {
+			if rule, ok, err := s.repo.FindRuleByName(tenantID, item.RuleName); err == nil && ok {
+				item.RuleID = rule.ID
+				item.EscalationPolicyID = rule.EscalationPolicyID
+			}
+		}
+		if status == model.StateFiring {
+			switch {
+			case isSilenced(silences, item.RuleID, labels, now):
+				status = model.StateSilenced
+			case isInhibited(inhibitions, sources, fingerprint, labels):
+				status = model.StateInhibited
+			}
+		}
+		item.Status = status
+
+		if found {
+			if err := s.repo.SaveHistory(&item); err != nil {
+				return result, err
+			}
+			result.Updated++
+		} else {
+			if err := s.repo.CreateHistory(&item); err != nil {
+				return result, err
+			}
+			result.Created++
+		}
+
+		// Repeated firing notifications for an already-open alert are left to
+		// Alertmanager's own repeat_interval; we only notify on transitions.
+		switch {
+		case status == model.StateFiring && previous != model.StateFiring:
+			s.notifyIngested(tenantID, item, labels)
+		case status == model.StateResolved && found:
+			result.Resolved++
+			if previous == model.StateFiring {
+				s.notifyIngested(tenantID, item, labels)
+			}
+		}
+	}
+	return result, nil
+}
+
+// webhookInhibitSources returns the tenant's open alerts plus the firing
+// alerts of the payload, minus the ones the payload resolves.
+func (s *AlertService) webhookInhibitSources(tenantID uint, payload AlertmanagerWebhook) []inhibitSource {
+	open, err := s.repo.ListTenantOpenHistory(tenantID)
+	if err != nil {
+		logWarn("加载未恢复告警失败", zap.Error(err))
+	}
+	resolved := make(map[string]bool)
+	var sources []inhibitSource
+	for _, alert := range payload.Alerts {
+		labels := mergeLabels(payload.CommonLabels, alert.Labels)
+		fingerprint := webhookFingerprint(alert, labels)
+		switch webhookAlertStatus(payload, alert) {
+		case model.StateFiring:
+			sources = append(sources, inhibitSource{fingerprint: fingerprint, labels: labels})
+		case model.StateResolved:
+			resolved[fingerprint] = true
+		}
+	}
+	for _, src := range historySources(open) {
+		if !resolved[src.fingerprint] {
+			sources = append(sources, src)
+		}
+	}
+	return sources
+}
+
+// resolvedByHand reports whether an operator resolved the row while the alert
+// kept firing. Alertmanager keeps the alert's StartsAt until it resolves, so
+// the row stays closed until the alert starts again after the manual resolve.
+func resolvedByHand(item model.History, alert AlertmanagerAlert) bool {
+	return item.ResolvedBy != "" && item.ResolvedAt != nil && !alert.StartsAt.After(*item.ResolvedAt)
+}
+
+func webhookAlertStatus(payload AlertmanagerWebhook, alert AlertmanagerAlert) string {
+	status := strings.ToLower(strings.TrimSpace(alert.Status))
+	if status == "" {
+		status = strings.ToLower(payload.Status)
+	}
+	return status
+}
+
+func webhookFingerprint(alert AlertmanagerAlert, labels map[string]string) string {
+	if fingerprint := strings.TrimSpace(alert.Fingerprint); fingerprint != "" {
+		return fingerprint
+	}
+	return Fingerprint(labels)
+}
+
+func (s *AlertService) notifyIngested(tenantID uint, item model.History, labels map[string]string) {
+	if s.bridge == nil {
+		return
+	}
+	err := s.bridge.SendAlert(tenantID, AlertInfo{
+		RuleName:    item.RuleName,
+		Severity:    item.Severity,
+		Cluster:     item.Cluster,
+		Status:      item.Status,
+		Description: item.Summary,
+		Labels:      labels,
+	})
+	if err != nil {
+		logWarn("Alertmanager 告警通知发送失败", zap.String("fingerprint", item.Fingerprint), zap.Error(err))
+	}
+}
+
+func mergeLabels(common, own map[string]string) map[string]string {
+	merged := make(map[string]string, len(common)+len(own))
+	for k, v := range common {
+		merged[k] = v
+	}
+	for k, v := range own {
+		merged[k] = v
+	}
+	return merged
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	notifModel "devops-platform/internal/modules/notification/model"
+	notifService "devops-platform/internal/modules/notification/service"
+)
+
+func TestIngestAlertmanagerWebhook_UpsertByFingerprint(t *testing.T) {
+	db := setupTestDB(t)
+	seedAlertFixtures(t, db)
+	svc := NewAlertService(db)
+	if err := db.AutoMigrate(&notifModel.ChannelConfig{}, &notifModel.SendLog{}, &notifModel.Template{}); err != nil {
+		t.Fatalf("failed to migrate notification tables: %v", err)
+	}
+	db.Create(&notifModel.ChannelConfig{TenantID: testTenantID, Channel: notifModel.ChannelFeishu, Enabled: true})
+	ns := notifService.NewNotificationService(db)
+	notifier := &recordingNotifier{}
+	ns.RegisterNotifier(notifModel.ChannelFeishu, notifier)
+	svc.SetNotificationBridge(NewAlertNotificationBridge(ns))
+
+	startsAt := time.Now().Add(-5 * time.Minute).UTC()
+	firing := AlertmanagerWebhook{
+		Version:      "4",
+		Status:       "firing",
+		CommonLabels: map[string]string{"cluster": "prod-bj"},
+		Alerts: []AlertmanagerAlert{{
+			Status:      "firing",
+			Labels:      map[string]string{"alertname": "PodCrashLooping", "severity": "warning", "instance": "node-9"},
+			Annotations: map[string]string{"summary": "node-9 容器重启频繁"},
+			StartsAt:    startsAt,
+			Fingerprint: "abc123",
+		}},
+	}
+	result, err := svc.IngestAlertmanagerWebhook(testTenantID, firing)
+	if err != nil {
+		t.Fatalf("ingest firing failed: %v", err)
+	}
+	if result.Created != 1 {
+		t.Fatalf("expected one created history, got %+v", result)
+	}
+	// Alertmanager re-sends the same firing alert on repeat_interval.
+	if result, err = svc.IngestAlertmanagerWebhook(testTenantID, firing); err != nil || result.Updated != 1 {
+		t.Fatalf("expected repeated firing to update, got %+v err=%v", result, err)
+	}
+
+	var item model.History
+	if err := db.Where("fingerprint = ?", "abc123").First(&item).Error; err != nil {
+		t.Fatalf("load history failed: %v", err)
+	}
+	if item.RuleID != 1 || item.Cluster != "prod-bj" || item.Summary == "" {
+		t.Fatalf("unexpected ingested history: %+v", item)
+	}
+
+	resolved := firing
+	resolved.Status = "resolved"
+	resolved.Alerts = []AlertmanagerAlert{firing.Alerts[0]}
+	resolved.Alerts[0].Status = "resolved"
+	resolved.Alerts[0].EndsAt = time.Now().UTC()
+	if result, err = svc.IngestAlertmanagerWebhook(testTenantID, resolved); err != nil || result.Resolved != 1 {
+		t.Fatalf("expected resolve, got %+v err=%v", result, err)
+	}
+
+	var count int64
+	db.Model(&model.History{}).Where("fingerprint = ?", "abc123").Count(&count)
+	if count != 1 {
+		t.Fatalf("expected a single history row per fingerprint, got %d", count)
+	}
+	if len(notifier.subjects) != 2 {
+		t.Fatalf("expected firing and resolved notifications only, got %v", notifier.subjects)
+	}
+}
+
+func TestIngestAlertmanagerWebhook_SilencedByRule(t *testing.T) {
+	svc := newTestAlertService(t)
+	payload := AlertmanagerWebhook{
+		Status: "firing",
+		Alerts: []AlertmanagerAlert{{
+			Status:      "firing",
+			Labels:      map[string]string{"alertname": "NodeMemoryHigh", "instance": "node-3"},
+			Fingerprint: "mem-node-3",
+		}},
+	}
+	if _, err := svc.IngestAlertmanagerWebhook(testTenantID, payload); err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	history, _ := svc.ListHistory(testTenantID, model.StateSilenced, time.Time{}, time.Time{})
+	if history.Total != 1 || history.Items[0].Fingerprint != "mem-node-3" {
+		t.Fatalf("expected alert of silenced rule stored as silenced, got %+v", history.Items)
+	}
+}
+
+func TestIngestAlertmanagerWebhook_ManualResolveStaysClosed(t *testing.T) {
+	db := setupTestDB(t)
+	svc := NewAlertService(db)
+	startsAt := time.Now().Add(-time.Hour).UTC()
+	payload := func(status string, startsAt time.Time) AlertmanagerWebhook {
+		return AlertmanagerWebhook{Status: status, Alerts: []AlertmanagerAlert{{
+			Status: status, Labels: map[string]string{"alertname": "DiskFull", "instance": "node-4"},
+			StartsAt: startsAt, Fingerprint: "disk-node-4",
+		}}}
+	}
+
+	// A resolution for an alert never seen firing is not recorded.
+	if result, err := svc.IngestAlertmanagerWebhook(testTenantID, payload("resolved", startsAt)); err != nil || result.Resolved != 0 {
+		t.Fatalf("expected stray resolution ignored, got %+v err=%v", result, err)
+	}
+	var count int64
+	db.Model(&model.History{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected no history for a stray resolution, got %d rows", count)
+	}
+
+	if _, err := svc.IngestAlertmanagerWebhook(testTenantID, payload("firing", startsAt)); err != nil {
+		t.Fatalf("ingest firing failed: %v", err)
+	}
+	var item model.History
+	db.Where("fingerprint = ?", "disk-node-4").First(&item)
+	if _, err := svc.ResolveHistory(testTenantID, item.ID, "alice"); err != nil {
+		t.Fatalf("manual resolve failed: %v", err)
+	}
+
+	// Alertmanager repeats the same alert; it must stay resolved.
+	result, err := svc.IngestAlertmanagerWebhook(testTenantID, payload("firing", startsAt))
+	if err != nil || result.Created != 0 || result.Updated != 0 {
+		t.Fatalf("expected repeat of a manually resolved alert ignored, got %+v err=%v", result, err)
+	}
+	db.First(&item, item.ID)
+	if item.Status != model.StateResolved || item.ResolvedBy != "alice" {
+		t.Fatalf("manually resolved alert was reopened: %+v", item)
+	}
+
+	// Once it starts again after the manual resolve it is a new alert.
+	if result, err = svc.IngestAlertmanagerWebhook(testTenantID, payload("firing", time.Now().Add(time.Minute).UTC())); err != nil || result.Created != 1 {
+		t.Fatalf("expected a new firing row, got %+v err=%v", result, err)
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	"devops-platform/internal/modules/alert/repository"
+	notifService "devops-platform/internal/modules/notification/service"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Escalator pages firing, unacknowledged alerts level by level according to
+// their rule's escalation policy.
+type Escalator struct {
+	repo *repository.AlertRepo
+	ns   *notifService.NotificationService
+	now  func() time.Time
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func NewEscalator(db *gorm.DB, ns *notifService.NotificationService) *Escalator {
+	return &Escalator{
+		repo: repository.NewAlertRepo(db),
+		ns:   ns,
+		now:  time.Now,
+	}
+}
+
+// Start runs Escalate on the given interval until Stop is called.
+func (e *Escalator) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.mu.Lock()
+	e.cancel = cancel
+	e.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.Escalate()
+			}
+		}
+	}()
+}
+
+func (e *Escalator) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cancel != nil {
+		e.cancel()
+		e.cancel = nil
+	}
+}
+
+// Escalate pages the next level of every alert whose previous level has not
+// acknowledged within that level's delay.
+func (e *Escalator) Escalate() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items, err := e.repo.ListEscalatingHistory()
+	if err != nil {
+		logWarn("加载待升级告警失败", zap.Error(err))
+		return
+	}
+	now := e.now()
+	for i := range items {
+		item := &items[i]
+		policy, ok, err := e.repo.GetEscalationPolicy(item.TenantID, item.EscalationPolicyID)
+		if err != nil || !ok || item.EscalationLevel >= len(policy.Levels) {
+			continue
+		}
+		level := policy.Levels[item.EscalationLevel]
+		if item.EscalationLevel > 0 && item.LastEscalatedAt != nil &&
+			now.Before(item.LastEscalatedAt.Add(time.Duration(level.DelayMinutes)*time.Minute)) {
+			continue
+		}
+		if err := e.page(item, level, now); err != nil {
+			// Not advancing the level means the next tick retries this page.
+			logWarn("告警升级通知发送失败", zap.Uint("historyID", item.ID), zap.Int("level", item.EscalationLevel+1), zap.Error(err))
+			continue
+		}
+		// Only the escalation columns are written, and only while the alert is
+		// still firing unacknowledged, so an ack or resolve made during the
+		// page is kept.
+		if _, err := e.repo.AdvanceEscalation(item.ID, item.EscalationLevel+1, now); err != nil {
+			logWarn("更新告警升级状态失败", zap.Uint("historyID", item.ID), zap.Error(err))
+		}
+	}
+}
+
+func (e *Escalator) page(item *model.History, level model.EscalationLevel, now time.Time) error {
+	userIDs := append([]uint{}, level.UserIDs...)
+	if level.ScheduleID > 0 {
+		schedule, ok, err := e.repo.GetSchedule(item.TenantID, level.ScheduleID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if userID, onCall := OnCallAt(schedule, now); onCall {
+				userIDs = append(userIDs, userID)
+			}
+		}
+	}
+	users, err := e.repo.ListUsers(item.TenantID, userIDs)
+	if err != nil {
+		return err
+	}
+	recipients := make([]string, 0, len(users))
+	names := make([]string, 0, len(users))
+	for _, u := range users {
+		recipient := u.Email
+		if recipient == "" {
+			recipient = u.Username
+		}
+		recipients = append(recipients, recipient)
+		name := u.Name
+		if name == "" {
+			name = u.Username
+		}
+		names = append(names, "@"+name)
+	}
+
+	subject := fmt.Sprintf("[ESCALATION L%d][%s] %s", item.EscalationLevel+1, strings.ToUpper(item.Severity), item.RuleName)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("**告警:** %s\n\n", item.RuleName))
+	sb.WriteString(fmt.Sprintf("**级别:** %s\n\n", item.Severity))
+	sb.WriteString(fmt.Sprintf("**开始时间:** %s\n\n", item.StartsAt.Format(time.RFC3339)))
+	if item.Summary != "" {
+		sb.WriteString(fmt.Sprintf("**描述:** %s\n\n", item.Summary))
+	}
+	if len(names) > 0 {
+		sb.WriteString(fmt.Sprintf("**值班人员:** %s\n\n", strings.Join(names, " ")))
+	} else {
+		sb.WriteString("**值班人员:** 当前无人值班\n\n")
+	}
+	sb.WriteString("告警未确认将继续升级，请及时确认。")
+	return e.ns.SendWithFallback(item.TenantID, recipients, subject, sb.String())
+}
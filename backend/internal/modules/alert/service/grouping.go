@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	"devops-platform/internal/modules/alert/repository"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// GroupPolicy controls how alerts are batched into notifications, following
+// Alertmanager route semantics:
+//   - GroupWait delays the first notification of a new group to collect more alerts.
+//   - GroupInterval is the minimum gap before notifying about changes to a group.
+//   - RepeatInterval re-sends an unchanged group that is still firing.
+type GroupPolicy struct {
+	GroupBy        []string
+	GroupWait      time.Duration
+	GroupInterval  time.Duration
+	RepeatInterval time.Duration
+}
+
+// AlertGrouper aggregates alerts by GroupBy labels and sends one notification
+// per group through the bridge. Group state is persisted so restarts do not re-page.
+type AlertGrouper struct {
+	repo   *repository.AlertRepo
+	bridge *AlertNotificationBridge
+	policy GroupPolicy
+	now    func() time.Time
+
+	// mu guards group state; flushMu keeps flushes from overlapping.
+	mu      sync.Mutex
+	flushMu sync.Mutex
+	cancel  context.CancelFunc
+}
+
+func NewAlertGrouper(db *gorm.DB, bridge *AlertNotificationBridge, policy GroupPolicy) *AlertGrouper {
+	if len(policy.GroupBy) == 0 {
+		policy.GroupBy = []string{"alertname"}
+	}
+	if policy.GroupWait < 0 {
+		policy.GroupWait = 0
+	}
+	if policy.GroupInterval <= 0 {
+		policy.GroupInterval = 5 * time.Minute
+	}
+	if policy.RepeatInterval <= 0 {
+		policy.RepeatInterval = 4 * time.Hour
+	}
+	return &AlertGrouper{
+		repo:   repository.NewAlertRepo(db),
+		bridge: bridge,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// Start runs Flush on the given interval until Stop is called.
+func (g *AlertGrouper) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	g.mu.Lock()
+	g.cancel = cancel
+	g.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				g.Flush()
+			}
+		}
+	}()
+}
+
+func (g *AlertGrouper) Stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.cancel != nil {
+		g.cancel()
+		g.cancel = nil
+	}
+}
+
+// Enqueue adds an alert to its group or updates it in place. An alert that
+// repeats with the same status is deduplicated and does not mark the group
+// changed; a resolved alert the group never saw is dropped.
+func (g *AlertGrouper) Enqueue(tenantID uint, alert AlertInfo) error {
+	labels := make(map[string]string, len(alert.Labels)+2)
+	for k, v := range alert.Labels {
+		labels[k] = v
+	}
+	if labels["alertname"] == "" {
+		labels["alertname"] = alert.RuleName
+	}
+	if labels["severity"] == "" && alert.Severity != "" {
+		labels["severity"] = alert.Severity
+	}
+	if labels["cluster"] == "" && alert.Cluster != "" {
+		labels["cluster"] = alert.Cluster
+	}
+	groupLabels := make(map[string]string, len(g.policy.GroupBy))
+	for _, name := range g.policy.GroupBy {
+		groupLabels[name] = labels[name]
+	}
+	groupKey := Fingerprint(groupLabels)
+	fingerprint := Fingerprint(labels)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	group, found, err := g.repo.FindNotificationGroup(tenantID, groupKey)
+	if err != nil {
+		return err
+	}
+	if !found {
+		if alert.Status != model.StateFiring {
+			return nil
+		}
+		group = model.NotificationGroup{
+			TenantID:    tenantID,
+			GroupKey:    groupKey,
+			GroupLabels: groupLabels,
+			FirstSeenAt: g.now(),
+		}
+	}
+
+	entry := model.GroupedAlert{
+		Fingerprint: fingerprint,
+		RuleName:    alert.RuleName,
+		Severity:    alert.Severity,
+		Status:      alert.Status,
+		Summary:     alert.Description,
+		Labels:      labels,
+	}
+	idx := -1
+	for i, existing := range group.Alerts {
+		if existing.Fingerprint == fingerprint {
+			idx = i
+			break
+		}
+	}
+	switch {
+	case idx < 0 && alert.Status != model.StateFiring:
+		return nil
+	case idx < 0:
+		group.Alerts = append(group.Alerts, entry)
+		group.Changed = true
+	default:
+		if group.Alerts[idx].Status != entry.Status {
+			group.Changed = true
+		}
+		group.Alerts[idx] = entry
+	}
+	return g.repo.SaveNotificationGroup(&group)
+}
+
+// Flush sends every group whose wait, interval or repeat timer has elapsed.
+// Notifications go out without g.mu held, so enqueueing never waits on a
+// channel; alerts enqueued meanwhile stay pending for the next flush.
+func (g *AlertGrouper) Flush() {
+	g.flushMu.Lock()
+	defer g.flushMu.Unlock()
+
+	now := g.now()
+	for _, group := range g.dueGroups(now) {
+		if err := g.bridge.sendGroup(group.TenantID, group); err != nil {
+			// Leave the state untouched so the next tick retries.
+			logWarn("告警分组通知发送失败", zap.String("groupKey", group.GroupKey), zap.Error(err))
+			continue
+		}
+		g.markSent(group, now)
+	}
+}
+
+// dueGroups returns copies of the groups to send now, dropping groups that
+// resolved before they were ever sent.
+func (g *AlertGrouper) dueGroups(now time.Time) []model.NotificationGroup {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	groups, err := g.repo.ListNotificationGroups()
+	if err != nil {
+		logWarn("加载告警分组失败", zap.Error(err))
+		return nil
+	}
+	var due []model.NotificationGroup
+	for i := range groups {
+		group := &groups[i]
+		firing := countGroupAlerts(group.Alerts, model.StateFiring)
+		// Everything resolved before the group was ever sent: nobody was paged.
+		if group.LastNotifiedAt == nil && firing == 0 {
+			g.deleteGroup(group)
+			continue
+		}
+		if g.due(group, firing, now) {
+			due = append(due, *group)
+		}
+	}
+	return due
+}
+
+// markSent applies a sent notification to the group as it is now: resolved
+// alerts it reported are dropped, and alerts added or changed since it was
+// copied keep the group changed.
+func (g *AlertGrouper) markSent(sent model.NotificationGroup, now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	group, found, err := g.repo.FindNotificationGroup(sent.TenantID, sent.GroupKey)
+	if err != nil {
+		logWarn("加载告警分组失败", zap.String("groupKey", sent.GroupKey), zap.Error(err))
+		return
+	}
+	if !found {
+		return
+	}
+	reported := make(map[string]string, len(sent.Alerts))
+	for _, alert := range sent.Alerts {
+		reported[alert.Fingerprint] = alert.Status
+	}
+	kept := group.Alerts[:0]
+	changed := false
+	for _, alert := range group.Alerts {
+		status, ok := reported[alert.Fingerprint]
+		if ok && status == alert.Status && alert.Status != model.StateFiring {
+			continue
+		}
+		if !ok || status != alert.Status {
+			changed = true
+		}
+		kept = append(kept, alert)
+	}
+	if len(kept) == 0 {
+		g.deleteGroup(&group)
+		return
+	}
+	group.Alerts = kept
+	group.Changed = changed
+	group.LastNotifiedAt = &now
+	if err := g.repo.SaveNotificationGroup(&group); err != nil {
+		logWarn("更新告警分组失败", zap.String("groupKey", group.GroupKey), zap.Error(err))
+	}
+}
+
+func (g *AlertGrouper) due(group *model.NotificationGroup, firing int, now time.Time) bool {
+	if group.LastNotifiedAt == nil {
+		return !now.Before(group.FirstSeenAt.Add(g.policy.GroupWait))
+	}
+	if group.Changed {
+		return !now.Before(group.LastNotifiedAt.Add(g.policy.GroupInterval))
+	}
+	return firing > 0 && !now.Before(group.LastNotifiedAt.Add(g.policy.RepeatInterval))
+}
+
+func (g *AlertGrouper) deleteGroup(group *model.NotificationGroup) {
+	if err := g.repo.DeleteNotificationGroup(group.ID); err != nil {
+		logWarn("删除告警分组失败", zap.String("groupKey", group.GroupKey), zap.Error(err))
+	}
+}
+
+func countGroupAlerts(alerts []model.GroupedAlert, status string) int {
+	count := 0
+	for _, alert := range alerts {
+		if alert.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// sendGroup sends one aggregated notification for all alerts in a group.
+func (b *AlertNotificationBridge) sendGroup(tenantID uint, group model.NotificationGroup) error {
+	firing := countGroupAlerts(group.Alerts, model.StateFiring)
+	status, count := model.StateFiring, firing
+	if firing == 0 {
+		status, count = model.StateResolved, len(group.Alerts)
+	}
+	groupDesc := formatGroupLabels(group.GroupLabels)
+	subject := fmt.Sprintf("[%s:%d] %s", strings.ToUpper(status), count, groupDesc)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("**分组:** %s\n\n", groupDesc))
+	for _, section := range []struct {
+		status string
+		title  string
+	}{
+		{model.StateFiring, "触发中"},
+		{model.StateResolved, "已恢复"},
+	} {
+		n := countGroupAlerts(group.Alerts, section.status)
+		if n == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("**%s (%d):**\n", section.title, n))
+		for _, alert := range group.Alerts {
+			if alert.Status != section.status {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("- [%s] %s", strings.ToUpper(alert.Severity), alert.RuleName))
+			if instance := alert.Labels["instance"]; instance != "" {
+				sb.WriteString(" " + instance)
+			}
+			if alert.Summary != "" {
+				sb.WriteString(": " + alert.Summary)
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	return b.send(tenantID, alertGroupTemplateName, groupTemplateData(group, status, count), subject, sb.String())
+}
+
+func groupTemplateData(group model.NotificationGroup, status string, count int) map[string]interface{} {
+	alerts := make([]map[string]interface{}, 0, len(group.Alerts))
+	for _, alert := range group.Alerts {
+		alerts = append(alerts, map[string]interface{}{
+			"status":    alert.Status,
+			"rule_name": alert.RuleName,
+			"severity":  alert.Severity,
+			"summary":   alert.Summary,
+			"labels":    alert.Labels,
+		})
+	}
+	return map[string]interface{}{
+		"status":       status,
+		"count":        count,
+		"group_labels": group.GroupLabels,
+		"alerts":       alerts,
+	}
+}
+
+func formatGroupLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", k, labels[k]))
+	}
+	return strings.Join(parts, " ")
+}
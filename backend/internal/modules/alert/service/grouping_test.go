@@ -0,0 +1,222 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	notifModel "devops-platform/internal/modules/notification/model"
+	notifService "devops-platform/internal/modules/notification/service"
+)
+
+func TestAlertGrouper_AggregatesAndThrottles(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&notifModel.ChannelConfig{}, &notifModel.SendLog{}, &notifModel.Template{}); err != nil {
+		t.Fatalf("failed to migrate notification tables: %v", err)
+	}
+	db.Create(&notifModel.ChannelConfig{TenantID: testTenantID, Channel: notifModel.ChannelFeishu, Enabled: true})
+	ns := notifService.NewNotificationService(db)
+	notifier := &recordingNotifier{}
+	ns.RegisterNotifier(notifModel.ChannelFeishu, notifier)
+
+	policy := GroupPolicy{
+		GroupBy:        []string{"cluster"},
+		GroupWait:      30 * time.Second,
+		GroupInterval:  5 * time.Minute,
+		RepeatInterval: time.Hour,
+	}
+	bridge := NewAlertNotificationBridge(ns)
+	grouper := NewAlertGrouper(db, bridge, policy)
+	bridge.SetGrouper(grouper)
+	now := time.Now()
+	grouper.now = func() time.Time { return now }
+
+	podAlert := func(i int, status string) AlertInfo {
+		return AlertInfo{
+			RuleName: "PodNotReady",
+			Severity: "warning",
+			Status:   status,
+			Labels:   map[string]string{"cluster": "prod-bj", "pod": fmt.Sprintf("api-%d", i)},
+		}
+	}
+	for i := 0; i < 40; i++ {
+		if err := bridge.SendAlert(testTenantID, podAlert(i, model.StateFiring)); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+	}
+	grouper.Flush()
+	if len(notifier.subjects) != 0 {
+		t.Fatalf("expected nothing sent during group_wait, got %v", notifier.subjects)
+	}
+
+	now = now.Add(31 * time.Second)
+	grouper.Flush()
+	if len(notifier.subjects) != 1 || notifier.subjects[0] != "[FIRING:40] cluster=prod-bj" {
+		t.Fatalf("expected one aggregated notification, got %v", notifier.subjects)
+	}
+
+	// Duplicates of already-sent alerts do not re-notify.
+	bridge.SendAlert(testTenantID, podAlert(0, model.StateFiring))
+	now = now.Add(10 * time.Minute)
+	grouper.Flush()
+	if len(notifier.subjects) != 1 {
+		t.Fatalf("expected duplicate alert deduplicated, got %v", notifier.subjects)
+	}
+
+	// A change waits for group_interval since the last notification.
+	bridge.SendAlert(testTenantID, podAlert(1, model.StateResolved))
+	now = now.Add(time.Minute)
+	grouper.Flush()
+	if len(notifier.subjects) != 2 || notifier.subjects[1] != "[FIRING:39] cluster=prod-bj" {
+		t.Fatalf("expected change notification, got %v", notifier.subjects)
+	}
+	bridge.SendAlert(testTenantID, podAlert(2, model.StateResolved))
+	now = now.Add(time.Minute)
+	grouper.Flush()
+	if len(notifier.subjects) != 2 {
+		t.Fatalf("expected change held back by group_interval, got %v", notifier.subjects)
+	}
+
+	// A restarted grouper picks up the persisted state instead of re-paging.
+	restarted := NewAlertGrouper(db, bridge, policy)
+	restarted.now = func() time.Time { return now }
+	restarted.Flush()
+	if len(notifier.subjects) != 2 {
+		t.Fatalf("expected restart not to re-page, got %v", notifier.subjects)
+	}
+	now = now.Add(5 * time.Minute)
+	restarted.Flush()
+	if len(notifier.subjects) != 3 {
+		t.Fatalf("expected pending change sent after group_interval, got %v", notifier.subjects)
+	}
+	now = now.Add(time.Hour)
+	restarted.Flush()
+	if len(notifier.subjects) != 4 || notifier.subjects[3] != "[FIRING:38] cluster=prod-bj" {
+		t.Fatalf("expected repeat notification after repeat_interval, got %v", notifier.subjects)
+	}
+}
+
+func TestAlertGrouper_DropsGroupResolvedBeforeFirstNotification(t *testing.T) {
+	db := setupTestDB(t)
+	grouper := NewAlertGrouper(db, NewAlertNotificationBridge(nil), GroupPolicy{GroupWait: time.Minute})
+	alert := AlertInfo{RuleName: "NodeDown", Severity: "critical", Status: model.StateFiring, Labels: map[string]string{"instance": "node-1"}}
+	if err := grouper.Enqueue(testTenantID, alert); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	alert.Status = model.StateResolved
+	if err := grouper.Enqueue(testTenantID, alert); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	grouper.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	// The bridge has no notification service; sending would panic.
+	grouper.Flush()
+
+	var count int64
+	db.Model(&model.NotificationGroup{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected resolved-only group dropped, got %d groups", count)
+	}
+}
+
+// enqueueingNotifier enqueues an alert from inside Send, as a concurrent
+// evaluation would while a group is being delivered.
+type enqueueingNotifier struct {
+	recordingNotifier
+	during func()
+}
+
+func (n *enqueueingNotifier) Send(recipients []string, subject, body string) error {
+	if n.during != nil {
+		n.during()
+		n.during = nil
+	}
+	return n.recordingNotifier.Send(recipients, subject, body)
+}
+
+func TestAlertGrouper_EnqueueDuringSendIsKept(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&notifModel.ChannelConfig{}, &notifModel.SendLog{}, &notifModel.Template{}); err != nil {
+		t.Fatalf("failed to migrate notification tables: %v", err)
+	}
+	db.Create(&notifModel.ChannelConfig{TenantID: testTenantID, Channel: notifModel.ChannelFeishu, Enabled: true})
+	ns := notifService.NewNotificationService(db)
+	notifier := &enqueueingNotifier{}
+	ns.RegisterNotifier(notifModel.ChannelFeishu, notifier)
+
+	policy := GroupPolicy{GroupBy: []string{"cluster"}, GroupWait: time.Second, GroupInterval: time.Minute, RepeatInterval: time.Hour}
+	grouper := NewAlertGrouper(db, NewAlertNotificationBridge(ns), policy)
+	now := time.Now()
+	grouper.now = func() time.Time { return now }
+	podAlert := func(pod string) AlertInfo {
+		return AlertInfo{RuleName: "PodNotReady", Severity: "warning", Status: model.StateFiring, Labels: map[string]string{"cluster": "prod-bj", "pod": pod}}
+	}
+	if err := grouper.Enqueue(testTenantID, podAlert("api-0")); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	enqueued := make(chan error, 1)
+	notifier.during = func() {
+		// Holding the grouper lock while sending would deadlock here.
+		go func() { enqueued <- grouper.Enqueue(testTenantID, podAlert("api-1")) }()
+		select {
+		case err := <-enqueued:
+			enqueued <- err
+		case <-time.After(2 * time.Second):
+			t.Error("enqueue blocked behind the notification send")
+		}
+	}
+	now = now.Add(2 * time.Second)
+	grouper.Flush()
+	if err := <-enqueued; err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	if len(notifier.subjects) != 1 || notifier.subjects[0] != "[FIRING:1] cluster=prod-bj" {
+		t.Fatalf("expected the first alert sent, got %v", notifier.subjects)
+	}
+
+	now = now.Add(time.Minute)
+	grouper.Flush()
+	if len(notifier.subjects) != 2 || notifier.subjects[1] != "[FIRING:2] cluster=prod-bj" {
+		t.Fatalf("expected the alert enqueued during the send to follow, got %v", notifier.subjects)
+	}
+}
+
+func TestAlertNotificationBridge_RendersTenantTemplates(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&notifModel.ChannelConfig{}, &notifModel.SendLog{}, &notifModel.Template{}); err != nil {
+		t.Fatalf("failed to migrate notification tables: %v", err)
+	}
+	db.Create(&notifModel.ChannelConfig{TenantID: testTenantID, Channel: notifModel.ChannelFeishu, Enabled: true})
+	db.Create(&[]notifModel.Template{
+		{TenantID: testTenantID, Name: "alert", Channel: notifModel.ChannelFeishu, Subject: "告警 {{.rule_name}} {{label .labels \"pod\"}}", Body: "{{.status}}"},
+		{TenantID: testTenantID, Name: "alert_group", Channel: notifModel.ChannelFeishu, Subject: "分组 {{label .group_labels \"cluster\"}} {{.count}}", Body: "{{range .alerts}}{{.rule_name}} {{end}}"},
+	})
+	ns := notifService.NewNotificationService(db)
+	notifier := &recordingNotifier{}
+	ns.RegisterNotifier(notifModel.ChannelFeishu, notifier)
+	ns.RegisterNotifier(notifModel.ChannelDingTalk, notifier)
+	alert := AlertInfo{RuleName: "PodNotReady", Severity: "warning", Status: model.StateFiring, Labels: map[string]string{"cluster": "prod-bj", "pod": "api-0"}}
+
+	bridge := NewAlertNotificationBridge(ns)
+	if err := bridge.SendAlert(testTenantID, alert); err != nil {
+		t.Fatalf("send alert failed: %v", err)
+	}
+	// Another tenant without templates keeps the built-in format.
+	db.Create(&notifModel.ChannelConfig{TenantID: 2, Channel: notifModel.ChannelDingTalk, Enabled: true})
+	if err := bridge.SendAlert(2, alert); err != nil {
+		t.Fatalf("send alert failed: %v", err)
+	}
+
+	grouper := NewAlertGrouper(db, bridge, GroupPolicy{GroupBy: []string{"cluster"}})
+	bridge.SetGrouper(grouper)
+	if err := bridge.SendAlert(testTenantID, alert); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	grouper.Flush()
+
+	want := []string{"告警 PodNotReady api-0", "[WARNING] firing - PodNotReady", "分组 prod-bj 1"}
+	if fmt.Sprint(notifier.subjects) != fmt.Sprint(want) {
+		t.Fatalf("subjects = %v, want %v", notifier.subjects, want)
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"strings"
+
+	"devops-platform/internal/modules/alert/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+type ListInhibitRuleResponse struct {
+	Total int                 `json:"total"`
+	Items []model.InhibitRule `json:"items"`
+}
+
+type InhibitRuleUpsertRequest struct {
+	ID             uint                 `json:"id"`
+	Name           string               `json:"name"`
+	SourceMatchers []model.Matcher      `json:"sourceMatchers"`
+	TargetMatchers []model.Matcher      `json:"targetMatchers"`
+	Equal          []model.InhibitEqual `json:"equal"`
+	Enabled        bool                 `json:"enabled"`
+	Description    string               `json:"description"`
+}
+
+// inhibitMatcher is an inhibit rule with its matchers compiled.
+type inhibitMatcher struct {
+	rule   model.InhibitRule
+	source *matcherSet
+	target *matcherSet
+}
+
+// inhibitSource is an open alert that may inhibit others.
+type inhibitSource struct {
+	fingerprint string
+	labels      map[string]string
+}
+
+func compileInhibitRule(rule model.InhibitRule) (*inhibitMatcher, error) {
+	source, err := compileMatchers(rule.SourceMatchers)
+	if err != nil {
+		return nil, err
+	}
+	target, err := compileMatchers(rule.TargetMatchers)
+	if err != nil {
+		return nil, err
+	}
+	return &inhibitMatcher{rule: rule, source: source, target: target}, nil
+}
+
+// compileInhibitRules compiles inhibit rules grouped by tenant, skipping invalid ones.
+func compileInhibitRules(rules []model.InhibitRule) map[uint][]*inhibitMatcher {
+	byTenant := make(map[uint][]*inhibitMatcher)
+	for _, rule := range rules {
+		im, err := compileInhibitRule(rule)
+		if err != nil {
+			continue
+		}
+		byTenant[rule.TenantID] = append(byTenant[rule.TenantID], im)
+	}
+	return byTenant
+}
+
+func (im *inhibitMatcher) equal(source, target map[string]string) bool {
+	for _, eq := range im.rule.Equal {
+		targetLabel := eq.Target
+		if targetLabel == "" {
+			targetLabel = eq.Source
+		}
+		if source[eq.Source] != target[targetLabel] {
+			return false
+		}
+	}
+	return true
+}
+
+// isInhibited reports whether any source alert other than the alert itself
+// inhibits an alert with the given fingerprint and labels.
+func isInhibited(rules []*inhibitMatcher, sources []inhibitSource, fingerprint string, labels map[string]string) bool {
+	for _, rule := range rules {
+		if !rule.target.matches(labels) {
+			continue
+		}
+		for _, src := range sources {
+			if src.fingerprint == fingerprint || !rule.source.matches(src.labels) {
+				continue
+			}
+			if rule.equal(src.labels, labels) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *AlertService) ListInhibitRules(tenantID uint) (ListInhibitRuleResponse, error) {
+	items, err := s.repo.ListInhibitRules(tenantID)
+	if err != nil {
+		return ListInhibitRuleResponse{}, err
+	}
+	return ListInhibitRuleResponse{Total: len(items), Items: items}, nil
+}
+
+func (s *AlertService) UpsertInhibitRule(tenantID uint, req InhibitRuleUpsertRequest) (model.InhibitRule, error) {
+	const op = "alert.UpsertInhibitRule"
+	if strings.TrimSpace(req.Name) == "" || len(req.SourceMatchers) == 0 || len(req.TargetMatchers) == 0 {
+		return model.InhibitRule{}, obserr.New("ALERT_INHIBIT_INVALID", op, "抑制规则名称、源匹配和目标匹配不能为空")
+	}
+	equal := make([]model.InhibitEqual, 0, len(req.Equal))
+	for _, eq := range req.Equal {
+		eq.Source = strings.TrimSpace(eq.Source)
+		eq.Target = strings.TrimSpace(eq.Target)
+		if eq.Source == "" {
+			return model.InhibitRule{}, obserr.New("ALERT_INHIBIT_INVALID", op, "equal 标签名不能为空")
+		}
+		equal = append(equal, eq)
+	}
+	rule := model.InhibitRule{
+		ID:             req.ID,
+		TenantID:       tenantID,
+		Name:           strings.TrimSpace(req.Name),
+		SourceMatchers: normalizeMatchers(req.SourceMatchers),
+		TargetMatchers: normalizeMatchers(req.TargetMatchers),
+		Equal:          equal,
+		Enabled:        req.Enabled,
+		Description:    req.Description,
+	}
+	if _, err := compileInhibitRule(rule); err != nil {
+		return model.InhibitRule{}, obserr.Wrap("ALERT_INHIBIT_MATCHER_INVALID", op, "抑制匹配规则无效", err)
+	}
+	return s.repo.UpsertInhibitRule(rule)
+}
+
+// historySources turns open history rows into inhibition sources.
+func historySources(items []model.History) []inhibitSource {
+	sources := make([]inhibitSource, 0, len(items))
+	for _, item := range items {
+		sources = append(sources, inhibitSource{fingerprint: item.Fingerprint, labels: historyLabels(item)})
+	}
+	return sources
+}
+
+// activeInhibitRules loads the tenant's enabled inhibit rules.
+func (s *AlertService) activeInhibitRules(tenantID uint) []*inhibitMatcher {
+	rules, err := s.repo.ListInhibitRules(tenantID)
+	if err != nil {
+		return nil
+	}
+	enabled := rules[:0]
+	for _, rule := range rules {
+		if rule.Enabled {
+			enabled = append(enabled, rule)
+		}
+	}
+	return compileInhibitRules(enabled)[tenantID]
+}
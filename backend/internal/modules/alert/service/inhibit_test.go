@@ -0,0 +1,114 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	monitorModel "devops-platform/internal/modules/monitor/model"
+	notifModel "devops-platform/internal/modules/notification/model"
+	notifService "devops-platform/internal/modules/notification/service"
+)
+
+func TestRuleEvaluator_InhibitsDependentAlerts(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&notifModel.ChannelConfig{}, &notifModel.SendLog{}, &notifModel.Template{}); err != nil {
+		t.Fatalf("failed to migrate notification tables: %v", err)
+	}
+	db.Create(&notifModel.ChannelConfig{TenantID: testTenantID, Channel: notifModel.ChannelFeishu, Enabled: true})
+	// PodCrashLooping is evaluated before NodeDown to prove the order does not matter.
+	db.Create(&model.Rule{ID: 1, TenantID: testTenantID, Name: "PodCrashLooping", Expr: `restarts > 0`, Severity: "warning", Enabled: true, PrometheusConfigID: 1})
+	db.Create(&model.Rule{ID: 2, TenantID: testTenantID, Name: "NodeDown", Expr: `up == 0`, Severity: "critical", Enabled: true, PrometheusConfigID: 2})
+
+	svc := NewAlertService(db)
+	if _, err := svc.UpsertInhibitRule(testTenantID, InhibitRuleUpsertRequest{
+		Name:           "节点宕机抑制 Pod 告警",
+		SourceMatchers: []model.Matcher{{Name: "alertname", Value: "NodeDown"}},
+		TargetMatchers: []model.Matcher{{Name: "alertname", Value: "PodCrashLooping"}},
+		Equal:          []model.InhibitEqual{{Source: "instance", Target: "node"}},
+		Enabled:        true,
+	}); err != nil {
+		t.Fatalf("upsert inhibit rule failed: %v", err)
+	}
+	if _, err := svc.UpsertInhibitRule(testTenantID, InhibitRuleUpsertRequest{
+		ID:             999,
+		Name:           "unknown",
+		SourceMatchers: []model.Matcher{{Name: "alertname", Value: "NodeDown"}},
+		TargetMatchers: []model.Matcher{{Name: "alertname", Value: "PodCrashLooping"}},
+	}); !svc.IsNotFound(err) {
+		t.Fatalf("expected update of an unknown inhibit rule not found, got %v", err)
+	}
+	if _, err := svc.UpsertInhibitRule(testTenantID, InhibitRuleUpsertRequest{Name: "invalid", SourceMatchers: []model.Matcher{{Name: "a", Op: "=~", Value: "("}}, TargetMatchers: []model.Matcher{{Name: "b", Value: "c"}}}); err == nil {
+		t.Fatalf("expected invalid matcher rejected")
+	}
+
+	ns := notifService.NewNotificationService(db)
+	notifier := &recordingNotifier{}
+	ns.RegisterNotifier(notifModel.ChannelFeishu, notifier)
+	querier := &fakeQuerier{results: map[uint][]monitorModel.MetricSeries{
+		1: {
+			{Metric: map[string]string{"pod": "api-1", "node": "node-1"}, Values: []monitorModel.MetricResult{{Value: 5}}},
+			{Metric: map[string]string{"pod": "api-2", "node": "node-2"}, Values: []monitorModel.MetricResult{{Value: 2}}},
+		},
+		2: {{Metric: map[string]string{"instance": "node-1"}, Values: []monitorModel.MetricResult{{Value: 0}}}},
+	}}
+	evaluator := NewRuleEvaluator(db, querier, NewAlertNotificationBridge(ns))
+	now := time.Now()
+	evaluator.now = func() time.Time { return now }
+	evaluator.Evaluate()
+
+	var inhibited model.History
+	if err := db.Where("status = ?", model.StateInhibited).First(&inhibited).Error; err != nil {
+		t.Fatalf("expected an inhibited history row: %v", err)
+	}
+	if inhibited.LabelMap()["node"] != "node-1" {
+		t.Fatalf("expected the pod on node-1 inhibited, got %v", inhibited.LabelMap())
+	}
+	var firing int64
+	db.Model(&model.History{}).Where("status = ?", model.StateFiring).Count(&firing)
+	if firing != 2 || len(notifier.subjects) != 2 {
+		t.Fatalf("expected NodeDown and the unrelated pod to fire, got firing=%d notifications=%v", firing, notifier.subjects)
+	}
+
+	// Once the node recovers the pod alert is no longer inhibited and pages.
+	querier.results[2] = nil
+	now = now.Add(time.Minute)
+	evaluator.Evaluate()
+	if err := db.First(&inhibited, inhibited.ID).Error; err != nil {
+		t.Fatalf("reload history failed: %v", err)
+	}
+	if inhibited.Status != model.StateFiring {
+		t.Fatalf("expected inhibited alert to fire after source resolved, got %s", inhibited.Status)
+	}
+	if len(notifier.subjects) != 4 {
+		t.Fatalf("expected NodeDown resolved and pod firing notifications, got %v", notifier.subjects)
+	}
+}
+
+func TestIngestAlertmanagerWebhook_InhibitedWithinPayload(t *testing.T) {
+	svc := newTestAlertService(t)
+	if _, err := svc.UpsertInhibitRule(testTenantID, InhibitRuleUpsertRequest{
+		Name:           "critical 抑制同集群 warning",
+		SourceMatchers: []model.Matcher{{Name: "severity", Value: "critical"}},
+		TargetMatchers: []model.Matcher{{Name: "severity", Value: "warning"}},
+		Equal:          []model.InhibitEqual{{Source: "cluster"}},
+		Enabled:        true,
+	}); err != nil {
+		t.Fatalf("upsert inhibit rule failed: %v", err)
+	}
+	payload := AlertmanagerWebhook{
+		Status: "firing",
+		Alerts: []AlertmanagerAlert{
+			{Status: "firing", Labels: map[string]string{"alertname": "HighLatency", "severity": "warning", "cluster": "prod-gz"}, Fingerprint: "latency"},
+			{Status: "firing", Labels: map[string]string{"alertname": "ClusterDown", "severity": "critical", "cluster": "prod-gz"}, Fingerprint: "down"},
+			{Status: "firing", Labels: map[string]string{"alertname": "HighLatency", "severity": "warning", "cluster": "prod-sz"}, Fingerprint: "latency-sz"},
+		},
+	}
+	if _, err := svc.IngestAlertmanagerWebhook(testTenantID, payload); err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	history, _ := svc.ListHistory(testTenantID, model.StateInhibited, time.Time{}, time.Time{})
+	if history.Total != 1 || history.Items[0].Fingerprint != "latency" {
+		t.Fatalf("expected only the same-cluster warning inhibited, got %+v", history.Items)
+	}
+}
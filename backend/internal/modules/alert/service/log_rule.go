@@ -0,0 +1,158 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	logModel "devops-platform/internal/modules/log/model"
+	monitorModel "devops-platform/internal/modules/monitor/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// defaultLogWindow is the span a log rule counts over when it names none.
+const defaultLogWindow = "5m"
+
+// LogCounter counts the entries a log search matches in its time range.
+// log/service.LogService satisfies it.
+type LogCounter interface {
+	Count(req logModel.SearchRequest) (int64, error)
+}
+
+// SavedLogQueries looks up the saved log queries a user may see.
+// log/service.LogService satisfies it.
+type SavedLogQueries interface {
+	GetSavedQuery(tenantID, userID, id uint) (*logModel.SavedQueryView, error)
+}
+
+// SetSavedLogQueries lets log rules be created from saved log queries.
+func (s *AlertService) SetSavedLogQueries(queries SavedLogQueries) {
+	s.savedQueries = queries
+}
+
+// applySavedQuery copies a saved query's source and filters onto a log rule.
+// Its relative time range becomes the window unless the rule sets one. Later
+// edits of the saved query do not change the rule.
+func (s *AlertService) applySavedQuery(rule *model.Rule, userID, id uint) error {
+	if s.savedQueries == nil {
+		return obserr.New("ALERT_RULE_INVALID", "alert.UpsertRule", "未启用已保存的日志查询")
+	}
+	view, err := s.savedQueries.GetSavedQuery(rule.TenantID, userID, id)
+	if err != nil {
+		return obserr.Wrap("ALERT_RULE_INVALID", "alert.UpsertRule", "已保存的日志查询不存在", err)
+	}
+	query := view.Query
+	rule.SavedQueryID = view.ID
+	rule.LogSourceID = view.SourceID
+	rule.LogQuery = &query
+	if rule.LogWindow == "" && view.TimeRange != "" {
+		window, err := savedQueryWindow(view.TimeRange)
+		if err != nil {
+			return err
+		}
+		rule.LogWindow = window
+	}
+	return nil
+}
+
+// savedQueryWindow turns a saved query's time range into a log window. Whole
+// days ("7d"), which time.ParseDuration rejects, become hours ("168h").
+func savedQueryWindow(timeRange string) (string, error) {
+	d, err := logModel.ParseTimeRange(timeRange)
+	if err != nil {
+		return "", obserr.New("ALERT_RULE_INVALID", "alert.UpsertRule", "已保存的日志查询时间范围无效")
+	}
+	if strings.HasSuffix(timeRange, "d") {
+		return fmt.Sprintf("%dh", int(d.Hours())), nil
+	}
+	return timeRange, nil
+}
+
+// SetLogCounter enables log rules; without a counter they are skipped.
+func (e *RuleEvaluator) SetLogCounter(counter LogCounter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.logCounter = counter
+}
+
+// queryLogs counts a log rule's matches over its window ending now. Above the
+// threshold it returns one series carrying the count, so the rule goes
+// through the same pending, firing and resolved states as a PromQL rule.
+func (e *RuleEvaluator) queryLogs(rule model.Rule, now time.Time) (*monitorModel.MetricQueryResponse, error) {
+	window, _ := time.ParseDuration(logWindow(rule))
+	req := logModel.SearchRequest{}
+	if rule.LogQuery != nil {
+		req = *rule.LogQuery
+	}
+	req.SourceID = rule.LogSourceID
+	req.StartTime = now.Add(-window).UTC().Format(time.RFC3339Nano)
+	req.EndTime = now.UTC().Format(time.RFC3339Nano)
+	count, err := e.logCounter.Count(req)
+	if err != nil {
+		return nil, err
+	}
+	resp := &monitorModel.MetricQueryResponse{ResultType: "vector"}
+	if count > rule.Threshold {
+		metric := map[string]string{"log_source_id": strconv.FormatUint(uint64(rule.LogSourceID), 10)}
+		if req.Service != "" {
+			metric["service"] = req.Service
+		}
+		if req.Host != "" {
+			metric["host"] = req.Host
+		}
+		resp.Results = []monitorModel.MetricSeries{{
+			Metric: metric,
+			Values: []monitorModel.MetricResult{{Timestamp: now.Unix(), Value: float64(count)}},
+		}}
+	}
+	return resp, nil
+}
+
+func logWindow(rule model.Rule) string {
+	if rule.LogWindow == "" {
+		return defaultLogWindow
+	}
+	return rule.LogWindow
+}
+
+// validateLogRule checks a log rule and fills in its defaults and Expr, a
+// readable rendering of the condition for lists and notifications.
+func validateLogRule(rule *model.Rule) error {
+	if rule.LogSourceID == 0 {
+		return obserr.New("ALERT_RULE_INVALID", "alert.UpsertRule", "日志告警规则必须指定日志源")
+	}
+	if rule.Threshold < 0 {
+		return obserr.New("ALERT_RULE_INVALID", "alert.UpsertRule", "日志告警阈值不能为负数")
+	}
+	rule.LogWindow = logWindow(*rule)
+	if d, err := time.ParseDuration(rule.LogWindow); err != nil || d <= 0 {
+		return obserr.New("ALERT_RULE_INVALID", "alert.UpsertRule", "日志统计窗口格式无效")
+	}
+	if rule.LogQuery == nil {
+		rule.LogQuery = &logModel.SearchRequest{}
+	}
+	q := rule.LogQuery
+	*q = logModel.SearchRequest{Keywords: q.Keywords, Level: q.Level, Service: q.Service, Host: q.Host}
+
+	var filters []string
+	for _, f := range []struct{ name, value string }{{"service", q.Service}, {"host", q.Host}, {"level", q.Level}} {
+		if f.value != "" {
+			filters = append(filters, fmt.Sprintf("%s=%q", f.name, f.value))
+		}
+	}
+	if len(q.Keywords) > 0 {
+		filters = append(filters, fmt.Sprintf("keywords=%q", strings.Join(q.Keywords, ",")))
+	}
+	rule.Expr = fmt.Sprintf("count(logs{source=%d%s}[%s]) > %d",
+		rule.LogSourceID, prefixJoin(", ", filters), rule.LogWindow, rule.Threshold)
+	return nil
+}
+
+func prefixJoin(sep string, items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	return sep + strings.Join(items, sep)
+}
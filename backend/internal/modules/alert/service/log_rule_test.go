@@ -0,0 +1,153 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	logModel "devops-platform/internal/modules/log/model"
+	monitorModel "devops-platform/internal/modules/monitor/model"
+	notifModel "devops-platform/internal/modules/notification/model"
+	notifService "devops-platform/internal/modules/notification/service"
+)
+
+type fakeLogCounter struct {
+	count    int64
+	requests []logModel.SearchRequest
+}
+
+func (c *fakeLogCounter) Count(req logModel.SearchRequest) (int64, error) {
+	c.requests = append(c.requests, req)
+	return c.count, nil
+}
+
+func TestRuleEvaluator_LogRuleFiresAboveThreshold(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&notifModel.ChannelConfig{}, &notifModel.SendLog{}, &notifModel.Template{}); err != nil {
+		t.Fatalf("failed to migrate notification tables: %v", err)
+	}
+	db.Create(&notifModel.ChannelConfig{TenantID: testTenantID, Channel: notifModel.ChannelFeishu, Enabled: true})
+
+	svc := NewAlertService(db)
+	rule, err := svc.UpsertRule(testTenantID, RuleUpsertRequest{
+		Name: "OrderOOM", Type: "log", Severity: "critical", Enabled: true,
+		LogSourceID: 3, LogQuery: &logModel.SearchRequest{Keywords: []string{"OutOfMemoryError"}, Service: "order-api", Page: 4},
+		Threshold: 50,
+	})
+	if err != nil {
+		t.Fatalf("upsert log rule: %v", err)
+	}
+	if rule.LogWindow != "5m" || rule.Expr != `count(logs{source=3, service="order-api", keywords="OutOfMemoryError"}[5m]) > 50` {
+		t.Fatalf("unexpected rule: window=%s expr=%s", rule.LogWindow, rule.Expr)
+	}
+
+	ns := notifService.NewNotificationService(db)
+	notifier := &recordingNotifier{}
+	ns.RegisterNotifier(notifModel.ChannelFeishu, notifier)
+	counter := &fakeLogCounter{count: 50}
+	evaluator := NewRuleEvaluator(db, &fakeQuerier{results: map[uint][]monitorModel.MetricSeries{}}, NewAlertNotificationBridge(ns))
+	evaluator.SetLogCounter(counter)
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	evaluator.now = func() time.Time { return now }
+
+	// At the threshold nothing fires.
+	evaluator.Evaluate()
+	req := counter.requests[0]
+	if req.SourceID != 3 || req.Service != "order-api" || req.Page != 0 ||
+		req.StartTime != "2026-10-18T09:55:00Z" || req.EndTime != "2026-10-18T10:00:00Z" {
+		t.Fatalf("unexpected count request: %+v", req)
+	}
+	var count int64
+	db.Model(&model.History{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected no alert at the threshold, got %d", count)
+	}
+
+	counter.count = 73
+	now = now.Add(time.Minute)
+	evaluator.Evaluate()
+	var firing model.History
+	if err := db.Where("status = ?", model.StateFiring).First(&firing).Error; err != nil {
+		t.Fatalf("expected firing history: %v", err)
+	}
+	if labels := firing.LabelMap(); labels["alertname"] != "OrderOOM" || labels["service"] != "order-api" || labels["log_source_id"] != "3" {
+		t.Fatalf("unexpected labels: %v", labels)
+	}
+	if firing.Summary != "OrderOOM (当前值 73)" {
+		t.Fatalf("unexpected summary: %s", firing.Summary)
+	}
+
+	counter.count = 2
+	now = now.Add(time.Minute)
+	evaluator.Evaluate()
+	var resolved model.History
+	db.First(&resolved, firing.ID)
+	if resolved.Status != model.StateResolved {
+		t.Fatalf("expected resolved history, got %s", resolved.Status)
+	}
+	if len(notifier.subjects) != 2 {
+		t.Fatalf("expected firing and resolved notifications, got %v", notifier.subjects)
+	}
+}
+
+func TestUpsertRule_ValidatesLogRules(t *testing.T) {
+	svc := NewAlertService(setupTestDB(t))
+	cases := []RuleUpsertRequest{
+		{Name: "no-source", Type: "log", Threshold: 1},
+		{Name: "bad-window", Type: "log", LogSourceID: 1, LogWindow: "soon"},
+		{Name: "negative", Type: "log", LogSourceID: 1, Threshold: -1},
+		{Name: "unknown", Type: "trace", Expr: "x"},
+	}
+	for _, req := range cases {
+		if _, err := svc.UpsertRule(testTenantID, req); err == nil {
+			t.Errorf("rule %s should be rejected", req.Name)
+		}
+	}
+}
+
+type fakeSavedQueries map[uint]logModel.SavedQuery
+
+func (f fakeSavedQueries) GetSavedQuery(tenantID, userID, id uint) (*logModel.SavedQueryView, error) {
+	q, ok := f[id]
+	if !ok || q.TenantID != tenantID || (q.OwnerID != userID && q.Visibility != logModel.VisibilityTenant) {
+		return nil, errors.New("saved query not found")
+	}
+	return &logModel.SavedQueryView{SavedQuery: q}, nil
+}
+
+func TestUpsertRule_LogRuleFromSavedQuery(t *testing.T) {
+	svc := NewAlertService(setupTestDB(t))
+	svc.SetSavedLogQueries(fakeSavedQueries{
+		4: {ID: 4, TenantID: testTenantID, OwnerID: 9, SourceID: 2, TimeRange: "10m",
+			Query: logModel.SearchRequest{Service: "billing", Level: "error", StartTime: "2026-10-01T00:00:00Z"}},
+	})
+	rule, err := svc.UpsertRule(testTenantID, RuleUpsertRequest{Name: "BillingErrors", Type: "log", SavedQueryID: 4, Threshold: 10, UserID: 9})
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if rule.SavedQueryID != 4 || rule.LogSourceID != 2 || rule.LogWindow != "10m" || rule.LogQuery.Service != "billing" || rule.LogQuery.StartTime != "" {
+		t.Fatalf("saved query not applied: %+v %+v", rule, rule.LogQuery)
+	}
+	if _, err := svc.UpsertRule(testTenantID, RuleUpsertRequest{Name: "Other", Type: "log", SavedQueryID: 4, UserID: 8}); err == nil {
+		t.Fatal("another user's private query must not be usable")
+	}
+}
+
+func TestUpsertRule_SavedQueryDayRangeBecomesWindow(t *testing.T) {
+	svc := NewAlertService(setupTestDB(t))
+	svc.SetSavedLogQueries(fakeSavedQueries{
+		4: {ID: 4, TenantID: testTenantID, OwnerID: 9, SourceID: 2, TimeRange: "7d", Query: logModel.SearchRequest{Service: "billing"}},
+		5: {ID: 5, TenantID: testTenantID, OwnerID: 9, SourceID: 2, TimeRange: "soon", Query: logModel.SearchRequest{Service: "billing"}},
+	})
+	rule, err := svc.UpsertRule(testTenantID, RuleUpsertRequest{Name: "WeeklyErrors", Type: "log", SavedQueryID: 4, Threshold: 10, UserID: 9})
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if rule.LogWindow != "168h" {
+		t.Fatalf("expected a 7d range to count over 168h, got %q", rule.LogWindow)
+	}
+	if _, err := svc.UpsertRule(testTenantID, RuleUpsertRequest{Name: "Broken", Type: "log", SavedQueryID: 5, Threshold: 10, UserID: 9}); err == nil {
+		t.Fatal("an unparsable time range must be rejected instead of falling back to the default window")
+	}
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+type ListScheduleResponse struct {
+	Total int                    `json:"total"`
+	Items []model.OnCallSchedule `json:"items"`
+}
+
+type ListEscalationPolicyResponse struct {
+	Total int                      `json:"total"`
+	Items []model.EscalationPolicy `json:"items"`
+}
+
+type ScheduleUpsertRequest struct {
+	ID          uint                   `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Layers      []model.OnCallLayer    `json:"layers"`
+	Overrides   []model.OnCallOverride `json:"overrides"`
+}
+
+type EscalationPolicyUpsertRequest struct {
+	ID          uint                    `json:"id"`
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Levels      []model.EscalationLevel `json:"levels"`
+}
+
+type HistoryActionRequest struct {
+	ID uint `json:"id"`
+}
+
+// OnCallResponse is the user on call for a schedule at a point in time.
+type OnCallResponse struct {
+	ScheduleID uint      `json:"scheduleId"`
+	At         time.Time `json:"at"`
+	UserID     uint      `json:"userId"`
+	Username   string    `json:"username"`
+	Name       string    `json:"name"`
+	Email      string    `json:"email"`
+}
+
+// OnCallAt returns the user on call for the schedule at the given time.
+// Overrides win over layers, and later layers win over earlier ones.
+func OnCallAt(schedule model.OnCallSchedule, at time.Time) (uint, bool) {
+	for i := len(schedule.Overrides) - 1; i >= 0; i-- {
+		o := schedule.Overrides[i]
+		if !at.Before(o.StartsAt) && at.Before(o.EndsAt) {
+			return o.UserID, true
+		}
+	}
+	for i := len(schedule.Layers) - 1; i >= 0; i-- {
+		layer := schedule.Layers[i]
+		if len(layer.UserIDs) == 0 || at.Before(layer.StartAt) {
+			continue
+		}
+		if layer.EndAt != nil && !at.Before(*layer.EndAt) {
+			continue
+		}
+		shift := 24 * time.Hour
+		if layer.Rotation == model.RotationWeekly {
+			shift = 7 * 24 * time.Hour
+		}
+		// Handoffs happen at StartAt's time of day, every shift.
+		n := int(at.Sub(layer.StartAt) / shift)
+		return layer.UserIDs[n%len(layer.UserIDs)], true
+	}
+	return 0, false
+}
+
+func (s *AlertService) ListSchedules(tenantID uint) (ListScheduleResponse, error) {
+	items, err := s.repo.ListSchedules(tenantID)
+	if err != nil {
+		return ListScheduleResponse{}, err
+	}
+	return ListScheduleResponse{Total: len(items), Items: items}, nil
+}
+
+func (s *AlertService) UpsertSchedule(tenantID uint, req ScheduleUpsertRequest) (model.OnCallSchedule, error) {
+	const op = "alert.UpsertSchedule"
+	if strings.TrimSpace(req.Name) == "" {
+		return model.OnCallSchedule{}, obserr.New("ALERT_SCHEDULE_INVALID", op, "值班表名称不能为空")
+	}
+	if len(req.Layers) == 0 {
+		return model.OnCallSchedule{}, obserr.New("ALERT_SCHEDULE_INVALID", op, "值班表至少需要一个轮值层")
+	}
+	layers := make([]model.OnCallLayer, 0, len(req.Layers))
+	for _, layer := range req.Layers {
+		layer.Rotation = strings.TrimSpace(strings.ToLower(layer.Rotation))
+		if layer.Rotation == "" {
+			layer.Rotation = model.RotationDaily
+		}
+		if layer.Rotation != model.RotationDaily && layer.Rotation != model.RotationWeekly {
+			return model.OnCallSchedule{}, obserr.New("ALERT_SCHEDULE_INVALID", op, "轮值周期仅支持 daily 或 weekly")
+		}
+		if len(layer.UserIDs) == 0 || layer.StartAt.IsZero() {
+			return model.OnCallSchedule{}, obserr.New("ALERT_SCHEDULE_INVALID", op, "轮值层需要值班人员和交接起始时间")
+		}
+		if layer.EndAt != nil && !layer.EndAt.After(layer.StartAt) {
+			return model.OnCallSchedule{}, obserr.New("ALERT_SCHEDULE_INVALID", op, "轮值层结束时间必须晚于开始时间")
+		}
+		if err := s.checkUsers(tenantID, layer.UserIDs, op); err != nil {
+			return model.OnCallSchedule{}, err
+		}
+		layers = append(layers, layer)
+	}
+	for _, o := range req.Overrides {
+		if o.UserID == 0 || !o.EndsAt.After(o.StartsAt) {
+			return model.OnCallSchedule{}, obserr.New("ALERT_SCHEDULE_INVALID", op, "替班需要值班人员和有效的时间范围")
+		}
+		if err := s.checkUsers(tenantID, []uint{o.UserID}, op); err != nil {
+			return model.OnCallSchedule{}, err
+		}
+	}
+	return s.repo.UpsertSchedule(model.OnCallSchedule{
+		ID:          req.ID,
+		TenantID:    tenantID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Layers:      layers,
+		Overrides:   req.Overrides,
+	})
+}
+
+// CurrentOnCall returns who is on call for the schedule at the given time.
+func (s *AlertService) CurrentOnCall(tenantID, scheduleID uint, at time.Time) (OnCallResponse, error) {
+	const op = "alert.CurrentOnCall"
+	schedule, ok, err := s.repo.GetSchedule(tenantID, scheduleID)
+	if err != nil {
+		return OnCallResponse{}, err
+	}
+	if !ok {
+		return OnCallResponse{}, obserr.New("ALERT_SCHEDULE_NOT_FOUND", op, "值班表不存在")
+	}
+	resp := OnCallResponse{ScheduleID: schedule.ID, At: at}
+	userID, ok := OnCallAt(schedule, at)
+	if !ok {
+		return resp, nil
+	}
+	resp.UserID = userID
+	users, err := s.repo.ListUsers(tenantID, []uint{userID})
+	if err != nil {
+		return OnCallResponse{}, err
+	}
+	if len(users) > 0 {
+		resp.Username = users[0].Username
+		resp.Name = users[0].Name
+		resp.Email = users[0].Email
+	}
+	return resp, nil
+}
+
+func (s *AlertService) ListEscalationPolicies(tenantID uint) (ListEscalationPolicyResponse, error) {
+	items, err := s.repo.ListEscalationPolicies(tenantID)
+	if err != nil {
+		return ListEscalationPolicyResponse{}, err
+	}
+	return ListEscalationPolicyResponse{Total: len(items), Items: items}, nil
+}
+
+func (s *AlertService) UpsertEscalationPolicy(tenantID uint, req EscalationPolicyUpsertRequest) (model.EscalationPolicy, error) {
+	const op = "alert.UpsertEscalationPolicy"
+	if strings.TrimSpace(req.Name) == "" || len(req.Levels) == 0 {
+		return model.EscalationPolicy{}, obserr.New("ALERT_ESCALATION_INVALID", op, "升级策略名称和升级层级不能为空")
+	}
+	for _, level := range req.Levels {
+		if level.DelayMinutes < 0 {
+			return model.EscalationPolicy{}, obserr.New("ALERT_ESCALATION_INVALID", op, "升级等待时间不能为负数")
+		}
+		if level.ScheduleID == 0 && len(level.UserIDs) == 0 {
+			return model.EscalationPolicy{}, obserr.New("ALERT_ESCALATION_INVALID", op, "每个升级层级需要值班表或通知人员")
+		}
+		if level.ScheduleID > 0 {
+			if _, ok, err := s.repo.GetSchedule(tenantID, level.ScheduleID); err != nil {
+				return model.EscalationPolicy{}, err
+			} else if !ok {
+				return model.EscalationPolicy{}, obserr.New("ALERT_SCHEDULE_NOT_FOUND", op, "值班表不存在")
+			}
+		}
+		if err := s.checkUsers(tenantID, level.UserIDs, op); err != nil {
+			return model.EscalationPolicy{}, err
+		}
+	}
+	return s.repo.UpsertEscalationPolicy(model.EscalationPolicy{
+		ID:          req.ID,
+		TenantID:    tenantID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Levels:      req.Levels,
+	})
+}
+
+// AckHistory acknowledges an open alert, which stops its escalation.
+func (s *AlertService) AckHistory(tenantID, id uint, actor string) (model.History, error) {
+	item, err := s.openHistory(tenantID, id, "alert.AckHistory")
+	if err != nil {
+		return model.History{}, err
+	}
+	if item.AckedAt != nil {
+		return item, nil
+	}
+	now := time.Now()
+	ok, err := s.repo.AckHistory(tenantID, id, actor, now)
+	if err != nil {
+		return model.History{}, err
+	}
+	if !ok {
+		// Resolved or acknowledged since it was loaded.
+		return s.openHistory(tenantID, id, "alert.AckHistory")
+	}
+	item.AckedBy = actor
+	item.AckedAt = &now
+	return item, nil
+}
+
+// ResolveHistory manually resolves an open alert.
+func (s *AlertService) ResolveHistory(tenantID, id uint, actor string) (model.History, error) {
+	item, err := s.openHistory(tenantID, id, "alert.ResolveHistory")
+	if err != nil {
+		return model.History{}, err
+	}
+	now := time.Now()
+	ok, err := s.repo.ResolveHistory(tenantID, id, actor, now)
+	if err != nil {
+		return model.History{}, err
+	}
+	if !ok {
+		// Resolved since it was loaded.
+		return s.openHistory(tenantID, id, "alert.ResolveHistory")
+	}
+	item.Status = model.StateResolved
+	item.EndsAt = now
+	item.ResolvedBy = actor
+	item.ResolvedAt = &now
+	return item, nil
+}
+
+func (s *AlertService) openHistory(tenantID, id uint, op string) (model.History, error) {
+	item, ok, err := s.repo.GetTenantHistory(tenantID, id)
+	if err != nil {
+		return model.History{}, err
+	}
+	if !ok {
+		return model.History{}, obserr.New("ALERT_HISTORY_NOT_FOUND", op, "告警记录不存在")
+	}
+	if item.Status == model.StateResolved {
+		return model.History{}, obserr.New("ALERT_HISTORY_RESOLVED", op, "告警已恢复")
+	}
+	return item, nil
+}
+
+// checkUsers rejects user IDs that do not belong to the tenant.
+func (s *AlertService) checkUsers(tenantID uint, ids []uint, op string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	users, err := s.repo.ListUsers(tenantID, ids)
+	if err != nil {
+		return err
+	}
+	found := make(map[uint]bool, len(users))
+	for _, u := range users {
+		found[u.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return obserr.New("ALERT_USER_NOT_FOUND", op, "值班人员不存在")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	notifModel "devops-platform/internal/modules/notification/model"
+	notifService "devops-platform/internal/modules/notification/service"
+	userModel "devops-platform/internal/modules/user/model"
+
+	"gorm.io/gorm"
+)
+
+func seedOnCallUsers(t *testing.T, db *gorm.DB) {
+	if err := db.AutoMigrate(&userModel.User{}); err != nil {
+		t.Fatalf("failed to migrate users: %v", err)
+	}
+	tenantID := testTenantID
+	for _, u := range []userModel.User{
+		{ID: 11, TenantID: &tenantID, Username: "alice", Name: "Alice", Email: "alice@example.com"},
+		{ID: 12, TenantID: &tenantID, Username: "bob", Name: "Bob", Email: "bob@example.com"},
+		{ID: 13, TenantID: &tenantID, Username: "carol", Name: "Carol", Email: "carol@example.com"},
+	} {
+		if err := db.Create(&u).Error; err != nil {
+			t.Fatalf("seed user failed: %v", err)
+		}
+	}
+}
+
+func TestOnCallAt_RotationLayersAndOverrides(t *testing.T) {
+	handoff := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC) // Monday 09:00
+	schedule := model.OnCallSchedule{
+		Layers: []model.OnCallLayer{
+			{Rotation: model.RotationWeekly, UserIDs: []uint{11, 12}, StartAt: handoff},
+			// Weekend layer on top of the weekly rotation for the first weekend only.
+			{Rotation: model.RotationDaily, UserIDs: []uint{13}, StartAt: handoff.Add(5 * 24 * time.Hour), EndAt: ptrTime(handoff.Add(7 * 24 * time.Hour))},
+		},
+		Overrides: []model.OnCallOverride{
+			{UserID: 11, StartsAt: handoff.Add(8 * 24 * time.Hour), EndsAt: handoff.Add(9 * 24 * time.Hour)},
+		},
+	}
+
+	cases := []struct {
+		at   time.Time
+		want uint
+	}{
+		{handoff.Add(-time.Hour), 0},
+		{handoff.Add(time.Hour), 11},
+		{handoff.Add(6 * 24 * time.Hour), 13},
+		{handoff.Add(7*24*time.Hour - time.Minute), 13},
+		{handoff.Add(7 * 24 * time.Hour), 12},
+		{handoff.Add(8*24*time.Hour + time.Hour), 11},
+		{handoff.Add(14 * 24 * time.Hour), 11},
+	}
+	for _, tc := range cases {
+		got, _ := OnCallAt(schedule, tc.at)
+		if got != tc.want {
+			t.Fatalf("at %s expected user %d, got %d", tc.at, tc.want, got)
+		}
+	}
+}
+
+func ptrTime(t time.Time) *time.Time { return &t }
+
+func TestEscalator_PagesNextLevelUntilAcked(t *testing.T) {
+	db := setupTestDB(t)
+	seedOnCallUsers(t, db)
+	if err := db.AutoMigrate(&model.OnCallSchedule{}, &model.EscalationPolicy{}, &notifModel.ChannelConfig{}, &notifModel.SendLog{}, &notifModel.Template{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	db.Create(&notifModel.ChannelConfig{TenantID: testTenantID, Channel: notifModel.ChannelEmail, Enabled: true})
+	svc := NewAlertService(db)
+
+	start := time.Now().Add(-48 * time.Hour).Truncate(time.Hour)
+	schedule, err := svc.UpsertSchedule(testTenantID, ScheduleUpsertRequest{
+		Name:   "SRE 主值班",
+		Layers: []model.OnCallLayer{{Rotation: "daily", UserIDs: []uint{11}, StartAt: start}},
+	})
+	if err != nil {
+		t.Fatalf("upsert schedule failed: %v", err)
+	}
+	if _, err := svc.UpsertSchedule(testTenantID, ScheduleUpsertRequest{
+		Name:   "unknown user",
+		Layers: []model.OnCallLayer{{UserIDs: []uint{99}, StartAt: start}},
+	}); err == nil {
+		t.Fatalf("expected unknown on-call user rejected")
+	}
+	policy, err := svc.UpsertEscalationPolicy(testTenantID, EscalationPolicyUpsertRequest{
+		Name: "核心服务",
+		Levels: []model.EscalationLevel{
+			{ScheduleID: schedule.ID},
+			{DelayMinutes: 15, UserIDs: []uint{13}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("upsert policy failed: %v", err)
+	}
+	item := model.History{TenantID: testTenantID, RuleName: "NodeDown", Severity: "critical", Status: model.StateFiring, StartsAt: time.Now(), EscalationPolicyID: policy.ID}
+	db.Create(&item)
+
+	ns := notifService.NewNotificationService(db)
+	notifier := &recipientNotifier{}
+	ns.RegisterNotifier(notifModel.ChannelEmail, notifier)
+	escalator := NewEscalator(db, ns)
+	now := time.Now()
+	escalator.now = func() time.Time { return now }
+
+	escalator.Escalate()
+	if len(notifier.recipients) != 1 || notifier.recipients[0] != "alice@example.com" {
+		t.Fatalf("expected level 1 to page on-call alice, got %v", notifier.recipients)
+	}
+	now = now.Add(10 * time.Minute)
+	escalator.Escalate()
+	if len(notifier.recipients) != 1 {
+		t.Fatalf("expected level 2 to wait for its delay, got %v", notifier.recipients)
+	}
+	now = now.Add(6 * time.Minute)
+	escalator.Escalate()
+	if len(notifier.recipients) != 2 || notifier.recipients[1] != "carol@example.com" {
+		t.Fatalf("expected level 2 to page carol, got %v", notifier.recipients)
+	}
+
+	// An acknowledged alert is no longer escalated.
+	second := model.History{TenantID: testTenantID, RuleName: "DiskFull", Severity: "critical", Status: model.StateFiring, StartsAt: time.Now(), EscalationPolicyID: policy.ID}
+	db.Create(&second)
+	acked, err := svc.AckHistory(testTenantID, second.ID, "bob")
+	if err != nil || acked.AckedBy != "bob" || acked.AckedAt == nil {
+		t.Fatalf("expected ack recorded, got %+v err=%v", acked, err)
+	}
+	escalator.Escalate()
+	if len(notifier.recipients) != 2 {
+		t.Fatalf("expected acked alert not paged, got %v", notifier.recipients)
+	}
+
+	resolved, err := svc.ResolveHistory(testTenantID, second.ID, "bob")
+	if err != nil || resolved.Status != model.StateResolved || resolved.ResolvedBy != "bob" || resolved.ResolvedAt == nil {
+		t.Fatalf("expected manual resolve recorded, got %+v err=%v", resolved, err)
+	}
+	if _, err := svc.AckHistory(testTenantID, second.ID, "bob"); err == nil {
+		t.Fatalf("expected ack of resolved alert rejected")
+	}
+	if _, err := svc.AckHistory(2, item.ID, "mallory"); !svc.IsNotFound(err) {
+		t.Fatalf("expected other tenant's alert not found, got %v", err)
+	}
+}
+
+type recipientNotifier struct {
+	recipients []string
+}
+
+func (n *recipientNotifier) Send(recipients []string, subject, body string) error {
+	n.recipients = append(n.recipients, recipients...)
+	return nil
+}
+
+// ackingNotifier acknowledges the alert while its page is being sent.
+type ackingNotifier struct {
+	recipientNotifier
+	ack func()
+}
+
+func (n *ackingNotifier) Send(recipients []string, subject, body string) error {
+	n.ack()
+	return n.recipientNotifier.Send(recipients, subject, body)
+}
+
+func TestEscalator_KeepsAckMadeDuringPage(t *testing.T) {
+	db := setupTestDB(t)
+	seedOnCallUsers(t, db)
+	if err := db.AutoMigrate(&model.OnCallSchedule{}, &model.EscalationPolicy{}, &notifModel.ChannelConfig{}, &notifModel.SendLog{}, &notifModel.Template{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	db.Create(&notifModel.ChannelConfig{TenantID: testTenantID, Channel: notifModel.ChannelEmail, Enabled: true})
+	svc := NewAlertService(db)
+	policy, err := svc.UpsertEscalationPolicy(testTenantID, EscalationPolicyUpsertRequest{
+		Name:   "核心服务",
+		Levels: []model.EscalationLevel{{UserIDs: []uint{11}}, {UserIDs: []uint{13}}},
+	})
+	if err != nil {
+		t.Fatalf("upsert policy failed: %v", err)
+	}
+	item := model.History{TenantID: testTenantID, RuleName: "NodeDown", Severity: "critical", Status: model.StateFiring, StartsAt: time.Now(), EscalationPolicyID: policy.ID}
+	db.Create(&item)
+
+	ns := notifService.NewNotificationService(db)
+	notifier := &ackingNotifier{ack: func() {
+		if _, err := svc.AckHistory(testTenantID, item.ID, "bob"); err != nil {
+			t.Errorf("ack during page failed: %v", err)
+		}
+	}}
+	ns.RegisterNotifier(notifModel.ChannelEmail, notifier)
+	escalator := NewEscalator(db, ns)
+	escalator.Escalate()
+	escalator.Escalate()
+
+	db.First(&item, item.ID)
+	if item.AckedBy != "bob" || item.AckedAt == nil {
+		t.Fatalf("ack made during the page was overwritten: %+v", item)
+	}
+	if len(notifier.recipients) != 1 {
+		t.Fatalf("expected the acknowledged alert paged once, got %v", notifier.recipients)
+	}
+}
+
+func TestUpsertScheduleAndPolicy_RejectUnknownIDs(t *testing.T) {
+	db := setupTestDB(t)
+	seedOnCallUsers(t, db)
+	if err := db.AutoMigrate(&model.OnCallSchedule{}, &model.EscalationPolicy{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	svc := NewAlertService(db)
+	layers := []model.OnCallLayer{{Rotation: "daily", UserIDs: []uint{11}, StartAt: time.Now()}}
+	schedule, err := svc.UpsertSchedule(testTenantID, ScheduleUpsertRequest{Name: "SRE", Layers: layers})
+	if err != nil {
+		t.Fatalf("upsert schedule failed: %v", err)
+	}
+	policy, err := svc.UpsertEscalationPolicy(testTenantID, EscalationPolicyUpsertRequest{Name: "核心服务", Levels: []model.EscalationLevel{{UserIDs: []uint{11}}}})
+	if err != nil {
+		t.Fatalf("upsert policy failed: %v", err)
+	}
+
+	if _, err := svc.UpsertSchedule(testTenantID, ScheduleUpsertRequest{ID: schedule.ID + 100, Name: "SRE", Layers: layers}); !svc.IsNotFound(err) {
+		t.Fatalf("expected unknown schedule not found, got %v", err)
+	}
+	if _, err := svc.UpsertEscalationPolicy(testTenantID, EscalationPolicyUpsertRequest{ID: policy.ID + 100, Name: "核心服务", Levels: []model.EscalationLevel{{UserIDs: []uint{11}}}}); !svc.IsNotFound(err) {
+		t.Fatalf("expected unknown policy not found, got %v", err)
+	}
+	var schedules, policies int64
+	db.Model(&model.OnCallSchedule{}).Count(&schedules)
+	db.Model(&model.EscalationPolicy{}).Count(&policies)
+	if schedules != 1 || policies != 1 {
+		t.Fatalf("expected no rows created for unknown IDs, got %d schedules and %d policies", schedules, policies)
+	}
+}
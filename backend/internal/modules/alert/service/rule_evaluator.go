@@ -0,0 +1,475 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	"devops-platform/internal/modules/alert/repository"
+	monitorModel "devops-platform/internal/modules/monitor/model"
+	"devops-platform/internal/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MetricQuerier runs an instant PromQL query against a configured Prometheus source.
+// monitor/repository.MonitorRepo satisfies it.
+type MetricQuerier interface {
+	QueryInstant(configID uint, promQL string) (*monitorModel.MetricQueryResponse, error)
+}
+
+// activeAlert is the in-memory state of one pending or firing alert series.
+type activeAlert struct {
+	tenantID  uint
+	ruleID    uint
+	state     string
+	activeAt  time.Time
+	historyID uint
+	labels    map[string]string
+	value     float64
+}
+
+// RuleEvaluator periodically evaluates enabled alert rules against their
+// Prometheus or log source, tracking pending/firing/resolved state per
+// fingerprint.
+type RuleEvaluator struct {
+	repo       *repository.AlertRepo
+	querier    MetricQuerier
+	logCounter LogCounter
+	bridge     *AlertNotificationBridge
+	now        func() time.Time
+
+	mu       sync.Mutex
+	active   map[string]*activeAlert
+	restored bool
+	cancel   context.CancelFunc
+}
+
+func NewRuleEvaluator(db *gorm.DB, querier MetricQuerier, bridge *AlertNotificationBridge) *RuleEvaluator {
+	return &RuleEvaluator{
+		repo:    repository.NewAlertRepo(db),
+		querier: querier,
+		bridge:  bridge,
+		now:     time.Now,
+		active:  make(map[string]*activeAlert),
+	}
+}
+
+// Start runs Evaluate on the given interval until Stop is called.
+func (e *RuleEvaluator) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.mu.Lock()
+	e.cancel = cancel
+	e.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.Evaluate()
+			}
+		}
+	}()
+}
+
+func (e *RuleEvaluator) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cancel != nil {
+		e.cancel()
+		e.cancel = nil
+	}
+}
+
+// Evaluate runs one evaluation round over all enabled rules. Series are
+// observed for every rule first and states decided afterwards, so an
+// inhibiting alert and the alert it suppresses are settled in the same round.
+func (e *RuleEvaluator) Evaluate() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.restored {
+		e.restoreOpen()
+	}
+
+	rules, err := e.repo.ListEnabledRules()
+	if err != nil {
+		logWarn("加载告警规则失败", zap.Error(err))
+		return
+	}
+	now := e.now()
+	activeSilences, err := e.repo.ListActiveSilences(ruleTenants(rules), now)
+	if err != nil {
+		logWarn("加载告警静默失败", zap.Error(err))
+	}
+	silences := compileSilences(activeSilences)
+	inhibitRules, err := e.repo.ListEnabledInhibitRules()
+	if err != nil {
+		logWarn("加载告警抑制规则失败", zap.Error(err))
+	}
+	inhibitions := compileInhibitRules(inhibitRules)
+
+	enabled := make(map[uint]model.Rule, len(rules))
+	observed := make(map[uint]bool, len(rules))
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		enabled[rule.ID] = rule
+		resp, err := e.query(rule, now)
+		if resp == nil && err == nil {
+			continue
+		}
+		if err != nil {
+			// Keep the current state on query failure rather than resolving everything.
+			logWarn("告警规则查询失败", zap.Uint("ruleID", rule.ID), zap.Error(err))
+			continue
+		}
+		observed[rule.ID] = true
+		e.observe(rule, resp, now, seen)
+	}
+
+	for fp, alert := range e.active {
+		rule, ok := enabled[alert.ruleID]
+		switch {
+		case !ok:
+			// The rule was disabled or deleted.
+			e.resolve(fp, alert, model.Rule{ID: alert.ruleID, TenantID: alert.tenantID})
+		case observed[alert.ruleID] && !seen[fp]:
+			e.resolve(fp, alert, rule)
+		}
+	}
+
+	external := e.externalOpen()
+	sources := e.inhibitSources(now, enabled, external)
+	for fp, alert := range e.active {
+		rule := enabled[alert.ruleID]
+		if alert.state == model.StatePending && !e.due(alert, rule, now) {
+			continue
+		}
+		status := model.StateFiring
+		switch {
+		case isSilenced(silences[rule.TenantID], rule.ID, alert.labels, now):
+			status = model.StateSilenced
+		case isInhibited(inhibitions[rule.TenantID], sources[rule.TenantID], fp, alert.labels):
+			status = model.StateInhibited
+		}
+		switch alert.state {
+		case model.StatePending:
+			e.fire(fp, alert, rule, status)
+		case status:
+		default:
+			e.transition(alert, rule, status)
+		}
+	}
+	e.reinhibitExternal(external, inhibitions, sources)
+}
+
+// ruleTenants lists the tenants owning the rules, each once.
+func ruleTenants(rules []model.Rule) []uint {
+	var tenants []uint
+	seen := make(map[uint]bool)
+	for _, rule := range rules {
+		if !seen[rule.TenantID] {
+			seen[rule.TenantID] = true
+			tenants = append(tenants, rule.TenantID)
+		}
+	}
+	return tenants
+}
+
+// query runs a rule against its source. It returns nil and no error for a
+// rule without a source, which is skipped.
+func (e *RuleEvaluator) query(rule model.Rule, now time.Time) (*monitorModel.MetricQueryResponse, error) {
+	if rule.Type == model.RuleTypeLog {
+		if rule.LogSourceID == 0 || e.logCounter == nil {
+			return nil, nil
+		}
+		return e.queryLogs(rule, now)
+	}
+	if rule.PrometheusConfigID == 0 {
+		return nil, nil
+	}
+	return e.querier.QueryInstant(rule.PrometheusConfigID, rule.Expr)
+}
+
+// observe records the series returned for a rule, starting new ones as pending.
+func (e *RuleEvaluator) observe(rule model.Rule, resp *monitorModel.MetricQueryResponse, now time.Time, seen map[string]bool) {
+	for _, series := range resp.Results {
+		if len(series.Values) == 0 {
+			continue
+		}
+		labels := alertLabels(rule, series.Metric)
+		fp := Fingerprint(labels)
+		seen[fp] = true
+
+		alert, ok := e.active[fp]
+		if !ok {
+			alert = &activeAlert{
+				tenantID: rule.TenantID,
+				ruleID:   rule.ID,
+				state:    model.StatePending,
+				activeAt: now,
+				labels:   labels,
+			}
+			e.active[fp] = alert
+		}
+		alert.value = series.Values[len(series.Values)-1].Value
+	}
+}
+
+// due reports whether a pending alert has been active for the rule's For duration.
+func (e *RuleEvaluator) due(alert *activeAlert, rule model.Rule, now time.Time) bool {
+	forDuration, _ := time.ParseDuration(rule.For)
+	return now.Sub(alert.activeAt) >= forDuration
+}
+
+// externalOpen returns open history rows not tracked by this evaluator, such
+// as alerts ingested from the Alertmanager webhook.
+func (e *RuleEvaluator) externalOpen() []model.History {
+	items, err := e.repo.ListOpenHistory("")
+	if err != nil {
+		logWarn("加载未恢复告警失败", zap.Error(err))
+		return nil
+	}
+	external := items[:0]
+	for _, item := range items {
+		if _, ok := e.active[item.Fingerprint]; !ok {
+			external = append(external, item)
+		}
+	}
+	return external
+}
+
+// inhibitSources collects, per tenant, every alert that is open or about to fire.
+func (e *RuleEvaluator) inhibitSources(now time.Time, rules map[uint]model.Rule, external []model.History) map[uint][]inhibitSource {
+	sources := make(map[uint][]inhibitSource)
+	for fp, alert := range e.active {
+		if alert.state == model.StatePending && !e.due(alert, rules[alert.ruleID], now) {
+			continue
+		}
+		sources[alert.tenantID] = append(sources[alert.tenantID], inhibitSource{fingerprint: fp, labels: alert.labels})
+	}
+	for _, item := range external {
+		sources[item.TenantID] = append(sources[item.TenantID], historySources([]model.History{item})...)
+	}
+	return sources
+}
+
+// reinhibitExternal flips externally ingested alerts between firing and
+// inhibited as their inhibiting alerts come and go. Silenced ones are left
+// to the webhook, which re-evaluates them on every delivery.
+func (e *RuleEvaluator) reinhibitExternal(external []model.History, inhibitions map[uint][]*inhibitMatcher, sources map[uint][]inhibitSource) {
+	for i := range external {
+		item := &external[i]
+		if item.Status != model.StateFiring && item.Status != model.StateInhibited {
+			continue
+		}
+		labels := historyLabels(*item)
+		status := model.StateFiring
+		if isInhibited(inhibitions[item.TenantID], sources[item.TenantID], item.Fingerprint, labels) {
+			status = model.StateInhibited
+		}
+		if status == item.Status {
+			continue
+		}
+		item.Status = status
+		if err := e.repo.SaveHistory(item); err != nil {
+			logWarn("更新告警历史失败", zap.Uint("historyID", item.ID), zap.Error(err))
+			continue
+		}
+		if status == model.StateFiring && e.bridge != nil {
+			err := e.bridge.SendAlert(item.TenantID, AlertInfo{
+				RuleName:    item.RuleName,
+				Severity:    item.Severity,
+				Cluster:     item.Cluster,
+				Status:      status,
+				Description: item.Summary,
+				Labels:      labels,
+			})
+			if err != nil {
+				logWarn("告警通知发送失败", zap.Uint("historyID", item.ID), zap.Error(err))
+			}
+		}
+	}
+}
+
+// fire records a pending alert that reached its For duration with the given
+// status, notifying only when it is firing.
+func (e *RuleEvaluator) fire(fp string, alert *activeAlert, rule model.Rule, status string) {
+	cluster := alert.labels["cluster"]
+	if cluster == "" {
+		cluster = rule.Cluster
+	}
+	summary := rule.Description
+	if summary == "" {
+		summary = rule.Name
+	}
+	item := &model.History{
+		TenantID:    rule.TenantID,
+		RuleID:      rule.ID,
+		RuleName:    rule.Name,
+		Status:      status,
+		Severity:    rule.Severity,
+		Summary:     fmt.Sprintf("%s (当前值 %g)", summary, alert.value),
+		StartsAt:    alert.activeAt,
+		Cluster:     cluster,
+		Namespace:   alert.labels["namespace"],
+		Instance:    alert.labels["instance"],
+		Fingerprint: fp,
+		Source:      model.HistorySourceRule,
+		Labels:      model.EncodeLabels(alert.labels),
+
+		EscalationPolicyID: rule.EscalationPolicyID,
+	}
+	if err := e.repo.CreateHistory(item); err != nil {
+		logWarn("写入告警历史失败", zap.Uint("ruleID", rule.ID), zap.Error(err))
+		return
+	}
+	alert.state = status
+	alert.historyID = item.ID
+	if status == model.StateFiring {
+		e.notify(rule, alert, model.StateFiring)
+	}
+}
+
+// transition moves an open alert between firing, silenced and inhibited,
+// notifying when it starts firing again. Alerts resolved by hand are left
+// alone and acknowledged ones are not notified.
+func (e *RuleEvaluator) transition(alert *activeAlert, rule model.Rule, status string) {
+	item, err := e.repo.GetHistory(alert.historyID)
+	if err != nil {
+		logWarn("读取告警历史失败", zap.Uint("historyID", alert.historyID), zap.Error(err))
+		return
+	}
+	if item.Status == model.StateResolved {
+		// Resolved by hand; it stays closed and quiet until the condition clears.
+		alert.state = status
+		return
+	}
+	item.Status = status
+	if err := e.repo.SaveHistory(&item); err != nil {
+		logWarn("更新告警历史失败", zap.Uint("historyID", alert.historyID), zap.Error(err))
+		return
+	}
+	alert.state = status
+	// An acknowledged alert is already being handled and is not paged again.
+	if status == model.StateFiring && item.AckedAt == nil {
+		e.notify(rule, alert, model.StateFiring)
+	}
+}
+
+func (e *RuleEvaluator) resolve(fp string, alert *activeAlert, rule model.Rule) {
+	delete(e.active, fp)
+	if alert.state == model.StatePending {
+		return
+	}
+	if alert.historyID > 0 {
+		item, err := e.repo.GetHistory(alert.historyID)
+		if err != nil {
+			logWarn("读取告警历史失败", zap.Uint("historyID", alert.historyID), zap.Error(err))
+		} else if item.Status == model.StateResolved {
+			// Already resolved by hand; keep who resolved it and stay quiet.
+			return
+		} else {
+			item.Status = model.StateResolved
+			item.EndsAt = e.now()
+			if err := e.repo.SaveHistory(&item); err != nil {
+				logWarn("更新告警历史失败", zap.Uint("historyID", alert.historyID), zap.Error(err))
+			}
+			if rule.Name == "" {
+				rule.Name = item.RuleName
+				rule.Severity = item.Severity
+				rule.Cluster = item.Cluster
+			}
+		}
+	}
+	// Silenced and inhibited alerts never paged, so their resolution stays quiet too.
+	if alert.state == model.StateFiring {
+		e.notify(rule, alert, model.StateResolved)
+	}
+}
+
+func (e *RuleEvaluator) notify(rule model.Rule, alert *activeAlert, status string) {
+	if e.bridge == nil {
+		return
+	}
+	if err := e.bridge.HandleAlertRuleTriggered(rule.TenantID, rule, status, alert.labels); err != nil {
+		logWarn("告警通知发送失败", zap.Uint("ruleID", rule.ID), zap.String("status", status), zap.Error(err))
+	}
+}
+
+// restoreOpen reloads the unresolved alerts this evaluator raised so a restart
+// can still resolve them instead of leaving them open forever. Webhook alerts
+// linked to a rule are left alone: their query never returns them.
+func (e *RuleEvaluator) restoreOpen() {
+	items, err := e.repo.ListOpenHistory(model.HistorySourceRule)
+	if err != nil {
+		logWarn("恢复告警状态失败", zap.Error(err))
+		return
+	}
+	for _, item := range items {
+		if item.RuleID == 0 || item.Fingerprint == "" {
+			continue
+		}
+		e.active[item.Fingerprint] = &activeAlert{
+			tenantID:  item.TenantID,
+			ruleID:    item.RuleID,
+			state:     item.Status,
+			activeAt:  item.StartsAt,
+			historyID: item.ID,
+			labels:    item.LabelMap(),
+		}
+	}
+	e.restored = true
+}
+
+// alertLabels merges the series labels with the rule identity labels.
+func alertLabels(rule model.Rule, metric map[string]string) map[string]string {
+	labels := make(map[string]string, len(metric)+3)
+	for k, v := range metric {
+		if k == "__name__" {
+			continue
+		}
+		labels[k] = v
+	}
+	labels["alertname"] = rule.Name
+	labels["rule_id"] = fmt.Sprintf("%d", rule.ID)
+	if rule.Severity != "" {
+		labels["severity"] = rule.Severity
+	}
+	return labels
+}
+
+// Fingerprint returns a stable hash of a label set.
+func Fingerprint(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := fnv.New64a()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0xff})
+		h.Write([]byte(labels[k]))
+		h.Write([]byte{0xff})
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func logWarn(msg string, fields ...zap.Field) {
+	if logger.Log != nil {
+		logger.Log.Warn(msg, fields...)
+	}
+}
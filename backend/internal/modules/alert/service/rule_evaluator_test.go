@@ -0,0 +1,247 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	monitorModel "devops-platform/internal/modules/monitor/model"
+	notifModel "devops-platform/internal/modules/notification/model"
+	notifService "devops-platform/internal/modules/notification/service"
+)
+
+type fakeQuerier struct {
+	results map[uint][]monitorModel.MetricSeries
+	err     error
+}
+
+func (q *fakeQuerier) QueryInstant(configID uint, promQL string) (*monitorModel.MetricQueryResponse, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	return &monitorModel.MetricQueryResponse{ResultType: "vector", Results: q.results[configID]}, nil
+}
+
+type recordingNotifier struct {
+	subjects []string
+}
+
+func (n *recordingNotifier) Send(recipients []string, subject, body string) error {
+	n.subjects = append(n.subjects, subject)
+	return nil
+}
+
+func TestRuleEvaluator_PendingFiringResolved(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&notifModel.ChannelConfig{}, &notifModel.SendLog{}, &notifModel.Template{}); err != nil {
+		t.Fatalf("failed to migrate notification tables: %v", err)
+	}
+	db.Create(&notifModel.ChannelConfig{TenantID: testTenantID, Channel: notifModel.ChannelFeishu, Enabled: true})
+	db.Create(&model.Rule{TenantID: testTenantID, Name: "NodeDown", Expr: `up == 0`, Severity: "critical", Enabled: true, PrometheusConfigID: 7, For: "1m"})
+
+	ns := notifService.NewNotificationService(db)
+	notifier := &recordingNotifier{}
+	ns.RegisterNotifier(notifModel.ChannelFeishu, notifier)
+
+	querier := &fakeQuerier{results: map[uint][]monitorModel.MetricSeries{
+		7: {{Metric: map[string]string{"__name__": "up", "instance": "node-1:9100"}, Values: []monitorModel.MetricResult{{Value: 0}}}},
+	}}
+	evaluator := NewRuleEvaluator(db, querier, NewAlertNotificationBridge(ns))
+	now := time.Now()
+	evaluator.now = func() time.Time { return now }
+
+	evaluator.Evaluate()
+	var count int64
+	db.Model(&model.History{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected pending alert to not write history yet, got %d rows", count)
+	}
+
+	now = now.Add(2 * time.Minute)
+	evaluator.Evaluate()
+	var firing model.History
+	if err := db.Where("status = ?", model.StateFiring).First(&firing).Error; err != nil {
+		t.Fatalf("expected firing history: %v", err)
+	}
+	if firing.Instance != "node-1:9100" || firing.Fingerprint == "" || firing.TenantID != testTenantID {
+		t.Fatalf("unexpected firing history: %+v", firing)
+	}
+	if firing.LabelMap()["alertname"] != "NodeDown" {
+		t.Fatalf("expected alertname label, got %v", firing.LabelMap())
+	}
+
+	// A failed query must not resolve the alert.
+	querier.err = errors.New("prometheus down")
+	evaluator.Evaluate()
+	querier.err = nil
+
+	querier.results[7] = nil
+	now = now.Add(time.Minute)
+	evaluator.Evaluate()
+	var resolved model.History
+	if err := db.First(&resolved, firing.ID).Error; err != nil {
+		t.Fatalf("reload history failed: %v", err)
+	}
+	if resolved.Status != model.StateResolved || resolved.EndsAt.IsZero() {
+		t.Fatalf("expected resolved history, got %+v", resolved)
+	}
+	if len(notifier.subjects) != 2 {
+		t.Fatalf("expected firing and resolved notifications, got %v", notifier.subjects)
+	}
+}
+
+func TestRuleEvaluator_RestoresFiringAfterRestart(t *testing.T) {
+	db := setupTestDB(t)
+	rule := model.Rule{TenantID: testTenantID, Name: "NodeDown", Expr: `up == 0`, Severity: "critical", Enabled: true, PrometheusConfigID: 7}
+	db.Create(&rule)
+	labels := alertLabels(rule, map[string]string{"instance": "node-2:9100"})
+	db.Create(&model.History{TenantID: testTenantID, RuleID: rule.ID, RuleName: rule.Name, Status: model.StateFiring, StartsAt: time.Now().Add(-time.Hour), Fingerprint: Fingerprint(labels), Labels: model.EncodeLabels(labels)})
+
+	evaluator := NewRuleEvaluator(db, &fakeQuerier{results: map[uint][]monitorModel.MetricSeries{}}, nil)
+	evaluator.Evaluate()
+
+	var firing int64
+	db.Model(&model.History{}).Where("status = ?", model.StateFiring).Count(&firing)
+	if firing != 0 {
+		t.Fatalf("expected restored alert to resolve once the series disappears, got %d firing", firing)
+	}
+}
+
+func TestRuleEvaluator_RestartKeepsWebhookAlertsOpen(t *testing.T) {
+	db := setupTestDB(t)
+	rule := model.Rule{TenantID: testTenantID, Name: "KubePodCrashLooping", Expr: `restarts > 0`, Severity: "warning", Enabled: true, PrometheusConfigID: 7}
+	db.Create(&rule)
+
+	// The webhook links the Alertmanager alert to the local rule of the same name.
+	svc := NewAlertService(db)
+	_, err := svc.IngestAlertmanagerWebhook(testTenantID, AlertmanagerWebhook{
+		Status: "firing",
+		Alerts: []AlertmanagerAlert{{
+			Status:   "firing",
+			Labels:   map[string]string{"alertname": "KubePodCrashLooping", "pod": "api-0"},
+			StartsAt: time.Now().Add(-time.Hour),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("ingest webhook: %v", err)
+	}
+	var item model.History
+	if err := db.First(&item).Error; err != nil || item.RuleID != rule.ID || item.Source != model.HistorySourceWebhook {
+		t.Fatalf("expected webhook history linked to the rule: %+v %v", item, err)
+	}
+
+	// A fresh evaluator whose query returns nothing must not resolve it.
+	evaluator := NewRuleEvaluator(db, &fakeQuerier{results: map[uint][]monitorModel.MetricSeries{}}, nil)
+	evaluator.Evaluate()
+	db.First(&item, item.ID)
+	if item.Status != model.StateFiring {
+		t.Fatalf("expected webhook alert to stay firing after restart, got %s", item.Status)
+	}
+}
+
+func TestRuleEvaluator_SilencedAlertIsNotNotified(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&notifModel.ChannelConfig{}, &notifModel.SendLog{}, &notifModel.Template{}); err != nil {
+		t.Fatalf("failed to migrate notification tables: %v", err)
+	}
+	db.Create(&notifModel.ChannelConfig{TenantID: testTenantID, Channel: notifModel.ChannelFeishu, Enabled: true})
+	db.Create(&model.Rule{TenantID: testTenantID, Name: "PodCrashLooping", Expr: `restarts > 0`, Severity: "warning", Enabled: true, PrometheusConfigID: 7})
+	now := time.Now()
+	silence := model.Silence{TenantID: testTenantID, Matchers: []model.Matcher{{Name: "namespace", Op: "=", Value: "batch"}}, StartsAt: now.Add(-time.Minute), EndsAt: now.Add(time.Hour)}
+	db.Create(&silence)
+
+	ns := notifService.NewNotificationService(db)
+	notifier := &recordingNotifier{}
+	ns.RegisterNotifier(notifModel.ChannelFeishu, notifier)
+	querier := &fakeQuerier{results: map[uint][]monitorModel.MetricSeries{
+		7: {
+			{Metric: map[string]string{"namespace": "batch"}, Values: []monitorModel.MetricResult{{Value: 3}}},
+			{Metric: map[string]string{"namespace": "payments"}, Values: []monitorModel.MetricResult{{Value: 1}}},
+		},
+	}}
+	evaluator := NewRuleEvaluator(db, querier, NewAlertNotificationBridge(ns))
+	evaluator.now = func() time.Time { return now }
+	evaluator.Evaluate()
+
+	var silenced, firing int64
+	db.Model(&model.History{}).Where("status = ?", model.StateSilenced).Count(&silenced)
+	db.Model(&model.History{}).Where("status = ?", model.StateFiring).Count(&firing)
+	if silenced != 1 || firing != 1 {
+		t.Fatalf("expected one silenced and one firing alert, got silenced=%d firing=%d", silenced, firing)
+	}
+	if len(notifier.subjects) != 1 {
+		t.Fatalf("expected only the unsilenced alert notified, got %v", notifier.subjects)
+	}
+
+	// Once the silence expires the alert starts paging.
+	now = silence.EndsAt.Add(time.Second)
+	evaluator.Evaluate()
+	db.Model(&model.History{}).Where("status = ?", model.StateFiring).Count(&firing)
+	if firing != 2 || len(notifier.subjects) != 2 {
+		t.Fatalf("expected expired silence to re-fire, got firing=%d notifications=%v", firing, notifier.subjects)
+	}
+}
+
+func TestRuleEvaluator_ManualResolveAndAckSurviveTransitions(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&notifModel.ChannelConfig{}, &notifModel.SendLog{}, &notifModel.Template{}); err != nil {
+		t.Fatalf("failed to migrate notification tables: %v", err)
+	}
+	db.Create(&notifModel.ChannelConfig{TenantID: testTenantID, Channel: notifModel.ChannelFeishu, Enabled: true})
+	db.Create(&model.Rule{TenantID: testTenantID, Name: "PodCrashLooping", Expr: `restarts > 0`, Severity: "warning", Enabled: true, PrometheusConfigID: 7})
+	now := time.Now()
+	silence := model.Silence{TenantID: testTenantID, Matchers: []model.Matcher{{Name: "namespace", Op: "=", Value: "batch"}}, StartsAt: now.Add(-time.Minute), EndsAt: now.Add(time.Hour)}
+	db.Create(&silence)
+
+	ns := notifService.NewNotificationService(db)
+	notifier := &recordingNotifier{}
+	ns.RegisterNotifier(notifModel.ChannelFeishu, notifier)
+	querier := &fakeQuerier{results: map[uint][]monitorModel.MetricSeries{
+		7: {
+			{Metric: map[string]string{"namespace": "batch", "pod": "resolved"}, Values: []monitorModel.MetricResult{{Value: 3}}},
+			{Metric: map[string]string{"namespace": "batch", "pod": "acked"}, Values: []monitorModel.MetricResult{{Value: 1}}},
+		},
+	}}
+	evaluator := NewRuleEvaluator(db, querier, NewAlertNotificationBridge(ns))
+	evaluator.now = func() time.Time { return now }
+	evaluator.Evaluate()
+
+	var rows []model.History
+	db.Order("id").Find(&rows)
+	if len(rows) != 2 {
+		t.Fatalf("expected two silenced alerts, got %+v", rows)
+	}
+	svc := NewAlertService(db)
+	for _, row := range rows {
+		var err error
+		if row.LabelMap()["pod"] == "resolved" {
+			_, err = svc.ResolveHistory(testTenantID, row.ID, "alice")
+		} else {
+			_, err = svc.AckHistory(testTenantID, row.ID, "bob")
+		}
+		if err != nil {
+			t.Fatalf("manual action failed: %v", err)
+		}
+	}
+
+	// The silence expires while the condition still holds.
+	now = silence.EndsAt.Add(time.Second)
+	evaluator.Evaluate()
+	db.Order("id").Find(&rows)
+	for _, row := range rows {
+		switch row.LabelMap()["pod"] {
+		case "resolved":
+			if row.Status != model.StateResolved || row.ResolvedBy != "alice" {
+				t.Fatalf("manually resolved alert was reopened: %+v", row)
+			}
+		case "acked":
+			if row.Status != model.StateFiring || row.AckedBy != "bob" {
+				t.Fatalf("acknowledged alert should fire quietly: %+v", row)
+			}
+		}
+	}
+	if len(notifier.subjects) != 0 {
+		t.Fatalf("expected no notifications, got %v", notifier.subjects)
+	}
+}
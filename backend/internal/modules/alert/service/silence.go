@@ -0,0 +1,203 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/alert/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// matcherSet is a list of label matchers compiled for repeated evaluation.
+type matcherSet struct {
+	matchers []model.Matcher
+	regexps  []*regexp.Regexp
+}
+
+func compileMatchers(matchers []model.Matcher) (*matcherSet, error) {
+	ms := &matcherSet{matchers: matchers, regexps: make([]*regexp.Regexp, len(matchers))}
+	for i, m := range matchers {
+		if strings.TrimSpace(m.Name) == "" {
+			return nil, fmt.Errorf("matcher #%d 缺少标签名", i+1)
+		}
+		switch m.Op {
+		case model.MatchEqual, model.MatchNotEqual:
+		case model.MatchRegexp, model.MatchNotRegexp:
+			// Anchored like Alertmanager so "prod" does not match "prod-bj".
+			re, err := regexp.Compile("^(?:" + m.Value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("matcher %s 正则无效: %w", m.Name, err)
+			}
+			ms.regexps[i] = re
+		default:
+			return nil, fmt.Errorf("matcher %s 操作符 %q 不支持", m.Name, m.Op)
+		}
+	}
+	return ms, nil
+}
+
+// matches reports whether all matchers match the label set.
+func (ms *matcherSet) matches(labels map[string]string) bool {
+	for i, m := range ms.matchers {
+		value := labels[m.Name]
+		var ok bool
+		switch m.Op {
+		case model.MatchEqual:
+			ok = value == m.Value
+		case model.MatchNotEqual:
+			ok = value != m.Value
+		case model.MatchRegexp:
+			ok = ms.regexps[i].MatchString(value)
+		case model.MatchNotRegexp:
+			ok = !ms.regexps[i].MatchString(value)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeMatchers trims matcher fields and defaults the operator to "=".
+func normalizeMatchers(matchers []model.Matcher) []model.Matcher {
+	normalized := make([]model.Matcher, 0, len(matchers))
+	for _, m := range matchers {
+		m.Name = strings.TrimSpace(m.Name)
+		m.Op = strings.TrimSpace(m.Op)
+		if m.Op == "" {
+			m.Op = model.MatchEqual
+		}
+		normalized = append(normalized, m)
+	}
+	return normalized
+}
+
+// silenceMatcher is a silence with its matchers compiled for repeated evaluation.
+type silenceMatcher struct {
+	silence  model.Silence
+	matchers *matcherSet
+}
+
+func compileSilence(silence model.Silence) (*silenceMatcher, error) {
+	ms, err := compileMatchers(silence.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	return &silenceMatcher{silence: silence, matchers: ms}, nil
+}
+
+// matches reports whether the silence mutes an alert of ruleID with the given labels at time at.
+func (sm *silenceMatcher) matches(ruleID uint, labels map[string]string, at time.Time) bool {
+	if at.Before(sm.silence.StartsAt) || !at.Before(sm.silence.EndsAt) {
+		return false
+	}
+	if sm.silence.RuleID != 0 && sm.silence.RuleID != ruleID {
+		return false
+	}
+	return sm.matchers.matches(labels)
+}
+
+// compileSilences compiles silences grouped by tenant, skipping invalid ones.
+func compileSilences(silences []model.Silence) map[uint][]*silenceMatcher {
+	byTenant := make(map[uint][]*silenceMatcher)
+	for _, silence := range silences {
+		sm, err := compileSilence(silence)
+		if err != nil {
+			continue
+		}
+		byTenant[silence.TenantID] = append(byTenant[silence.TenantID], sm)
+	}
+	return byTenant
+}
+
+func isSilenced(silences []*silenceMatcher, ruleID uint, labels map[string]string, at time.Time) bool {
+	for _, sm := range silences {
+		if sm.matches(ruleID, labels, at) {
+			return true
+		}
+	}
+	return false
+}
+
+// historyLabels returns the label set of a history row, falling back to its
+// columns for rows written before labels were recorded.
+func historyLabels(item model.History) map[string]string {
+	labels := item.LabelMap()
+	fallback := map[string]string{
+		"alertname": item.RuleName,
+		"severity":  item.Severity,
+		"cluster":   item.Cluster,
+		"namespace": item.Namespace,
+		"instance":  item.Instance,
+	}
+	for k, v := range fallback {
+		if _, ok := labels[k]; !ok && v != "" {
+			labels[k] = v
+		}
+	}
+	if _, ok := labels["rule_id"]; !ok && item.RuleID != 0 {
+		labels["rule_id"] = strconv.FormatUint(uint64(item.RuleID), 10)
+	}
+	return labels
+}
+
+func (s *AlertService) buildSilence(tenantID uint, req SilenceUpsertRequest, op string) (model.Silence, error) {
+	if req.RuleID == 0 && len(req.Matchers) == 0 {
+		return model.Silence{}, obserr.New("ALERT_SILENCE_RULE_REQUIRED", op, "ruleId 与 matchers 不能同时为空")
+	}
+	if req.StartsAt.IsZero() || req.EndsAt.IsZero() || !req.EndsAt.After(req.StartsAt) {
+		return model.Silence{}, obserr.New("ALERT_SILENCE_RANGE_INVALID", op, "静默时间范围无效")
+	}
+	matchers := normalizeMatchers(req.Matchers)
+	silence := model.Silence{
+		ID:        req.ID,
+		TenantID:  tenantID,
+		RuleID:    req.RuleID,
+		Matchers:  matchers,
+		Reason:    req.Reason,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		CreatedBy: req.CreatedBy,
+	}
+	if _, err := compileSilence(silence); err != nil {
+		return model.Silence{}, obserr.Wrap("ALERT_SILENCE_MATCHER_INVALID", op, "静默匹配规则无效", err)
+	}
+	return silence, nil
+}
+
+// PreviewSilence returns the tenant's unresolved alerts the silence would
+// match if it were active now, without saving it.
+func (s *AlertService) PreviewSilence(tenantID uint, req SilenceUpsertRequest) (ListHistoryResponse, error) {
+	silence, err := s.buildSilence(tenantID, req, "alert.PreviewSilence")
+	if err != nil {
+		return ListHistoryResponse{}, err
+	}
+	sm, _ := compileSilence(silence)
+	open, err := s.repo.ListTenantOpenHistory(tenantID)
+	if err != nil {
+		return ListHistoryResponse{}, err
+	}
+	at := time.Now()
+	if at.Before(silence.StartsAt) {
+		at = silence.StartsAt
+	}
+	items := make([]model.History, 0, len(open))
+	for _, item := range open {
+		if sm.matches(item.RuleID, historyLabels(item), at) {
+			items = append(items, item)
+		}
+	}
+	return ListHistoryResponse{Total: len(items), Items: items}, nil
+}
+
+// activeSilences loads the tenant's silences active at the given time.
+func (s *AlertService) activeSilences(tenantID uint, at time.Time) []*silenceMatcher {
+	silences, err := s.repo.ListActiveSilences([]uint{tenantID}, at)
+	if err != nil {
+		return nil
+	}
+	return compileSilences(silences)[tenantID]
+}
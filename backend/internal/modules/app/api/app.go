@@ -19,6 +19,12 @@ var buildEnvService = service.NewBuildEnvService()
 var containerConfigService = service.NewContainerConfigService()
 var enumService = service.NewEnumServiceWithRepo(sharedAppRepo)
 
+// SharedAppService returns the app service behind these handlers, so other
+// modules deploy against the same store.
+func SharedAppService() *service.AppService {
+	return appService
+}
+
 func getCurrentTenantID(c *gin.Context) uint {
 	if tenantID, exists := c.Get("tenantID"); exists {
 		if id, ok := tenantID.(uint); ok {
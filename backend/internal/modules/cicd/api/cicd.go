@@ -3,8 +3,10 @@ package api
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"devops-platform/internal/modules/cicd/model"
+	"devops-platform/internal/modules/cicd/repository"
 	"devops-platform/internal/modules/cicd/service"
 	"devops-platform/internal/pkg/obserr"
 
@@ -18,8 +20,26 @@ func SetCICDDB(db *gorm.DB) {
 	cicdSvc = service.NewCICDService(db)
 }
 
-// Jenkins Config
-func ListJenkinsConfigs(c *gin.Context) {
+func InitCICDService(svc *service.CICDService) {
+	cicdSvc = svc
+}
+
+// providerConfigRequest also accepts the legacy Jenkins "apiToken" field.
+type providerConfigRequest struct {
+	model.CIProviderConfig
+	APIToken string `json:"apiToken"`
+}
+
+func (r providerConfigRequest) config() model.CIProviderConfig {
+	cfg := r.CIProviderConfig
+	if cfg.Token == "" {
+		cfg.Token = r.APIToken
+	}
+	return cfg
+}
+
+// CI provider configs
+func ListProviderConfigs(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
 	configs, total, err := cicdSvc.ListConfigs(page, pageSize)
@@ -30,20 +50,25 @@ func ListJenkinsConfigs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"code": 200, "data": configs, "total": total})
 }
 
-func SaveJenkinsConfig(c *gin.Context) {
-	var cfg model.JenkinsConfig
-	if err := c.ShouldBindJSON(&cfg); err != nil {
+func SaveProviderConfig(c *gin.Context) {
+	var req providerConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
 		return
 	}
+	cfg := req.config()
+	if id, err := strconv.ParseUint(c.Param("id"), 10, 64); err == nil {
+		cfg.ID = uint(id)
+	}
 	if err := cicdSvc.SaveConfig(&cfg); err != nil {
 		writeObservableError(c, http.StatusInternalServerError, err)
 		return
 	}
+	cfg.Token = ""
 	c.JSON(http.StatusOK, gin.H{"code": 200, "data": cfg})
 }
 
-func DeleteJenkinsConfig(c *gin.Context) {
+func DeleteProviderConfig(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err := cicdSvc.DeleteConfig(uint(id)); err != nil {
 		writeObservableError(c, http.StatusInternalServerError, err)
@@ -52,17 +77,13 @@ func DeleteJenkinsConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "deleted"})
 }
 
-func TestJenkinsConnection(c *gin.Context) {
-	var req struct {
-		URL      string `json:"url" binding:"required"`
-		Username string `json:"username" binding:"required"`
-		APIToken string `json:"apiToken" binding:"required"`
-	}
+func TestProviderConnection(c *gin.Context) {
+	var req providerConfigRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
 		return
 	}
-	if err := cicdSvc.TestConnection(req.URL, req.Username, req.APIToken); err != nil {
+	if err := cicdSvc.TestConnection(req.config()); err != nil {
 		writeObservableError(c, http.StatusBadRequest, err)
 		return
 	}
@@ -84,17 +105,33 @@ func ListJobs(c *gin.Context) {
 func TriggerBuild(c *gin.Context) {
 	configID, _ := strconv.ParseUint(c.Param("configId"), 10, 64)
 	var req struct {
-		JobName string `json:"jobName" binding:"required"`
+		JobName    string            `json:"jobName" binding:"required"`
+		Parameters map[string]string `json:"parameters"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
 		return
 	}
-	if err := cicdSvc.TriggerBuild(uint(configID), req.JobName); err != nil {
+	run, err := cicdSvc.TriggerBuild(uint(configID), req.JobName, req.Parameters, c.GetString("username"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if obserr.Details(err)["code"] == "INVALID_PARAM" {
+			status = http.StatusBadRequest
+		}
+		writeObservableError(c, status, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "build triggered", "data": run})
+}
+
+func GetJobParameters(c *gin.Context) {
+	configID, _ := strconv.ParseUint(c.Param("configId"), 10, 64)
+	params, err := cicdSvc.JobParameters(uint(configID), c.Query("jobName"))
+	if err != nil {
 		writeObservableError(c, http.StatusInternalServerError, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "build triggered"})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": params})
 }
 
 func ListBuilds(c *gin.Context) {
@@ -112,7 +149,8 @@ func GetBuildLog(c *gin.Context) {
 	configID, _ := strconv.ParseUint(c.Param("configId"), 10, 64)
 	jobName := c.Query("jobName")
 	buildNumber, _ := strconv.Atoi(c.Query("buildNumber"))
-	logEntry, err := cicdSvc.GetBuildLog(uint(configID), jobName, buildNumber)
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	logEntry, err := cicdSvc.GetBuildLog(uint(configID), jobName, buildNumber, offset)
 	if err != nil {
 		writeObservableError(c, http.StatusInternalServerError, err)
 		return
@@ -154,6 +192,96 @@ func DeletePipeline(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "deleted"})
 }
 
+// Pipeline runs
+func ListRuns(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	pipelineID, _ := strconv.ParseUint(c.Query("pipelineId"), 10, 64)
+	configID, _ := strconv.ParseUint(c.Query("providerConfigId"), 10, 64)
+	runs, total, err := cicdSvc.ListRuns(repository.RunFilter{
+		PipelineID:       uint(pipelineID),
+		ProviderConfigID: uint(configID),
+		JobName:          c.Query("jobName"),
+		Status:           c.Query("status"),
+	}, page, pageSize)
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": runs, "total": total})
+}
+
+func GetRun(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	run, err := cicdSvc.GetRun(uint(id))
+	if err != nil {
+		writeObservableError(c, http.StatusNotFound, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": run})
+}
+
+// Analytics
+
+// parseWindow reads from/to as RFC3339, defaulting to the last `days` days (7).
+func parseWindow(c *gin.Context) (time.Time, time.Time, error) {
+	to := time.Now()
+	if raw := c.Query("to"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = t
+	}
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if days <= 0 {
+		days = 7
+	}
+	from := to.AddDate(0, 0, -days)
+	if raw := c.Query("from"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = t
+	}
+	return from, to, nil
+}
+
+func GetPipelineStats(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	from, to, err := parseWindow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid time window"})
+		return
+	}
+	stats, err := cicdSvc.PipelineStats(uint(id), from, to)
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": stats})
+}
+
+func GetHealthReport(c *gin.Context) {
+	from, to, err := parseWindow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid time window"})
+		return
+	}
+	report, err := cicdSvc.HealthReport(from, to)
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": report})
+}
+
+func SyncRuns(c *gin.Context) {
+	written := cicdSvc.SyncRuns()
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": gin.H{"written": written}})
+}
+
 func writeObservableError(c *gin.Context, status int, err error) {
 	details := obserr.Details(err)
 	c.JSON(status, gin.H{"code": 500, "message": details["message"], "error": details})
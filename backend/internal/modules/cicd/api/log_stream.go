@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"devops-platform/internal/modules/cicd/model"
+	cmdbterminal "devops-platform/internal/modules/cmdb/terminal"
+	"devops-platform/internal/pkg/obserr"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// logStreamWriteWait bounds a single push; a client that stops reading for
+// longer is dropped and can reconnect from its last offset.
+const logStreamWriteWait = 30 * time.Second
+
+var buildLogUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 64 << 10,
+	CheckOrigin:     cmdbterminal.CheckOrigin,
+}
+
+// StreamBuildLog pushes a build log as it grows, over WebSocket when the
+// request asks for an upgrade and as server-sent events otherwise. Clients
+// resume with ?offset=, or through Last-Event-ID when an EventSource reconnects.
+func StreamBuildLog(c *gin.Context) {
+	configID, _ := strconv.ParseUint(c.Param("configId"), 10, 64)
+	jobName := c.Query("jobName")
+	buildNumber, _ := strconv.Atoi(c.Query("buildNumber"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	if lastID, err := strconv.Atoi(c.GetHeader("Last-Event-ID")); err == nil {
+		offset = lastID
+	}
+	if jobName == "" || buildNumber <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "jobName and buildNumber are required"})
+		return
+	}
+	stream := func(ctx context.Context, send func(*model.BuildLogEntry) error) error {
+		return cicdSvc.StreamBuildLog(ctx, uint(configID), jobName, buildNumber, offset, send)
+	}
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		streamBuildLogWS(c, offset, stream)
+		return
+	}
+	streamBuildLogSSE(c, offset, stream)
+}
+
+func streamBuildLogWS(c *gin.Context, offset int, stream func(context.Context, func(*model.BuildLogEntry) error) error) {
+	conn, err := buildLogUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// The client only sends control frames; reading them notices when it leaves.
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(message cmdbterminal.WSMessage) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(logStreamWriteWait))
+		return conn.WriteJSON(message)
+	}
+	err = stream(ctx, func(entry *model.BuildLogEntry) error {
+		offset = entry.Offset
+		return write(cmdbterminal.WSMessage{Operation: "log", Data: entry})
+	})
+	if err != nil {
+		if !cmdbterminal.IsNormalClose(err) {
+			_ = write(cmdbterminal.WSMessage{Operation: "error", Data: obserr.Details(err)["message"]})
+		}
+		_ = conn.WriteControl(websocket.CloseMessage, cmdbterminal.EncodeClosedMessage("log stream failed"), time.Now().Add(2*time.Second))
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	_ = write(cmdbterminal.WSMessage{Operation: "end", Data: gin.H{"offset": offset}})
+	_ = conn.WriteControl(websocket.CloseMessage, cmdbterminal.EncodeClosedMessage("build finished"), time.Now().Add(2*time.Second))
+}
+
+func streamBuildLogSSE(c *gin.Context, offset int, stream func(context.Context, func(*model.BuildLogEntry) error) error) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	rc := http.NewResponseController(c.Writer)
+
+	writeEvent := func(event string, id int, data interface{}) error {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		_ = rc.SetWriteDeadline(time.Now().Add(logStreamWriteWait))
+		if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", id, event, payload); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+	ctx := c.Request.Context()
+	err := stream(ctx, func(entry *model.BuildLogEntry) error {
+		offset = entry.Offset
+		return writeEvent("log", entry.Offset, entry)
+	})
+	if err != nil {
+		_ = writeEvent("error", offset, gin.H{"message": obserr.Details(err)["message"]})
+		return
+	}
+	if ctx.Err() == nil {
+		_ = writeEvent("end", offset, gin.H{"offset": offset})
+	}
+}
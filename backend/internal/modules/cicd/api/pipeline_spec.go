@@ -0,0 +1,135 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"devops-platform/internal/modules/cicd/model"
+	"devops-platform/internal/pkg/obserr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// specErrorStatus maps spec and execution error codes onto HTTP statuses.
+func specErrorStatus(err error) int {
+	switch obserr.Details(err)["code"] {
+	case "INVALID_PARAM":
+		return http.StatusBadRequest
+	case "PIPELINE_SPEC_NOT_FOUND", "PIPELINE_EXECUTION_NOT_FOUND":
+		return http.StatusNotFound
+	case "PIPELINE_EXECUTION_STATE":
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// Pipeline specs
+func ListPipelineSpecs(c *gin.Context) {
+	appID, _ := strconv.ParseUint(c.Query("appId"), 10, 64)
+	specs, err := cicdSvc.ListSpecs(c.GetUint("tenantID"), uint(appID))
+	if err != nil {
+		writeObservableError(c, specErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": specs})
+}
+
+func GetPipelineSpec(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	spec, err := cicdSvc.GetSpec(c.GetUint("tenantID"), uint(id))
+	if err != nil {
+		writeObservableError(c, specErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": spec})
+}
+
+func SavePipelineSpec(c *gin.Context) {
+	var spec model.PipelineSpec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+		return
+	}
+	spec.ID = 0
+	if id, err := strconv.ParseUint(c.Param("id"), 10, 64); err == nil {
+		spec.ID = uint(id)
+	}
+	spec.TenantID = c.GetUint("tenantID")
+	if err := cicdSvc.SaveSpec(&spec); err != nil {
+		writeObservableError(c, specErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": spec})
+}
+
+func DeletePipelineSpec(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err := cicdSvc.DeleteSpec(c.GetUint("tenantID"), uint(id)); err != nil {
+		writeObservableError(c, specErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "deleted"})
+}
+
+// RunPipelineSpec starts an execution; version is optional.
+func RunPipelineSpec(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	var req struct {
+		Version string `json:"version"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+			return
+		}
+	}
+	exec, err := cicdSvc.StartExecution(c.GetUint("tenantID"), c.GetUint("userID"), c.GetString("username"), uint(id), req.Version)
+	if err != nil {
+		writeObservableError(c, specErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "pipeline started", "data": exec})
+}
+
+// Pipeline executions
+func ListPipelineExecutions(c *gin.Context) {
+	specID, _ := strconv.ParseUint(c.Query("specId"), 10, 64)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	execs, total, err := cicdSvc.ListExecutions(c.GetUint("tenantID"), uint(specID), page, pageSize)
+	if err != nil {
+		writeObservableError(c, specErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": execs, "total": total})
+}
+
+func GetPipelineExecution(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	exec, err := cicdSvc.GetExecution(c.GetUint("tenantID"), uint(id))
+	if err != nil {
+		writeObservableError(c, specErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": exec})
+}
+
+func ResumePipelineExecution(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	exec, err := cicdSvc.ResumeExecution(c.GetUint("tenantID"), uint(id))
+	if err != nil {
+		writeObservableError(c, specErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "pipeline resumed", "data": exec})
+}
+
+func CancelPipelineExecution(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	exec, err := cicdSvc.CancelExecution(c.GetUint("tenantID"), uint(id))
+	if err != nil {
+		writeObservableError(c, specErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "pipeline cancelled", "data": exec})
+}
@@ -6,7 +6,39 @@ import (
 	"gorm.io/gorm"
 )
 
-// JenkinsConfig holds Jenkins server connection info
+// ProviderType identifies a CI backend.
+type ProviderType string
+
+const (
+	ProviderJenkins ProviderType = "jenkins"
+	ProviderGitLab  ProviderType = "gitlab"
+	ProviderGitHub  ProviderType = "github"
+)
+
+// CIProviderConfig holds connection info for a CI backend.
+// For GitLab, URL is the instance root and jobs are projects. For GitHub, URL is
+// the API root (default https://api.github.com), Project is "owner/repo" and
+// jobs are workflow files. Ref is the branch to trigger; empty means the
+// project's default branch.
+type CIProviderConfig struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Name      string         `gorm:"size:128;not null" json:"name"`
+	Type      ProviderType   `gorm:"size:20;not null;default:'jenkins'" json:"type"`
+	URL       string         `gorm:"size:512" json:"url"`
+	Username  string         `gorm:"size:128" json:"username"`
+	Token     string         `gorm:"size:256;not null" json:"token,omitempty"`
+	Project   string         `gorm:"size:256" json:"project"`
+	Ref       string         `gorm:"size:128" json:"ref"`
+	Status    string         `gorm:"size:20;default:'unknown'" json:"status"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (CIProviderConfig) TableName() string { return "cicd_provider_configs" }
+
+// JenkinsConfig is the legacy Jenkins-only config table. It is only read to
+// backfill CIProviderConfig on startup.
 type JenkinsConfig struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	Name      string         `gorm:"size:128;not null" json:"name"`
@@ -21,7 +53,7 @@ type JenkinsConfig struct {
 
 func (JenkinsConfig) TableName() string { return "cicd_jenkins_configs" }
 
-// JobInfo represents a Jenkins job
+// JobInfo represents a buildable unit: a Jenkins job, GitLab project or GitHub workflow
 type JobInfo struct {
 	Name        string `json:"name"`
 	DisplayName string `json:"displayName"`
@@ -31,17 +63,23 @@ type JobInfo struct {
 	Description string `json:"description"`
 }
 
-// BuildInfo represents a Jenkins build
+// BuildInfo represents a single build. Result uses Jenkins' vocabulary
+// (SUCCESS, FAILURE, ABORTED, UNSTABLE) for every provider.
 type BuildInfo struct {
-	Number    int   `json:"number"`
+	Number    int    `json:"number"`
 	URL       string `json:"url"`
 	Result    string `json:"result"`
 	Duration  int64  `json:"duration"`
 	Timestamp int64  `json:"timestamp"`
 	Building  bool   `json:"building"`
+	// TriggeredBy, Commit and Parameters are filled in when the provider reports them.
+	TriggeredBy string            `json:"triggeredBy,omitempty"`
+	Commit      string            `json:"commit,omitempty"`
+	Parameters  map[string]string `json:"parameters,omitempty"`
 }
 
-// BuildLogEntry represents log output
+// BuildLogEntry is a chunk of build log starting at the requested offset.
+// Offset is where the next chunk starts; HasMore is set while the build runs.
 type BuildLogEntry struct {
 	Offset  int    `json:"offset"`
 	Text    string `json:"text"`
@@ -50,28 +88,61 @@ type BuildLogEntry struct {
 
 // Pipeline is a DB-backed model representing a CI/CD pipeline
 type Pipeline struct {
-	ID              uint           `gorm:"primaryKey" json:"id"`
-	Name            string         `gorm:"size:128;not null" json:"name"`
-	JenkinsConfigID uint           `gorm:"index" json:"jenkinsConfigId"`
-	JobName         string         `gorm:"size:256;not null" json:"jobName"`
-	CreatedAt       time.Time      `json:"createdAt"`
-	UpdatedAt       time.Time      `json:"updatedAt"`
-	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	Name             string         `gorm:"size:128;not null" json:"name"`
+	ProviderConfigID uint           `gorm:"index" json:"providerConfigId"`
+	JobName          string         `gorm:"size:256;not null" json:"jobName"`
+	CreatedAt        time.Time      `json:"createdAt"`
+	UpdatedAt        time.Time      `json:"updatedAt"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (Pipeline) TableName() string { return "cicd_pipelines" }
 
-// PipelineRun holds a pipeline execution record
+// PipelineRun statuses.
+const (
+	RunQueued    = "queued"
+	RunRunning   = "running"
+	RunSuccess   = "success"
+	RunFailed    = "failed"
+	RunAborted   = "aborted"
+	RunCancelled = "cancelled"
+	// RunUnknown marks a run whose build was not found within the queue
+	// timeout. The run sync still numbers it if the build turns up later.
+	RunUnknown = "unknown"
+)
+
+// PipelineRun holds a pipeline execution record. A run starts "queued" when the
+// provider queues the build (Jenkins) or does not report it (GitHub);
+// BuildNumber is filled in once it starts.
+// Runs are also recorded by the run syncer for builds started outside the platform.
 type PipelineRun struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	PipelineID  uint           `gorm:"index" json:"pipelineId"`
-	BuildNumber int            `json:"buildNumber"`
-	Status      string         `gorm:"size:32" json:"status"`
-	Log         string         `gorm:"type:longtext" json:"log,omitempty"`
-	StartedAt   *time.Time     `json:"startedAt"`
-	FinishedAt  *time.Time     `json:"finishedAt"`
-	CreatedAt   time.Time      `json:"createdAt"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint              `gorm:"primaryKey" json:"id"`
+	PipelineID       uint              `gorm:"index" json:"pipelineId"`
+	ProviderConfigID uint              `gorm:"index:idx_cicd_run_build" json:"providerConfigId"`
+	JobName          string            `gorm:"size:256;index:idx_cicd_run_build" json:"jobName"`
+	BuildNumber      int               `gorm:"index:idx_cicd_run_build" json:"buildNumber"`
+	QueueURL         string            `gorm:"size:512" json:"queueUrl,omitempty"`
+	URL              string            `gorm:"size:512" json:"url,omitempty"`
+	Commit           string            `gorm:"size:64" json:"commit,omitempty"`
+	Parameters       map[string]string `gorm:"serializer:json;type:text" json:"parameters,omitempty"`
+	TriggeredBy      string            `gorm:"size:128" json:"triggeredBy"`
+	Status           string            `gorm:"size:32" json:"status"`
+	Duration         int64             `json:"duration"` // milliseconds, set once finished
+	Log              string            `gorm:"type:longtext" json:"log,omitempty"`
+	StartedAt        *time.Time        `json:"startedAt"`
+	FinishedAt       *time.Time        `json:"finishedAt"`
+	CreatedAt        time.Time         `json:"createdAt"`
+	DeletedAt        gorm.DeletedAt    `gorm:"index" json:"-"`
 }
 
 func (PipelineRun) TableName() string { return "cicd_pipeline_runs" }
+
+// JobParameter describes a build parameter a job accepts.
+type JobParameter struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Default     string   `json:"default"`
+	Choices     []string `json:"choices,omitempty"`
+	Description string   `json:"description"`
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Stage types a pipeline spec can chain.
+const (
+	StageBuild         = "build"
+	StageArtifactCheck = "artifact_check"
+	StageApproval      = "approval"
+	StageDeploy        = "deploy"
+)
+
+// Execution and stage statuses. An execution is "waiting" while a stage waits
+// on someone else, e.g. an approver.
+const (
+	ExecPending   = "pending"
+	ExecRunning   = "running"
+	ExecWaiting   = "waiting"
+	ExecSuccess   = "success"
+	ExecFailed    = "failed"
+	ExecCancelled = "cancelled"
+)
+
+// StageSpec is one step of a pipeline spec. Exactly the section matching Type
+// is used. Retries is how many extra attempts a failed stage gets before the
+// execution stops; an approval that is rejected is never retried.
+type StageSpec struct {
+	Name          string              `json:"name"`
+	Type          string              `json:"type"`
+	Retries       int                 `json:"retries"`
+	Build         *BuildStage         `json:"build,omitempty"`
+	ArtifactCheck *ArtifactCheckStage `json:"artifactCheck,omitempty"`
+	Approval      *ApprovalStage      `json:"approval,omitempty"`
+	Deploy        *DeployStage        `json:"deploy,omitempty"`
+}
+
+// BuildStage triggers a CI job and waits for its result.
+type BuildStage struct {
+	ProviderConfigID uint              `json:"providerConfigId"`
+	JobName          string            `json:"jobName"`
+	Parameters       map[string]string `json:"parameters,omitempty"`
+}
+
+// ArtifactCheckStage requires an image to exist in Harbor and, with
+// EnforceScanPolicy, to pass Harbor's vulnerability scan policy. Tag may
+// contain ${version}, which is replaced with the execution's version.
+type ArtifactCheckStage struct {
+	HarborConfigID    uint   `json:"harborConfigId"`
+	Project           string `json:"project"`
+	Repository        string `json:"repository"`
+	Tag               string `json:"tag"`
+	EnforceScanPolicy bool   `json:"enforceScanPolicy"`
+}
+
+// ApprovalStage opens a workflow change order and waits for its decision.
+type ApprovalStage struct {
+	Title          string `json:"title"`
+	ApprovalLevels int    `json:"approvalLevels"`
+}
+
+// DeployStage deploys the app with a template to a target environment.
+type DeployStage struct {
+	TemplateID  uint              `json:"templateId"`
+	Cluster     string            `json:"cluster"`
+	Environment string            `json:"environment"`
+	Namespace   string            `json:"namespace"`
+	Variables   map[string]string `json:"variables,omitempty"`
+}
+
+// PipelineSpec is a declarative, multi-stage pipeline stored per app.
+type PipelineSpec struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	TenantID    uint           `gorm:"index;not null" json:"tenantId"`
+	AppID       uint           `gorm:"index;not null" json:"appId"`
+	Name        string         `gorm:"size:128;not null" json:"name"`
+	Description string         `gorm:"size:512" json:"description"`
+	Stages      []StageSpec    `gorm:"serializer:json;type:text" json:"stages"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (PipelineSpec) TableName() string { return "cicd_pipeline_specs" }
+
+// StageState tracks one stage of an execution. RunID, OrderID and
+// DeploymentID point at the record the stage produced in its own module.
+type StageState struct {
+	Name         string     `json:"name"`
+	Type         string     `json:"type"`
+	Status       string     `json:"status"`
+	Attempts     int        `json:"attempts"`
+	Message      string     `json:"message,omitempty"`
+	RunID        uint       `json:"runId,omitempty"`
+	BuildNumber  int        `json:"buildNumber,omitempty"`
+	OrderID      uint       `json:"orderId,omitempty"`
+	DeploymentID uint       `json:"deploymentId,omitempty"`
+	StartedAt    *time.Time `json:"startedAt,omitempty"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+}
+
+// PipelineExecution is one run of a PipelineSpec. The stages are copied from
+// the spec at start, so editing the spec does not change running executions.
+type PipelineExecution struct {
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	TenantID      uint         `gorm:"index;not null" json:"tenantId"`
+	SpecID        uint         `gorm:"index;not null" json:"specId"`
+	AppID         uint         `gorm:"index" json:"appId"`
+	Version       string       `gorm:"size:128" json:"version"`
+	Status        string       `gorm:"size:32;index" json:"status"`
+	CurrentStage  int          `json:"currentStage"`
+	Spec          []StageSpec  `gorm:"serializer:json;type:text" json:"spec"`
+	Stages        []StageState `gorm:"serializer:json;type:text" json:"stages"`
+	TriggeredByID uint         `json:"triggeredById"`
+	TriggeredBy   string       `gorm:"size:128" json:"triggeredBy"`
+	StartedAt     *time.Time   `json:"startedAt"`
+	FinishedAt    *time.Time   `json:"finishedAt"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	UpdatedAt     time.Time    `json:"updatedAt"`
+}
+
+func (PipelineExecution) TableName() string { return "cicd_pipeline_executions" }
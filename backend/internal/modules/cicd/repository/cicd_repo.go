@@ -1,12 +1,7 @@
 package repository
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
-	"net/url"
-	"strings"
 	"time"
 
 	"devops-platform/internal/modules/cicd/model"
@@ -31,225 +26,271 @@ func NewCICDRepo(db *gorm.DB) *CICDRepo {
 
 // --- Config CRUD ---
 
-func (r *CICDRepo) ListConfigs(page, pageSize int) ([]model.JenkinsConfig, int64, error) {
-	var configs []model.JenkinsConfig
+func (r *CICDRepo) ListConfigs(page, pageSize int) ([]model.CIProviderConfig, int64, error) {
+	var configs []model.CIProviderConfig
 	var total int64
-	q := r.db.Model(&model.JenkinsConfig{})
+	q := r.db.Model(&model.CIProviderConfig{})
 	q.Count(&total)
 	if err := q.Offset((page - 1) * pageSize).Limit(pageSize).Order("created_at DESC").Find(&configs).Error; err != nil {
-		return nil, 0, obserr.Wrap("DB_ERROR", op, "list jenkins configs failed", err)
+		return nil, 0, obserr.Wrap("DB_ERROR", op, "list ci provider configs failed", err)
 	}
 	return configs, total, nil
 }
 
-func (r *CICDRepo) GetConfig(id uint) (*model.JenkinsConfig, error) {
-	var cfg model.JenkinsConfig
+func (r *CICDRepo) GetConfig(id uint) (*model.CIProviderConfig, error) {
+	var cfg model.CIProviderConfig
 	if err := r.db.First(&cfg, id).Error; err != nil {
-		return nil, obserr.Wrap("DB_ERROR", op, "get jenkins config failed", err)
+		return nil, obserr.Wrap("DB_ERROR", op, "get ci provider config failed", err)
 	}
 	return &cfg, nil
 }
 
-func (r *CICDRepo) SaveConfig(cfg *model.JenkinsConfig) error {
+func (r *CICDRepo) SaveConfig(cfg *model.CIProviderConfig) error {
 	if err := r.db.Save(cfg).Error; err != nil {
-		return obserr.Wrap("DB_ERROR", op, "save jenkins config failed", err)
+		return obserr.Wrap("DB_ERROR", op, "save ci provider config failed", err)
 	}
 	return nil
 }
 
 func (r *CICDRepo) DeleteConfig(id uint) error {
-	if err := r.db.Delete(&model.JenkinsConfig{}, id).Error; err != nil {
-		return obserr.Wrap("DB_ERROR", op, "delete jenkins config failed", err)
+	if err := r.db.Delete(&model.CIProviderConfig{}, id).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "delete ci provider config failed", err)
 	}
 	return nil
 }
 
-// --- Jenkins API helpers ---
+// --- Providers ---
 
-func (r *CICDRepo) jenkinsGet(cfg *model.JenkinsConfig, path string, result interface{}) error {
-	u := strings.TrimRight(cfg.URL, "/") + path
-	req, err := http.NewRequest("GET", u, nil)
-	if err != nil {
-		return obserr.Wrap("JENKINS_REQUEST_FAILED", op, "failed to build request", err)
-	}
-	req.SetBasicAuth(cfg.Username, cfg.APIToken)
-	resp, err := r.httpClient.Do(req)
+// Provider loads the config and returns its CI backend.
+func (r *CICDRepo) Provider(configID uint) (Provider, error) {
+	cfg, err := r.GetConfig(configID)
 	if err != nil {
-		return obserr.Wrap("JENKINS_CONNECT_FAILED", op, "cannot reach jenkins server", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode == 404 {
-		return obserr.New("JENKINS_NOT_FOUND", op, "resource not found on jenkins")
-	}
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return obserr.New("JENKINS_REQUEST_FAILED", op, fmt.Sprintf("jenkins returned %d: %s", resp.StatusCode, string(body)))
-	}
-	if result != nil {
-		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
-			return obserr.Wrap("JENKINS_PARSE_FAILED", op, "failed to parse jenkins response", err)
-		}
+		return nil, obserr.Wrap("CI_CONFIG_NOT_FOUND", op, "config not found", err)
 	}
-	return nil
+	return NewProvider(cfg, r.httpClient)
 }
 
-func (r *CICDRepo) jenkinsPost(cfg *model.JenkinsConfig, path string) error {
-	u := strings.TrimRight(cfg.URL, "/") + path
-	req, err := http.NewRequest("POST", u, nil)
+func (r *CICDRepo) TestConnection(cfg *model.CIProviderConfig) error {
+	p, err := NewProvider(cfg, r.httpClient)
 	if err != nil {
-		return obserr.Wrap("JENKINS_REQUEST_FAILED", op, "failed to build request", err)
+		return err
 	}
-	req.SetBasicAuth(cfg.Username, cfg.APIToken)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return obserr.Wrap("JENKINS_CONNECT_FAILED", op, "cannot reach jenkins server", err)
+	return p.TestConnection()
+}
+
+// Pipeline CRUD (DB-backed)
+func (r *CICDRepo) ListPipelines(page, pageSize int) ([]model.Pipeline, int64, error) {
+	var pipelines []model.Pipeline
+	var total int64
+	q := r.db.Model(&model.Pipeline{})
+	q.Count(&total)
+	if err := q.Offset((page-1)*pageSize).Limit(pageSize).Order("created_at DESC").Find(&pipelines).Error; err != nil {
+		return nil, 0, obserr.Wrap("DB_ERROR", op, "list pipelines failed", err)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return obserr.New("JENKINS_REQUEST_FAILED", op, fmt.Sprintf("jenkins returned %d: %s", resp.StatusCode, string(body)))
+	return pipelines, total, nil
+}
+
+func (r *CICDRepo) SavePipeline(p *model.Pipeline) error {
+	if err := r.db.Save(p).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "save pipeline failed", err)
 	}
 	return nil
 }
 
-// --- Connection test ---
-
-func (r *CICDRepo) TestConnection(urlStr, username, apiToken string) error {
-	cfg := &model.JenkinsConfig{URL: urlStr, Username: username, APIToken: apiToken}
-	var result map[string]interface{}
-	if err := r.jenkinsGet(cfg, "/api/json", &result); err != nil {
-		return err
+func (r *CICDRepo) DeletePipeline(id uint) error {
+	if err := r.db.Delete(&model.Pipeline{}, id).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "delete pipeline failed", err)
 	}
 	return nil
 }
 
-// --- Job browsing ---
+// --- Pipeline runs ---
 
-type jenkinsJob struct {
-	Name  string       `json:"name"`
-	URL   string       `json:"url"`
-	Color string       `json:"color"`
-	Jobs  []jenkinsJob `json:"jobs,omitempty"`
+// RunFilter narrows ListRuns; zero fields are ignored.
+type RunFilter struct {
+	PipelineID       uint
+	ProviderConfigID uint
+	JobName          string
+	Status           string
 }
 
-func (r *CICDRepo) ListJobs(configID uint, keyword string) ([]model.JobInfo, error) {
-	cfg, err := r.GetConfig(configID)
-	if err != nil {
-		return nil, obserr.Wrap("JENKINS_CONFIG_NOT_FOUND", op, "config not found", err)
+func (r *CICDRepo) FindPipelineByJob(configID uint, jobName string) (*model.Pipeline, error) {
+	var p model.Pipeline
+	if err := r.db.Where("provider_config_id = ? AND job_name = ?", configID, jobName).First(&p).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "find pipeline failed", err)
 	}
+	return &p, nil
+}
 
-	var rootJobs []jenkinsJob
-	if err := r.jenkinsGet(cfg, "/api/json?tree=jobs[name,url,color,jobs[name,url,color]]", &struct {
-		Jobs *[]jenkinsJob `json:"jobs"`
-	}{Jobs: &rootJobs}); err != nil {
-		return nil, err
+func (r *CICDRepo) SaveRun(run *model.PipelineRun) error {
+	if err := r.db.Save(run).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "save pipeline run failed", err)
 	}
+	return nil
+}
 
-	var result []model.JobInfo
-	keyword = strings.ToLower(keyword)
-	for _, j := range rootJobs {
-		if keyword == "" || strings.Contains(strings.ToLower(j.Name), keyword) || strings.Contains(strings.ToLower(j.URL), keyword) {
-			result = append(result, model.JobInfo{
-				Name: j.Name, DisplayName: j.Name, URL: j.URL, Color: j.Color, Buildable: j.Color != "disabled",
-			})
-		}
+func (r *CICDRepo) GetRun(id uint) (*model.PipelineRun, error) {
+	var run model.PipelineRun
+	if err := r.db.First(&run, id).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "get pipeline run failed", err)
 	}
-	return result, nil
+	return &run, nil
 }
 
-// --- Build management ---
-
-func (r *CICDRepo) TriggerBuild(configID uint, jobName string) error {
-	cfg, err := r.GetConfig(configID)
-	if err != nil {
-		return obserr.Wrap("JENKINS_CONFIG_NOT_FOUND", op, "config not found", err)
+func (r *CICDRepo) ListRuns(filter RunFilter, page, pageSize int) ([]model.PipelineRun, int64, error) {
+	var runs []model.PipelineRun
+	var total int64
+	q := r.db.Model(&model.PipelineRun{}).Omit("log")
+	if filter.PipelineID > 0 {
+		q = q.Where("pipeline_id = ?", filter.PipelineID)
+	}
+	if filter.ProviderConfigID > 0 {
+		q = q.Where("provider_config_id = ?", filter.ProviderConfigID)
+	}
+	if filter.JobName != "" {
+		q = q.Where("job_name = ?", filter.JobName)
 	}
-	path := fmt.Sprintf("/job/%s/build", url.PathEscape(jobName))
-	return r.jenkinsPost(cfg, path)
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	q.Count(&total)
+	if err := q.Offset((page - 1) * pageSize).Limit(pageSize).Order("id DESC").Find(&runs).Error; err != nil {
+		return nil, 0, obserr.Wrap("DB_ERROR", op, "list pipeline runs failed", err)
+	}
+	return runs, total, nil
 }
 
-func (r *CICDRepo) ListBuilds(configID uint, jobName string) ([]model.BuildInfo, error) {
-	cfg, err := r.GetConfig(configID)
-	if err != nil {
-		return nil, obserr.Wrap("JENKINS_CONFIG_NOT_FOUND", op, "config not found", err)
+func (r *CICDRepo) ListAllPipelines() ([]model.Pipeline, error) {
+	var pipelines []model.Pipeline
+	if err := r.db.Order("id").Find(&pipelines).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list pipelines failed", err)
 	}
+	return pipelines, nil
+}
 
-	path := fmt.Sprintf("/job/%s/api/json?tree=builds[number,url,result,duration,timestamp,building]", url.PathEscape(jobName))
-	var resp struct {
-		Builds []struct {
-			Number    int    `json:"number"`
-			URL       string `json:"url"`
-			Result    string `json:"result"`
-			Duration  int64  `json:"duration"`
-			Timestamp int64  `json:"timestamp"`
-			Building  bool   `json:"building"`
-		} `json:"builds"`
+func (r *CICDRepo) GetPipeline(id uint) (*model.Pipeline, error) {
+	var p model.Pipeline
+	if err := r.db.First(&p, id).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "get pipeline failed", err)
 	}
-	if err := r.jenkinsGet(cfg, path, &resp); err != nil {
-		return nil, err
+	return &p, nil
+}
+
+// UpdateRun writes only the given columns of a run.
+func (r *CICDRepo) UpdateRun(id uint, fields map[string]interface{}) error {
+	if err := r.db.Model(&model.PipelineRun{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "update pipeline run failed", err)
 	}
+	return nil
+}
 
-	var builds []model.BuildInfo
-	for _, b := range resp.Builds {
-		builds = append(builds, model.BuildInfo{
-			Number: b.Number, URL: b.URL, Result: b.Result,
-			Duration: b.Duration, Timestamp: b.Timestamp, Building: b.Building,
-		})
+// ListUnnumberedRuns returns a job's runs triggered here whose build is not
+// known yet, oldest first.
+func (r *CICDRepo) ListUnnumberedRuns(configID uint, jobName string) ([]model.PipelineRun, error) {
+	var runs []model.PipelineRun
+	if err := r.db.Omit("log").
+		Where("provider_config_id = ? AND job_name = ? AND build_number = 0 AND status IN ?", configID, jobName, []string{model.RunQueued, model.RunUnknown}).
+		Order("id").Find(&runs).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list unnumbered pipeline runs failed", err)
 	}
-	return builds, nil
+	return runs, nil
 }
 
-func (r *CICDRepo) GetBuildLog(configID uint, jobName string, buildNumber int) (*model.BuildLogEntry, error) {
-	cfg, err := r.GetConfig(configID)
-	if err != nil {
-		return nil, obserr.Wrap("JENKINS_CONFIG_NOT_FOUND", op, "config not found", err)
+// FindRun returns the run recorded for a build, or nil when there is none yet.
+func (r *CICDRepo) FindRun(configID uint, jobName string, buildNumber int) (*model.PipelineRun, error) {
+	var runs []model.PipelineRun
+	if err := r.db.Where("provider_config_id = ? AND job_name = ? AND build_number = ?", configID, jobName, buildNumber).
+		Limit(1).Find(&runs).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "find pipeline run failed", err)
+	}
+	if len(runs) == 0 {
+		return nil, nil
 	}
+	return &runs[0], nil
+}
 
-	path := fmt.Sprintf("/job/%s/%d/consoleText", url.PathEscape(jobName), buildNumber)
-	u := strings.TrimRight(cfg.URL, "/") + path
-	req, err := http.NewRequest("GET", u, nil)
-	if err != nil {
-		return nil, obserr.Wrap("JENKINS_REQUEST_FAILED", op, "failed to build request", err)
+// ListFinishedRuns returns a pipeline's finished runs started in [from, to), oldest first.
+func (r *CICDRepo) ListFinishedRuns(pipelineID uint, from, to time.Time) ([]model.PipelineRun, error) {
+	var runs []model.PipelineRun
+	if err := r.db.Omit("log").
+		Where("pipeline_id = ? AND started_at >= ? AND started_at < ?", pipelineID, from, to).
+		Where("status IN ?", []string{model.RunSuccess, model.RunFailed, model.RunAborted}).
+		Order("started_at, build_number").Find(&runs).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list pipeline runs failed", err)
 	}
-	req.SetBasicAuth(cfg.Username, cfg.APIToken)
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, obserr.Wrap("JENKINS_CONNECT_FAILED", op, "cannot reach jenkins", err)
+	return runs, nil
+}
+
+// --- Pipeline specs & executions ---
+
+func (r *CICDRepo) ListSpecs(tenantID, appID uint) ([]model.PipelineSpec, error) {
+	var specs []model.PipelineSpec
+	q := r.db.Where("tenant_id = ?", tenantID)
+	if appID > 0 {
+		q = q.Where("app_id = ?", appID)
 	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20)) // limit to 1MB
-	if err != nil {
-		return nil, obserr.Wrap("JENKINS_LOG_FAILED", op, "failed to read build log", err)
+	if err := q.Order("id").Find(&specs).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list pipeline specs failed", err)
 	}
+	return specs, nil
+}
 
-	text := string(body)
-	hasMore := len(text) >= 1<<20
-	return &model.BuildLogEntry{Text: text, HasMore: hasMore}, nil
+func (r *CICDRepo) GetSpec(tenantID, id uint) (*model.PipelineSpec, error) {
+	var spec model.PipelineSpec
+	if err := r.db.Where("tenant_id = ?", tenantID).First(&spec, id).Error; err != nil {
+		return nil, obserr.Wrap("PIPELINE_SPEC_NOT_FOUND", op, "pipeline spec not found", err)
+	}
+	return &spec, nil
 }
 
-// Pipeline CRUD (DB-backed)
-func (r *CICDRepo) ListPipelines(page, pageSize int) ([]model.Pipeline, int64, error) {
-	var pipelines []model.Pipeline
-	var total int64
-	q := r.db.Model(&model.Pipeline{})
-	q.Count(&total)
-	if err := q.Offset((page-1)*pageSize).Limit(pageSize).Order("created_at DESC").Find(&pipelines).Error; err != nil {
-		return nil, 0, obserr.Wrap("DB_ERROR", op, "list pipelines failed", err)
+func (r *CICDRepo) SaveSpec(spec *model.PipelineSpec) error {
+	if err := r.db.Save(spec).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "save pipeline spec failed", err)
 	}
-	return pipelines, total, nil
+	return nil
 }
 
-func (r *CICDRepo) SavePipeline(p *model.Pipeline) error {
-	if err := r.db.Save(p).Error; err != nil {
-		return obserr.Wrap("DB_ERROR", op, "save pipeline failed", err)
+func (r *CICDRepo) DeleteSpec(tenantID, id uint) error {
+	if err := r.db.Where("tenant_id = ?", tenantID).Delete(&model.PipelineSpec{}, id).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "delete pipeline spec failed", err)
 	}
 	return nil
 }
 
-func (r *CICDRepo) DeletePipeline(id uint) error {
-	if err := r.db.Delete(&model.Pipeline{}, id).Error; err != nil {
-		return obserr.Wrap("DB_ERROR", op, "delete pipeline failed", err)
+func (r *CICDRepo) SaveExecution(exec *model.PipelineExecution) error {
+	if err := r.db.Save(exec).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "save pipeline execution failed", err)
 	}
 	return nil
 }
+
+func (r *CICDRepo) GetExecution(tenantID, id uint) (*model.PipelineExecution, error) {
+	var exec model.PipelineExecution
+	if err := r.db.Where("tenant_id = ?", tenantID).First(&exec, id).Error; err != nil {
+		return nil, obserr.Wrap("PIPELINE_EXECUTION_NOT_FOUND", op, "pipeline execution not found", err)
+	}
+	return &exec, nil
+}
+
+func (r *CICDRepo) ListExecutions(tenantID, specID uint, page, pageSize int) ([]model.PipelineExecution, int64, error) {
+	var execs []model.PipelineExecution
+	var total int64
+	q := r.db.Model(&model.PipelineExecution{}).Where("tenant_id = ?", tenantID)
+	if specID > 0 {
+		q = q.Where("spec_id = ?", specID)
+	}
+	q.Count(&total)
+	if err := q.Offset((page - 1) * pageSize).Limit(pageSize).Order("id DESC").Find(&execs).Error; err != nil {
+		return nil, 0, obserr.Wrap("DB_ERROR", op, "list pipeline executions failed", err)
+	}
+	return execs, total, nil
+}
+
+// ListActiveExecutions returns executions the runner still has to advance, across tenants.
+func (r *CICDRepo) ListActiveExecutions() ([]model.PipelineExecution, error) {
+	var execs []model.PipelineExecution
+	if err := r.db.Where("status IN ?", []string{model.ExecRunning, model.ExecWaiting}).Order("id").Find(&execs).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list active pipeline executions failed", err)
+	}
+	return execs, nil
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/cicd/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+const defaultGitHubAPI = "https://api.github.com"
+
+// githubProvider treats each workflow file of the configured repository as a
+// job, triggered through workflow_dispatch, and its runs as builds.
+type githubProvider struct {
+	api  *httpAPI
+	repo string
+	ref  string
+}
+
+func newGitHubProvider(cfg *model.CIProviderConfig, client *http.Client) *githubProvider {
+	baseURL := cfg.URL
+	if baseURL == "" {
+		baseURL = defaultGitHubAPI
+	}
+	return &githubProvider{
+		repo: strings.Trim(cfg.Project, "/"),
+		ref:  cfg.Ref,
+		api: &httpAPI{
+			code:    "GITHUB",
+			name:    "github",
+			baseURL: baseURL,
+			client:  client,
+			auth: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+cfg.Token)
+				req.Header.Set("Accept", "application/vnd.github+json")
+				req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+			},
+		},
+	}
+}
+
+func (p *githubProvider) repoPath() string {
+	return "/repos/" + p.repo
+}
+
+func (p *githubProvider) workflowPath(workflow string) string {
+	return p.repoPath() + "/actions/workflows/" + url.PathEscape(workflow)
+}
+
+func (p *githubProvider) TestConnection() error {
+	var repo map[string]interface{}
+	return p.api.getJSON(p.repoPath(), &repo)
+}
+
+func (p *githubProvider) ListJobs(keyword string) ([]model.JobInfo, error) {
+	var resp struct {
+		Workflows []struct {
+			Name    string `json:"name"`
+			Path    string `json:"path"`
+			State   string `json:"state"`
+			HTMLURL string `json:"html_url"`
+		} `json:"workflows"`
+	}
+	if err := p.api.getJSON(p.repoPath()+"/actions/workflows?per_page=100", &resp); err != nil {
+		return nil, err
+	}
+	keyword = strings.ToLower(keyword)
+	var jobs []model.JobInfo
+	for _, wf := range resp.Workflows {
+		file := wf.Path[strings.LastIndex(wf.Path, "/")+1:]
+		if keyword != "" && !strings.Contains(strings.ToLower(wf.Name), keyword) && !strings.Contains(strings.ToLower(file), keyword) {
+			continue
+		}
+		jobs = append(jobs, model.JobInfo{
+			Name:        file,
+			DisplayName: wf.Name,
+			URL:         wf.HTMLURL,
+			Buildable:   wf.State == "active",
+			Description: wf.Path,
+		})
+	}
+	return jobs, nil
+}
+
+func (p *githubProvider) resolveRef() (string, error) {
+	if p.ref != "" {
+		return p.ref, nil
+	}
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := p.api.getJSON(p.repoPath(), &info); err != nil {
+		return "", err
+	}
+	return info.DefaultBranch, nil
+}
+
+// TriggerBuild dispatches the workflow with params as its inputs. GitHub does
+// not return the run it creates, so the build number is left 0.
+func (p *githubProvider) TriggerBuild(workflow string, params map[string]string) (TriggerResult, error) {
+	ref, err := p.resolveRef()
+	if err != nil {
+		return TriggerResult{}, err
+	}
+	payload := map[string]interface{}{"ref": ref}
+	if len(params) > 0 {
+		payload["inputs"] = params
+	}
+	_, _, err = p.api.do(http.MethodPost, p.workflowPath(workflow)+"/dispatches", payload, "")
+	return TriggerResult{}, err
+}
+
+type githubRun struct {
+	ID           int       `json:"id"`
+	Status       string    `json:"status"`
+	Conclusion   string    `json:"conclusion"`
+	HTMLURL      string    `json:"html_url"`
+	HeadSHA      string    `json:"head_sha"`
+	RunStartedAt time.Time `json:"run_started_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Actor        struct {
+		Login string `json:"login"`
+	} `json:"triggering_actor"`
+}
+
+func githubResult(conclusion string) string {
+	switch conclusion {
+	case "success":
+		return "SUCCESS"
+	case "failure", "timed_out", "startup_failure":
+		return "FAILURE"
+	case "cancelled", "skipped":
+		return "ABORTED"
+	case "":
+		return ""
+	}
+	return strings.ToUpper(conclusion)
+}
+
+func (p *githubProvider) ListBuilds(workflow string) ([]model.BuildInfo, error) {
+	var resp struct {
+		WorkflowRuns []githubRun `json:"workflow_runs"`
+	}
+	if err := p.api.getJSON(p.workflowPath(workflow)+"/runs?per_page=50", &resp); err != nil {
+		return nil, err
+	}
+	builds := make([]model.BuildInfo, 0, len(resp.WorkflowRuns))
+	for _, run := range resp.WorkflowRuns {
+		b := model.BuildInfo{
+			Number:    run.ID,
+			URL:       run.HTMLURL,
+			Result:    githubResult(run.Conclusion),
+			Timestamp: run.RunStartedAt.UnixMilli(),
+			Building:  run.Status != "completed",
+			Commit:    run.HeadSHA,
+			// Re-runs keep the original actor; triggering_actor is who started this attempt.
+			TriggeredBy: run.Actor.Login,
+		}
+		if !b.Building {
+			b.Duration = run.UpdatedAt.Sub(run.RunStartedAt).Milliseconds()
+		}
+		builds = append(builds, b)
+	}
+	return builds, nil
+}
+
+// GetBuildLog concatenates the logs of the run's jobs. GitHub only serves a
+// job's log once it has finished, so output stops at the first running job.
+func (p *githubProvider) GetBuildLog(workflow string, runID, offset int) (*model.BuildLogEntry, error) {
+	var run githubRun
+	if err := p.api.getJSON(fmt.Sprintf("%s/actions/runs/%d", p.repoPath(), runID), &run); err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Jobs []struct {
+			ID     int    `json:"id"`
+			Name   string `json:"name"`
+			Status string `json:"status"`
+		} `json:"jobs"`
+	}
+	if err := p.api.getJSON(fmt.Sprintf("%s/actions/runs/%d/jobs?per_page=100", p.repoPath(), runID), &resp); err != nil {
+		return nil, err
+	}
+	var sb strings.Builder
+	for _, job := range resp.Jobs {
+		if job.Status != "completed" {
+			break
+		}
+		sb.WriteString(fmt.Sprintf("==> %s\n", job.Name))
+		_, data, err := p.api.do(http.MethodGet, fmt.Sprintf("%s/actions/jobs/%d/logs", p.repoPath(), job.ID), nil, "")
+		if err != nil {
+			// Logs of expired or skipped jobs are gone; keep the other jobs readable.
+			if obserr.Details(err)["code"] != "GITHUB_NOT_FOUND" {
+				return nil, err
+			}
+			data = nil
+		}
+		sb.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			sb.WriteByte('\n')
+		}
+	}
+	return sliceLog(sb.String(), offset, run.Status != "completed"), nil
+}
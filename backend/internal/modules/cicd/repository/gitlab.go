@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/cicd/model"
+)
+
+// gitlabProvider treats each GitLab project as a job and its pipelines as builds.
+type gitlabProvider struct {
+	api *httpAPI
+	ref string
+}
+
+func newGitLabProvider(cfg *model.CIProviderConfig, client *http.Client) *gitlabProvider {
+	return &gitlabProvider{
+		ref: cfg.Ref,
+		api: &httpAPI{
+			code:    "GITLAB",
+			name:    "gitlab",
+			baseURL: strings.TrimRight(cfg.URL, "/") + "/api/v4",
+			client:  client,
+			auth: func(req *http.Request) {
+				req.Header.Set("PRIVATE-TOKEN", cfg.Token)
+			},
+		},
+	}
+}
+
+func gitlabProjectPath(project string) string {
+	return "/projects/" + url.PathEscape(strings.Trim(project, "/"))
+}
+
+func (p *gitlabProvider) TestConnection() error {
+	var user map[string]interface{}
+	return p.api.getJSON("/user", &user)
+}
+
+func (p *gitlabProvider) ListJobs(keyword string) ([]model.JobInfo, error) {
+	var projects []struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		NameWithNamespace string `json:"name_with_namespace"`
+		WebURL            string `json:"web_url"`
+		Description       string `json:"description"`
+		Archived          bool   `json:"archived"`
+	}
+	path := "/projects?membership=true&order_by=last_activity_at&per_page=100&search=" + url.QueryEscape(keyword)
+	if err := p.api.getJSON(path, &projects); err != nil {
+		return nil, err
+	}
+	jobs := make([]model.JobInfo, 0, len(projects))
+	for _, pr := range projects {
+		jobs = append(jobs, model.JobInfo{
+			Name:        pr.PathWithNamespace,
+			DisplayName: pr.NameWithNamespace,
+			URL:         pr.WebURL,
+			Buildable:   !pr.Archived,
+			Description: pr.Description,
+		})
+	}
+	return jobs, nil
+}
+
+func (p *gitlabProvider) resolveRef(project string) (string, error) {
+	if p.ref != "" {
+		return p.ref, nil
+	}
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := p.api.getJSON(gitlabProjectPath(project), &info); err != nil {
+		return "", err
+	}
+	return info.DefaultBranch, nil
+}
+
+// TriggerBuild creates a pipeline on the ref; params become pipeline variables.
+func (p *gitlabProvider) TriggerBuild(project string, params map[string]string) (TriggerResult, error) {
+	ref, err := p.resolveRef(project)
+	if err != nil {
+		return TriggerResult{}, err
+	}
+	payload := map[string]interface{}{"ref": ref}
+	if len(params) > 0 {
+		variables := make([]map[string]string, 0, len(params))
+		for _, k := range sortedKeys(params) {
+			variables = append(variables, map[string]string{"key": k, "value": params[k]})
+		}
+		payload["variables"] = variables
+	}
+	_, data, err := p.api.do(http.MethodPost, gitlabProjectPath(project)+"/pipeline", payload, "")
+	if err != nil {
+		return TriggerResult{}, err
+	}
+	var created struct {
+		ID int `json:"id"`
+	}
+	if err := p.api.decode(data, &created); err != nil {
+		return TriggerResult{}, err
+	}
+	return TriggerResult{BuildNumber: created.ID}, nil
+}
+
+type gitlabPipeline struct {
+	ID        int       `json:"id"`
+	Status    string    `json:"status"`
+	SHA       string    `json:"sha"`
+	WebURL    string    `json:"web_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// gitlabRunning reports whether a pipeline or job status is not yet final.
+func gitlabRunning(status string) bool {
+	switch status {
+	case "created", "waiting_for_resource", "preparing", "pending", "running", "scheduled", "manual":
+		return true
+	}
+	return false
+}
+
+func gitlabResult(status string) string {
+	switch status {
+	case "success":
+		return "SUCCESS"
+	case "failed":
+		return "FAILURE"
+	case "canceled", "skipped":
+		return "ABORTED"
+	}
+	return ""
+}
+
+func (p *gitlabProvider) ListBuilds(project string) ([]model.BuildInfo, error) {
+	var pipelines []gitlabPipeline
+	if err := p.api.getJSON(gitlabProjectPath(project)+"/pipelines?per_page=50", &pipelines); err != nil {
+		return nil, err
+	}
+	builds := make([]model.BuildInfo, 0, len(pipelines))
+	for _, pl := range pipelines {
+		b := model.BuildInfo{
+			Number:    pl.ID,
+			URL:       pl.WebURL,
+			Result:    gitlabResult(pl.Status),
+			Timestamp: pl.CreatedAt.UnixMilli(),
+			Building:  gitlabRunning(pl.Status),
+			Commit:    pl.SHA,
+		}
+		if !b.Building {
+			b.Duration = pl.UpdatedAt.Sub(pl.CreatedAt).Milliseconds()
+		}
+		builds = append(builds, b)
+	}
+	return builds, nil
+}
+
+// GetBuildLog concatenates the traces of every job in the pipeline, in stage order.
+func (p *gitlabProvider) GetBuildLog(project string, pipelineID, offset int) (*model.BuildLogEntry, error) {
+	base := gitlabProjectPath(project)
+	var pipeline gitlabPipeline
+	if err := p.api.getJSON(fmt.Sprintf("%s/pipelines/%d", base, pipelineID), &pipeline); err != nil {
+		return nil, err
+	}
+	var jobs []struct {
+		ID     int    `json:"id"`
+		Name   string `json:"name"`
+		Stage  string `json:"stage"`
+		Status string `json:"status"`
+	}
+	if err := p.api.getJSON(fmt.Sprintf("%s/pipelines/%d/jobs?per_page=100", base, pipelineID), &jobs); err != nil {
+		return nil, err
+	}
+	// The jobs API lists newest first; traces read better oldest first. Output
+	// stops at the first unfinished job so earlier offsets stay valid as it grows.
+	var sb strings.Builder
+	for i := len(jobs) - 1; i >= 0; i-- {
+		job := jobs[i]
+		if job.Status == "created" || job.Status == "manual" || job.Status == "skipped" {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("==> [%s] %s\n", job.Stage, job.Name))
+		_, trace, err := p.api.do(http.MethodGet, fmt.Sprintf("%s/jobs/%d/trace", base, job.ID), nil, "")
+		if err != nil {
+			return nil, err
+		}
+		sb.Write(trace)
+		if gitlabRunning(job.Status) {
+			break
+		}
+		if len(trace) > 0 && trace[len(trace)-1] != '\n' {
+			sb.WriteByte('\n')
+		}
+	}
+	return sliceLog(sb.String(), offset, gitlabRunning(pipeline.Status)), nil
+}
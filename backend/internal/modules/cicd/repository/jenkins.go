@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"devops-platform/internal/modules/cicd/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+type jenkinsProvider struct {
+	api *httpAPI
+}
+
+func newJenkinsProvider(cfg *model.CIProviderConfig, client *http.Client) *jenkinsProvider {
+	// Jenkins binds CSRF crumbs to the session, so keep its cookie between the
+	// crumb request and the build request.
+	jarClient := *client
+	jarClient.Jar, _ = cookiejar.New(nil)
+	return &jenkinsProvider{api: &httpAPI{
+		code:    "JENKINS",
+		name:    "jenkins",
+		baseURL: cfg.URL,
+		client:  &jarClient,
+		auth: func(req *http.Request) {
+			req.SetBasicAuth(cfg.Username, cfg.Token)
+		},
+	}}
+}
+
+// jenkinsJobPath maps "folder/job" to "/job/folder/job/job".
+func jenkinsJobPath(jobName string) string {
+	var sb strings.Builder
+	for _, part := range strings.Split(strings.Trim(jobName, "/"), "/") {
+		sb.WriteString("/job/")
+		sb.WriteString(url.PathEscape(part))
+	}
+	return sb.String()
+}
+
+func (p *jenkinsProvider) TestConnection() error {
+	var result map[string]interface{}
+	return p.api.getJSON("/api/json", &result)
+}
+
+type jenkinsJob struct {
+	Name  string       `json:"name"`
+	URL   string       `json:"url"`
+	Color string       `json:"color"`
+	Jobs  []jenkinsJob `json:"jobs,omitempty"`
+}
+
+func (p *jenkinsProvider) ListJobs(keyword string) ([]model.JobInfo, error) {
+	var rootJobs []jenkinsJob
+	if err := p.api.getJSON("/api/json?tree=jobs[name,url,color,jobs[name,url,color]]", &struct {
+		Jobs *[]jenkinsJob `json:"jobs"`
+	}{Jobs: &rootJobs}); err != nil {
+		return nil, err
+	}
+
+	var result []model.JobInfo
+	keyword = strings.ToLower(keyword)
+	for _, j := range rootJobs {
+		if keyword == "" || strings.Contains(strings.ToLower(j.Name), keyword) || strings.Contains(strings.ToLower(j.URL), keyword) {
+			result = append(result, model.JobInfo{
+				Name: j.Name, DisplayName: j.Name, URL: j.URL, Color: j.Color, Buildable: j.Color != "disabled",
+			})
+		}
+	}
+	return result, nil
+}
+
+// JobParameters reads the job's parameter definitions.
+func (p *jenkinsProvider) JobParameters(jobName string) ([]model.JobParameter, error) {
+	var resp struct {
+		Property []struct {
+			ParameterDefinitions []struct {
+				Name                  string   `json:"name"`
+				Type                  string   `json:"type"`
+				Description           string   `json:"description"`
+				Choices               []string `json:"choices"`
+				DefaultParameterValue *struct {
+					Value interface{} `json:"value"`
+				} `json:"defaultParameterValue"`
+			} `json:"parameterDefinitions"`
+		} `json:"property"`
+	}
+	path := jenkinsJobPath(jobName) + "/api/json?tree=property[parameterDefinitions[name,type,description,choices,defaultParameterValue[value]]]"
+	if err := p.api.getJSON(path, &resp); err != nil {
+		return nil, err
+	}
+	var params []model.JobParameter
+	for _, prop := range resp.Property {
+		for _, def := range prop.ParameterDefinitions {
+			param := model.JobParameter{
+				Name:        def.Name,
+				Type:        strings.TrimSuffix(def.Type, "ParameterDefinition"),
+				Choices:     def.Choices,
+				Description: def.Description,
+			}
+			if def.DefaultParameterValue != nil && def.DefaultParameterValue.Value != nil {
+				param.Default = fmt.Sprint(def.DefaultParameterValue.Value)
+			}
+			params = append(params, param)
+		}
+	}
+	return params, nil
+}
+
+// buildForm merges params over the job's defaults and checks them against the
+// definitions: unknown names, choices outside the list and non-boolean values
+// for boolean parameters are rejected.
+func buildForm(defs []model.JobParameter, params map[string]string) (url.Values, error) {
+	known := make(map[string]model.JobParameter, len(defs))
+	form := url.Values{}
+	for _, def := range defs {
+		known[def.Name] = def
+		form.Set(def.Name, def.Default)
+	}
+	for _, name := range sortedKeys(params) {
+		value := params[name]
+		def, ok := known[name]
+		if !ok {
+			return nil, obserr.New("INVALID_PARAM", op, fmt.Sprintf("job has no parameter %q", name))
+		}
+		switch def.Type {
+		case "Choice":
+			valid := false
+			for _, c := range def.Choices {
+				if c == value {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return nil, obserr.New("INVALID_PARAM", op, fmt.Sprintf("parameter %s must be one of %s", name, strings.Join(def.Choices, ", ")))
+			}
+		case "Boolean":
+			if _, err := strconv.ParseBool(value); err != nil {
+				return nil, obserr.New("INVALID_PARAM", op, fmt.Sprintf("parameter %s must be true or false", name))
+			}
+		}
+		form.Set(name, value)
+	}
+	return form, nil
+}
+
+// crumbHeader fetches a CSRF crumb. Jenkins without CSRF protection has no
+// crumb issuer, which is not an error.
+func (p *jenkinsProvider) crumbHeader() (map[string]string, error) {
+	var crumb struct {
+		Crumb             string `json:"crumb"`
+		CrumbRequestField string `json:"crumbRequestField"`
+	}
+	if err := p.api.getJSON("/crumbIssuer/api/json", &crumb); err != nil {
+		if obserr.Details(err)["code"] == "JENKINS_NOT_FOUND" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if crumb.Crumb == "" || crumb.CrumbRequestField == "" {
+		return nil, nil
+	}
+	return map[string]string{crumb.CrumbRequestField: crumb.Crumb}, nil
+}
+
+// TriggerBuild posts to build, or to buildWithParameters when the job defines
+// parameters, and returns the queue item URL from the Location header.
+func (p *jenkinsProvider) TriggerBuild(jobName string, params map[string]string) (TriggerResult, error) {
+	defs, err := p.JobParameters(jobName)
+	if err != nil {
+		return TriggerResult{}, err
+	}
+	if len(defs) == 0 && len(params) > 0 {
+		return TriggerResult{}, obserr.New("INVALID_PARAM", op, "job does not accept parameters")
+	}
+	path := jenkinsJobPath(jobName) + "/build"
+	var body string
+	if len(defs) > 0 {
+		form, err := buildForm(defs, params)
+		if err != nil {
+			return TriggerResult{}, err
+		}
+		path = jenkinsJobPath(jobName) + "/buildWithParameters"
+		body = form.Encode()
+	}
+	headers, err := p.crumbHeader()
+	if err != nil {
+		return TriggerResult{}, err
+	}
+	resp, _, err := p.api.doWithHeaders(http.MethodPost, path, body, "application/x-www-form-urlencoded", headers)
+	if err != nil {
+		return TriggerResult{}, err
+	}
+	return TriggerResult{QueueURL: resp.Header.Get("Location")}, nil
+}
+
+var queueItemPattern = regexp.MustCompile(`/queue/item/(\d+)`)
+
+// ResolveQueueItem polls a queue item. The item is re-addressed against the
+// configured URL because Jenkins builds Location from its own root URL setting.
+func (p *jenkinsProvider) ResolveQueueItem(queueURL string) (int, bool, error) {
+	m := queueItemPattern.FindStringSubmatch(queueURL)
+	if m == nil {
+		return 0, false, obserr.New("JENKINS_QUEUE_INVALID", op, "invalid queue item url: "+queueURL)
+	}
+	var item struct {
+		Cancelled  bool `json:"cancelled"`
+		Executable *struct {
+			Number int `json:"number"`
+		} `json:"executable"`
+	}
+	if err := p.api.getJSON("/queue/item/"+m[1]+"/api/json", &item); err != nil {
+		return 0, false, err
+	}
+	if item.Executable != nil {
+		return item.Executable.Number, false, nil
+	}
+	return 0, item.Cancelled, nil
+}
+
+type jenkinsAction struct {
+	Causes []struct {
+		UserID   string `json:"userId"`
+		UserName string `json:"userName"`
+	} `json:"causes"`
+	Parameters []struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	} `json:"parameters"`
+	LastBuiltRevision *struct {
+		SHA1 string `json:"SHA1"`
+	} `json:"lastBuiltRevision"`
+}
+
+func (p *jenkinsProvider) ListBuilds(jobName string) ([]model.BuildInfo, error) {
+	var resp struct {
+		Builds []struct {
+			Number    int             `json:"number"`
+			URL       string          `json:"url"`
+			Result    string          `json:"result"`
+			Duration  int64           `json:"duration"`
+			Timestamp int64           `json:"timestamp"`
+			Building  bool            `json:"building"`
+			Actions   []jenkinsAction `json:"actions"`
+		} `json:"builds"`
+	}
+	path := jenkinsJobPath(jobName) + "/api/json?tree=builds[number,url,result,duration,timestamp,building," +
+		"actions[causes[userId,userName],parameters[name,value],lastBuiltRevision[SHA1]]]"
+	if err := p.api.getJSON(path, &resp); err != nil {
+		return nil, err
+	}
+
+	var builds []model.BuildInfo
+	for _, b := range resp.Builds {
+		build := model.BuildInfo{
+			Number: b.Number, URL: b.URL, Result: b.Result,
+			Duration: b.Duration, Timestamp: b.Timestamp, Building: b.Building,
+		}
+		// Actions are a heterogeneous list; each kind fills only its own fields.
+		for _, a := range b.Actions {
+			for _, cause := range a.Causes {
+				if build.TriggeredBy == "" && cause.UserID != "" {
+					build.TriggeredBy = cause.UserID
+				}
+			}
+			for _, param := range a.Parameters {
+				if build.Parameters == nil {
+					build.Parameters = map[string]string{}
+				}
+				build.Parameters[param.Name] = fmt.Sprint(param.Value)
+			}
+			if a.LastBuiltRevision != nil && build.Commit == "" {
+				build.Commit = a.LastBuiltRevision.SHA1
+			}
+		}
+		builds = append(builds, build)
+	}
+	return builds, nil
+}
+
+// GetBuildLog reads the progressive console, which reports the next offset in
+// X-Text-Size and sets X-More-Data while the build is running.
+func (p *jenkinsProvider) GetBuildLog(jobName string, buildNumber, offset int) (*model.BuildLogEntry, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	path := fmt.Sprintf("%s/%d/logText/progressiveText?start=%d", jenkinsJobPath(jobName), buildNumber, offset)
+	resp, data, err := p.api.do(http.MethodGet, path, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	next := offset + len(data)
+	if size, err := strconv.Atoi(resp.Header.Get("X-Text-Size")); err == nil {
+		next = size
+	}
+	return &model.BuildLogEntry{
+		Offset:  next,
+		Text:    string(data),
+		HasMore: resp.Header.Get("X-More-Data") == "true",
+	}, nil
+}
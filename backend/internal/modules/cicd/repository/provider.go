@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"devops-platform/internal/modules/cicd/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// maxLogBytes caps how much build log a provider reads per request.
+const maxLogBytes = 4 << 20
+
+// Provider is a CI backend that can list, trigger and inspect builds.
+type Provider interface {
+	TestConnection() error
+	ListJobs(keyword string) ([]model.JobInfo, error)
+	// TriggerBuild starts a build with optional parameters.
+	TriggerBuild(jobName string, params map[string]string) (TriggerResult, error)
+	ListBuilds(jobName string) ([]model.BuildInfo, error)
+	// GetBuildLog returns the log from offset on; poll with the returned Offset while HasMore.
+	GetBuildLog(jobName string, buildNumber, offset int) (*model.BuildLogEntry, error)
+}
+
+// TriggerResult describes a started build. BuildNumber is 0 when the backend
+// does not report it up front; QueueURL is set when it can be followed via QueueTracker.
+type TriggerResult struct {
+	BuildNumber int
+	QueueURL    string
+}
+
+// QueueTracker is implemented by providers that queue builds before numbering them.
+type QueueTracker interface {
+	// ResolveQueueItem reports the build number once assigned, or cancelled if
+	// the queue item was dropped. Both are zero while the item is still waiting.
+	ResolveQueueItem(queueURL string) (buildNumber int, cancelled bool, err error)
+}
+
+// ParameterDescriber is implemented by providers that expose a job's build parameters.
+type ParameterDescriber interface {
+	JobParameters(jobName string) ([]model.JobParameter, error)
+}
+
+// NewProvider returns the Provider implementation for the config's type.
+func NewProvider(cfg *model.CIProviderConfig, client *http.Client) (Provider, error) {
+	switch cfg.Type {
+	case model.ProviderJenkins, "":
+		return newJenkinsProvider(cfg, client), nil
+	case model.ProviderGitLab:
+		return newGitLabProvider(cfg, client), nil
+	case model.ProviderGitHub:
+		return newGitHubProvider(cfg, client), nil
+	}
+	return nil, obserr.New("CI_PROVIDER_UNSUPPORTED", op, fmt.Sprintf("unsupported ci provider type: %s", cfg.Type))
+}
+
+// httpAPI is the request plumbing shared by providers. code prefixes error
+// codes, e.g. "GITLAB" yields GITLAB_CONNECT_FAILED.
+type httpAPI struct {
+	code    string
+	name    string
+	baseURL string
+	client  *http.Client
+	auth    func(req *http.Request)
+}
+
+func (a *httpAPI) do(method, path string, payload interface{}, contentType string) (*http.Response, []byte, error) {
+	return a.doWithHeaders(method, path, payload, contentType, nil)
+}
+
+func (a *httpAPI) doWithHeaders(method, path string, payload interface{}, contentType string, headers map[string]string) (*http.Response, []byte, error) {
+	var body io.Reader
+	switch p := payload.(type) {
+	case nil:
+	case []byte:
+		body = bytes.NewReader(p)
+	case string:
+		body = strings.NewReader(p)
+	default:
+		data, err := json.Marshal(p)
+		if err != nil {
+			return nil, nil, obserr.Wrap(a.code+"_REQUEST_FAILED", op, "failed to encode request", err)
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	target := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		target = strings.TrimRight(a.baseURL, "/") + path
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, nil, obserr.Wrap(a.code+"_REQUEST_FAILED", op, "failed to build request", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if a.auth != nil {
+		a.auth(req)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, nil, obserr.Wrap(a.code+"_CONNECT_FAILED", op, fmt.Sprintf("cannot reach %s server", a.name), err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxLogBytes))
+	if err != nil {
+		return resp, nil, obserr.Wrap(a.code+"_REQUEST_FAILED", op, fmt.Sprintf("failed to read %s response", a.name), err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return resp, data, obserr.New(a.code+"_NOT_FOUND", op, fmt.Sprintf("resource not found on %s", a.name))
+	}
+	if resp.StatusCode >= 400 {
+		return resp, data, obserr.New(a.code+"_REQUEST_FAILED", op, fmt.Sprintf("%s returned %d: %s", a.name, resp.StatusCode, string(data)))
+	}
+	return resp, data, nil
+}
+
+func (a *httpAPI) getJSON(path string, result interface{}) error {
+	_, data, err := a.do(http.MethodGet, path, nil, "")
+	if err != nil {
+		return err
+	}
+	return a.decode(data, result)
+}
+
+func (a *httpAPI) decode(data []byte, result interface{}) error {
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return obserr.Wrap(a.code+"_PARSE_FAILED", op, fmt.Sprintf("failed to parse %s response", a.name), err)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sliceLog returns the part of a full log from offset on.
+func sliceLog(text string, offset int, hasMore bool) *model.BuildLogEntry {
+	if offset < 0 || offset > len(text) {
+		offset = 0
+	}
+	return &model.BuildLogEntry{Offset: len(text), Text: text[offset:], HasMore: hasMore}
+}
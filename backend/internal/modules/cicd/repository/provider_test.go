@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"devops-platform/internal/modules/cicd/model"
+)
+
+// newFakeCI serves canned responses keyed by "METHOD path?query" and records request bodies.
+func newFakeCI(t *testing.T, routes map[string]string, checkAuth func(*http.Request) bool) (*httptest.Server, map[string]string) {
+	t.Helper()
+	bodies := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if checkAuth != nil && !checkAuth(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		key := r.Method + " " + r.URL.EscapedPath()
+		if r.URL.RawQuery != "" {
+			key += "?" + r.URL.RawQuery
+		}
+		data, _ := io.ReadAll(r.Body)
+		bodies[key] = string(data)
+		resp, ok := routes[key]
+		if !ok {
+			t.Logf("unexpected request: %s", key)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if strings.HasPrefix(resp, "HEADERS ") {
+			lines := strings.SplitN(strings.TrimPrefix(resp, "HEADERS "), "\n", 2)
+			for _, h := range strings.Split(lines[0], ";") {
+				kv := strings.SplitN(h, "=", 2)
+				w.Header().Set(kv[0], kv[1])
+			}
+			resp = lines[1]
+		}
+		_, _ = w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, bodies
+}
+
+func TestNewProvider_UnsupportedType(t *testing.T) {
+	if _, err := NewProvider(&model.CIProviderConfig{Type: "travis"}, http.DefaultClient); err == nil {
+		t.Fatalf("expected unsupported provider rejected")
+	}
+}
+
+func TestJenkinsProvider_ProgressiveLogAndFolders(t *testing.T) {
+	srv, _ := newFakeCI(t, map[string]string{
+		"GET /job/team/job/api/12/logText/progressiveText?start=0": "HEADERS X-Text-Size=6;X-More-Data=true\nline1\n",
+		"GET /job/team/job/api/12/logText/progressiveText?start=6": "HEADERS X-Text-Size=12\nline2\n",
+		"GET /job/team/job/api/api/json?tree=" + jenkinsParamTree:  `{"property":[]}`,
+		"POST /job/team/job/api/build":                             "HEADERS Location=" + "http://jenkins/queue/item/7/\n",
+	}, func(r *http.Request) bool {
+		user, pass, ok := r.BasicAuth()
+		return ok && user == "admin" && pass == "token"
+	})
+	p, _ := NewProvider(&model.CIProviderConfig{Type: model.ProviderJenkins, URL: srv.URL, Username: "admin", Token: "token"}, srv.Client())
+
+	entry, err := p.GetBuildLog("team/api", 12, 0)
+	if err != nil {
+		t.Fatalf("get log failed: %v", err)
+	}
+	if entry.Text != "line1\n" || entry.Offset != 6 || !entry.HasMore {
+		t.Fatalf("unexpected first chunk: %+v", entry)
+	}
+	entry, err = p.GetBuildLog("team/api", 12, entry.Offset)
+	if err != nil || entry.Text != "line2\n" || entry.Offset != 12 || entry.HasMore {
+		t.Fatalf("unexpected second chunk: %+v, %v", entry, err)
+	}
+	result, err := p.TriggerBuild("team/api", nil)
+	if err != nil || result.QueueURL != "http://jenkins/queue/item/7/" {
+		t.Fatalf("unexpected trigger result: %+v, %v", result, err)
+	}
+	if _, err := p.TriggerBuild("team/api", map[string]string{"ENV": "prod"}); err == nil {
+		t.Fatalf("expected parameters rejected for a job without definitions")
+	}
+}
+
+const jenkinsParamTree = "property[parameterDefinitions[name,type,description,choices,defaultParameterValue[value]]]"
+
+func TestJenkinsProvider_ParameterizedBuildWithCrumbAndQueue(t *testing.T) {
+	var crumbHeader string
+	srv, bodies := newFakeCI(t, map[string]string{
+		"GET /crumbIssuer/api/json":                         `{"crumb":"c0ffee","crumbRequestField":"Jenkins-Crumb"}`,
+		"GET /job/deploy/api/json?tree=" + jenkinsParamTree: `{"property":[{},{"parameterDefinitions":[{"name":"ENV","type":"ChoiceParameterDefinition","choices":["dev","prod"],"defaultParameterValue":{"value":"dev"}},{"name":"DRY_RUN","type":"BooleanParameterDefinition","defaultParameterValue":{"value":true}},{"name":"TAG","type":"StringParameterDefinition","defaultParameterValue":{"value":"latest"}}]}]}`,
+		"POST /job/deploy/buildWithParameters":              "HEADERS Location=" + "http://jenkins.internal/queue/item/42/\n",
+		"GET /queue/item/42/api/json":                       `{"cancelled":false,"executable":{"number":88}}`,
+	}, func(r *http.Request) bool {
+		if r.Method == http.MethodPost {
+			crumbHeader = r.Header.Get("Jenkins-Crumb")
+		}
+		return true
+	})
+	p, _ := NewProvider(&model.CIProviderConfig{Type: model.ProviderJenkins, URL: srv.URL}, srv.Client())
+
+	params, err := p.(ParameterDescriber).JobParameters("deploy")
+	if err != nil || len(params) != 3 || params[0].Type != "Choice" || params[1].Default != "true" {
+		t.Fatalf("unexpected parameters: %+v, %v", params, err)
+	}
+	if _, err := p.TriggerBuild("deploy", map[string]string{"ENV": "staging"}); err == nil {
+		t.Fatalf("expected choice outside the list rejected")
+	}
+	if _, err := p.TriggerBuild("deploy", map[string]string{"DRY_RUN": "maybe"}); err == nil {
+		t.Fatalf("expected non-boolean value rejected")
+	}
+	if _, err := p.TriggerBuild("deploy", map[string]string{"UNKNOWN": "x"}); err == nil {
+		t.Fatalf("expected unknown parameter rejected")
+	}
+
+	result, err := p.TriggerBuild("deploy", map[string]string{"ENV": "prod"})
+	if err != nil || result.QueueURL != "http://jenkins.internal/queue/item/42/" {
+		t.Fatalf("unexpected trigger result: %+v, %v", result, err)
+	}
+	if crumbHeader != "c0ffee" {
+		t.Fatalf("expected crumb sent, got %q", crumbHeader)
+	}
+	form, _ := url.ParseQuery(bodies["POST /job/deploy/buildWithParameters"])
+	if form.Get("ENV") != "prod" || form.Get("DRY_RUN") != "true" || form.Get("TAG") != "latest" {
+		t.Fatalf("expected defaults merged with overrides, got %v", form)
+	}
+
+	number, cancelled, err := p.(QueueTracker).ResolveQueueItem(result.QueueURL)
+	if err != nil || number != 88 || cancelled {
+		t.Fatalf("unexpected queue item: %d %v %v", number, cancelled, err)
+	}
+}
+
+func TestGitLabProvider_ProjectsPipelinesAndTraces(t *testing.T) {
+	srv, bodies := newFakeCI(t, map[string]string{
+		"GET /api/v4/projects?membership=true&order_by=last_activity_at&per_page=100&search=api": `[{"path_with_namespace":"team/api","name_with_namespace":"Team / api","web_url":"https://gitlab/team/api","archived":false}]`,
+		"GET /api/v4/projects/team%2Fapi":                                 `{"default_branch":"main"}`,
+		"POST /api/v4/projects/team%2Fapi/pipeline":                       `{"id":501,"status":"created"}`,
+		"GET /api/v4/projects/team%2Fapi/pipelines?per_page=50":           `[{"id":501,"status":"running","web_url":"u1","created_at":"2024-05-20T10:00:00Z","updated_at":"2024-05-20T10:01:00Z"},{"id":500,"status":"failed","web_url":"u0","created_at":"2024-05-20T09:00:00Z","updated_at":"2024-05-20T09:02:30Z"}]`,
+		"GET /api/v4/projects/team%2Fapi/pipelines/501":                   `{"id":501,"status":"running"}`,
+		"GET /api/v4/projects/team%2Fapi/pipelines/501/jobs?per_page=100": `[{"id":3,"name":"deploy","stage":"deploy","status":"created"},{"id":2,"name":"test","stage":"test","status":"running"},{"id":1,"name":"build","stage":"build","status":"success"}]`,
+		"GET /api/v4/projects/team%2Fapi/jobs/1/trace":                    "compiled",
+		"GET /api/v4/projects/team%2Fapi/jobs/2/trace":                    "ok 1/3\n",
+	}, func(r *http.Request) bool { return r.Header.Get("PRIVATE-TOKEN") == "glpat" })
+	p, _ := NewProvider(&model.CIProviderConfig{Type: model.ProviderGitLab, URL: srv.URL, Token: "glpat"}, srv.Client())
+
+	jobs, err := p.ListJobs("api")
+	if err != nil || len(jobs) != 1 || jobs[0].Name != "team/api" || !jobs[0].Buildable {
+		t.Fatalf("unexpected jobs: %+v, %v", jobs, err)
+	}
+	result, err := p.TriggerBuild("team/api", map[string]string{"DEPLOY": "1"})
+	if err != nil || result.BuildNumber != 501 {
+		t.Fatalf("expected pipeline 501 on default branch, got %+v, %v", result, err)
+	}
+	var created struct {
+		Ref       string              `json:"ref"`
+		Variables []map[string]string `json:"variables"`
+	}
+	_ = json.Unmarshal([]byte(bodies["POST /api/v4/projects/team%2Fapi/pipeline"]), &created)
+	if created.Ref != "main" || len(created.Variables) != 1 || created.Variables[0]["key"] != "DEPLOY" {
+		t.Fatalf("unexpected pipeline request: %+v", created)
+	}
+	builds, err := p.ListBuilds("team/api")
+	if err != nil || len(builds) != 2 {
+		t.Fatalf("unexpected builds: %+v, %v", builds, err)
+	}
+	if !builds[0].Building || builds[0].Result != "" || builds[1].Result != "FAILURE" || builds[1].Duration != 150000 {
+		t.Fatalf("unexpected build mapping: %+v", builds)
+	}
+
+	entry, err := p.GetBuildLog("team/api", 501, 0)
+	if err != nil {
+		t.Fatalf("get log failed: %v", err)
+	}
+	want := "==> [build] build\ncompiled\n==> [test] test\nok 1/3\n"
+	if entry.Text != want || entry.Offset != len(want) || !entry.HasMore {
+		t.Fatalf("unexpected log: %q %+v", entry.Text, entry)
+	}
+	tail, _ := p.GetBuildLog("team/api", 501, len("==> [build] build\ncompiled\n"))
+	if tail.Text != "==> [test] test\nok 1/3\n" {
+		t.Fatalf("unexpected log from offset: %q", tail.Text)
+	}
+}
+
+func TestGitHubProvider_WorkflowDispatchAndRuns(t *testing.T) {
+	srv, bodies := newFakeCI(t, map[string]string{
+		"GET /repos/acme/api/actions/workflows?per_page=100":               `{"workflows":[{"name":"Build","path":".github/workflows/build.yml","state":"active","html_url":"h"},{"name":"Nightly","path":".github/workflows/nightly.yml","state":"disabled_manually"}]}`,
+		"POST /repos/acme/api/actions/workflows/build.yml/dispatches":      "",
+		"GET /repos/acme/api/actions/workflows/build.yml/runs?per_page=50": `{"workflow_runs":[{"id":9001,"status":"completed","conclusion":"cancelled","html_url":"r","run_started_at":"2024-05-20T10:00:00Z","updated_at":"2024-05-20T10:00:30Z"}]}`,
+		"GET /repos/acme/api/actions/runs/9001":                            `{"id":9001,"status":"completed","conclusion":"cancelled"}`,
+		"GET /repos/acme/api/actions/runs/9001/jobs?per_page=100":          `{"jobs":[{"id":1,"name":"lint","status":"completed"},{"id":2,"name":"test","status":"completed"}]}`,
+		"GET /repos/acme/api/actions/jobs/1/logs":                          "lint ok\n",
+	}, func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer ghp" })
+	p, _ := NewProvider(&model.CIProviderConfig{Type: model.ProviderGitHub, URL: srv.URL, Project: "acme/api", Ref: "release", Token: "ghp"}, srv.Client())
+
+	jobs, err := p.ListJobs("")
+	if err != nil || len(jobs) != 2 || jobs[0].Name != "build.yml" || jobs[1].Buildable {
+		t.Fatalf("unexpected workflows: %+v, %v", jobs, err)
+	}
+	if _, err := p.TriggerBuild("build.yml", nil); err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+	var dispatch map[string]string
+	_ = json.Unmarshal([]byte(bodies["POST /repos/acme/api/actions/workflows/build.yml/dispatches"]), &dispatch)
+	if dispatch["ref"] != "release" {
+		t.Fatalf("expected configured ref dispatched, got %v", dispatch)
+	}
+	builds, err := p.ListBuilds("build.yml")
+	if err != nil || len(builds) != 1 || builds[0].Number != 9001 || builds[0].Result != "ABORTED" || builds[0].Duration != 30000 {
+		t.Fatalf("unexpected runs: %+v, %v", builds, err)
+	}
+	entry, err := p.GetBuildLog("build.yml", 9001, 0)
+	if err != nil {
+		t.Fatalf("get log failed: %v", err)
+	}
+	if entry.Text != "==> lint\nlint ok\n==> test\n" || entry.HasMore {
+		t.Fatalf("expected expired job log skipped, got %q %+v", entry.Text, entry)
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"devops-platform/internal/modules/cicd/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// A pipeline whose result keeps flipping between success and failure without
+// a same-commit retry to prove it is still flagged once flakyMinRuns runs show
+// a flip rate of at least flakyFlipRate.
+const (
+	flakyMinRuns  = 5
+	flakyFlipRate = 0.3
+)
+
+// PipelineStats summarises a pipeline's finished runs over a window. Aborted
+// runs count towards Total but not towards SuccessRate. Durations are in ms.
+type PipelineStats struct {
+	PipelineID   uint    `json:"pipelineId"`
+	Name         string  `json:"name"`
+	JobName      string  `json:"jobName"`
+	Total        int     `json:"total"`
+	Succeeded    int     `json:"succeeded"`
+	Failed       int     `json:"failed"`
+	Aborted      int     `json:"aborted"`
+	SuccessRate  float64 `json:"successRate"`
+	MeanDuration int64   `json:"meanDuration"`
+	P95Duration  int64   `json:"p95Duration"`
+	// FlakyRuns counts failures that passed when rebuilt on the same commit.
+	FlakyRuns int     `json:"flakyRuns"`
+	FlipRate  float64 `json:"flipRate"`
+	Flaky     bool    `json:"flaky"`
+}
+
+// HealthReport is the CI health summary over a window, e.g. the weekly report.
+type HealthReport struct {
+	From        time.Time       `json:"from"`
+	To          time.Time       `json:"to"`
+	Total       int             `json:"total"`
+	Succeeded   int             `json:"succeeded"`
+	Failed      int             `json:"failed"`
+	SuccessRate float64         `json:"successRate"`
+	Pipelines   []PipelineStats `json:"pipelines"`
+	// FlakyPipelines and SlowestPipelines name pipelines worth a look, worst first.
+	FlakyPipelines   []string `json:"flakyPipelines"`
+	SlowestPipelines []string `json:"slowestPipelines"`
+}
+
+func validateWindow(from, to time.Time) error {
+	if !from.Before(to) {
+		return obserr.New("INVALID_PARAM", op, "from must be before to")
+	}
+	return nil
+}
+
+// PipelineStats computes the stats of one pipeline over [from, to).
+func (s *CICDService) PipelineStats(pipelineID uint, from, to time.Time) (*PipelineStats, error) {
+	if err := validateWindow(from, to); err != nil {
+		return nil, err
+	}
+	pl, err := s.repo.GetPipeline(pipelineID)
+	if err != nil {
+		return nil, obserr.Wrap("PIPELINE_NOT_FOUND", op, "pipeline not found", err)
+	}
+	runs, err := s.repo.ListFinishedRuns(pl.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	stats := computeStats(runs)
+	stats.PipelineID, stats.Name, stats.JobName = pl.ID, pl.Name, pl.JobName
+	return &stats, nil
+}
+
+// HealthReport computes stats for every pipeline with runs in [from, to).
+func (s *CICDService) HealthReport(from, to time.Time) (*HealthReport, error) {
+	if err := validateWindow(from, to); err != nil {
+		return nil, err
+	}
+	pipelines, err := s.repo.ListAllPipelines()
+	if err != nil {
+		return nil, err
+	}
+	report := &HealthReport{From: from, To: to, Pipelines: []PipelineStats{}, FlakyPipelines: []string{}, SlowestPipelines: []string{}}
+	for _, pl := range pipelines {
+		runs, err := s.repo.ListFinishedRuns(pl.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) == 0 {
+			continue
+		}
+		stats := computeStats(runs)
+		stats.PipelineID, stats.Name, stats.JobName = pl.ID, pl.Name, pl.JobName
+		report.Pipelines = append(report.Pipelines, stats)
+		report.Total += stats.Total
+		report.Succeeded += stats.Succeeded
+		report.Failed += stats.Failed
+	}
+	report.SuccessRate = ratio(report.Succeeded, report.Succeeded+report.Failed)
+
+	flaky := append([]PipelineStats(nil), report.Pipelines...)
+	sort.SliceStable(flaky, func(i, j int) bool {
+		if flaky[i].FlakyRuns != flaky[j].FlakyRuns {
+			return flaky[i].FlakyRuns > flaky[j].FlakyRuns
+		}
+		return flaky[i].FlipRate > flaky[j].FlipRate
+	})
+	for _, st := range flaky {
+		if st.Flaky {
+			report.FlakyPipelines = append(report.FlakyPipelines, st.Name)
+		}
+	}
+	slow := append([]PipelineStats(nil), report.Pipelines...)
+	sort.SliceStable(slow, func(i, j int) bool { return slow[i].P95Duration > slow[j].P95Duration })
+	for i := 0; i < len(slow) && i < 5; i++ {
+		report.SlowestPipelines = append(report.SlowestPipelines, slow[i].Name)
+	}
+	return report, nil
+}
+
+// computeStats expects runs oldest first.
+func computeStats(runs []model.PipelineRun) PipelineStats {
+	var stats PipelineStats
+	var durations []int64
+	var sum int64
+	var outcomes []model.PipelineRun
+	for _, run := range runs {
+		stats.Total++
+		switch run.Status {
+		case model.RunSuccess:
+			stats.Succeeded++
+		case model.RunFailed:
+			stats.Failed++
+		default:
+			stats.Aborted++
+			continue
+		}
+		outcomes = append(outcomes, run)
+		durations = append(durations, run.Duration)
+		sum += run.Duration
+	}
+	stats.SuccessRate = ratio(stats.Succeeded, stats.Succeeded+stats.Failed)
+	if len(durations) > 0 {
+		stats.MeanDuration = sum / int64(len(durations))
+		stats.P95Duration = percentile(durations, 0.95)
+	}
+
+	flips := 0
+	for i := 1; i < len(outcomes); i++ {
+		if outcomes[i].Status != outcomes[i-1].Status {
+			flips++
+		}
+	}
+	if len(outcomes) > 1 {
+		stats.FlipRate = ratio(flips, len(outcomes)-1)
+	}
+	// A failure is flaky when a later run of the same commit succeeded.
+	passed := map[string]bool{}
+	for i := len(outcomes) - 1; i >= 0; i-- {
+		run := outcomes[i]
+		if run.Commit == "" {
+			continue
+		}
+		if run.Status == model.RunSuccess {
+			passed[run.Commit] = true
+		} else if passed[run.Commit] {
+			stats.FlakyRuns++
+		}
+	}
+	stats.Flaky = stats.FlakyRuns > 0 || (len(outcomes) >= flakyMinRuns && stats.FlipRate >= flakyFlipRate)
+	return stats
+}
+
+// percentile uses the nearest-rank method.
+func percentile(values []int64, p float64) int64 {
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(d)*10000) / 10000
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/cicd/model"
+	"devops-platform/internal/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+func TestSyncRuns_RecordsBuildsAndSkipsFinished(t *testing.T) {
+	logger.Log = zap.NewNop()
+	builds := `{"builds":[
+		{"number":3,"url":"u3","building":true,"timestamp":1716199200000,"actions":[{"causes":[{"userId":"bob"}]}]},
+		{"number":2,"url":"u2","result":"FAILURE","duration":90000,"timestamp":1716195600000,"actions":[
+			{"causes":[{"userId":"alice","userName":"Alice"}]},
+			{"parameters":[{"name":"ENV","value":"prod"},{"name":"DRY_RUN","value":false}]},
+			{"lastBuiltRevision":{"SHA1":"abc123"}}]}
+	]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/job/deploy/api/json" || !strings.HasPrefix(r.URL.Query().Get("tree"), "builds[") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(builds))
+	}))
+	defer srv.Close()
+
+	db := setupCICDDB(t)
+	cfg := model.CIProviderConfig{Name: "ci", Type: model.ProviderJenkins, URL: srv.URL, Username: "admin", Token: "t"}
+	db.Create(&cfg)
+	pipeline := model.Pipeline{Name: "deploy", ProviderConfigID: cfg.ID, JobName: "deploy"}
+	db.Create(&pipeline)
+	// Build 3 was triggered from the platform and already has a run.
+	db.Create(&model.PipelineRun{ProviderConfigID: cfg.ID, JobName: "deploy", BuildNumber: 3, TriggeredBy: "carol", Status: model.RunRunning})
+
+	svc := NewCICDService(db)
+	if written := svc.SyncRuns(); written != 2 {
+		t.Fatalf("expected 2 runs written, got %d", written)
+	}
+	var runs []model.PipelineRun
+	db.Order("build_number").Find(&runs)
+	if len(runs) != 2 {
+		t.Fatalf("expected existing run reused, got %d runs", len(runs))
+	}
+	failed, running := runs[0], runs[1]
+	if failed.Status != model.RunFailed || failed.Duration != 90000 || failed.Commit != "abc123" || failed.TriggeredBy != "alice" ||
+		failed.Parameters["DRY_RUN"] != "false" || failed.PipelineID != pipeline.ID || failed.FinishedAt == nil {
+		t.Fatalf("unexpected synced run: %+v", failed)
+	}
+	if running.Status != model.RunRunning || running.TriggeredBy != "carol" || running.PipelineID != pipeline.ID || running.StartedAt == nil {
+		t.Fatalf("expected platform trigger user kept, got %+v", running)
+	}
+
+	if written := svc.SyncRuns(); written != 0 {
+		t.Fatalf("expected an unchanged running build not rewritten, got %d", written)
+	}
+
+	builds = strings.Replace(builds, `"building":true`, `"result":"SUCCESS","duration":60000`, 1)
+	if written := svc.SyncRuns(); written != 1 {
+		t.Fatalf("expected the finished build rewritten, got %d", written)
+	}
+}
+
+func TestPipelineStats_RatesDurationsAndFlakiness(t *testing.T) {
+	db := setupCICDDB(t)
+	pipeline := model.Pipeline{Name: "api", JobName: "api"}
+	db.Create(&pipeline)
+	start := time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)
+	add := func(n int, status, commit string, duration int64) {
+		started := start.Add(time.Duration(n) * time.Hour)
+		db.Create(&model.PipelineRun{PipelineID: pipeline.ID, BuildNumber: n, Status: status, Commit: commit, Duration: duration, StartedAt: &started})
+	}
+	add(1, model.RunSuccess, "a", 100)
+	add(2, model.RunFailed, "b", 200)
+	add(3, model.RunSuccess, "b", 300) // same commit passed on retry
+	add(4, model.RunAborted, "c", 50)
+	add(5, model.RunSuccess, "c", 400)
+	add(6, model.RunRunning, "d", 0)
+	add(30, model.RunFailed, "e", 999) // outside the window
+
+	svc := NewCICDService(db)
+	stats, err := svc.PipelineStats(pipeline.ID, start, start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	if stats.Total != 5 || stats.Succeeded != 3 || stats.Failed != 1 || stats.Aborted != 1 || stats.SuccessRate != 0.75 {
+		t.Fatalf("unexpected counts: %+v", stats)
+	}
+	if stats.MeanDuration != 250 || stats.P95Duration != 400 {
+		t.Fatalf("unexpected durations: mean=%d p95=%d", stats.MeanDuration, stats.P95Duration)
+	}
+	if stats.FlakyRuns != 1 || !stats.Flaky {
+		t.Fatalf("expected same-commit retry detected as flaky: %+v", stats)
+	}
+
+	report, err := svc.HealthReport(start, start.Add(24*time.Hour))
+	if err != nil || len(report.Pipelines) != 1 || report.SuccessRate != 0.75 || len(report.FlakyPipelines) != 1 {
+		t.Fatalf("unexpected report: %+v, %v", report, err)
+	}
+	if _, err := svc.PipelineStats(pipeline.ID, start, start); err == nil {
+		t.Fatalf("expected empty window rejected")
+	}
+}
+
+func TestComputeStats_FlipRateWithoutCommits(t *testing.T) {
+	var runs []model.PipelineRun
+	for i, status := range []string{model.RunSuccess, model.RunFailed, model.RunSuccess, model.RunFailed, model.RunSuccess} {
+		runs = append(runs, model.PipelineRun{BuildNumber: i + 1, Status: status})
+	}
+	if stats := computeStats(runs); !stats.Flaky || stats.FlipRate != 1 || stats.FlakyRuns != 0 {
+		t.Fatalf("expected alternating results flagged flaky: %+v", stats)
+	}
+	if stats := computeStats(runs[:3]); stats.Flaky {
+		t.Fatalf("expected too few runs not flagged: %+v", stats)
+	}
+}
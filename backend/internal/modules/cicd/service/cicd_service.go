@@ -1,10 +1,16 @@
 package service
 
 import (
+	"strings"
+	"sync"
+	"time"
+
 	"devops-platform/internal/modules/cicd/model"
 	"devops-platform/internal/modules/cicd/repository"
+	"devops-platform/internal/pkg/logger"
 	"devops-platform/internal/pkg/obserr"
 
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -13,34 +19,67 @@ const op = "cicd/service"
 type CICDService struct {
 	repo *repository.CICDRepo
 	db   *gorm.DB
+
+	// queuePoll and queueTimeout bound how queued builds are followed.
+	queuePoll    time.Duration
+	queueTimeout time.Duration
+	goAsync      func(fn func())
+	// logPoll is how often a streamed build log is re-read while running.
+	logPoll  time.Duration
+	syncLoop loop
+	// hooks and runner drive multi-stage pipeline executions. runnerMu guards
+	// execution state changes; advanceMu keeps runner passes from overlapping.
+	// execGen counts the cancels and resumes of each execution, so a pass
+	// does not save over one made while it was waiting on a provider.
+	hooks     StageHooks
+	runner    loop
+	runnerMu  sync.Mutex
+	advanceMu sync.Mutex
+	execGen   map[uint]int
 }
 
 func NewCICDService(db *gorm.DB) *CICDService {
-	return &CICDService{repo: repository.NewCICDRepo(db), db: db}
+	return &CICDService{
+		repo:         repository.NewCICDRepo(db),
+		db:           db,
+		queuePoll:    2 * time.Second,
+		queueTimeout: 10 * time.Minute,
+		goAsync:      func(fn func()) { go fn() },
+		logPoll:      time.Second,
+		execGen:      map[uint]int{},
+	}
 }
 
 // Config management
-func (s *CICDService) ListConfigs(page, pageSize int) ([]model.JenkinsConfig, int64, error) {
+func (s *CICDService) ListConfigs(page, pageSize int) ([]model.CIProviderConfig, int64, error) {
 	if page <= 0 {
 		page = 1
 	}
 	if pageSize <= 0 || pageSize > 100 {
 		pageSize = 20
 	}
-	return s.repo.ListConfigs(page, pageSize)
+	configs, total, err := s.repo.ListConfigs(page, pageSize)
+	for i := range configs {
+		configs[i].Token = ""
+	}
+	return configs, total, err
 }
 
-func (s *CICDService) SaveConfig(cfg *model.JenkinsConfig) error {
-	if cfg.URL == "" {
-		return obserr.New("INVALID_PARAM", op, "jenkins url is required")
+// SaveConfig validates and stores a provider config. On update an empty token
+// keeps the stored one, since tokens are never returned to clients.
+func (s *CICDService) SaveConfig(cfg *model.CIProviderConfig) error {
+	if cfg.Type == "" {
+		cfg.Type = model.ProviderJenkins
 	}
-	if cfg.Username == "" {
-		return obserr.New("INVALID_PARAM", op, "username is required")
+	if cfg.Token == "" && cfg.ID > 0 {
+		if existing, err := s.repo.GetConfig(cfg.ID); err == nil {
+			cfg.Token = existing.Token
+		}
 	}
-	if cfg.APIToken == "" {
-		return obserr.New("INVALID_PARAM", op, "api token is required")
+	if err := validateConfig(cfg); err != nil {
+		return err
 	}
-	if err := s.repo.TestConnection(cfg.URL, cfg.Username, cfg.APIToken); err != nil {
+	if err := s.repo.TestConnection(cfg); err != nil {
 		cfg.Status = "error"
 	} else {
 		cfg.Status = "connected"
@@ -48,35 +87,255 @@ func (s *CICDService) SaveConfig(cfg *model.JenkinsConfig) error {
 	return s.repo.SaveConfig(cfg)
 }
 
+func validateConfig(cfg *model.CIProviderConfig) error {
+	switch cfg.Type {
+	case model.ProviderJenkins:
+		if cfg.URL == "" {
+			return obserr.New("INVALID_PARAM", op, "jenkins url is required")
+		}
+		if cfg.Username == "" {
+			return obserr.New("INVALID_PARAM", op, "username is required")
+		}
+	case model.ProviderGitLab:
+		if cfg.URL == "" {
+			return obserr.New("INVALID_PARAM", op, "gitlab url is required")
+		}
+	case model.ProviderGitHub:
+		if strings.Count(strings.Trim(cfg.Project, "/"), "/") != 1 {
+			return obserr.New("INVALID_PARAM", op, "github project must be owner/repo")
+		}
+	default:
+		return obserr.New("INVALID_PARAM", op, "unsupported provider type: "+string(cfg.Type))
+	}
+	if cfg.Token == "" {
+		return obserr.New("INVALID_PARAM", op, "api token is required")
+	}
+	return nil
+}
+
 func (s *CICDService) DeleteConfig(id uint) error {
 	return s.repo.DeleteConfig(id)
 }
 
-func (s *CICDService) TestConnection(url, username, apiToken string) error {
-	if url == "" {
+func (s *CICDService) TestConnection(cfg model.CIProviderConfig) error {
+	if cfg.Type == "" {
+		cfg.Type = model.ProviderJenkins
+	}
+	if cfg.URL == "" && cfg.Type != model.ProviderGitHub {
 		return obserr.New("INVALID_PARAM", op, "url is required")
 	}
-	return s.repo.TestConnection(url, username, apiToken)
+	return s.repo.TestConnection(&cfg)
 }
 
 // Job management
 func (s *CICDService) ListJobs(configID uint, keyword string) ([]model.JobInfo, error) {
-	return s.repo.ListJobs(configID, keyword)
+	p, err := s.repo.Provider(configID)
+	if err != nil {
+		return nil, err
+	}
+	return p.ListJobs(keyword)
 }
 
-func (s *CICDService) TriggerBuild(configID uint, jobName string) error {
+// TriggerBuild starts a build and records it as a PipelineRun. Builds the
+// provider queues first are followed in the background until they get a number;
+// the run sync numbers those still unresolved and those the provider does not
+// report at all.
+func (s *CICDService) TriggerBuild(configID uint, jobName string, params map[string]string, triggeredBy string) (*model.PipelineRun, error) {
 	if jobName == "" {
-		return obserr.New("INVALID_PARAM", op, "job name is required")
+		return nil, obserr.New("INVALID_PARAM", op, "job name is required")
+	}
+	p, err := s.repo.Provider(configID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.TriggerBuild(jobName, params)
+	if err != nil {
+		return nil, err
+	}
+	run := &model.PipelineRun{
+		ProviderConfigID: configID,
+		JobName:          jobName,
+		BuildNumber:      result.BuildNumber,
+		QueueURL:         result.QueueURL,
+		Parameters:       params,
+		TriggeredBy:      triggeredBy,
+		Status:           model.RunQueued,
+	}
+	if pipeline, err := s.repo.FindPipelineByJob(configID, jobName); err == nil {
+		run.PipelineID = pipeline.ID
+	}
+	if result.BuildNumber > 0 {
+		now := time.Now()
+		run.Status = model.RunRunning
+		run.StartedAt = &now
+	}
+	if err := s.repo.SaveRun(run); err != nil {
+		return nil, err
+	}
+	if tracker, ok := p.(repository.QueueTracker); ok && run.BuildNumber == 0 && run.QueueURL != "" {
+		queued := *run
+		s.goAsync(func() { s.followQueue(tracker, &queued) })
+	}
+	return run, nil
+}
+
+// followQueue polls the queue item until the build is numbered or dropped.
+// A run still waiting at queueTimeout is marked unknown.
+func (s *CICDService) followQueue(tracker repository.QueueTracker, run *model.PipelineRun) {
+	deadline := time.Now().Add(s.queueTimeout)
+	for {
+		if fields := s.pollQueueItem(tracker, run); fields != nil {
+			s.updateRun(run, fields)
+			return
+		}
+		if time.Now().After(deadline) {
+			logger.Log.Warn("Build still queued, giving up", zap.Uint("run_id", run.ID), zap.String("queue_url", run.QueueURL))
+			run.Status = model.RunUnknown
+			s.updateRun(run, map[string]interface{}{"status": run.Status})
+			return
+		}
+		time.Sleep(s.queuePoll)
 	}
-	return s.repo.TriggerBuild(configID, jobName)
+}
+
+// resolveRun checks once whether an unnumbered run's build has appeared.
+func (s *CICDService) resolveRun(run *model.PipelineRun) {
+	p, err := s.repo.Provider(run.ProviderConfigID)
+	if err != nil {
+		logger.Log.Warn("Load ci provider for unnumbered run failed", zap.Uint("run_id", run.ID), zap.Error(err))
+		return
+	}
+	var builds []model.BuildInfo
+	if run.QueueURL == "" {
+		if builds, err = p.ListBuilds(run.JobName); err != nil {
+			logger.Log.Warn("List builds for unnumbered run failed", zap.Uint("run_id", run.ID), zap.Error(err))
+			return
+		}
+	}
+	s.numberRun(p, run, builds)
+}
+
+// numberRun finds the build of a run that has none yet: through the queue
+// item when there is one, otherwise as the earliest unrecorded build among
+// builds started after the trigger, since GitHub does not say which run a
+// workflow dispatch created.
+func (s *CICDService) numberRun(p repository.Provider, run *model.PipelineRun, builds []model.BuildInfo) {
+	var fields map[string]interface{}
+	if run.QueueURL != "" {
+		tracker, ok := p.(repository.QueueTracker)
+		if !ok {
+			return
+		}
+		fields = s.pollQueueItem(tracker, run)
+	} else if number := s.firstBuildSince(run, builds); number > 0 {
+		fields = startRun(run, number)
+	}
+	if fields != nil {
+		s.updateRun(run, fields)
+	}
+}
+
+// dispatchClockSkew allows for the CI server's clock running behind ours when
+// matching builds to the time they were triggered.
+const dispatchClockSkew = time.Minute
+
+func (s *CICDService) firstBuildSince(run *model.PipelineRun, builds []model.BuildInfo) int {
+	since := run.CreatedAt.Add(-dispatchClockSkew).UnixMilli()
+	var first *model.BuildInfo
+	for i := range builds {
+		b := &builds[i]
+		if b.Timestamp < since || (first != nil && b.Timestamp >= first.Timestamp) {
+			continue
+		}
+		if existing, err := s.repo.FindRun(run.ProviderConfigID, run.JobName, b.Number); err != nil || existing != nil {
+			continue
+		}
+		first = b
+	}
+	if first == nil {
+		return 0
+	}
+	return first.Number
+}
+
+// pollQueueItem applies the queue item's state to the run and returns the
+// changed columns, or nil while it is still waiting. Poll errors are logged.
+func (s *CICDService) pollQueueItem(tracker repository.QueueTracker, run *model.PipelineRun) map[string]interface{} {
+	number, cancelled, err := tracker.ResolveQueueItem(run.QueueURL)
+	switch {
+	case err != nil:
+		logger.Log.Warn("Poll build queue item failed", zap.Uint("run_id", run.ID), zap.Error(err))
+	case number > 0:
+		return startRun(run, number)
+	case cancelled:
+		now := time.Now()
+		run.Status = model.RunCancelled
+		run.FinishedAt = &now
+		return map[string]interface{}{"status": run.Status, "finished_at": run.FinishedAt}
+	}
+	return nil
+}
+
+// startRun records that the run's build started with the given number.
+func startRun(run *model.PipelineRun, number int) map[string]interface{} {
+	now := time.Now()
+	run.BuildNumber = number
+	run.Status = model.RunRunning
+	run.StartedAt = &now
+	return map[string]interface{}{"build_number": number, "status": run.Status, "started_at": run.StartedAt}
+}
+
+func (s *CICDService) updateRun(run *model.PipelineRun, fields map[string]interface{}) {
+	if err := s.repo.UpdateRun(run.ID, fields); err != nil {
+		logger.Log.Error("Save pipeline run failed", zap.Uint("run_id", run.ID), zap.Error(err))
+	}
+}
+
+// JobParameters lists a job's build parameters; providers without parameter
+// definitions return none.
+func (s *CICDService) JobParameters(configID uint, jobName string) ([]model.JobParameter, error) {
+	if jobName == "" {
+		return nil, obserr.New("INVALID_PARAM", op, "job name is required")
+	}
+	p, err := s.repo.Provider(configID)
+	if err != nil {
+		return nil, err
+	}
+	describer, ok := p.(repository.ParameterDescriber)
+	if !ok {
+		return []model.JobParameter{}, nil
+	}
+	return describer.JobParameters(jobName)
+}
+
+func (s *CICDService) ListRuns(filter repository.RunFilter, page, pageSize int) ([]model.PipelineRun, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	return s.repo.ListRuns(filter, page, pageSize)
+}
+
+func (s *CICDService) GetRun(id uint) (*model.PipelineRun, error) {
+	return s.repo.GetRun(id)
 }
 
 func (s *CICDService) ListBuilds(configID uint, jobName string) ([]model.BuildInfo, error) {
-	return s.repo.ListBuilds(configID, jobName)
+	p, err := s.repo.Provider(configID)
+	if err != nil {
+		return nil, err
+	}
+	return p.ListBuilds(jobName)
 }
 
-func (s *CICDService) GetBuildLog(configID uint, jobName string, buildNumber int) (*model.BuildLogEntry, error) {
-	return s.repo.GetBuildLog(configID, jobName, buildNumber)
+func (s *CICDService) GetBuildLog(configID uint, jobName string, buildNumber, offset int) (*model.BuildLogEntry, error) {
+	p, err := s.repo.Provider(configID)
+	if err != nil {
+		return nil, err
+	}
+	return p.GetBuildLog(jobName, buildNumber, offset)
 }
 
 // Pipeline CRUD
@@ -21,7 +21,7 @@ func TestCICDServiceSaveConfig_NilPointer(t *testing.T) {
 func TestCICDServiceTriggerBuild_EmptyJobName(t *testing.T) {
 	svc := NewCICDService(nil)
 
-	err := svc.TriggerBuild(1, "")
+	_, err := svc.TriggerBuild(1, "", nil, "admin")
 	if err == nil {
 		t.Fatalf("expected error for empty job name")
 	}
@@ -30,7 +30,7 @@ func TestCICDServiceTriggerBuild_EmptyJobName(t *testing.T) {
 func TestCICDServiceTestConnection_EmptyURL(t *testing.T) {
 	svc := NewCICDService(nil)
 
-	err := svc.TestConnection("", "admin", "token")
+	err := svc.TestConnection(model.CIProviderConfig{URL: "", Username: "admin", Token: "token"})
 	if err == nil {
 		t.Fatalf("expected error for empty URL")
 	}
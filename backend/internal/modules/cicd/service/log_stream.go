@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"devops-platform/internal/modules/cicd/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// streamChunkBytes caps a single pushed chunk so one large read from the
+// provider is handed to a slow client piece by piece.
+const streamChunkBytes = 64 << 10
+
+// StreamBuildLog tails a build log from offset and passes each chunk to send
+// until the build finishes or ctx is done, returning nil in both cases. send
+// blocks while the client is behind and the provider is not polled again until
+// it returns, so a slow client slows the tail instead of buffering the log in
+// memory. Every chunk carries the offset to resume from.
+func (s *CICDService) StreamBuildLog(ctx context.Context, configID uint, jobName string, buildNumber, offset int, send func(*model.BuildLogEntry) error) error {
+	if jobName == "" || buildNumber <= 0 {
+		return obserr.New("INVALID_PARAM", op, "job name and build number are required")
+	}
+	p, err := s.repo.Provider(configID)
+	if err != nil {
+		return err
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	for {
+		entry, err := p.GetBuildLog(jobName, buildNumber, offset)
+		if err != nil {
+			return err
+		}
+		text := entry.Text
+		for pos := offset; len(text) > 0; {
+			n := len(text)
+			if n > streamChunkBytes {
+				n = streamChunkBytes
+			}
+			chunk := &model.BuildLogEntry{Offset: pos + n, Text: text[:n], HasMore: true}
+			if n == len(text) {
+				chunk.Offset, chunk.HasMore = entry.Offset, entry.HasMore
+			}
+			if err := send(chunk); err != nil {
+				return err
+			}
+			pos += n
+			text = text[n:]
+		}
+		offset = entry.Offset
+		if !entry.HasMore {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.logPoll):
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/cicd/model"
+)
+
+// newFakeJenkinsLog serves a console that grows by one part per request.
+func newFakeJenkinsLog(t *testing.T, parts []string) (*httptest.Server, *[]int) {
+	t.Helper()
+	full := strings.Join(parts, "")
+	var starts []int
+	served := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/job/api/7/logText/progressiveText" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		starts = append(starts, start)
+		if served < len(parts) {
+			served++
+		}
+		end := len(strings.Join(parts[:served], ""))
+		w.Header().Set("X-Text-Size", strconv.Itoa(end))
+		if served < len(parts) {
+			w.Header().Set("X-More-Data", "true")
+		}
+		_, _ = w.Write([]byte(full[start:end]))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &starts
+}
+
+func newStreamService(t *testing.T, url string) (*CICDService, uint) {
+	t.Helper()
+	db := setupCICDDB(t)
+	cfg := model.CIProviderConfig{Name: "ci", Type: model.ProviderJenkins, URL: url, Username: "admin", Token: "t"}
+	db.Create(&cfg)
+	svc := NewCICDService(db)
+	svc.logPoll = time.Millisecond
+	return svc, cfg.ID
+}
+
+func TestStreamBuildLog_TailsUntilFinishedInBoundedChunks(t *testing.T) {
+	big := strings.Repeat("x", streamChunkBytes+10)
+	srv, starts := newFakeJenkinsLog(t, []string{big, "", "done\n"})
+	svc, configID := newStreamService(t, srv.URL)
+
+	var chunks []model.BuildLogEntry
+	err := svc.StreamBuildLog(context.Background(), configID, "api", 7, 0, func(e *model.BuildLogEntry) error {
+		chunks = append(chunks, *e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("expected large read split and tail appended, got %d chunks", len(chunks))
+	}
+	if len(chunks[0].Text) != streamChunkBytes || chunks[0].Offset != streamChunkBytes || !chunks[0].HasMore {
+		t.Fatalf("unexpected first chunk: offset=%d len=%d", chunks[0].Offset, len(chunks[0].Text))
+	}
+	if chunks[1].Offset != len(big) || chunks[2].Text != "done\n" || chunks[2].Offset != len(big)+5 || chunks[2].HasMore {
+		t.Fatalf("unexpected tail chunks: %+v %+v", chunks[1].Offset, chunks[2])
+	}
+	if got := *starts; len(got) != 3 || got[1] != len(big) || got[2] != len(big) {
+		t.Fatalf("expected polling to continue from the last offset, got %v", got)
+	}
+}
+
+func TestStreamBuildLog_ResumesFromOffset(t *testing.T) {
+	srv, starts := newFakeJenkinsLog(t, []string{"line1\nline2\n"})
+	svc, configID := newStreamService(t, srv.URL)
+
+	var text string
+	err := svc.StreamBuildLog(context.Background(), configID, "api", 7, 6, func(e *model.BuildLogEntry) error {
+		text += e.Text
+		return nil
+	})
+	if err != nil || text != "line2\n" || (*starts)[0] != 6 {
+		t.Fatalf("expected resume at offset 6, got %q %v %v", text, *starts, err)
+	}
+}
+
+func TestStreamBuildLog_StopsWhenClientFails(t *testing.T) {
+	srv, starts := newFakeJenkinsLog(t, []string{"a", "b", "c"})
+	svc, configID := newStreamService(t, srv.URL)
+
+	gone := errors.New("client gone")
+	err := svc.StreamBuildLog(context.Background(), configID, "api", 7, 0, func(e *model.BuildLogEntry) error {
+		return gone
+	})
+	if !errors.Is(err, gone) || len(*starts) != 1 {
+		t.Fatalf("expected stream to stop at the failed send, got %v after %d polls", err, len(*starts))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := svc.StreamBuildLog(ctx, configID, "api", 7, 0, func(*model.BuildLogEntry) error { return nil }); err != nil {
+		t.Fatalf("expected cancelled stream to end cleanly, got %v", err)
+	}
+}
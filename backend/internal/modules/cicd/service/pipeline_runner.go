@@ -0,0 +1,468 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/cicd/model"
+	"devops-platform/internal/pkg/logger"
+	"devops-platform/internal/pkg/obserr"
+
+	"go.uber.org/zap"
+)
+
+// Approval decisions reported by StageHooks.ApprovalStatus.
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// StageHooks connect pipeline stages to the modules that carry them out. They
+// are wired at startup; a stage whose hook is missing fails without retrying.
+type StageHooks struct {
+	// CheckArtifact returns an error unless the image reference exists and,
+	// when enforceScanPolicy is set, passes the registry's scan policy.
+	CheckArtifact func(harborConfigID uint, project, repository, reference string, enforceScanPolicy bool) error
+	// RequestApproval opens a change order and submits it for review.
+	RequestApproval func(tenantID, userID uint, title, description string, levels int) (orderID uint, err error)
+	// ApprovalStatus reports ApprovalPending, ApprovalApproved or ApprovalRejected.
+	ApprovalStatus func(tenantID, orderID uint) (string, error)
+	// Deploy deploys the app at version and returns the deployment ID.
+	Deploy func(tenantID, appID uint, stage model.DeployStage, version, operator string) (deploymentID uint, err error)
+}
+
+func (s *CICDService) SetStageHooks(hooks StageHooks) {
+	s.hooks = hooks
+}
+
+// StartPipelineRunner advances active executions on the given interval until
+// StopPipelineRunner is called.
+func (s *CICDService) StartPipelineRunner(interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	s.runner.start(interval, s.AdvanceExecutions)
+}
+
+func (s *CICDService) StopPipelineRunner() {
+	s.runner.stop()
+}
+
+// --- Specs ---
+
+func (s *CICDService) ListSpecs(tenantID, appID uint) ([]model.PipelineSpec, error) {
+	return s.repo.ListSpecs(tenantID, appID)
+}
+
+func (s *CICDService) GetSpec(tenantID, id uint) (*model.PipelineSpec, error) {
+	return s.repo.GetSpec(tenantID, id)
+}
+
+func (s *CICDService) SaveSpec(spec *model.PipelineSpec) error {
+	if spec.ID > 0 {
+		if _, err := s.repo.GetSpec(spec.TenantID, spec.ID); err != nil {
+			return err
+		}
+	}
+	if err := validateSpec(spec); err != nil {
+		return err
+	}
+	return s.repo.SaveSpec(spec)
+}
+
+func (s *CICDService) DeleteSpec(tenantID, id uint) error {
+	return s.repo.DeleteSpec(tenantID, id)
+}
+
+func validateSpec(spec *model.PipelineSpec) error {
+	spec.Name = strings.TrimSpace(spec.Name)
+	if spec.Name == "" {
+		return obserr.New("INVALID_PARAM", op, "pipeline name is required")
+	}
+	if spec.AppID == 0 {
+		return obserr.New("INVALID_PARAM", op, "app is required")
+	}
+	if len(spec.Stages) == 0 {
+		return obserr.New("INVALID_PARAM", op, "pipeline needs at least one stage")
+	}
+	names := map[string]bool{}
+	for i := range spec.Stages {
+		st := &spec.Stages[i]
+		st.Name = strings.TrimSpace(st.Name)
+		if st.Name == "" {
+			st.Name = st.Type
+		}
+		if names[st.Name] {
+			return obserr.New("INVALID_PARAM", op, fmt.Sprintf("duplicate stage name %q", st.Name))
+		}
+		names[st.Name] = true
+		if st.Retries < 0 || st.Retries > 10 {
+			return obserr.New("INVALID_PARAM", op, fmt.Sprintf("stage %s: retries must be between 0 and 10", st.Name))
+		}
+		if err := validateStage(st); err != nil {
+			return obserr.New("INVALID_PARAM", op, fmt.Sprintf("stage %s: %s", st.Name, err.Error()))
+		}
+	}
+	return nil
+}
+
+func validateStage(st *model.StageSpec) error {
+	switch st.Type {
+	case model.StageBuild:
+		if st.Build == nil || st.Build.ProviderConfigID == 0 || st.Build.JobName == "" {
+			return errors.New("build needs a provider config and job name")
+		}
+	case model.StageArtifactCheck:
+		c := st.ArtifactCheck
+		if c == nil || c.HarborConfigID == 0 || c.Project == "" || c.Repository == "" || c.Tag == "" {
+			return errors.New("artifact check needs a harbor config, project, repository and tag")
+		}
+	case model.StageApproval:
+		if st.Approval == nil {
+			st.Approval = &model.ApprovalStage{}
+		}
+	case model.StageDeploy:
+		if st.Deploy == nil || st.Deploy.TemplateID == 0 || st.Deploy.Environment == "" {
+			return errors.New("deploy needs a template and target environment")
+		}
+	default:
+		return fmt.Errorf("unknown stage type %q", st.Type)
+	}
+	return nil
+}
+
+// --- Executions ---
+
+// StartExecution starts a spec. version names what is built and deployed; when
+// empty, the first successful build stage sets it to its build number.
+func (s *CICDService) StartExecution(tenantID, userID uint, username string, specID uint, version string) (*model.PipelineExecution, error) {
+	spec, err := s.repo.GetSpec(tenantID, specID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	exec := &model.PipelineExecution{
+		TenantID:      tenantID,
+		SpecID:        spec.ID,
+		AppID:         spec.AppID,
+		Version:       strings.TrimSpace(version),
+		Status:        model.ExecRunning,
+		Spec:          spec.Stages,
+		TriggeredByID: userID,
+		TriggeredBy:   username,
+		StartedAt:     &now,
+	}
+	for _, st := range spec.Stages {
+		exec.Stages = append(exec.Stages, model.StageState{Name: st.Name, Type: st.Type, Status: model.ExecPending})
+	}
+	if err := s.repo.SaveExecution(exec); err != nil {
+		return nil, err
+	}
+	s.goAsync(s.AdvanceExecutions)
+	return exec, nil
+}
+
+func (s *CICDService) GetExecution(tenantID, id uint) (*model.PipelineExecution, error) {
+	return s.repo.GetExecution(tenantID, id)
+}
+
+func (s *CICDService) ListExecutions(tenantID, specID uint, page, pageSize int) ([]model.PipelineExecution, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	return s.repo.ListExecutions(tenantID, specID, page, pageSize)
+}
+
+// ResumeExecution restarts a failed or cancelled execution from the stage it
+// stopped at, with that stage's retry budget reset.
+func (s *CICDService) ResumeExecution(tenantID, id uint) (*model.PipelineExecution, error) {
+	s.runnerMu.Lock()
+	exec, err := s.repo.GetExecution(tenantID, id)
+	if err != nil {
+		s.runnerMu.Unlock()
+		return nil, err
+	}
+	if exec.Status != model.ExecFailed && exec.Status != model.ExecCancelled {
+		s.runnerMu.Unlock()
+		return nil, obserr.New("PIPELINE_EXECUTION_STATE", op, "only failed or cancelled executions can be resumed")
+	}
+	exec.Stages[exec.CurrentStage] = model.StageState{
+		Name:   exec.Stages[exec.CurrentStage].Name,
+		Type:   exec.Stages[exec.CurrentStage].Type,
+		Status: model.ExecPending,
+	}
+	exec.Status = model.ExecRunning
+	exec.FinishedAt = nil
+	err = s.repo.SaveExecution(exec)
+	s.execGen[exec.ID]++
+	s.runnerMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	s.goAsync(s.AdvanceExecutions)
+	return exec, nil
+}
+
+// CancelExecution stops an active execution. A build already running in CI
+// is not aborted.
+func (s *CICDService) CancelExecution(tenantID, id uint) (*model.PipelineExecution, error) {
+	s.runnerMu.Lock()
+	defer s.runnerMu.Unlock()
+	exec, err := s.repo.GetExecution(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if exec.Status != model.ExecRunning && exec.Status != model.ExecWaiting {
+		return nil, obserr.New("PIPELINE_EXECUTION_STATE", op, "execution is not active")
+	}
+	now := time.Now()
+	if exec.CurrentStage < len(exec.Stages) {
+		st := &exec.Stages[exec.CurrentStage]
+		st.Status = model.ExecCancelled
+		st.FinishedAt = &now
+	}
+	exec.Status = model.ExecCancelled
+	exec.FinishedAt = &now
+	s.execGen[exec.ID]++
+	return exec, s.repo.SaveExecution(exec)
+}
+
+// AdvanceExecutions moves every active execution forward as far as it can go
+// without waiting. Provider calls are made without runnerMu held, so cancels
+// and resumes never wait on a slow CI server; an execution cancelled or
+// resumed in the meantime is left as the API call saved it.
+func (s *CICDService) AdvanceExecutions() {
+	s.advanceMu.Lock()
+	defer s.advanceMu.Unlock()
+	s.runnerMu.Lock()
+	execs, err := s.repo.ListActiveExecutions()
+	gens := make([]int, len(execs))
+	for i := range execs {
+		gens[i] = s.execGen[execs[i].ID]
+	}
+	s.runnerMu.Unlock()
+	if err != nil {
+		logger.Log.Warn("Load pipeline executions failed", zap.Error(err))
+		return
+	}
+	for i := range execs {
+		if err := s.advance(&execs[i], gens[i]); err != nil && !errors.Is(err, errExecutionChanged) {
+			logger.Log.Error("Save pipeline execution failed", zap.Uint("execution_id", execs[i].ID), zap.Error(err))
+		}
+	}
+}
+
+// errExecutionChanged stops a runner pass on an execution that was cancelled
+// or resumed after the pass loaded it.
+var errExecutionChanged = errors.New("pipeline execution changed during the runner pass")
+
+// saveAdvanced saves the runner's progress unless the execution was cancelled
+// or resumed since gen was read.
+func (s *CICDService) saveAdvanced(exec *model.PipelineExecution, gen int) error {
+	s.runnerMu.Lock()
+	defer s.runnerMu.Unlock()
+	if s.execGen[exec.ID] != gen {
+		return errExecutionChanged
+	}
+	return s.repo.SaveExecution(exec)
+}
+
+// finalError marks a stage failure that retrying cannot fix.
+type finalError struct{ error }
+
+func (s *CICDService) advance(exec *model.PipelineExecution, gen int) error {
+	for exec.CurrentStage < len(exec.Stages) {
+		spec := exec.Spec[exec.CurrentStage]
+		st := &exec.Stages[exec.CurrentStage]
+		now := time.Now()
+		if st.Status == model.ExecPending {
+			st.Status = model.ExecRunning
+			st.Attempts++
+			st.StartedAt = &now
+			st.Message = ""
+		}
+		done, err := s.runStage(exec, spec, st)
+		if err != nil {
+			st.Message = err.Error()
+			var final finalError
+			if !errors.As(err, &final) && st.Attempts <= spec.Retries {
+				// Retry on the next pass with a fresh build or order.
+				st.Status = model.ExecPending
+				st.RunID, st.BuildNumber, st.OrderID = 0, 0, 0
+				exec.Status = model.ExecRunning
+				return s.saveAdvanced(exec, gen)
+			}
+			st.Status = model.ExecFailed
+			st.FinishedAt = &now
+			exec.Status = model.ExecFailed
+			exec.FinishedAt = &now
+			return s.saveAdvanced(exec, gen)
+		}
+		if !done {
+			exec.Status = model.ExecRunning
+			if st.Status == model.ExecWaiting {
+				exec.Status = model.ExecWaiting
+			}
+			return s.saveAdvanced(exec, gen)
+		}
+		st.Status = model.ExecSuccess
+		st.FinishedAt = &now
+		exec.CurrentStage++
+		exec.Status = model.ExecRunning
+		if err := s.saveAdvanced(exec, gen); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	exec.Status = model.ExecSuccess
+	exec.FinishedAt = &now
+	return s.saveAdvanced(exec, gen)
+}
+
+// runStage makes as much progress on a stage as possible and reports whether it finished.
+func (s *CICDService) runStage(exec *model.PipelineExecution, spec model.StageSpec, st *model.StageState) (bool, error) {
+	switch spec.Type {
+	case model.StageBuild:
+		return s.runBuildStage(exec, spec.Build, st)
+	case model.StageArtifactCheck:
+		if s.hooks.CheckArtifact == nil {
+			return false, finalError{errors.New("artifact check is not available")}
+		}
+		c := spec.ArtifactCheck
+		if err := s.hooks.CheckArtifact(c.HarborConfigID, c.Project, c.Repository, expandVersion(c.Tag, exec.Version), c.EnforceScanPolicy); err != nil {
+			return false, fmt.Errorf("artifact %s/%s:%s: %s", c.Project, c.Repository, expandVersion(c.Tag, exec.Version), obserr.Details(err)["message"])
+		}
+		return true, nil
+	case model.StageApproval:
+		return s.runApprovalStage(exec, spec, st)
+	case model.StageDeploy:
+		if s.hooks.Deploy == nil {
+			return false, finalError{errors.New("deploy is not available")}
+		}
+		d := *spec.Deploy
+		d.Variables = make(map[string]string, len(spec.Deploy.Variables))
+		for k, v := range spec.Deploy.Variables {
+			d.Variables[k] = expandVersion(v, exec.Version)
+		}
+		id, err := s.hooks.Deploy(exec.TenantID, exec.AppID, d, exec.Version, exec.TriggeredBy)
+		if err != nil {
+			return false, err
+		}
+		st.DeploymentID = id
+		return true, nil
+	}
+	return false, finalError{fmt.Errorf("unknown stage type %q", spec.Type)}
+}
+
+func (s *CICDService) runBuildStage(exec *model.PipelineExecution, b *model.BuildStage, st *model.StageState) (bool, error) {
+	if st.RunID == 0 {
+		params := make(map[string]string, len(b.Parameters))
+		for k, v := range b.Parameters {
+			params[k] = expandVersion(v, exec.Version)
+		}
+		run, err := s.TriggerBuild(b.ProviderConfigID, b.JobName, params, exec.TriggeredBy)
+		if err != nil {
+			return false, err
+		}
+		st.RunID, st.BuildNumber = run.ID, run.BuildNumber
+		return false, nil
+	}
+	run, err := s.repo.GetRun(st.RunID)
+	if err != nil {
+		return false, err
+	}
+	if run.BuildNumber == 0 {
+		if run.Status == model.RunQueued || run.Status == model.RunUnknown {
+			s.resolveRun(run)
+		}
+		if run.Status == model.RunCancelled {
+			return false, errors.New("build was cancelled in the queue")
+		}
+		if run.BuildNumber == 0 {
+			// Retrying would queue a second build next to the waiting one.
+			if st.StartedAt != nil && time.Since(*st.StartedAt) > s.queueTimeout {
+				return false, finalError{fmt.Errorf("build not started after %s", s.queueTimeout)}
+			}
+			return false, nil
+		}
+	}
+	st.BuildNumber = run.BuildNumber
+	if err := s.refreshRun(run); err != nil {
+		return false, err
+	}
+	switch run.Status {
+	case model.RunSuccess:
+		if exec.Version == "" {
+			exec.Version = strconv.Itoa(run.BuildNumber)
+		}
+		return true, nil
+	case model.RunFailed, model.RunAborted, model.RunCancelled:
+		return false, fmt.Errorf("build #%d %s", run.BuildNumber, run.Status)
+	}
+	return false, nil
+}
+
+// refreshRun updates an unfinished run from the provider's build list.
+func (s *CICDService) refreshRun(run *model.PipelineRun) error {
+	if run.FinishedAt != nil {
+		return nil
+	}
+	p, err := s.repo.Provider(run.ProviderConfigID)
+	if err != nil {
+		return err
+	}
+	builds, err := p.ListBuilds(run.JobName)
+	if err != nil {
+		return err
+	}
+	for _, b := range builds {
+		if b.Number == run.BuildNumber {
+			applyBuild(run, b)
+			return s.repo.SaveRun(run)
+		}
+	}
+	return nil
+}
+
+func (s *CICDService) runApprovalStage(exec *model.PipelineExecution, spec model.StageSpec, st *model.StageState) (bool, error) {
+	if s.hooks.RequestApproval == nil || s.hooks.ApprovalStatus == nil {
+		return false, finalError{errors.New("approval is not available")}
+	}
+	if st.OrderID == 0 {
+		title := spec.Approval.Title
+		if title == "" {
+			title = fmt.Sprintf("流水线 #%d %s 审批", exec.ID, spec.Name)
+		}
+		desc := fmt.Sprintf("流水线执行 #%d 等待审批，版本 %s，发起人 %s", exec.ID, exec.Version, exec.TriggeredBy)
+		orderID, err := s.hooks.RequestApproval(exec.TenantID, exec.TriggeredByID, title, desc, spec.Approval.ApprovalLevels)
+		if err != nil {
+			return false, err
+		}
+		st.OrderID = orderID
+		st.Status = model.ExecWaiting
+		return false, nil
+	}
+	status, err := s.hooks.ApprovalStatus(exec.TenantID, st.OrderID)
+	if err != nil {
+		return false, err
+	}
+	switch status {
+	case ApprovalApproved:
+		return true, nil
+	case ApprovalRejected:
+		return false, finalError{fmt.Errorf("change order #%d was rejected", st.OrderID)}
+	}
+	st.Status = model.ExecWaiting
+	return false, nil
+}
+
+func expandVersion(s, version string) string {
+	return strings.ReplaceAll(s, "${version}", version)
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/cicd/model"
+	"devops-platform/internal/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// fakeGitLab creates pipelines with increasing IDs and reports each one with
+// the status set in statuses (running until set).
+func fakeGitLab(t *testing.T, statuses map[int]string) *httptest.Server {
+	t.Helper()
+	next := 100
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v4/projects/api/pipeline":
+			next++
+			_, _ = fmt.Fprintf(w, `{"id":%d}`, next)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/api/pipelines":
+			var items []string
+			for id := next; id > 100; id-- {
+				status := statuses[id]
+				if status == "" {
+					status = "running"
+				}
+				items = append(items, fmt.Sprintf(`{"id":%d,"status":%q,"sha":"abc","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:01:00Z"}`, id, status))
+			}
+			_, _ = w.Write([]byte("[" + strings.Join(items, ",") + "]"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func setupRunner(t *testing.T, srvURL string, stages []model.StageSpec) (*CICDService, *model.PipelineSpec) {
+	t.Helper()
+	logger.Log = zap.NewNop()
+	db := setupCICDDB(t)
+	if err := db.AutoMigrate(&model.PipelineSpec{}, &model.PipelineExecution{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	cfg := model.CIProviderConfig{Name: "gl", Type: model.ProviderGitLab, URL: srvURL, Token: "t", Ref: "main"}
+	db.Create(&cfg)
+	for i := range stages {
+		if stages[i].Build != nil {
+			stages[i].Build.ProviderConfigID = cfg.ID
+		}
+	}
+	svc := NewCICDService(db)
+	svc.goAsync = func(fn func()) {}
+	spec := &model.PipelineSpec{TenantID: 1, AppID: 7, Name: "release", Stages: stages}
+	if err := svc.SaveSpec(spec); err != nil {
+		t.Fatalf("save spec: %v", err)
+	}
+	return svc, spec
+}
+
+func TestPipelineRunner_RunsStagesInOrder(t *testing.T) {
+	statuses := map[int]string{}
+	srv := fakeGitLab(t, statuses)
+	defer srv.Close()
+
+	svc, spec := setupRunner(t, srv.URL, []model.StageSpec{
+		{Type: model.StageBuild, Build: &model.BuildStage{JobName: "api"}},
+		{Type: model.StageArtifactCheck, ArtifactCheck: &model.ArtifactCheckStage{HarborConfigID: 1, Project: "team", Repository: "api", Tag: "v${version}"}},
+		{Type: model.StageApproval, Approval: &model.ApprovalStage{ApprovalLevels: 1}},
+		{Type: model.StageDeploy, Deploy: &model.DeployStage{TemplateID: 3, Environment: "prod", Variables: map[string]string{"IMAGE_TAG": "v${version}"}}},
+	})
+	var calls []string
+	approval := ApprovalPending
+	svc.SetStageHooks(StageHooks{
+		CheckArtifact: func(_ uint, project, repository, reference string, _ bool) error {
+			calls = append(calls, "check "+project+"/"+repository+":"+reference)
+			return nil
+		},
+		RequestApproval: func(tenantID, userID uint, title, description string, levels int) (uint, error) {
+			calls = append(calls, "approval")
+			return 55, nil
+		},
+		ApprovalStatus: func(tenantID, orderID uint) (string, error) { return approval, nil },
+		Deploy: func(tenantID, appID uint, stage model.DeployStage, version, operator string) (uint, error) {
+			calls = append(calls, fmt.Sprintf("deploy app=%d env=%s version=%s tag=%s by=%s", appID, stage.Environment, version, stage.Variables["IMAGE_TAG"], operator))
+			return 9, nil
+		},
+	})
+
+	exec, err := svc.StartExecution(1, 2, "alice", spec.ID, "")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	svc.AdvanceExecutions()
+	exec, _ = svc.GetExecution(1, exec.ID)
+	if exec.Status != model.ExecRunning || exec.Stages[0].BuildNumber != 101 || len(calls) != 0 {
+		t.Fatalf("expected build 101 running, got %+v calls=%v", exec, calls)
+	}
+
+	statuses[101] = "success"
+	svc.AdvanceExecutions()
+	exec, _ = svc.GetExecution(1, exec.ID)
+	if exec.Status != model.ExecWaiting || exec.CurrentStage != 2 || exec.Stages[2].OrderID != 55 {
+		t.Fatalf("expected waiting on approval, got status=%s stage=%d", exec.Status, exec.CurrentStage)
+	}
+	if exec.Version != "101" {
+		t.Fatalf("version should default to build number, got %q", exec.Version)
+	}
+
+	svc.AdvanceExecutions()
+	exec, _ = svc.GetExecution(1, exec.ID)
+	if exec.Status != model.ExecWaiting {
+		t.Fatalf("pending approval should keep waiting, got %s", exec.Status)
+	}
+
+	approval = ApprovalApproved
+	svc.AdvanceExecutions()
+	exec, _ = svc.GetExecution(1, exec.ID)
+	if exec.Status != model.ExecSuccess || exec.FinishedAt == nil {
+		t.Fatalf("expected success, got %s", exec.Status)
+	}
+	if exec.Stages[3].DeploymentID != 9 {
+		t.Fatalf("deployment id not recorded: %+v", exec.Stages[3])
+	}
+	want := []string{"check team/api:v101", "approval", "deploy app=7 env=prod version=101 tag=v101 by=alice"}
+	if strings.Join(calls, "|") != strings.Join(want, "|") {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	if exec.Spec[3].Deploy.Variables["IMAGE_TAG"] != "v${version}" {
+		t.Fatalf("stored spec must keep the placeholder, got %q", exec.Spec[3].Deploy.Variables["IMAGE_TAG"])
+	}
+}
+
+func TestPipelineRunner_RetriesFailedBuild(t *testing.T) {
+	statuses := map[int]string{101: "failed", 102: "success"}
+	srv := fakeGitLab(t, statuses)
+	defer srv.Close()
+
+	svc, spec := setupRunner(t, srv.URL, []model.StageSpec{
+		{Type: model.StageBuild, Retries: 1, Build: &model.BuildStage{JobName: "api"}},
+	})
+	exec, _ := svc.StartExecution(1, 2, "alice", spec.ID, "1.2.0")
+	for i := 0; i < 4; i++ {
+		svc.AdvanceExecutions()
+	}
+	exec, _ = svc.GetExecution(1, exec.ID)
+	if exec.Status != model.ExecSuccess {
+		t.Fatalf("expected success after retry, got %s: %+v", exec.Status, exec.Stages[0])
+	}
+	if exec.Stages[0].Attempts != 2 || exec.Stages[0].BuildNumber != 102 {
+		t.Fatalf("expected second attempt on build 102, got %+v", exec.Stages[0])
+	}
+	if exec.Version != "1.2.0" {
+		t.Fatalf("explicit version must be kept, got %q", exec.Version)
+	}
+}
+
+func TestPipelineRunner_FailureAndResume(t *testing.T) {
+	svc, spec := setupRunner(t, "", []model.StageSpec{
+		{Type: model.StageArtifactCheck, ArtifactCheck: &model.ArtifactCheckStage{HarborConfigID: 1, Project: "team", Repository: "api", Tag: "${version}"}},
+	})
+	missing := true
+	svc.SetStageHooks(StageHooks{CheckArtifact: func(uint, string, string, string, bool) error {
+		if missing {
+			return errors.New("not found")
+		}
+		return nil
+	}})
+
+	exec, _ := svc.StartExecution(1, 2, "alice", spec.ID, "1.0")
+	svc.AdvanceExecutions()
+	exec, _ = svc.GetExecution(1, exec.ID)
+	if exec.Status != model.ExecFailed || exec.Stages[0].Status != model.ExecFailed || exec.Stages[0].Message == "" {
+		t.Fatalf("expected failed stage, got %+v", exec)
+	}
+	if _, err := svc.CancelExecution(1, exec.ID); err == nil {
+		t.Fatal("cancelling a failed execution should be rejected")
+	}
+
+	missing = false
+	if _, err := svc.ResumeExecution(1, exec.ID); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	svc.AdvanceExecutions()
+	exec, _ = svc.GetExecution(1, exec.ID)
+	if exec.Status != model.ExecSuccess || exec.Stages[0].Attempts != 1 {
+		t.Fatalf("expected success after resume, got %+v", exec)
+	}
+	if _, err := svc.ResumeExecution(1, exec.ID); err == nil {
+		t.Fatal("resuming a finished execution should be rejected")
+	}
+}
+
+func TestPipelineRunner_RejectedApprovalIsNotRetried(t *testing.T) {
+	svc, spec := setupRunner(t, "", []model.StageSpec{
+		{Type: model.StageApproval, Retries: 3},
+	})
+	requests := 0
+	svc.SetStageHooks(StageHooks{
+		RequestApproval: func(uint, uint, string, string, int) (uint, error) {
+			requests++
+			return uint(requests), nil
+		},
+		ApprovalStatus: func(uint, uint) (string, error) { return ApprovalRejected, nil },
+	})
+
+	exec, _ := svc.StartExecution(1, 2, "alice", spec.ID, "")
+	for i := 0; i < 3; i++ {
+		svc.AdvanceExecutions()
+	}
+	exec, _ = svc.GetExecution(1, exec.ID)
+	if exec.Status != model.ExecFailed || requests != 1 {
+		t.Fatalf("rejection should fail without retry, status=%s requests=%d", exec.Status, requests)
+	}
+}
+
+// fakeJenkinsQueue queues every build as item 5, numbered once *number is set.
+func fakeJenkinsQueue(t *testing.T, number *int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/api/api/json":
+			_, _ = w.Write([]byte(`{}`))
+		case "/job/api/buildWithParameters", "/job/api/build":
+			w.Header().Set("Location", "http://jenkins/queue/item/5/")
+			w.WriteHeader(http.StatusCreated)
+		case "/queue/item/5/api/json":
+			if *number == 0 {
+				_, _ = w.Write([]byte(`{"why":"Waiting for next available executor"}`))
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"executable":{"number":%d}}`, *number)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func setupJenkinsRunner(t *testing.T, srvURL string, stages []model.StageSpec) (*CICDService, *model.PipelineSpec) {
+	t.Helper()
+	svc, spec := setupRunner(t, "", stages)
+	svc.db.Model(&model.CIProviderConfig{}).Where("id = ?", spec.Stages[0].Build.ProviderConfigID).
+		Updates(map[string]interface{}{"type": model.ProviderJenkins, "url": srvURL, "username": "admin"})
+	return svc, spec
+}
+
+func TestPipelineRunner_ResolvesQueuedBuild(t *testing.T) {
+	number := 0
+	srv := fakeJenkinsQueue(t, &number)
+	defer srv.Close()
+
+	svc, spec := setupJenkinsRunner(t, srv.URL, []model.StageSpec{
+		{Type: model.StageBuild, Build: &model.BuildStage{JobName: "api"}},
+	})
+	exec, _ := svc.StartExecution(1, 2, "alice", spec.ID, "")
+	svc.AdvanceExecutions()
+	svc.AdvanceExecutions()
+	exec, _ = svc.GetExecution(1, exec.ID)
+	if exec.Status != model.ExecRunning || exec.Stages[0].RunID == 0 || exec.Stages[0].BuildNumber != 0 {
+		t.Fatalf("expected build waiting in the queue, got %+v", exec.Stages[0])
+	}
+
+	// No background follower runs here: the stage polls the queue itself.
+	number = 31
+	svc.AdvanceExecutions()
+	exec, _ = svc.GetExecution(1, exec.ID)
+	if exec.Stages[0].BuildNumber != 31 {
+		t.Fatalf("expected stage to pick up build 31, got %+v", exec.Stages[0])
+	}
+	run, _ := svc.GetRun(exec.Stages[0].RunID)
+	if run.BuildNumber != 31 || run.Status != model.RunRunning {
+		t.Fatalf("expected run numbered from the queue, got %+v", run)
+	}
+}
+
+func TestPipelineRunner_FailsBuildStuckInQueue(t *testing.T) {
+	number := 0
+	srv := fakeJenkinsQueue(t, &number)
+	defer srv.Close()
+
+	svc, spec := setupJenkinsRunner(t, srv.URL, []model.StageSpec{
+		{Type: model.StageBuild, Retries: 2, Build: &model.BuildStage{JobName: "api"}},
+	})
+	svc.queueTimeout = 0
+	exec, _ := svc.StartExecution(1, 2, "alice", spec.ID, "")
+	svc.AdvanceExecutions()
+	time.Sleep(time.Millisecond)
+	svc.AdvanceExecutions()
+	exec, _ = svc.GetExecution(1, exec.ID)
+	if exec.Status != model.ExecFailed || exec.Stages[0].Attempts != 1 || !strings.Contains(exec.Stages[0].Message, "not started") {
+		t.Fatalf("expected stage failed without retry, got %s %+v", exec.Status, exec.Stages[0])
+	}
+}
+
+func TestPipelineRunner_CancelDoesNotWaitOnProvider(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	statuses := map[int]string{}
+	gitlab := fakeGitLab(t, statuses)
+	defer gitlab.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			entered <- struct{}{}
+			<-release
+		}
+		gitlab.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	svc, spec := setupRunner(t, srv.URL, []model.StageSpec{
+		{Type: model.StageBuild, Build: &model.BuildStage{JobName: "api"}},
+	})
+	exec, _ := svc.StartExecution(1, 2, "alice", spec.ID, "")
+	svc.AdvanceExecutions()
+
+	done := make(chan struct{})
+	go func() {
+		svc.AdvanceExecutions()
+		close(done)
+	}()
+	<-entered
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := svc.CancelExecution(1, exec.ID)
+		cancelled <- err
+	}()
+	select {
+	case err := <-cancelled:
+		if err != nil {
+			t.Fatalf("cancel: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("cancel blocked behind the provider call")
+	}
+	statuses[101] = "success"
+	close(release)
+	<-done
+
+	exec, _ = svc.GetExecution(1, exec.ID)
+	if exec.Status != model.ExecCancelled {
+		t.Fatalf("runner pass must not overwrite the cancel, got %s", exec.Status)
+	}
+}
+
+func TestSaveSpec_Validates(t *testing.T) {
+	svc, _ := setupRunner(t, "", []model.StageSpec{{Type: model.StageApproval}})
+	cases := []model.PipelineSpec{
+		{TenantID: 1, AppID: 7, Name: "x"},
+		{TenantID: 1, AppID: 7, Name: "x", Stages: []model.StageSpec{{Type: "shell"}}},
+		{TenantID: 1, AppID: 7, Name: "x", Stages: []model.StageSpec{{Type: model.StageBuild}}},
+		{TenantID: 1, AppID: 7, Name: "x", Stages: []model.StageSpec{{Type: model.StageApproval}, {Type: model.StageApproval}}},
+		{TenantID: 1, AppID: 7, Name: "x", Stages: []model.StageSpec{{Type: model.StageApproval, Retries: 11}}},
+	}
+	for i, spec := range cases {
+		if err := svc.SaveSpec(&spec); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"devops-platform/internal/modules/cicd/model"
+	"devops-platform/internal/modules/cicd/repository"
+	"devops-platform/internal/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// loop is a ticker-driven background job that can be stopped.
+type loop struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func (l *loop) start(interval time.Duration, fn func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.mu.Lock()
+	l.cancel = cancel
+	l.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+}
+
+func (l *loop) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cancel != nil {
+		l.cancel()
+		l.cancel = nil
+	}
+}
+
+// StartRunSync runs SyncRuns on the given interval until StopRunSync is called.
+func (s *CICDService) StartRunSync(interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	s.syncLoop.start(interval, func() { s.SyncRuns() })
+}
+
+func (s *CICDService) StopRunSync() {
+	s.syncLoop.stop()
+}
+
+// runStatus maps a provider build onto the PipelineRun status vocabulary.
+func runStatus(b model.BuildInfo) string {
+	if b.Building {
+		return model.RunRunning
+	}
+	switch b.Result {
+	case "SUCCESS":
+		return model.RunSuccess
+	case "FAILURE", "UNSTABLE":
+		return model.RunFailed
+	case "ABORTED", "NOT_BUILT":
+		return model.RunAborted
+	}
+	return model.RunRunning
+}
+
+// SyncRuns reads the recent builds of every pipeline and records new or
+// changed ones, returning how many runs were written. Runs that already
+// finished are left alone, so a pass over an idle pipeline writes nothing.
+func (s *CICDService) SyncRuns() int {
+	pipelines, err := s.repo.ListAllPipelines()
+	if err != nil {
+		logger.Log.Warn("Load pipelines for run sync failed", zap.Error(err))
+		return 0
+	}
+	providers := map[uint]repository.Provider{}
+	written := 0
+	for _, pl := range pipelines {
+		p, ok := providers[pl.ProviderConfigID]
+		if !ok {
+			if p, err = s.repo.Provider(pl.ProviderConfigID); err != nil {
+				logger.Log.Warn("Load ci provider for run sync failed", zap.Uint("pipeline_id", pl.ID), zap.Error(err))
+				continue
+			}
+			providers[pl.ProviderConfigID] = p
+		}
+		builds, err := p.ListBuilds(pl.JobName)
+		if err != nil {
+			logger.Log.Warn("List builds for run sync failed", zap.Uint("pipeline_id", pl.ID), zap.Error(err))
+			continue
+		}
+		s.numberRuns(p, pl, builds)
+		for _, b := range builds {
+			changed, err := s.recordBuild(pl, b)
+			if err != nil {
+				logger.Log.Warn("Record pipeline run failed", zap.Uint("pipeline_id", pl.ID), zap.Int("build", b.Number), zap.Error(err))
+				continue
+			}
+			if changed {
+				written++
+			}
+		}
+	}
+	return written
+}
+
+// numberRuns resolves the pipeline's runs triggered here that have no build
+// number yet, so recordBuild updates them instead of adding a second run.
+func (s *CICDService) numberRuns(p repository.Provider, pl model.Pipeline, builds []model.BuildInfo) {
+	runs, err := s.repo.ListUnnumberedRuns(pl.ProviderConfigID, pl.JobName)
+	if err != nil {
+		logger.Log.Warn("Load unnumbered pipeline runs failed", zap.Uint("pipeline_id", pl.ID), zap.Error(err))
+		return
+	}
+	for i := range runs {
+		s.numberRun(p, &runs[i], builds)
+	}
+}
+
+func (s *CICDService) recordBuild(pl model.Pipeline, b model.BuildInfo) (bool, error) {
+	run, err := s.repo.FindRun(pl.ProviderConfigID, pl.JobName, b.Number)
+	if err != nil {
+		return false, err
+	}
+	if run == nil {
+		run = &model.PipelineRun{ProviderConfigID: pl.ProviderConfigID, JobName: pl.JobName, BuildNumber: b.Number}
+	} else if run.FinishedAt != nil {
+		return false, nil
+	}
+	before := *run
+	run.PipelineID = pl.ID
+	applyBuild(run, b)
+	if run.ID != 0 && sameRun(before, *run) {
+		return false, nil
+	}
+	return true, s.repo.SaveRun(run)
+}
+
+// sameRun reports whether applyBuild left a run as it was, so a running build
+// that has not moved is not written again on every sync.
+func sameRun(a, b model.PipelineRun) bool {
+	return a.PipelineID == b.PipelineID && a.URL == b.URL && a.Status == b.Status &&
+		a.Commit == b.Commit && a.TriggeredBy == b.TriggeredBy && a.Duration == b.Duration &&
+		maps.Equal(a.Parameters, b.Parameters) &&
+		sameTime(a.StartedAt, b.StartedAt) && sameTime(a.FinishedAt, b.FinishedAt)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// applyBuild copies a provider build onto its run. Runs triggered here keep
+// the platform user and parameters they were started with.
+func applyBuild(run *model.PipelineRun, b model.BuildInfo) {
+	run.URL = b.URL
+	run.Status = runStatus(b)
+	if b.Commit != "" {
+		run.Commit = b.Commit
+	}
+	if run.TriggeredBy == "" {
+		run.TriggeredBy = b.TriggeredBy
+	}
+	if run.Parameters == nil {
+		run.Parameters = b.Parameters
+	}
+	if b.Timestamp > 0 {
+		started := time.UnixMilli(b.Timestamp)
+		run.StartedAt = &started
+	}
+	if run.Status != model.RunRunning {
+		run.Duration = b.Duration
+		finished := time.Now()
+		if run.StartedAt != nil {
+			finished = run.StartedAt.Add(time.Duration(b.Duration) * time.Millisecond)
+		}
+		run.FinishedAt = &finished
+	}
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/cicd/model"
+	"devops-platform/internal/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupCICDDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db failed: %v", err)
+	}
+	if err := db.AutoMigrate(&model.CIProviderConfig{}, &model.Pipeline{}, &model.PipelineRun{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	return db
+}
+
+func TestTriggerBuild_FollowsJenkinsQueueIntoRun(t *testing.T) {
+	logger.Log = zap.NewNop()
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/deploy/api/json":
+			_, _ = w.Write([]byte(`{"property":[{"parameterDefinitions":[{"name":"ENV","type":"StringParameterDefinition","defaultParameterValue":{"value":"dev"}}]}]}`))
+		case "/job/deploy/buildWithParameters":
+			w.Header().Set("Location", "http://jenkins/queue/item/5/")
+			w.WriteHeader(http.StatusCreated)
+		case "/queue/item/5/api/json":
+			polls++
+			if polls < 2 {
+				_, _ = w.Write([]byte(`{"why":"Waiting for next available executor"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"executable":{"number":31}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	db := setupCICDDB(t)
+	cfg := model.CIProviderConfig{Name: "ci", Type: model.ProviderJenkins, URL: srv.URL, Username: "admin", Token: "t"}
+	db.Create(&cfg)
+	pipeline := model.Pipeline{Name: "deploy", ProviderConfigID: cfg.ID, JobName: "deploy"}
+	db.Create(&pipeline)
+
+	svc := NewCICDService(db)
+	svc.queuePoll = time.Millisecond
+	var follow func()
+	svc.goAsync = func(fn func()) { follow = fn }
+
+	run, err := svc.TriggerBuild(cfg.ID, "deploy", map[string]string{"ENV": "prod"}, "alice")
+	if err != nil {
+		t.Fatalf("trigger failed: %v", err)
+	}
+	if run.Status != model.RunQueued || run.PipelineID != pipeline.ID || run.QueueURL == "" || run.TriggeredBy != "alice" {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	if follow == nil {
+		t.Fatalf("expected queue item followed")
+	}
+	follow()
+
+	stored, err := svc.GetRun(run.ID)
+	if err != nil {
+		t.Fatalf("get run failed: %v", err)
+	}
+	if stored.BuildNumber != 31 || stored.Status != model.RunRunning || stored.StartedAt == nil || stored.Parameters["ENV"] != "prod" {
+		t.Fatalf("expected run numbered after queue, got %+v", stored)
+	}
+}
+
+func TestFollowQueue_CancelledItem(t *testing.T) {
+	logger.Log = zap.NewNop()
+	db := setupCICDDB(t)
+	svc := NewCICDService(db)
+	run := &model.PipelineRun{JobName: "deploy", QueueURL: "q", Status: model.RunQueued}
+	db.Create(run)
+
+	svc.followQueue(queueFunc(func(string) (int, bool, error) { return 0, true, nil }), run)
+	stored, _ := svc.GetRun(run.ID)
+	if stored.Status != model.RunCancelled || stored.FinishedAt == nil {
+		t.Fatalf("expected cancelled run, got %+v", stored)
+	}
+}
+
+type queueFunc func(string) (int, bool, error)
+
+func (f queueFunc) ResolveQueueItem(queueURL string) (int, bool, error) { return f(queueURL) }
+
+func TestSyncRuns_NumbersQueuedRunInsteadOfDuplicating(t *testing.T) {
+	logger.Log = zap.NewNop()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/deploy/api/json":
+			_, _ = w.Write([]byte(`{"builds":[{"number":31,"url":"http://jenkins/job/deploy/31/","building":true,"timestamp":1700000000000}]}`))
+		case "/job/deploy/build":
+			w.Header().Set("Location", "http://jenkins/queue/item/5/")
+			w.WriteHeader(http.StatusCreated)
+		case "/queue/item/5/api/json":
+			_, _ = w.Write([]byte(`{"executable":{"number":31}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	db := setupCICDDB(t)
+	cfg := model.CIProviderConfig{Name: "ci", Type: model.ProviderJenkins, URL: srv.URL, Username: "admin", Token: "t"}
+	db.Create(&cfg)
+	db.Create(&model.Pipeline{Name: "deploy", ProviderConfigID: cfg.ID, JobName: "deploy"})
+
+	// The queue follower never runs, as after a restart mid-follow.
+	svc := NewCICDService(db)
+	svc.goAsync = func(fn func()) {}
+	run, err := svc.TriggerBuild(cfg.ID, "deploy", nil, "alice")
+	if err != nil {
+		t.Fatalf("trigger failed: %v", err)
+	}
+	svc.SyncRuns()
+
+	var runs []model.PipelineRun
+	db.Find(&runs)
+	if len(runs) != 1 || runs[0].ID != run.ID || runs[0].BuildNumber != 31 || runs[0].Status != model.RunRunning || runs[0].TriggeredBy != "alice" {
+		t.Fatalf("expected the triggered run numbered by the sync, got %+v", runs)
+	}
+}
+
+func TestSyncRuns_MatchesDispatchedGitHubRun(t *testing.T) {
+	logger.Log = zap.NewNop()
+	started := time.Now().UTC().Format(time.RFC3339)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/team/api/actions/workflows/ci.yml/dispatches":
+			w.WriteHeader(http.StatusNoContent)
+		case "/repos/team/api/actions/workflows/ci.yml/runs":
+			_, _ = w.Write([]byte(`{"workflow_runs":[
+				{"id":902,"status":"in_progress","run_started_at":"` + started + `","triggering_actor":{"login":"alice"}},
+				{"id":901,"status":"completed","conclusion":"success","run_started_at":"2020-01-01T00:00:00Z","updated_at":"2020-01-01T00:05:00Z"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	db := setupCICDDB(t)
+	cfg := model.CIProviderConfig{Name: "gh", Type: model.ProviderGitHub, URL: srv.URL, Project: "team/api", Ref: "main", Token: "t"}
+	db.Create(&cfg)
+	db.Create(&model.Pipeline{Name: "ci", ProviderConfigID: cfg.ID, JobName: "ci.yml"})
+
+	svc := NewCICDService(db)
+	run, err := svc.TriggerBuild(cfg.ID, "ci.yml", nil, "bob")
+	if err != nil {
+		t.Fatalf("trigger failed: %v", err)
+	}
+	if written := svc.SyncRuns(); written != 2 {
+		t.Fatalf("expected 2 runs written, got %d", written)
+	}
+
+	var runs []model.PipelineRun
+	db.Order("build_number").Find(&runs)
+	if len(runs) != 2 || runs[1].ID != run.ID || runs[1].BuildNumber != 902 || runs[1].TriggeredBy != "bob" {
+		t.Fatalf("expected dispatch matched to run 902, got %+v", runs)
+	}
+}
+
+func TestFollowQueue_TimeoutMarksUnknownAndKeepsOtherColumns(t *testing.T) {
+	logger.Log = zap.NewNop()
+	db := setupCICDDB(t)
+	svc := NewCICDService(db)
+	svc.queueTimeout = 0
+	run := &model.PipelineRun{JobName: "deploy", QueueURL: "q", Status: model.RunQueued}
+	db.Create(run)
+	db.Model(run).Update("url", "http://jenkins/job/deploy/")
+
+	svc.followQueue(queueFunc(func(string) (int, bool, error) { return 0, false, nil }), run)
+	stored, _ := svc.GetRun(run.ID)
+	if stored.Status != model.RunUnknown || stored.URL != "http://jenkins/job/deploy/" {
+		t.Fatalf("expected unknown run with its url kept, got %+v", stored)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -25,25 +24,7 @@ import (
 var terminalUpgrader = websocket.Upgrader{
 	ReadBufferSize:  4096,
 	WriteBufferSize: 4096,
-	CheckOrigin: func(r *http.Request) bool {
-		origin := r.Header.Get("Origin")
-		if origin == "" {
-			return true
-		}
-		u, err := url.Parse(origin)
-		if err != nil {
-			return false
-		}
-		if strings.EqualFold(u.Host, r.Host) {
-			return true
-		}
-		for _, allowed := range config.Cfg.GetStringSlice("cors.allow_origins") {
-			if strings.EqualFold(origin, allowed) {
-				return true
-			}
-		}
-		return false
-	},
+	CheckOrigin:     cmdbterminal.CheckOrigin,
 }
 
 func TerminalList(c *gin.Context) {
@@ -5,10 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
 
+	"devops-platform/config"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -17,6 +21,36 @@ var (
 	ErrTerminalMaxDuration = errors.New("terminal max session duration exceeded")
 )
 
+// CheckOrigin accepts same-host origins and those listed in cors.allow_origins.
+func CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range config.Cfg.GetStringSlice("cors.allow_origins") {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNormalClose reports whether a websocket read/write error is just the peer going away.
+func IsNormalClose(err error) bool {
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+		return true
+	}
+	messageText := strings.ToLower(err.Error())
+	return strings.Contains(messageText, "use of closed network connection") || strings.Contains(messageText, "websocket: close") || strings.Contains(messageText, "eof")
+}
+
 type WSMessage struct {
 	Operation string      `json:"operation"`
 	Data      interface{} `json:"data,omitempty"`
@@ -134,11 +168,7 @@ func (b *Bridge) ForwardWSInput() error {
 	for {
 		var message WSMessage
 		if err := b.conn.ReadJSON(&message); err != nil {
-			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
-				return nil
-			}
-			messageText := strings.ToLower(err.Error())
-			if strings.Contains(messageText, "use of closed network connection") || strings.Contains(messageText, "websocket: close") || strings.Contains(messageText, "eof") {
+			if IsNormalClose(err) {
 				return nil
 			}
 			return err
@@ -19,6 +19,11 @@ func SetHarborDB(db *gorm.DB) {
 	harborSvc = service.NewHarborService(db)
 }
 
+// InitHarborService injects a configured service, shared with other modules.
+func InitHarborService(svc *service.HarborService) {
+	harborSvc = svc
+}
+
 // Configs
 
 func ListHarborConfigs(c *gin.Context) {
@@ -138,6 +143,57 @@ func DeleteArtifact(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "deleted"})
 }
 
+func GetArtifact(c *gin.Context) {
+	configID, _ := strconv.ParseUint(c.DefaultQuery("configId", "0"), 10, 64)
+	artifact, err := harborSvc.GetArtifact(uint(configID), c.Param("projectName"), c.Param("repoName"), c.Param("reference"))
+	if err != nil {
+		writeObservableError(c, scanErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": artifact})
+}
+
+// Vulnerability scanning
+
+func ScanArtifact(c *gin.Context) {
+	configID, _ := strconv.ParseUint(c.DefaultQuery("configId", "0"), 10, 64)
+	if err := harborSvc.ScanArtifact(uint(configID), c.Param("projectName"), c.Param("repoName"), c.Param("reference")); err != nil {
+		writeObservableError(c, scanErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "scan triggered"})
+}
+
+func GetVulnerabilityReport(c *gin.Context) {
+	configID, _ := strconv.ParseUint(c.DefaultQuery("configId", "0"), 10, 64)
+	report, err := harborSvc.GetVulnerabilityReport(uint(configID), c.Param("projectName"), c.Param("repoName"), c.Param("reference"))
+	if err != nil {
+		writeObservableError(c, scanErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": report})
+}
+
+func CheckScanPolicy(c *gin.Context) {
+	configID, _ := strconv.ParseUint(c.DefaultQuery("configId", "0"), 10, 64)
+	decision, err := harborSvc.CheckScanPolicy(uint(configID), c.Param("projectName"), c.Param("repoName"), c.Param("reference"))
+	if err != nil {
+		writeObservableError(c, scanErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": decision})
+}
+
+func scanErrorStatus(err error) int {
+	switch obserr.Details(err)["code"] {
+	case "INVALID_PARAM":
+		return http.StatusBadRequest
+	case "HARBOR_NOT_FOUND", "HARBOR_SCAN_NOT_FOUND":
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
 func writeObservableError(c *gin.Context, status int, err error) {
 	details := obserr.Details(err)
 	msg, _ := details["message"].(string)
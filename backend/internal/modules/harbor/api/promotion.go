@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"devops-platform/internal/modules/harbor/model"
+	"devops-platform/internal/pkg/obserr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Promotions
+
+// CreatePromotion copies an artifact to another project or registry, or
+// opens an approval order for it when approval is required.
+func CreatePromotion(c *gin.Context) {
+	var req model.PromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+		return
+	}
+	p, err := harborSvc.RequestPromotion(c.GetUint("tenantID"), c.GetUint("userID"), c.GetString("username"), req)
+	if err != nil {
+		// A failed copy is still recorded in the promotion history.
+		writeObservableError(c, promotionErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": p})
+}
+
+func ListPromotions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	items, total, err := harborSvc.ListPromotions(c.GetUint("tenantID"), c.Query("status"), page, pageSize)
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": items, "total": total})
+}
+
+func GetPromotion(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	p, err := harborSvc.GetPromotion(c.GetUint("tenantID"), uint(id))
+	if err != nil {
+		writeObservableError(c, promotionErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": p})
+}
+
+func promotionErrorStatus(err error) int {
+	switch obserr.Details(err)["code"] {
+	case "INVALID_PARAM":
+		return http.StatusBadRequest
+	case "PROMOTION_NOT_FOUND", "HARBOR_NOT_FOUND", "HARBOR_CONFIG_NOT_FOUND":
+		return http.StatusNotFound
+	case "PROMOTION_TAG_CONFLICT":
+		return http.StatusConflict
+	case "PROMOTION_APPROVAL_UNAVAILABLE":
+		return http.StatusServiceUnavailable
+	case "HARBOR_CONNECT_FAILED", "HARBOR_AUTH_FAILED", "HARBOR_REGISTRY_FAILED", "HARBOR_REQUEST_FAILED":
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"devops-platform/internal/modules/harbor/model"
+	"devops-platform/internal/pkg/obserr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Retention policies
+
+func ListRetentionPolicies(c *gin.Context) {
+	configID, _ := strconv.ParseUint(c.DefaultQuery("configId", "0"), 10, 64)
+	policies, err := harborSvc.ListRetentionPolicies(uint(configID))
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": policies})
+}
+
+func GetRetentionPolicy(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	policy, err := harborSvc.GetRetentionPolicy(uint(id))
+	if err != nil {
+		writeObservableError(c, retentionErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": policy})
+}
+
+func SaveRetentionPolicy(c *gin.Context) {
+	var policy model.RetentionPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+		return
+	}
+	policy.ID = 0
+	if id, err := strconv.ParseUint(c.Param("id"), 10, 64); err == nil {
+		policy.ID = uint(id)
+	}
+	if err := harborSvc.SaveRetentionPolicy(&policy); err != nil {
+		writeObservableError(c, retentionErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": policy})
+}
+
+func DeleteRetentionPolicy(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err := harborSvc.DeleteRetentionPolicy(uint(id)); err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "deleted"})
+}
+
+// DryRunRetentionPolicy previews what the policy would delete.
+func DryRunRetentionPolicy(c *gin.Context) {
+	runRetention(c, true)
+}
+
+func ExecuteRetentionPolicy(c *gin.Context) {
+	runRetention(c, false)
+}
+
+func runRetention(c *gin.Context, dryRun bool) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	run, err := harborSvc.RunRetention(uint(id), dryRun, c.GetString("username"))
+	if err != nil {
+		writeObservableError(c, retentionErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": run})
+}
+
+func ListRetentionRuns(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	runs, total, err := harborSvc.ListRetentionRuns(uint(id), page, pageSize)
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": runs, "total": total})
+}
+
+func retentionErrorStatus(err error) int {
+	switch obserr.Details(err)["code"] {
+	case "INVALID_PARAM":
+		return http.StatusBadRequest
+	case "RETENTION_POLICY_NOT_FOUND":
+		return http.StatusNotFound
+	case "RETENTION_RUNNING_UNKNOWN":
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
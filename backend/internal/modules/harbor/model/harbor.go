@@ -51,6 +51,8 @@ type Artifact struct {
 	PullTime string        `json:"pullTime"`
 	Tags     []ArtifactTag `json:"tags"`
 	Type     string        `json:"type"`
+	// ScanOverview is nil when the artifact has never been scanned.
+	ScanOverview *ScanOverview `json:"scanOverview,omitempty"`
 }
 
 // ArtifactTag represents an image tag
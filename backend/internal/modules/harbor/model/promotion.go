@@ -0,0 +1,81 @@
+package model
+
+import "time"
+
+// Promotion methods. Within one Harbor instance the artifact is copied with
+// Harbor's copy API; across instances blobs and manifests are copied through
+// the registry v2 API.
+const (
+	PromotionMethodCopy     = "harbor-copy"
+	PromotionMethodBlobCopy = "blob-copy"
+)
+
+// Promotion statuses.
+const (
+	PromotionPendingApproval = "pending_approval"
+	PromotionRunning         = "running"
+	PromotionSuccess         = "success"
+	PromotionFailed          = "failed"
+	PromotionRejected        = "rejected"
+)
+
+// PromotionRequest asks to copy Source:Tag to Target:TargetTag. TargetTag
+// defaults to Tag.
+type PromotionRequest struct {
+	SourceConfigID   uint   `json:"sourceConfigId"`
+	SourceProject    string `json:"sourceProject"`
+	SourceRepository string `json:"sourceRepository"`
+	Tag              string `json:"tag"`
+	TargetConfigID   uint   `json:"targetConfigId"`
+	TargetProject    string `json:"targetProject"`
+	TargetRepository string `json:"targetRepository"`
+	TargetTag        string `json:"targetTag"`
+	// RequireApproval holds the promotion until a workflow order is approved.
+	RequireApproval bool `json:"requireApproval"`
+	ApprovalLevels  int  `json:"approvalLevels"`
+}
+
+// PromotionStats counts what a blob copy transferred.
+type PromotionStats struct {
+	BlobsCopied  int   `json:"blobsCopied"`
+	BlobsSkipped int   `json:"blobsSkipped"`
+	BytesCopied  int64 `json:"bytesCopied"`
+}
+
+// Promotion records one promotion and its outcome.
+type Promotion struct {
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	TenantID         uint           `gorm:"index" json:"tenantId"`
+	SourceConfigID   uint           `gorm:"index;not null" json:"sourceConfigId"`
+	SourceProject    string         `gorm:"size:255;not null" json:"sourceProject"`
+	SourceRepository string         `gorm:"size:255;not null" json:"sourceRepository"`
+	Tag              string         `gorm:"size:128;not null" json:"tag"`
+	TargetConfigID   uint           `gorm:"index;not null" json:"targetConfigId"`
+	TargetProject    string         `gorm:"size:255;not null" json:"targetProject"`
+	TargetRepository string         `gorm:"size:255;not null" json:"targetRepository"`
+	TargetTag        string         `gorm:"size:128;not null" json:"targetTag"`
+	Method           string         `gorm:"size:32" json:"method"`
+	Digest           string         `gorm:"size:128" json:"digest"`
+	Status           string         `gorm:"size:32;index" json:"status"`
+	Message          string         `gorm:"type:text" json:"message"`
+	OrderID          uint           `gorm:"index" json:"orderId"`
+	RequestedBy      string         `gorm:"size:128" json:"requestedBy"`
+	RequestedByID    uint           `json:"requestedById"`
+	Stats            PromotionStats `gorm:"embedded" json:"stats"`
+	StartedAt        *time.Time     `json:"startedAt"`
+	FinishedAt       *time.Time     `json:"finishedAt"`
+	CreatedAt        time.Time      `json:"createdAt"`
+	UpdatedAt        time.Time      `json:"updatedAt"`
+}
+
+func (Promotion) TableName() string { return "harbor_promotions" }
+
+// Source returns the source image as project/repository:tag.
+func (p *Promotion) Source() string {
+	return p.SourceProject + "/" + p.SourceRepository + ":" + p.Tag
+}
+
+// Target returns the target image as project/repository:tag.
+func (p *Promotion) Target() string {
+	return p.TargetProject + "/" + p.TargetRepository + ":" + p.TargetTag
+}
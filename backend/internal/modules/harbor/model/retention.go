@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Retention actions for an artifact.
+const (
+	RetentionKeep   = "keep"
+	RetentionDelete = "delete"
+)
+
+// Retention run statuses. A run is partial when some deletions failed.
+const (
+	RetentionRunSuccess = "success"
+	RetentionRunPartial = "partial"
+	RetentionRunFailed  = "failed"
+)
+
+// RetentionPolicy cleans up old artifacts of a project, or of one repository
+// when Repository is set. An artifact is kept if any rule keeps it:
+//   - tagged artifacts among the KeepLastN most recently pushed,
+//   - artifacts with a tag matching one of KeepTagPatterns (glob, e.g. release-*),
+//   - untagged artifacts younger than UntaggedOlderThanDays,
+//   - with ProtectRunning, artifacts used by a pod in any registered cluster.
+//
+// Tagged artifacts are only deleted when KeepLastN or KeepTagPatterns is set,
+// untagged ones only when UntaggedOlderThanDays is set.
+type RetentionPolicy struct {
+	ID                    uint           `gorm:"primaryKey" json:"id"`
+	Name                  string         `gorm:"size:128;not null" json:"name"`
+	ConfigID              uint           `gorm:"index;not null" json:"configId"`
+	Project               string         `gorm:"size:255;not null" json:"project"`
+	Repository            string         `gorm:"size:255" json:"repository"`
+	KeepLastN             int            `json:"keepLastN"`
+	KeepTagPatterns       []string       `gorm:"serializer:json;type:text" json:"keepTagPatterns"`
+	UntaggedOlderThanDays int            `json:"untaggedOlderThanDays"`
+	ProtectRunning        bool           `json:"protectRunning"`
+	Enabled               bool           `json:"enabled"`
+	LastRunAt             *time.Time     `json:"lastRunAt"`
+	CreatedAt             time.Time      `json:"createdAt"`
+	UpdatedAt             time.Time      `json:"updatedAt"`
+	DeletedAt             gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (RetentionPolicy) TableName() string { return "harbor_retention_policies" }
+
+// RetentionCandidate is the decision for one artifact.
+type RetentionCandidate struct {
+	Repository string   `json:"repository"`
+	Digest     string   `json:"digest"`
+	Tags       []string `json:"tags"`
+	PushTime   string   `json:"pushTime"`
+	Action     string   `json:"action"`
+	Reason     string   `json:"reason"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// RetentionRun records a dry run or an execution of a policy.
+type RetentionRun struct {
+	ID          uint                 `gorm:"primaryKey" json:"id"`
+	PolicyID    uint                 `gorm:"index;not null" json:"policyId"`
+	DryRun      bool                 `json:"dryRun"`
+	TriggeredBy string               `gorm:"size:128" json:"triggeredBy"`
+	Status      string               `gorm:"size:32" json:"status"`
+	Message     string               `gorm:"type:text" json:"message"`
+	Kept        int                  `json:"kept"`
+	Deleted     int                  `json:"deleted"`
+	Failed      int                  `json:"failed"`
+	Items       []RetentionCandidate `gorm:"serializer:json;type:text" json:"items"`
+	StartedAt   time.Time            `json:"startedAt"`
+	FinishedAt  time.Time            `json:"finishedAt"`
+}
+
+func (RetentionRun) TableName() string { return "harbor_retention_runs" }
@@ -0,0 +1,65 @@
+package model
+
+// Vulnerability severities as Harbor reports them.
+const (
+	SeverityCritical = "Critical"
+	SeverityHigh     = "High"
+	SeverityMedium   = "Medium"
+	SeverityLow      = "Low"
+	SeverityUnknown  = "Unknown"
+)
+
+// ScanStatusSuccess is the scan status of a finished, usable report.
+const ScanStatusSuccess = "Success"
+
+// ScanOverview summarises the latest vulnerability scan of an artifact.
+type ScanOverview struct {
+	ReportID        string         `json:"reportId"`
+	Status          string         `json:"status"`
+	Severity        string         `json:"severity"`
+	CompletePercent int            `json:"completePercent"`
+	StartTime       string         `json:"startTime"`
+	EndTime         string         `json:"endTime"`
+	Scanner         string         `json:"scanner"`
+	Total           int            `json:"total"`
+	Fixable         int            `json:"fixable"`
+	Summary         map[string]int `json:"summary"`
+}
+
+// Vulnerability is one finding of a scan report.
+type Vulnerability struct {
+	ID          string   `json:"id"`
+	Package     string   `json:"package"`
+	Version     string   `json:"version"`
+	FixVersion  string   `json:"fixVersion"`
+	Severity    string   `json:"severity"`
+	Description string   `json:"description"`
+	Links       []string `json:"links"`
+}
+
+// VulnerabilityReport is the full scan report of an artifact, most severe
+// findings first.
+type VulnerabilityReport struct {
+	GeneratedAt     string          `json:"generatedAt"`
+	Scanner         string          `json:"scanner"`
+	Severity        string          `json:"severity"`
+	Summary         map[string]int  `json:"summary"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// ScanPolicy decides whether an artifact may be deployed. A negative maximum
+// means no limit.
+type ScanPolicy struct {
+	MaxCritical int `json:"maxCritical"`
+	MaxHigh     int `json:"maxHigh"`
+	// RequireScan blocks artifacts without a successful scan.
+	RequireScan bool `json:"requireScan"`
+}
+
+// ScanDecision is the outcome of checking an artifact against a ScanPolicy.
+type ScanDecision struct {
+	Allowed  bool          `json:"allowed"`
+	Reasons  []string      `json:"reasons,omitempty"`
+	Policy   ScanPolicy    `json:"policy"`
+	Overview *ScanOverview `json:"overview,omitempty"`
+}
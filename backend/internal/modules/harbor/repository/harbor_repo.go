@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -20,12 +21,19 @@ const op = "harbor/repository"
 type HarborRepo struct {
 	db         *gorm.DB
 	httpClient *http.Client
+	// transferClient moves blobs, which can take far longer than an API
+	// call, so it only bounds the wait for response headers.
+	transferClient *http.Client
 }
 
 func NewHarborRepo(db *gorm.DB) *HarborRepo {
 	return &HarborRepo{
 		db:         db,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
+		transferClient: &http.Client{Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: 60 * time.Second,
+		}},
 	}
 }
 
@@ -67,13 +75,36 @@ func (r *HarborRepo) DeleteConfig(id uint) error {
 // --- Harbor API helpers ---
 
 func (r *HarborRepo) harborRequest(cfg *model.HarborConfig, method, path string, result interface{}) error {
+	return r.harborRequestWithHeaders(cfg, method, path, nil, result)
+}
+
+func (r *HarborRepo) harborRequestWithHeaders(cfg *model.HarborConfig, method, path string, headers map[string]string, result interface{}) error {
+	return r.harborSend(cfg, method, path, headers, nil, result)
+}
+
+// harborSend is harborRequestWithHeaders with a JSON request body.
+func (r *HarborRepo) harborSend(cfg *model.HarborConfig, method, path string, headers map[string]string, body, result interface{}) error {
 	u := strings.TrimRight(cfg.URL, "/") + "/api/v2.0" + path
-	req, err := http.NewRequest(method, u, nil)
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return obserr.Wrap("HARBOR_REQUEST_FAILED", op, "failed to encode request", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u, reader)
 	if err != nil {
 		return obserr.Wrap("HARBOR_REQUEST_FAILED", op, "failed to build request", err)
 	}
 	req.SetBasicAuth(cfg.Username, cfg.Password)
 	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
 	resp, err := r.httpClient.Do(req)
 	if err != nil {
 		return obserr.Wrap("HARBOR_CONNECT_FAILED", op, "cannot reach harbor server", err)
@@ -85,9 +116,12 @@ func (r *HarborRepo) harborRequest(cfg *model.HarborConfig, method, path string,
 	if resp.StatusCode == 404 {
 		return obserr.New("HARBOR_NOT_FOUND", op, "resource not found on harbor")
 	}
+	if resp.StatusCode == 409 {
+		return obserr.New("HARBOR_CONFLICT", op, "resource already exists on harbor")
+	}
 	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return obserr.New("HARBOR_REQUEST_FAILED", op, fmt.Sprintf("harbor returned %d: %s", resp.StatusCode, string(body)))
+		msg, _ := io.ReadAll(resp.Body)
+		return obserr.New("HARBOR_REQUEST_FAILED", op, fmt.Sprintf("harbor returned %d: %s", resp.StatusCode, string(msg)))
 	}
 	if result != nil {
 		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
@@ -167,16 +201,37 @@ func (r *HarborRepo) ListArtifacts(configID uint, projectName, repoName string,
 	params := url.Values{}
 	params.Set("page", fmt.Sprintf("%d", page))
 	params.Set("page_size", fmt.Sprintf("%d", pageSize))
+	params.Set("with_tag", "true")
+	params.Set("with_scan_overview", "true")
 
-	var artifacts []model.Artifact
+	var raw []harborArtifact
 	path := fmt.Sprintf("/projects/%s/repositories/%s/artifacts?%s",
 		url.PathEscape(projectName), url.PathEscape(repoName), params.Encode())
-	if err := r.harborRequest(cfg, "GET", path, &artifacts); err != nil {
+	if err := r.harborRequestWithHeaders(cfg, "GET", path, scanReportHeaders, &raw); err != nil {
 		return nil, 0, err
 	}
+	artifacts := make([]model.Artifact, 0, len(raw))
+	for _, a := range raw {
+		artifacts = append(artifacts, a.toModel())
+	}
 	return artifacts, int64(len(artifacts)), nil
 }
 
+func (r *HarborRepo) GetArtifact(configID uint, projectName, repoName, reference string) (*model.Artifact, error) {
+	cfg, err := r.GetConfig(configID)
+	if err != nil {
+		return nil, obserr.Wrap("HARBOR_CONFIG_NOT_FOUND", op, "config not found", err)
+	}
+
+	var raw harborArtifact
+	path := artifactPath(projectName, repoName, reference) + "?with_tag=true&with_scan_overview=true"
+	if err := r.harborRequestWithHeaders(cfg, "GET", path, scanReportHeaders, &raw); err != nil {
+		return nil, err
+	}
+	artifact := raw.toModel()
+	return &artifact, nil
+}
+
 // --- Delete artifact tag ---
 
 func (r *HarborRepo) DeleteArtifact(configID uint, projectName, repoName, reference string) error {
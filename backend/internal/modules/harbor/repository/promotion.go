@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"devops-platform/internal/modules/harbor/model"
+	"devops-platform/internal/pkg/obserr"
+
+	"gorm.io/gorm"
+)
+
+// --- Promotion history ---
+
+func (r *HarborRepo) SavePromotion(p *model.Promotion) error {
+	if err := r.db.Save(p).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "save promotion failed", err)
+	}
+	return nil
+}
+
+func (r *HarborRepo) GetPromotion(tenantID, id uint) (*model.Promotion, error) {
+	var p model.Promotion
+	if err := r.db.Where("tenant_id = ?", tenantID).First(&p, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, obserr.New("PROMOTION_NOT_FOUND", op, "promotion not found")
+		}
+		return nil, obserr.Wrap("DB_ERROR", op, "get promotion failed", err)
+	}
+	return &p, nil
+}
+
+// ClaimPromotion moves a promotion from one status to another and reports
+// whether it did, so that only one caller gets to run it.
+func (r *HarborRepo) ClaimPromotion(id uint, from, to string) (bool, error) {
+	res := r.db.Model(&model.Promotion{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	if res.Error != nil {
+		return false, obserr.Wrap("DB_ERROR", op, "update promotion status failed", res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *HarborRepo) ListPromotions(tenantID uint, status string, page, pageSize int) ([]model.Promotion, int64, error) {
+	var items []model.Promotion
+	var total int64
+	q := r.db.Model(&model.Promotion{}).Where("tenant_id = ?", tenantID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	q.Count(&total)
+	if err := q.Offset((page - 1) * pageSize).Limit(pageSize).Order("id DESC").Find(&items).Error; err != nil {
+		return nil, 0, obserr.Wrap("DB_ERROR", op, "list promotions failed", err)
+	}
+	return items, total, nil
+}
+
+// --- Harbor copy API ---
+
+// CopyArtifact copies an artifact into another project or repository of the
+// same Harbor instance. Harbor copies the blobs server side.
+func (r *HarborRepo) CopyArtifact(configID uint, srcProject, srcRepo, reference, dstProject, dstRepo string) error {
+	cfg, err := r.GetConfig(configID)
+	if err != nil {
+		return obserr.Wrap("HARBOR_CONFIG_NOT_FOUND", op, "config not found", err)
+	}
+	sep := ":"
+	if isDigest(reference) {
+		sep = "@"
+	}
+	params := url.Values{}
+	params.Set("from", srcProject+"/"+srcRepo+sep+reference)
+	path := fmt.Sprintf("/projects/%s/repositories/%s/artifacts?%s",
+		url.PathEscape(dstProject), url.PathEscape(dstRepo), params.Encode())
+	return r.harborRequest(cfg, "POST", path, nil)
+}
+
+// AddTag tags an existing artifact. Harbor answers HARBOR_CONFLICT when the
+// tag already exists in the repository.
+func (r *HarborRepo) AddTag(configID uint, projectName, repoName, reference, tag string) error {
+	cfg, err := r.GetConfig(configID)
+	if err != nil {
+		return obserr.Wrap("HARBOR_CONFIG_NOT_FOUND", op, "config not found", err)
+	}
+	body := map[string]string{"name": tag}
+	return r.harborSend(cfg, "POST", artifactPath(projectName, repoName, reference)+"/tags", nil, body, nil)
+}
+
+// CopyImage copies project/repo:reference from one Harbor instance to
+// dstProject/dstRepo:tag on another through the registry v2 API. Blobs the
+// target already has are skipped. It returns the digest of the copied
+// manifest.
+func (r *HarborRepo) CopyImage(srcConfigID uint, srcProject, srcRepo, reference string, dstConfigID uint, dstProject, dstRepo, tag string) (string, model.PromotionStats, error) {
+	var stats model.PromotionStats
+	src, err := r.GetConfig(srcConfigID)
+	if err != nil {
+		return "", stats, obserr.Wrap("HARBOR_CONFIG_NOT_FOUND", op, "source config not found", err)
+	}
+	dst, err := r.GetConfig(dstConfigID)
+	if err != nil {
+		return "", stats, obserr.Wrap("HARBOR_CONFIG_NOT_FOUND", op, "target config not found", err)
+	}
+	from := newRegistryClient(src, r.transferClient, srcProject+"/"+srcRepo, "pull")
+	to := newRegistryClient(dst, r.transferClient, dstProject+"/"+dstRepo, "pull,push")
+
+	body, mediaType, err := from.getManifest(reference)
+	if err != nil {
+		return "", stats, err
+	}
+	if err := copyManifest(from, to, body, mediaType, tag, &stats); err != nil {
+		return "", stats, err
+	}
+	return digestOf(body), stats, nil
+}
+
+// copyManifest copies what a manifest references, then the manifest itself.
+// Image indexes are copied child manifest first.
+func copyManifest(from, to *registryClient, body []byte, mediaType, reference string, stats *model.PromotionStats) error {
+	m, err := parseManifest(body)
+	if err != nil {
+		return err
+	}
+	for _, child := range m.Manifests {
+		childBody, childType, err := from.getManifest(child.Digest)
+		if err != nil {
+			return err
+		}
+		if err := copyManifest(from, to, childBody, childType, child.Digest, stats); err != nil {
+			return err
+		}
+	}
+	blobs := m.Layers
+	if m.Config != nil {
+		blobs = append([]descriptor{*m.Config}, blobs...)
+	}
+	for _, blob := range blobs {
+		if err := copyBlob(from, to, blob, stats); err != nil {
+			return err
+		}
+	}
+	return to.putManifest(reference, mediaType, body)
+}
+
+func copyBlob(from, to *registryClient, blob descriptor, stats *model.PromotionStats) error {
+	exists, err := to.hasBlob(blob.Digest)
+	if err != nil {
+		return err
+	}
+	if exists {
+		stats.BlobsSkipped++
+		return nil
+	}
+	content, size, err := from.getBlob(blob.Digest)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	if size <= 0 {
+		size = blob.Size
+	}
+	if err := to.uploadBlob(blob.Digest, content, size); err != nil {
+		return err
+	}
+	stats.BlobsCopied++
+	stats.BytesCopied += size
+	return nil
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"devops-platform/internal/modules/harbor/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// manifestAccept lists the manifest formats a promotion can copy.
+var manifestAccept = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// manifest covers both image manifests (Config, Layers) and image indexes
+// (Manifests).
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    *descriptor  `json:"config"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+func parseManifest(body []byte) (*manifest, error) {
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, obserr.Wrap("HARBOR_PARSE_FAILED", op, "failed to parse manifest", err)
+	}
+	return &m, nil
+}
+
+func isDigest(reference string) bool {
+	return strings.HasPrefix(reference, "sha256:")
+}
+
+func digestOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// registryClient talks to the registry v2 API of one Harbor instance for a
+// single repository. Harbor's registry wants a bearer token from its token
+// service; the client gets one with the config's credentials on first use.
+type registryClient struct {
+	cfg        *model.HarborConfig
+	httpClient *http.Client
+	repo       string
+	actions    string
+	token      string
+	authorized bool
+}
+
+func newRegistryClient(cfg *model.HarborConfig, httpClient *http.Client, repo, actions string) *registryClient {
+	return &registryClient{cfg: cfg, httpClient: httpClient, repo: repo, actions: actions}
+}
+
+func (c *registryClient) baseURL() string {
+	return strings.TrimRight(c.cfg.URL, "/")
+}
+
+// authorize pings /v2/ and, if the registry answers with a bearer challenge,
+// fetches a token scoped to the repository.
+func (c *registryClient) authorize() error {
+	if c.authorized {
+		return nil
+	}
+	resp, err := c.httpClient.Get(c.baseURL() + "/v2/")
+	if err != nil {
+		return obserr.Wrap("HARBOR_CONNECT_FAILED", op, "cannot reach registry", err)
+	}
+	resp.Body.Close()
+	challenge := resp.Header.Get("WWW-Authenticate")
+	if resp.StatusCode == http.StatusUnauthorized && strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		token, err := c.fetchToken(parseChallenge(challenge[len("bearer "):]))
+		if err != nil {
+			return err
+		}
+		c.token = token
+	}
+	c.authorized = true
+	return nil
+}
+
+func (c *registryClient) fetchToken(params map[string]string) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", obserr.New("HARBOR_AUTH_FAILED", op, "registry challenge has no realm")
+	}
+	q := url.Values{}
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	q.Set("scope", fmt.Sprintf("repository:%s:%s", c.repo, c.actions))
+	req, err := http.NewRequest(http.MethodGet, realm+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", obserr.Wrap("HARBOR_REQUEST_FAILED", op, "failed to build token request", err)
+	}
+	req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", obserr.Wrap("HARBOR_CONNECT_FAILED", op, "cannot reach registry token service", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", obserr.New("HARBOR_AUTH_FAILED", op, fmt.Sprintf("registry token service returned %d", resp.StatusCode))
+	}
+	var out struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", obserr.Wrap("HARBOR_PARSE_FAILED", op, "failed to parse registry token", err)
+	}
+	if out.Token == "" {
+		out.Token = out.AccessToken
+	}
+	return out.Token, nil
+}
+
+// parseChallenge parses the key="value" pairs of a WWW-Authenticate header.
+func parseChallenge(s string) map[string]string {
+	params := map[string]string{}
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = s[eq+1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = strings.TrimSpace(s[:end]), s[end:]
+		}
+		params[key] = value
+		s = strings.TrimLeft(s, ", ")
+	}
+	return params
+}
+
+// do sends a request against /v2/<repo>/<path>, or against path itself when
+// it is an absolute URL (upload locations).
+func (c *registryClient) do(method, path string, headers map[string]string, body io.Reader, size int64) (*http.Response, error) {
+	if err := c.authorize(); err != nil {
+		return nil, err
+	}
+	u := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		u = c.baseURL() + "/v2/" + c.repo + path
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, obserr.Wrap("HARBOR_REQUEST_FAILED", op, "failed to build registry request", err)
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	} else {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, obserr.Wrap("HARBOR_CONNECT_FAILED", op, "cannot reach registry", err)
+	}
+	return resp, nil
+}
+
+// expect returns an error, closing resp, unless its status is one of codes.
+func expect(resp *http.Response, what string, codes ...int) error {
+	for _, code := range codes {
+		if resp.StatusCode == code {
+			return nil
+		}
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return obserr.New("HARBOR_AUTH_FAILED", op, fmt.Sprintf("%s: registry denied access", what))
+	case http.StatusNotFound:
+		return obserr.New("HARBOR_NOT_FOUND", op, fmt.Sprintf("%s: not found on registry", what))
+	}
+	return obserr.New("HARBOR_REGISTRY_FAILED", op, fmt.Sprintf("%s: registry returned %d: %s", what, resp.StatusCode, string(msg)))
+}
+
+func (c *registryClient) getManifest(reference string) ([]byte, string, error) {
+	resp, err := c.do(http.MethodGet, "/manifests/"+reference, map[string]string{"Accept": manifestAccept}, nil, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := expect(resp, "get manifest "+reference, http.StatusOK); err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", obserr.Wrap("HARBOR_REGISTRY_FAILED", op, "failed to read manifest", err)
+	}
+	mediaType := resp.Header.Get("Content-Type")
+	if m, err := parseManifest(body); err == nil && m.MediaType != "" {
+		mediaType = m.MediaType
+	}
+	return body, mediaType, nil
+}
+
+func (c *registryClient) putManifest(reference, mediaType string, body []byte) error {
+	resp, err := c.do(http.MethodPut, "/manifests/"+reference, map[string]string{"Content-Type": mediaType},
+		bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return err
+	}
+	if err := expect(resp, "put manifest "+reference, http.StatusCreated, http.StatusOK); err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *registryClient) hasBlob(digest string) (bool, error) {
+	resp, err := c.do(http.MethodHead, "/blobs/"+digest, nil, nil, 0)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, expect(resp, "check blob "+digest)
+}
+
+// getBlob streams a blob. The caller closes the returned reader.
+func (c *registryClient) getBlob(digest string) (io.ReadCloser, int64, error) {
+	resp, err := c.do(http.MethodGet, "/blobs/"+digest, nil, nil, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := expect(resp, "get blob "+digest, http.StatusOK); err != nil {
+		return nil, 0, err
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// uploadBlob pushes a blob with a monolithic upload: POST opens the upload
+// session, PUT to its location with the digest completes it.
+func (c *registryClient) uploadBlob(digest string, content io.Reader, size int64) error {
+	resp, err := c.do(http.MethodPost, "/blobs/uploads/", nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	if err := expect(resp, "start upload "+digest, http.StatusAccepted); err != nil {
+		return err
+	}
+	resp.Body.Close()
+	location, err := resp.Location()
+	if err != nil {
+		return obserr.Wrap("HARBOR_REGISTRY_FAILED", op, "registry returned no upload location", err)
+	}
+	q := location.Query()
+	q.Set("digest", digest)
+	location.RawQuery = q.Encode()
+
+	resp, err = c.do(http.MethodPut, location.String(), map[string]string{"Content-Type": "application/octet-stream"}, content, size)
+	if err != nil {
+		return err
+	}
+	if err := expect(resp, "upload blob "+digest, http.StatusCreated); err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"devops-platform/internal/modules/harbor/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// --- Retention policies ---
+
+func (r *HarborRepo) ListRetentionPolicies(configID uint) ([]model.RetentionPolicy, error) {
+	var policies []model.RetentionPolicy
+	q := r.db.Model(&model.RetentionPolicy{})
+	if configID > 0 {
+		q = q.Where("config_id = ?", configID)
+	}
+	if err := q.Order("id").Find(&policies).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list retention policies failed", err)
+	}
+	return policies, nil
+}
+
+func (r *HarborRepo) ListEnabledRetentionPolicies() ([]model.RetentionPolicy, error) {
+	var policies []model.RetentionPolicy
+	if err := r.db.Where("enabled = ?", true).Order("id").Find(&policies).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list retention policies failed", err)
+	}
+	return policies, nil
+}
+
+func (r *HarborRepo) GetRetentionPolicy(id uint) (*model.RetentionPolicy, error) {
+	var policy model.RetentionPolicy
+	if err := r.db.First(&policy, id).Error; err != nil {
+		return nil, obserr.Wrap("RETENTION_POLICY_NOT_FOUND", op, "retention policy not found", err)
+	}
+	return &policy, nil
+}
+
+func (r *HarborRepo) SaveRetentionPolicy(policy *model.RetentionPolicy) error {
+	if err := r.db.Save(policy).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "save retention policy failed", err)
+	}
+	return nil
+}
+
+func (r *HarborRepo) DeleteRetentionPolicy(id uint) error {
+	if err := r.db.Delete(&model.RetentionPolicy{}, id).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "delete retention policy failed", err)
+	}
+	return nil
+}
+
+// --- Retention runs ---
+
+func (r *HarborRepo) SaveRetentionRun(run *model.RetentionRun) error {
+	if err := r.db.Save(run).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "save retention run failed", err)
+	}
+	return nil
+}
+
+func (r *HarborRepo) ListRetentionRuns(policyID uint, page, pageSize int) ([]model.RetentionRun, int64, error) {
+	var runs []model.RetentionRun
+	var total int64
+	q := r.db.Model(&model.RetentionRun{}).Where("policy_id = ?", policyID)
+	q.Count(&total)
+	if err := q.Offset((page - 1) * pageSize).Limit(pageSize).Order("id DESC").Find(&runs).Error; err != nil {
+		return nil, 0, obserr.Wrap("DB_ERROR", op, "list retention runs failed", err)
+	}
+	return runs, total, nil
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"fmt"
+	"net/url"
+
+	"devops-platform/internal/modules/harbor/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// scanReportHeaders asks Harbor for the vulnerability report formats we can
+// read; without it scan_overview is left out of artifact responses.
+var scanReportHeaders = map[string]string{
+	"X-Accept-Vulnerabilities": "application/vnd.security.vulnerability.report; version=1.1, " +
+		"application/vnd.scanner.adapter.vuln.report.harbor+json; version=1.0",
+}
+
+// harborArtifact is an artifact as the Harbor API returns it.
+type harborArtifact struct {
+	ID       int    `json:"id"`
+	Digest   string `json:"digest"`
+	Size     int64  `json:"size"`
+	PushTime string `json:"push_time"`
+	PullTime string `json:"pull_time"`
+	Type     string `json:"type"`
+	Tags     []struct {
+		ID        int    `json:"id"`
+		Name      string `json:"name"`
+		PushTime  string `json:"push_time"`
+		PullTime  string `json:"pull_time"`
+		Immutable bool   `json:"immutable"`
+	} `json:"tags"`
+	// ScanOverview is keyed by report MIME type.
+	ScanOverview map[string]harborScanOverview `json:"scan_overview"`
+}
+
+type harborScanner struct {
+	Name    string `json:"name"`
+	Vendor  string `json:"vendor"`
+	Version string `json:"version"`
+}
+
+func (s *harborScanner) String() string {
+	if s == nil {
+		return ""
+	}
+	if s.Version == "" {
+		return s.Name
+	}
+	return s.Name + " " + s.Version
+}
+
+type harborScanOverview struct {
+	ReportID        string         `json:"report_id"`
+	ScanStatus      string         `json:"scan_status"`
+	Severity        string         `json:"severity"`
+	CompletePercent int            `json:"complete_percent"`
+	StartTime       string         `json:"start_time"`
+	EndTime         string         `json:"end_time"`
+	Scanner         *harborScanner `json:"scanner"`
+	Summary         *struct {
+		Total   int            `json:"total"`
+		Fixable int            `json:"fixable"`
+		Summary map[string]int `json:"summary"`
+	} `json:"summary"`
+}
+
+func (a harborArtifact) toModel() model.Artifact {
+	artifact := model.Artifact{
+		ID: a.ID, Digest: a.Digest, Size: a.Size, PushTime: a.PushTime, PullTime: a.PullTime, Type: a.Type,
+	}
+	for _, t := range a.Tags {
+		artifact.Tags = append(artifact.Tags, model.ArtifactTag{
+			ID: t.ID, Name: t.Name, PushTime: t.PushTime, PullTime: t.PullTime, Immutable: t.Immutable,
+		})
+	}
+	// Harbor returns one overview per accepted report type; they describe the same scan.
+	for _, o := range a.ScanOverview {
+		overview := &model.ScanOverview{
+			ReportID:        o.ReportID,
+			Status:          o.ScanStatus,
+			Severity:        o.Severity,
+			CompletePercent: o.CompletePercent,
+			StartTime:       o.StartTime,
+			EndTime:         o.EndTime,
+			Scanner:         o.Scanner.String(),
+			Summary:         map[string]int{},
+		}
+		if o.Summary != nil {
+			overview.Total = o.Summary.Total
+			overview.Fixable = o.Summary.Fixable
+			for k, v := range o.Summary.Summary {
+				overview.Summary[k] = v
+			}
+		}
+		artifact.ScanOverview = overview
+		break
+	}
+	return artifact
+}
+
+func artifactPath(projectName, repoName, reference string) string {
+	return fmt.Sprintf("/projects/%s/repositories/%s/artifacts/%s",
+		url.PathEscape(projectName), url.PathEscape(repoName), url.PathEscape(reference))
+}
+
+// --- Vulnerability scanning ---
+
+// ScanArtifact asks Harbor's scanner to scan the artifact. The scan runs
+// asynchronously; its progress shows in the artifact's scan overview.
+func (r *HarborRepo) ScanArtifact(configID uint, projectName, repoName, reference string) error {
+	cfg, err := r.GetConfig(configID)
+	if err != nil {
+		return obserr.Wrap("HARBOR_CONFIG_NOT_FOUND", op, "config not found", err)
+	}
+	return r.harborRequest(cfg, "POST", artifactPath(projectName, repoName, reference)+"/scan", nil)
+}
+
+// GetVulnerabilityReport returns the artifact's latest report, or nil when it
+// has not been scanned.
+func (r *HarborRepo) GetVulnerabilityReport(configID uint, projectName, repoName, reference string) (*model.VulnerabilityReport, error) {
+	cfg, err := r.GetConfig(configID)
+	if err != nil {
+		return nil, obserr.Wrap("HARBOR_CONFIG_NOT_FOUND", op, "config not found", err)
+	}
+	var reports map[string]struct {
+		GeneratedAt     string         `json:"generated_at"`
+		Scanner         *harborScanner `json:"scanner"`
+		Severity        string         `json:"severity"`
+		Vulnerabilities []struct {
+			ID          string   `json:"id"`
+			Package     string   `json:"package"`
+			Version     string   `json:"version"`
+			FixVersion  string   `json:"fix_version"`
+			Severity    string   `json:"severity"`
+			Description string   `json:"description"`
+			Links       []string `json:"links"`
+		} `json:"vulnerabilities"`
+	}
+	path := artifactPath(projectName, repoName, reference) + "/additions/vulnerabilities"
+	if err := r.harborRequestWithHeaders(cfg, "GET", path, scanReportHeaders, &reports); err != nil {
+		return nil, err
+	}
+	for _, rep := range reports {
+		report := &model.VulnerabilityReport{
+			GeneratedAt: rep.GeneratedAt,
+			Scanner:     rep.Scanner.String(),
+			Severity:    rep.Severity,
+		}
+		for _, v := range rep.Vulnerabilities {
+			report.Vulnerabilities = append(report.Vulnerabilities, model.Vulnerability{
+				ID: v.ID, Package: v.Package, Version: v.Version, FixVersion: v.FixVersion,
+				Severity: v.Severity, Description: v.Description, Links: v.Links,
+			})
+		}
+		return report, nil
+	}
+	return nil, nil
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"context"
+	"sync"
+
 	"devops-platform/internal/modules/harbor/model"
 	"devops-platform/internal/modules/harbor/repository"
 	"devops-platform/internal/pkg/obserr"
@@ -11,11 +14,20 @@ import (
 const op = "harbor/service"
 
 type HarborService struct {
-	repo *repository.HarborRepo
+	repo   *repository.HarborRepo
+	policy model.ScanPolicy
+	// runningImages, audit and the executor fields serve retention policies.
+	runningImages RunningImagesFunc
+	audit         AuditFunc
+	retentionMu   sync.Mutex
+	cancel        context.CancelFunc
+	// approval gates promotions behind a workflow order.
+	approval        PromotionApproval
+	requireApproval bool
 }
 
 func NewHarborService(db *gorm.DB) *HarborService {
-	return &HarborService{repo: repository.NewHarborRepo(db)}
+	return &HarborService{repo: repository.NewHarborRepo(db), policy: DefaultScanPolicy}
 }
 
 func (s *HarborService) ListConfigs(page, pageSize int) ([]model.HarborConfig, int64, error) {
@@ -81,6 +93,13 @@ func (s *HarborService) ListArtifacts(configID uint, projectName, repoName strin
 	return s.repo.ListArtifacts(configID, projectName, repoName, page, pageSize)
 }
 
+func (s *HarborService) GetArtifact(configID uint, projectName, repoName, reference string) (*model.Artifact, error) {
+	if reference == "" {
+		return nil, obserr.New("INVALID_PARAM", op, "reference (tag or digest) is required")
+	}
+	return s.repo.GetArtifact(configID, projectName, repoName, reference)
+}
+
 func (s *HarborService) DeleteArtifact(configID uint, projectName, repoName, reference string) error {
 	if reference == "" {
 		return obserr.New("INVALID_PARAM", op, "reference (tag or digest) is required")
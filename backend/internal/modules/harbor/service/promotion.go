@@ -0,0 +1,243 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/harbor/model"
+	"devops-platform/internal/pkg/logger"
+	"devops-platform/internal/pkg/obserr"
+
+	"go.uber.org/zap"
+)
+
+// PromotionApproval connects promotions to the workflow engine. Request
+// creates and submits an order whose approval executes the promotion;
+// Rejected reports whether that order was turned down.
+type PromotionApproval struct {
+	Request  func(tenantID, userID uint, title, description string, levels int, promotionID uint) (orderID uint, err error)
+	Rejected func(tenantID, orderID uint) (bool, error)
+}
+
+// SetPromotionApproval sets the approval hooks. With requireApproval every
+// promotion waits for approval, whatever the request asks for.
+func (s *HarborService) SetPromotionApproval(approval PromotionApproval, requireApproval bool) {
+	s.approval = approval
+	s.requireApproval = requireApproval
+}
+
+// RequestPromotion records a promotion and runs it, or holds it for approval
+// when that is required.
+func (s *HarborService) RequestPromotion(tenantID, userID uint, username string, req model.PromotionRequest) (*model.Promotion, error) {
+	p, err := s.newPromotion(req)
+	if err != nil {
+		return nil, err
+	}
+	p.TenantID = tenantID
+	p.RequestedBy = username
+	p.RequestedByID = userID
+
+	if !req.RequireApproval && !s.requireApproval {
+		p.Status = model.PromotionRunning
+		if err := s.repo.SavePromotion(p); err != nil {
+			return nil, err
+		}
+		return p, s.runPromotion(p)
+	}
+
+	if s.approval.Request == nil {
+		return nil, obserr.New("PROMOTION_APPROVAL_UNAVAILABLE", op, "workflow approval is not configured")
+	}
+	p.Status = model.PromotionPendingApproval
+	if err := s.repo.SavePromotion(p); err != nil {
+		return nil, err
+	}
+	title := fmt.Sprintf("镜像晋级 %s -> %s", p.Source(), p.Target())
+	desc := fmt.Sprintf("申请人: %s\n源镜像: %s (%s)\n目标镜像: %s\n方式: %s", username, p.Source(), p.Digest, p.Target(), p.Method)
+	orderID, err := s.approval.Request(tenantID, userID, title, desc, req.ApprovalLevels, p.ID)
+	if err != nil {
+		s.finishPromotion(p, obserr.Wrap("PROMOTION_APPROVAL_FAILED", op, "failed to request approval", err))
+		return p, err
+	}
+	p.OrderID = orderID
+	return p, s.repo.SavePromotion(p)
+}
+
+// newPromotion validates a request and resolves the source artifact.
+func (s *HarborService) newPromotion(req model.PromotionRequest) (*model.Promotion, error) {
+	p := &model.Promotion{
+		SourceConfigID:   req.SourceConfigID,
+		SourceProject:    strings.TrimSpace(req.SourceProject),
+		SourceRepository: strings.Trim(strings.TrimSpace(req.SourceRepository), "/"),
+		Tag:              strings.TrimSpace(req.Tag),
+		TargetConfigID:   req.TargetConfigID,
+		TargetProject:    strings.TrimSpace(req.TargetProject),
+		TargetRepository: strings.Trim(strings.TrimSpace(req.TargetRepository), "/"),
+		TargetTag:        strings.TrimSpace(req.TargetTag),
+	}
+	if p.SourceConfigID == 0 || p.SourceProject == "" || p.SourceRepository == "" || p.Tag == "" {
+		return nil, obserr.New("INVALID_PARAM", op, "source config, project, repository and tag are required")
+	}
+	if p.TargetConfigID == 0 {
+		p.TargetConfigID = p.SourceConfigID
+	}
+	if p.TargetRepository == "" {
+		p.TargetRepository = p.SourceRepository
+	}
+	if p.TargetTag == "" {
+		p.TargetTag = p.Tag
+	}
+	if p.TargetProject == "" {
+		return nil, obserr.New("INVALID_PARAM", op, "target project is required")
+	}
+	if p.Source() == p.Target() && p.SourceConfigID == p.TargetConfigID {
+		return nil, obserr.New("INVALID_PARAM", op, "source and target are the same")
+	}
+	if _, err := s.repo.GetConfig(p.TargetConfigID); err != nil {
+		return nil, obserr.Wrap("HARBOR_CONFIG_NOT_FOUND", op, "target config not found", err)
+	}
+	artifact, err := s.repo.GetArtifact(p.SourceConfigID, p.SourceProject, p.SourceRepository, p.Tag)
+	if err != nil {
+		return nil, err
+	}
+	p.Digest = artifact.Digest
+	p.Method = model.PromotionMethodBlobCopy
+	if p.SourceConfigID == p.TargetConfigID {
+		p.Method = model.PromotionMethodCopy
+	}
+	return p, nil
+}
+
+// ExecutePromotion runs a promotion once its approval order went through.
+func (s *HarborService) ExecutePromotion(id, tenantID uint) error {
+	p, err := s.repo.GetPromotion(tenantID, id)
+	if err != nil {
+		return err
+	}
+	claimed, err := s.repo.ClaimPromotion(p.ID, model.PromotionPendingApproval, model.PromotionRunning)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return obserr.New("PROMOTION_NOT_PENDING", op, fmt.Sprintf("promotion is %s, not waiting for approval", p.Status))
+	}
+	p.Status = model.PromotionRunning
+	return s.runPromotion(p)
+}
+
+// runPromotion copies the artifact and records the outcome.
+func (s *HarborService) runPromotion(p *model.Promotion) error {
+	now := time.Now()
+	p.StartedAt = &now
+	if err := s.repo.SavePromotion(p); err != nil {
+		return err
+	}
+	var err error
+	if p.Method == model.PromotionMethodCopy {
+		err = s.copyWithinInstance(p)
+	} else {
+		var digest string
+		digest, p.Stats, err = s.repo.CopyImage(p.SourceConfigID, p.SourceProject, p.SourceRepository, p.Digest,
+			p.TargetConfigID, p.TargetProject, p.TargetRepository, p.TargetTag)
+		if err == nil && digest != p.Digest {
+			err = obserr.New("PROMOTION_DIGEST_MISMATCH", op, fmt.Sprintf("copied manifest %s does not match source %s", digest, p.Digest))
+		}
+	}
+	s.finishPromotion(p, err)
+	if s.audit != nil {
+		detail := fmt.Sprintf("source=%s digest=%s method=%s order=%d", p.Source(), p.Digest, p.Method, p.OrderID)
+		s.audit(p.RequestedBy, "Harbor 镜像晋级", p.Target(), detail, err)
+	}
+	return err
+}
+
+// copyWithinInstance uses Harbor's copy API, then makes sure the artifact
+// carries the target tag.
+func (s *HarborService) copyWithinInstance(p *model.Promotion) error {
+	if err := s.repo.CopyArtifact(p.SourceConfigID, p.SourceProject, p.SourceRepository, p.Digest,
+		p.TargetProject, p.TargetRepository); err != nil {
+		return err
+	}
+	copied, err := s.repo.GetArtifact(p.TargetConfigID, p.TargetProject, p.TargetRepository, p.Digest)
+	if err != nil {
+		return err
+	}
+	for _, t := range copied.Tags {
+		if t.Name == p.TargetTag {
+			return nil
+		}
+	}
+	if err := s.repo.AddTag(p.TargetConfigID, p.TargetProject, p.TargetRepository, p.Digest, p.TargetTag); err != nil {
+		if obserr.Details(err)["code"] == "HARBOR_CONFLICT" {
+			return obserr.New("PROMOTION_TAG_CONFLICT", op, fmt.Sprintf("tag %s already points to another artifact in %s/%s", p.TargetTag, p.TargetProject, p.TargetRepository))
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *HarborService) finishPromotion(p *model.Promotion, err error) {
+	now := time.Now()
+	p.FinishedAt = &now
+	p.Status = model.PromotionSuccess
+	p.Message = ""
+	if err != nil {
+		p.Status = model.PromotionFailed
+		p.Message = fmt.Sprint(obserr.Details(err)["message"])
+	}
+	if saveErr := s.repo.SavePromotion(p); saveErr != nil {
+		logger.Log.Error("Save promotion result failed", zap.Uint("promotion_id", p.ID), zap.Error(saveErr))
+	}
+}
+
+// --- History ---
+
+func (s *HarborService) GetPromotion(tenantID, id uint) (*model.Promotion, error) {
+	p, err := s.repo.GetPromotion(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	s.syncApproval(p)
+	return p, nil
+}
+
+func (s *HarborService) ListPromotions(tenantID uint, status string, page, pageSize int) ([]model.Promotion, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	items, total, err := s.repo.ListPromotions(tenantID, status, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range items {
+		s.syncApproval(&items[i])
+	}
+	return items, total, nil
+}
+
+// syncApproval marks a pending promotion rejected once its order is.
+// Approval needs no syncing: the order's callback runs the promotion.
+func (s *HarborService) syncApproval(p *model.Promotion) {
+	if p.Status != model.PromotionPendingApproval || p.OrderID == 0 || s.approval.Rejected == nil {
+		return
+	}
+	rejected, err := s.approval.Rejected(p.TenantID, p.OrderID)
+	if err != nil || !rejected {
+		return
+	}
+	claimed, err := s.repo.ClaimPromotion(p.ID, model.PromotionPendingApproval, model.PromotionRejected)
+	if err != nil || !claimed {
+		return
+	}
+	now := time.Now()
+	p.Status = model.PromotionRejected
+	p.Message = fmt.Sprintf("change order #%d was rejected", p.OrderID)
+	p.FinishedAt = &now
+	if err := s.repo.SavePromotion(p); err != nil {
+		logger.Log.Warn("Save rejected promotion failed", zap.Uint("promotion_id", p.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"devops-platform/internal/modules/harbor/model"
+	"devops-platform/internal/pkg/logger"
+	"devops-platform/internal/pkg/obserr"
+
+	"go.uber.org/zap"
+)
+
+func sha(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// fakeRegistry is a registry v2 API for one repository that hands out bearer
+// tokens the way Harbor does.
+type fakeRegistry struct {
+	mu        sync.Mutex
+	repo      string
+	blobs     map[string]string
+	manifests map[string]string
+	uploads   int
+}
+
+func (f *fakeRegistry) handler(t *testing.T, tokenURL func() string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.URL.Path == "/service/token" {
+			if u, p, _ := r.BasicAuth(); u != "admin" || p != "pw" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if want := "repository:" + f.repo + ":pull,push"; r.URL.Query().Get("scope") != want {
+				t.Errorf("token scope = %q, want %q", r.URL.Query().Get("scope"), want)
+			}
+			_, _ = w.Write([]byte(`{"token":"tok"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s",service="harbor-registry"`, tokenURL()))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		prefix := "/v2/" + f.repo
+		path := strings.TrimPrefix(r.URL.Path, prefix)
+		switch {
+		case r.Method == http.MethodHead && strings.HasPrefix(path, "/blobs/"):
+			if _, ok := f.blobs[strings.TrimPrefix(path, "/blobs/")]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPost && path == "/blobs/uploads/":
+			f.uploads++
+			w.Header().Set("Location", fmt.Sprintf("%s/blobs/uploads/u%d?_state=x", prefix, f.uploads))
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && strings.HasPrefix(path, "/blobs/uploads/"):
+			data, _ := io.ReadAll(r.Body)
+			digest := r.URL.Query().Get("digest")
+			if sha(string(data)) != digest || r.URL.Query().Get("_state") != "x" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f.blobs[digest] = string(data)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && strings.HasPrefix(path, "/manifests/"):
+			data, _ := io.ReadAll(r.Body)
+			var m struct {
+				Layers []struct{ Digest string } `json:"layers"`
+			}
+			_ = json.Unmarshal(data, &m)
+			for _, l := range m.Layers {
+				if _, ok := f.blobs[l.Digest]; !ok {
+					t.Errorf("manifest pushed before blob %s", l.Digest)
+				}
+			}
+			f.manifests[strings.TrimPrefix(path, "/manifests/")] = string(data)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+const (
+	configBlob = `{"architecture":"amd64"}`
+	layerOne   = "layer-one"
+	layerTwo   = "layer-two"
+)
+
+var imageManifest = fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json",
+"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":%q,"size":%d},
+"layers":[{"digest":%q,"size":%d},{"digest":%q,"size":%d}]}`,
+	sha(configBlob), len(configBlob), sha(layerOne), len(layerOne), sha(layerTwo), len(layerTwo))
+
+// setupPromotion serves the source image from a Harbor instance and returns
+// the id of a second instance to promote to.
+func setupPromotion(t *testing.T) (*HarborService, uint, uint, *fakeRegistry) {
+	t.Helper()
+	logger.Log = zap.NewNop()
+	blobs := map[string]string{sha(configBlob): configBlob, sha(layerOne): layerOne, sha(layerTwo): layerTwo}
+	svc, srcID := setupHarbor(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v2.0/projects/team/repositories/api/artifacts/v1":
+			_, _ = fmt.Fprintf(w, `{"digest":%q,"tags":[{"name":"v1"}]}`, sha(imageManifest))
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/v2/team/api/manifests/"+sha(imageManifest):
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			_, _ = w.Write([]byte(imageManifest))
+		case strings.HasPrefix(r.URL.Path, "/v2/team/api/blobs/"):
+			data, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/team/api/blobs/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(data))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	target := &fakeRegistry{
+		repo:      "prod/api",
+		blobs:     map[string]string{sha(configBlob): configBlob},
+		manifests: map[string]string{},
+	}
+	var srv *httptest.Server
+	srv = httptest.NewServer(target.handler(t, func() string { return srv.URL + "/service/token" }))
+	t.Cleanup(srv.Close)
+	cfg := &model.HarborConfig{Name: "prod", URL: srv.URL, Username: "admin", Password: "pw"}
+	if err := svc.repo.SaveConfig(cfg); err != nil {
+		t.Fatalf("save target config: %v", err)
+	}
+	return svc, srcID, cfg.ID, target
+}
+
+func TestPromotion_BlobCopyAcrossInstances(t *testing.T) {
+	svc, srcID, dstID, target := setupPromotion(t)
+	var audits []string
+	svc.SetAuditFunc(func(operator, operation, target, detail string, err error) {
+		audits = append(audits, fmt.Sprintf("%s %s ok=%v", operator, target, err == nil))
+	})
+
+	p, err := svc.RequestPromotion(1, 7, "alice", model.PromotionRequest{
+		SourceConfigID: srcID, SourceProject: "team", SourceRepository: "api", Tag: "v1",
+		TargetConfigID: dstID, TargetProject: "prod", TargetTag: "1.0.0",
+	})
+	if err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if p.Method != model.PromotionMethodBlobCopy || p.Status != model.PromotionSuccess || p.Digest != sha(imageManifest) {
+		t.Fatalf("unexpected promotion: %+v", p)
+	}
+	if p.Stats.BlobsCopied != 2 || p.Stats.BlobsSkipped != 1 || p.Stats.BytesCopied != int64(len(layerOne)+len(layerTwo)) {
+		t.Fatalf("unexpected stats: %+v", p.Stats)
+	}
+	if target.manifests["1.0.0"] != imageManifest || target.blobs[sha(layerTwo)] != layerTwo {
+		t.Fatalf("image not copied: %v", target.manifests)
+	}
+	if len(audits) != 1 || audits[0] != "alice prod/api:1.0.0 ok=true" {
+		t.Fatalf("unexpected audits: %v", audits)
+	}
+	history, total, _ := svc.ListPromotions(1, "", 1, 10)
+	if total != 1 || history[0].FinishedAt == nil || history[0].RequestedBy != "alice" {
+		t.Fatalf("promotion not recorded: %+v", history)
+	}
+}
+
+func TestPromotion_HarborCopyWithinInstance(t *testing.T) {
+	logger.Log = zap.NewNop()
+	var calls []string
+	svc, cfgID := setupHarbor(t, func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		switch {
+		case r.URL.Path == "/api/v2.0/projects/team/repositories/api/artifacts/v1":
+			_, _ = w.Write([]byte(`{"digest":"sha256:abc","tags":[{"name":"v1"}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2.0/projects/prod/repositories/api/artifacts":
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v2.0/projects/prod/repositories/api/artifacts/sha256:abc":
+			_, _ = w.Write([]byte(`{"digest":"sha256:abc","tags":[{"name":"v1"}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2.0/projects/prod/repositories/api/artifacts/sha256:abc/tags":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"name":"stable"}` {
+				t.Errorf("unexpected tag body %s", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	p, err := svc.RequestPromotion(1, 7, "alice", model.PromotionRequest{
+		SourceConfigID: cfgID, SourceProject: "team", SourceRepository: "api", Tag: "v1",
+		TargetProject: "prod", TargetTag: "stable",
+	})
+	if err != nil {
+		t.Fatalf("promote: %v (calls %v)", err, calls)
+	}
+	if p.Method != model.PromotionMethodCopy || p.Status != model.PromotionSuccess {
+		t.Fatalf("unexpected promotion: %+v", p)
+	}
+	joined := strings.Join(calls, "\n")
+	if !strings.Contains(joined, "POST /api/v2.0/projects/prod/repositories/api/artifacts?from=team%2Fapi%40sha256%3Aabc") ||
+		!strings.Contains(joined, "POST /api/v2.0/projects/prod/repositories/api/artifacts/sha256:abc/tags") {
+		t.Fatalf("unexpected calls:\n%s", joined)
+	}
+}
+
+func TestPromotion_WaitsForApproval(t *testing.T) {
+	svc, srcID, dstID, target := setupPromotion(t)
+	var orderPayload uint
+	rejected := map[uint]bool{}
+	svc.SetPromotionApproval(PromotionApproval{
+		Request: func(tenantID, userID uint, title, description string, levels int, promotionID uint) (uint, error) {
+			orderPayload = promotionID
+			return 40 + promotionID, nil
+		},
+		Rejected: func(tenantID, orderID uint) (bool, error) { return rejected[orderID], nil },
+	}, true)
+	req := model.PromotionRequest{
+		SourceConfigID: srcID, SourceProject: "team", SourceRepository: "api", Tag: "v1",
+		TargetConfigID: dstID, TargetProject: "prod",
+	}
+
+	p, err := svc.RequestPromotion(1, 7, "alice", req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if p.Status != model.PromotionPendingApproval || p.OrderID != 40+p.ID || orderPayload != p.ID {
+		t.Fatalf("promotion should wait for its order: %+v", p)
+	}
+	if len(target.manifests) != 0 {
+		t.Fatal("nothing may be copied before approval")
+	}
+	if err := svc.ExecutePromotion(p.ID, 2); err == nil {
+		t.Fatal("another tenant's order must not run the promotion")
+	}
+	if err := svc.ExecutePromotion(p.ID, 1); err != nil {
+		t.Fatalf("execute after approval: %v", err)
+	}
+	if target.manifests["v1"] == "" {
+		t.Fatal("approved promotion was not copied")
+	}
+	if err := svc.ExecutePromotion(p.ID, 1); err == nil {
+		t.Fatal("a promotion must run only once")
+	}
+
+	second, _ := svc.RequestPromotion(1, 7, "alice", req)
+	rejected[second.OrderID] = true
+	got, _ := svc.GetPromotion(1, second.ID)
+	if got.Status != model.PromotionRejected {
+		t.Fatalf("rejected order should reject the promotion: %+v", got)
+	}
+	if err := svc.ExecutePromotion(second.ID, 1); err == nil {
+		t.Fatal("a rejected promotion must not run")
+	}
+}
+
+func TestPromotion_HistoryIsTenantScoped(t *testing.T) {
+	svc, srcID, dstID, _ := setupPromotion(t)
+	p, err := svc.RequestPromotion(1, 7, "alice", model.PromotionRequest{
+		SourceConfigID: srcID, SourceProject: "team", SourceRepository: "api", Tag: "v1",
+		TargetConfigID: dstID, TargetProject: "prod",
+	})
+	if err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if _, err := svc.GetPromotion(2, p.ID); obserr.Details(err)["code"] != "PROMOTION_NOT_FOUND" {
+		t.Fatalf("another tenant must not read the promotion, got %v", err)
+	}
+	if items, total, _ := svc.ListPromotions(2, "", 1, 10); total != 0 || len(items) != 0 {
+		t.Fatalf("another tenant must not list the promotion, got %+v", items)
+	}
+	if got, err := svc.GetPromotion(1, p.ID); err != nil || got.ID != p.ID {
+		t.Fatalf("owner tenant should read the promotion: %v", err)
+	}
+}
+
+func TestPromotion_Validates(t *testing.T) {
+	svc, srcID, _, _ := setupPromotion(t)
+	cases := []model.PromotionRequest{
+		{SourceProject: "team", SourceRepository: "api", Tag: "v1", TargetProject: "prod"},
+		{SourceConfigID: srcID, SourceProject: "team", SourceRepository: "api", Tag: "v1"},
+		{SourceConfigID: srcID, SourceProject: "team", SourceRepository: "api", Tag: "v1", TargetProject: "team"},
+		{SourceConfigID: srcID, SourceProject: "team", SourceRepository: "api", Tag: "v1", TargetProject: "prod", TargetConfigID: 99},
+		{SourceConfigID: srcID, SourceProject: "team", SourceRepository: "api", Tag: "v1", TargetProject: "prod", RequireApproval: true},
+	}
+	for i, req := range cases {
+		if _, err := svc.RequestPromotion(1, 7, "alice", req); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
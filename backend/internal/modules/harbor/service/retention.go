@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/harbor/model"
+	"devops-platform/internal/pkg/logger"
+	"devops-platform/internal/pkg/obserr"
+
+	"go.uber.org/zap"
+)
+
+// retentionPageSize is the page size used to walk repositories and artifacts.
+const retentionPageSize = 100
+
+// maxRetentionPages bounds each walk in case Harbor keeps returning full pages.
+const maxRetentionPages = 100
+
+// RunningImagesFunc lists the images of all pods in every registered cluster,
+// as container image references or image IDs.
+type RunningImagesFunc func() ([]string, error)
+
+// AuditFunc records an operation in the platform audit log. err is nil when
+// the operation succeeded.
+type AuditFunc func(operator, operation, target, detail string, err error)
+
+// SetRunningImages sets the lookup ProtectRunning policies depend on. Without
+// it those policies refuse to run rather than risk deleting a live image.
+func (s *HarborService) SetRunningImages(fn RunningImagesFunc) {
+	s.runningImages = fn
+}
+
+func (s *HarborService) SetAuditFunc(fn AuditFunc) {
+	s.audit = fn
+}
+
+// --- Policies ---
+
+func (s *HarborService) ListRetentionPolicies(configID uint) ([]model.RetentionPolicy, error) {
+	return s.repo.ListRetentionPolicies(configID)
+}
+
+func (s *HarborService) GetRetentionPolicy(id uint) (*model.RetentionPolicy, error) {
+	return s.repo.GetRetentionPolicy(id)
+}
+
+func (s *HarborService) SaveRetentionPolicy(policy *model.RetentionPolicy) error {
+	if err := validateRetentionPolicy(policy); err != nil {
+		return err
+	}
+	if policy.ID > 0 {
+		existing, err := s.repo.GetRetentionPolicy(policy.ID)
+		if err != nil {
+			return err
+		}
+		policy.LastRunAt = existing.LastRunAt
+		policy.CreatedAt = existing.CreatedAt
+	}
+	return s.repo.SaveRetentionPolicy(policy)
+}
+
+func (s *HarborService) DeleteRetentionPolicy(id uint) error {
+	return s.repo.DeleteRetentionPolicy(id)
+}
+
+func validateRetentionPolicy(policy *model.RetentionPolicy) error {
+	policy.Name = strings.TrimSpace(policy.Name)
+	policy.Project = strings.TrimSpace(policy.Project)
+	policy.Repository = strings.Trim(strings.TrimSpace(policy.Repository), "/")
+	if policy.Name == "" {
+		return obserr.New("INVALID_PARAM", op, "name is required")
+	}
+	if policy.ConfigID == 0 || policy.Project == "" {
+		return obserr.New("INVALID_PARAM", op, "harbor config and project are required")
+	}
+	if policy.KeepLastN < 0 || policy.UntaggedOlderThanDays < 0 {
+		return obserr.New("INVALID_PARAM", op, "keepLastN and untaggedOlderThanDays must not be negative")
+	}
+	var patterns []string
+	for _, p := range policy.KeepTagPatterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return obserr.New("INVALID_PARAM", op, fmt.Sprintf("invalid tag pattern %q", p))
+		}
+		patterns = append(patterns, p)
+	}
+	policy.KeepTagPatterns = patterns
+	if policy.KeepLastN == 0 && len(patterns) == 0 && policy.UntaggedOlderThanDays == 0 {
+		return obserr.New("INVALID_PARAM", op, "policy needs keepLastN, keepTagPatterns or untaggedOlderThanDays")
+	}
+	return nil
+}
+
+// --- Runs ---
+
+func (s *HarborService) ListRetentionRuns(policyID uint, page, pageSize int) ([]model.RetentionRun, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	return s.repo.ListRetentionRuns(policyID, page, pageSize)
+}
+
+// RunRetention applies a policy. With dryRun nothing is deleted and the run
+// shows what would be. Every run is recorded; every deletion is audited.
+func (s *HarborService) RunRetention(id uint, dryRun bool, triggeredBy string) (*model.RetentionRun, error) {
+	policy, err := s.repo.GetRetentionPolicy(id)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		// Deletions of one policy must not interleave with a scheduled run.
+		s.retentionMu.Lock()
+		defer s.retentionMu.Unlock()
+	}
+	run := &model.RetentionRun{PolicyID: policy.ID, DryRun: dryRun, TriggeredBy: triggeredBy, StartedAt: time.Now()}
+	items, err := s.planRetention(policy)
+	if err != nil {
+		run.Status = model.RetentionRunFailed
+		run.Message = fmt.Sprint(obserr.Details(err)["message"])
+		run.FinishedAt = time.Now()
+		if saveErr := s.repo.SaveRetentionRun(run); saveErr != nil {
+			return nil, saveErr
+		}
+		return run, err
+	}
+
+	for i := range items {
+		item := &items[i]
+		if item.Action == model.RetentionKeep {
+			run.Kept++
+			continue
+		}
+		if dryRun {
+			run.Deleted++
+			continue
+		}
+		err := s.repo.DeleteArtifact(policy.ConfigID, policy.Project, item.Repository, item.Digest)
+		if err != nil {
+			item.Error = fmt.Sprint(obserr.Details(err)["message"])
+			run.Failed++
+		} else {
+			run.Deleted++
+		}
+		if s.audit != nil {
+			target := fmt.Sprintf("%s/%s@%s", policy.Project, item.Repository, item.Digest)
+			detail := fmt.Sprintf("policy=%s tags=%s reason=%s", policy.Name, strings.Join(item.Tags, ","), item.Reason)
+			s.audit(triggeredBy, "Harbor 镜像保留策略删除", target, detail, err)
+		}
+	}
+	run.Items = items
+	run.Status = model.RetentionRunSuccess
+	if run.Failed > 0 {
+		run.Status = model.RetentionRunPartial
+	}
+	run.FinishedAt = time.Now()
+	if !dryRun {
+		policy.LastRunAt = &run.FinishedAt
+		if err := s.repo.SaveRetentionPolicy(policy); err != nil {
+			return nil, err
+		}
+	}
+	return run, s.repo.SaveRetentionRun(run)
+}
+
+// planRetention decides keep or delete for every artifact the policy covers.
+func (s *HarborService) planRetention(policy *model.RetentionPolicy) ([]model.RetentionCandidate, error) {
+	var running map[string]bool
+	if policy.ProtectRunning {
+		if s.runningImages == nil {
+			return nil, obserr.New("RETENTION_RUNNING_UNKNOWN", op, "running images cannot be listed, refusing to run a policy that protects them")
+		}
+		images, err := s.runningImages()
+		if err != nil {
+			return nil, obserr.Wrap("RETENTION_RUNNING_UNKNOWN", op, "failed to list running images", err)
+		}
+		running = runningRefs(images)
+	}
+
+	repos := []string{policy.Repository}
+	if policy.Repository == "" {
+		var err error
+		if repos, err = s.listAllRepositories(policy.ConfigID, policy.Project); err != nil {
+			return nil, err
+		}
+	}
+	var items []model.RetentionCandidate
+	now := time.Now()
+	for _, repo := range repos {
+		artifacts, err := s.listAllArtifacts(policy.ConfigID, policy.Project, repo)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, planArtifacts(policy, policy.Project, repo, artifacts, running, now)...)
+	}
+	return items, nil
+}
+
+func (s *HarborService) listAllRepositories(configID uint, project string) ([]string, error) {
+	var names []string
+	for page := 1; page <= maxRetentionPages; page++ {
+		repos, _, err := s.repo.ListRepositories(configID, project, "", page, retentionPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range repos {
+			// Harbor names repositories "<project>/<repo>".
+			names = append(names, strings.TrimPrefix(r.Name, project+"/"))
+		}
+		if len(repos) < retentionPageSize {
+			break
+		}
+	}
+	return names, nil
+}
+
+func (s *HarborService) listAllArtifacts(configID uint, project, repo string) ([]model.Artifact, error) {
+	var all []model.Artifact
+	for page := 1; page <= maxRetentionPages; page++ {
+		artifacts, _, err := s.repo.ListArtifacts(configID, project, repo, page, retentionPageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, artifacts...)
+		if len(artifacts) < retentionPageSize {
+			break
+		}
+	}
+	return all, nil
+}
+
+func parsePushTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// planArtifacts applies the policy rules to one repository's artifacts.
+func planArtifacts(policy *model.RetentionPolicy, project, repo string, artifacts []model.Artifact, running map[string]bool, now time.Time) []model.RetentionCandidate {
+	sorted := append([]model.Artifact(nil), artifacts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return parsePushTime(sorted[i].PushTime).After(parsePushTime(sorted[j].PushTime))
+	})
+	tagRules := policy.KeepLastN > 0 || len(policy.KeepTagPatterns) > 0
+	name := project + "/" + repo
+	rank := 0
+	items := make([]model.RetentionCandidate, 0, len(sorted))
+	for _, a := range sorted {
+		item := model.RetentionCandidate{Repository: repo, Digest: a.Digest, PushTime: a.PushTime, Action: model.RetentionKeep}
+		for _, t := range a.Tags {
+			item.Tags = append(item.Tags, t.Name)
+		}
+		if len(item.Tags) > 0 {
+			rank++
+		}
+		switch {
+		case running != nil && isRunning(running, name, a.Digest, item.Tags):
+			item.Reason = "running in a cluster"
+		case len(item.Tags) > 0:
+			if p := matchingPattern(policy.KeepTagPatterns, item.Tags); p != "" {
+				item.Reason = "tag matches " + p
+			} else if rank <= policy.KeepLastN {
+				item.Reason = fmt.Sprintf("among the last %d tagged", policy.KeepLastN)
+			} else if !tagRules {
+				item.Reason = "no tag rule"
+			} else {
+				item.Action = model.RetentionDelete
+				item.Reason = fmt.Sprintf("not among the last %d tagged and no tag pattern matches", policy.KeepLastN)
+			}
+		default:
+			pushed := parsePushTime(a.PushTime)
+			if policy.UntaggedOlderThanDays > 0 && !pushed.IsZero() && now.Sub(pushed) > time.Duration(policy.UntaggedOlderThanDays)*24*time.Hour {
+				item.Action = model.RetentionDelete
+				item.Reason = fmt.Sprintf("untagged for more than %d days", policy.UntaggedOlderThanDays)
+			} else {
+				item.Reason = "untagged but recent"
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func matchingPattern(patterns, tags []string) string {
+	for _, p := range patterns {
+		for _, t := range tags {
+			if ok, _ := path.Match(p, t); ok {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+// runningRefs indexes image references as "<project>/<repo>:<tag>",
+// "<project>/<repo>@<digest>" and "@<digest>", ignoring the registry host.
+func runningRefs(images []string) map[string]bool {
+	refs := map[string]bool{}
+	for _, image := range images {
+		// Image IDs look like docker-pullable://host/project/repo@sha256:...
+		if i := strings.Index(image, "://"); i >= 0 {
+			image = image[i+3:]
+		}
+		name, digest := image, ""
+		if i := strings.Index(image, "@"); i >= 0 {
+			name, digest = image[:i], image[i+1:]
+		}
+		tag := ""
+		if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+			name, tag = name[:i], name[i+1:]
+		}
+		if parts := strings.SplitN(name, "/", 2); len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+			name = parts[1]
+		}
+		if digest != "" {
+			refs["@"+digest] = true
+			refs[name+"@"+digest] = true
+		}
+		if tag != "" {
+			refs[name+":"+tag] = true
+		}
+	}
+	return refs
+}
+
+func isRunning(refs map[string]bool, name, digest string, tags []string) bool {
+	if digest != "" && (refs["@"+digest] || refs[name+"@"+digest]) {
+		return true
+	}
+	for _, t := range tags {
+		if refs[name+":"+t] {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Scheduled executor ---
+
+// RunEnabledRetention runs every enabled policy.
+func (s *HarborService) RunEnabledRetention() {
+	policies, err := s.repo.ListEnabledRetentionPolicies()
+	if err != nil {
+		logger.Log.Warn("Load retention policies failed", zap.Error(err))
+		return
+	}
+	for _, p := range policies {
+		run, err := s.RunRetention(p.ID, false, "system")
+		if err != nil {
+			logger.Log.Error("Harbor retention run failed", zap.Uint("policy_id", p.ID), zap.Error(err))
+			continue
+		}
+		logger.Log.Info("Harbor retention run finished", zap.Uint("policy_id", p.ID),
+			zap.Int("deleted", run.Deleted), zap.Int("failed", run.Failed))
+	}
+}
+
+// StartRetentionExecutor runs enabled policies on the given interval until
+// StopRetentionExecutor is called.
+func (s *HarborService) StartRetentionExecutor(interval time.Duration) {
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.RunEnabledRetention()
+			}
+		}
+	}()
+}
+
+func (s *HarborService) StopRetentionExecutor() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
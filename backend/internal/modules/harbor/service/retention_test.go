@@ -0,0 +1,175 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/harbor/model"
+	"devops-platform/internal/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+func retentionArtifacts(now time.Time) string {
+	at := func(days int) string { return now.Add(-time.Duration(days) * 24 * time.Hour).Format(time.RFC3339) }
+	tagged := func(digest, tag string, days int) string {
+		return fmt.Sprintf(`{"digest":%q,"push_time":%q,"tags":[{"name":%q}]}`, digest, at(days), tag)
+	}
+	untagged := func(digest string, days int) string {
+		return fmt.Sprintf(`{"digest":%q,"push_time":%q,"tags":null}`, digest, at(days))
+	}
+	return "[" + strings.Join([]string{
+		tagged("sha256:d1", "v5", 1),
+		tagged("sha256:d2", "v4", 2),
+		tagged("sha256:d3", "release-1.0", 10),
+		tagged("sha256:d4", "v3", 5),
+		tagged("sha256:d5", "v2", 6),
+		untagged("sha256:d6", 40),
+		untagged("sha256:d7", 3),
+		untagged("sha256:d8", 50),
+	}, ",") + "]"
+}
+
+func setupRetention(t *testing.T) (*HarborService, *model.RetentionPolicy, *[]string) {
+	t.Helper()
+	logger.Log = zap.NewNop()
+	now := time.Now()
+	var deleted []string
+	svc, cfgID := setupHarbor(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v2.0/projects/team/repositories":
+			_, _ = w.Write([]byte(`[{"name":"team/api"}]`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v2.0/projects/team/repositories/api/artifacts":
+			_, _ = w.Write([]byte(retentionArtifacts(now)))
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v2.0/projects/team/repositories/api/artifacts/"):
+			digest := strings.TrimPrefix(r.URL.Path, "/api/v2.0/projects/team/repositories/api/artifacts/")
+			deleted = append(deleted, digest)
+			if digest == "sha256:d6" {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	svc.SetRunningImages(func() ([]string, error) {
+		return []string{
+			"harbor.example.com/team/api:v2",
+			"docker-pullable://harbor.example.com/team/api@sha256:d8",
+		}, nil
+	})
+	policy := &model.RetentionPolicy{
+		Name: "api cleanup", ConfigID: cfgID, Project: "team",
+		KeepLastN: 2, KeepTagPatterns: []string{"release-*"}, UntaggedOlderThanDays: 30,
+		ProtectRunning: true, Enabled: true,
+	}
+	if err := svc.SaveRetentionPolicy(policy); err != nil {
+		t.Fatalf("save policy: %v", err)
+	}
+	return svc, policy, &deleted
+}
+
+func actions(run *model.RetentionRun) map[string]string {
+	got := map[string]string{}
+	for _, item := range run.Items {
+		got[item.Digest] = item.Action
+	}
+	return got
+}
+
+func TestRetention_DryRunDeletesNothing(t *testing.T) {
+	svc, policy, deleted := setupRetention(t)
+	run, err := svc.RunRetention(policy.ID, true, "alice")
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(*deleted) != 0 {
+		t.Fatalf("dry run must not delete, got %v", *deleted)
+	}
+	want := map[string]string{
+		"sha256:d1": model.RetentionKeep,   // last 2
+		"sha256:d2": model.RetentionKeep,   // last 2
+		"sha256:d3": model.RetentionKeep,   // release-*
+		"sha256:d4": model.RetentionDelete, // third most recent tag
+		"sha256:d5": model.RetentionKeep,   // running by tag
+		"sha256:d6": model.RetentionDelete, // untagged, 40 days
+		"sha256:d7": model.RetentionKeep,   // untagged, recent
+		"sha256:d8": model.RetentionKeep,   // running by digest
+	}
+	got := actions(run)
+	for digest, action := range want {
+		if got[digest] != action {
+			t.Errorf("%s: got %s, want %s", digest, got[digest], action)
+		}
+	}
+	if !run.DryRun || run.Deleted != 2 || run.Kept != 6 || run.Status != model.RetentionRunSuccess {
+		t.Fatalf("unexpected run summary: %+v", run)
+	}
+	p, _ := svc.GetRetentionPolicy(policy.ID)
+	if p.LastRunAt != nil {
+		t.Fatal("dry run must not mark the policy as run")
+	}
+}
+
+func TestRetention_ExecuteDeletesAndAudits(t *testing.T) {
+	svc, policy, deleted := setupRetention(t)
+	var audits []string
+	svc.SetAuditFunc(func(operator, operation, target, detail string, err error) {
+		audits = append(audits, fmt.Sprintf("%s %s ok=%v", operator, target, err == nil))
+	})
+
+	run, err := svc.RunRetention(policy.ID, false, "alice")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	sort.Strings(*deleted)
+	if strings.Join(*deleted, ",") != "sha256:d4,sha256:d6" {
+		t.Fatalf("unexpected deletions: %v", *deleted)
+	}
+	if run.Deleted != 1 || run.Failed != 1 || run.Status != model.RetentionRunPartial {
+		t.Fatalf("unexpected run summary: %+v", run)
+	}
+	sort.Strings(audits)
+	want := []string{"alice team/api@sha256:d4 ok=true", "alice team/api@sha256:d6 ok=false"}
+	if strings.Join(audits, "|") != strings.Join(want, "|") {
+		t.Fatalf("audits = %v, want %v", audits, want)
+	}
+	runs, total, _ := svc.ListRetentionRuns(policy.ID, 1, 10)
+	if total != 1 || runs[0].Items == nil {
+		t.Fatalf("run not recorded: %d %+v", total, runs)
+	}
+	p, _ := svc.GetRetentionPolicy(policy.ID)
+	if p.LastRunAt == nil {
+		t.Fatal("execution should mark the policy as run")
+	}
+}
+
+func TestRetention_RefusesWithoutRunningImages(t *testing.T) {
+	svc, policy, deleted := setupRetention(t)
+	svc.SetRunningImages(func() ([]string, error) { return nil, fmt.Errorf("cluster prod unreachable") })
+	run, err := svc.RunRetention(policy.ID, false, "system")
+	if err == nil || run.Status != model.RetentionRunFailed {
+		t.Fatalf("expected failed run, got %+v err=%v", run, err)
+	}
+	if len(*deleted) != 0 {
+		t.Fatalf("nothing may be deleted when running images are unknown, got %v", *deleted)
+	}
+}
+
+func TestSaveRetentionPolicy_Validates(t *testing.T) {
+	svc, policy, _ := setupRetention(t)
+	cases := []model.RetentionPolicy{
+		{Name: "x", Project: "team"},
+		{Name: "x", ConfigID: policy.ConfigID, Project: "team"},
+		{Name: "x", ConfigID: policy.ConfigID, Project: "team", KeepTagPatterns: []string{"release-["}},
+		{Name: "x", ConfigID: policy.ConfigID, Project: "team", KeepLastN: -1},
+	}
+	for i, p := range cases {
+		if err := svc.SaveRetentionPolicy(&p); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"devops-platform/internal/modules/harbor/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// DefaultScanPolicy blocks deploys of unscanned artifacts and of artifacts
+// with any critical vulnerability.
+var DefaultScanPolicy = model.ScanPolicy{MaxCritical: 0, MaxHigh: -1, RequireScan: true}
+
+var severityRank = map[string]int{
+	model.SeverityCritical: 0,
+	model.SeverityHigh:     1,
+	model.SeverityMedium:   2,
+	model.SeverityLow:      3,
+}
+
+func rankSeverity(severity string) int {
+	if r, ok := severityRank[severity]; ok {
+		return r
+	}
+	return len(severityRank)
+}
+
+// SetScanPolicy replaces the policy CheckScanPolicy applies.
+func (s *HarborService) SetScanPolicy(policy model.ScanPolicy) {
+	s.policy = policy
+}
+
+func (s *HarborService) ScanPolicy() model.ScanPolicy {
+	return s.policy
+}
+
+func (s *HarborService) ScanArtifact(configID uint, projectName, repoName, reference string) error {
+	if reference == "" {
+		return obserr.New("INVALID_PARAM", op, "reference (tag or digest) is required")
+	}
+	return s.repo.ScanArtifact(configID, projectName, repoName, reference)
+}
+
+// GetVulnerabilityReport returns the artifact's findings, most severe first,
+// with counts per severity.
+func (s *HarborService) GetVulnerabilityReport(configID uint, projectName, repoName, reference string) (*model.VulnerabilityReport, error) {
+	if reference == "" {
+		return nil, obserr.New("INVALID_PARAM", op, "reference (tag or digest) is required")
+	}
+	report, err := s.repo.GetVulnerabilityReport(configID, projectName, repoName, reference)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, obserr.New("HARBOR_SCAN_NOT_FOUND", op, "artifact has no vulnerability report")
+	}
+	sort.SliceStable(report.Vulnerabilities, func(i, j int) bool {
+		a, b := report.Vulnerabilities[i], report.Vulnerabilities[j]
+		if ra, rb := rankSeverity(a.Severity), rankSeverity(b.Severity); ra != rb {
+			return ra < rb
+		}
+		return a.ID < b.ID
+	})
+	report.Summary = map[string]int{}
+	for _, v := range report.Vulnerabilities {
+		report.Summary[v.Severity]++
+	}
+	return report, nil
+}
+
+// CheckScanPolicy reports whether the artifact passes the configured policy.
+// Deploy flows call it before releasing an image.
+func (s *HarborService) CheckScanPolicy(configID uint, projectName, repoName, reference string) (*model.ScanDecision, error) {
+	artifact, err := s.GetArtifact(configID, projectName, repoName, reference)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateScanPolicy(s.policy, artifact.ScanOverview), nil
+}
+
+func evaluateScanPolicy(policy model.ScanPolicy, overview *model.ScanOverview) *model.ScanDecision {
+	decision := &model.ScanDecision{Policy: policy, Overview: overview}
+	if overview == nil || overview.Status != model.ScanStatusSuccess {
+		if policy.RequireScan {
+			status := "not scanned"
+			if overview != nil && overview.Status != "" {
+				status = "scan " + overview.Status
+			}
+			decision.Reasons = append(decision.Reasons, "artifact has no successful scan ("+status+")")
+		}
+		decision.Allowed = len(decision.Reasons) == 0
+		return decision
+	}
+	limits := []struct {
+		severity string
+		max      int
+	}{
+		{model.SeverityCritical, policy.MaxCritical},
+		{model.SeverityHigh, policy.MaxHigh},
+	}
+	for _, l := range limits {
+		if n := overview.Summary[l.severity]; l.max >= 0 && n > l.max {
+			decision.Reasons = append(decision.Reasons, fmt.Sprintf("%d %s vulnerabilities exceed the limit of %d", n, l.severity, l.max))
+		}
+	}
+	decision.Allowed = len(decision.Reasons) == 0
+	return decision
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"devops-platform/internal/modules/harbor/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const artifactJSON = `{"id":3,"digest":"sha256:abc","size":1024,"push_time":"2026-01-02T03:04:05Z",
+"tags":[{"id":1,"name":"v1","push_time":"2026-01-02T03:04:05Z"}],
+"scan_overview":{"application/vnd.security.vulnerability.report; version=1.1":{
+"report_id":"r1","scan_status":"Success","severity":"Critical","complete_percent":100,
+"scanner":{"name":"Trivy","vendor":"Aqua","version":"v0.50"},
+"summary":{"total":4,"fixable":3,"summary":{"Critical":1,"High":2,"Low":1}}}}}`
+
+const reportJSON = `{"application/vnd.security.vulnerability.report; version=1.1":{
+"generated_at":"2026-01-02T03:05:00Z","scanner":{"name":"Trivy","version":"v0.50"},"severity":"Critical",
+"vulnerabilities":[
+{"id":"CVE-2024-3","package":"zlib","version":"1.2","severity":"Low"},
+{"id":"CVE-2024-2","package":"openssl","version":"3.0.1","fix_version":"3.0.2","severity":"High"},
+{"id":"CVE-2024-1","package":"glibc","version":"2.31","fix_version":"2.32","severity":"Critical","links":["https://avd.aquasec.com/nvd/cve-2024-1"]}]}}`
+
+func setupHarbor(t *testing.T, handler http.HandlerFunc) (*HarborService, uint) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db failed: %v", err)
+	}
+	if err := db.AutoMigrate(&model.HarborConfig{}, &model.RetentionPolicy{}, &model.RetentionRun{}, &model.Promotion{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	cfg := model.HarborConfig{Name: "hub", URL: srv.URL, Username: "admin", Password: "pw"}
+	db.Create(&cfg)
+	return NewHarborService(db), cfg.ID
+}
+
+func TestGetArtifact_ParsesScanOverview(t *testing.T) {
+	svc, cfgID := setupHarbor(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2.0/projects/team/repositories/api/artifacts/v1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if !strings.Contains(r.Header.Get("X-Accept-Vulnerabilities"), "vulnerability.report") || r.URL.Query().Get("with_scan_overview") != "true" {
+			t.Errorf("scan overview not requested: %v %v", r.Header, r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(artifactJSON))
+	})
+
+	a, err := svc.GetArtifact(cfgID, "team", "api", "v1")
+	if err != nil {
+		t.Fatalf("get artifact: %v", err)
+	}
+	if a.PushTime == "" || len(a.Tags) != 1 || a.Tags[0].Name != "v1" {
+		t.Fatalf("artifact fields not mapped: %+v", a)
+	}
+	o := a.ScanOverview
+	if o == nil || o.Status != "Success" || o.Scanner != "Trivy v0.50" || o.Total != 4 || o.Summary["Critical"] != 1 {
+		t.Fatalf("unexpected overview: %+v", o)
+	}
+}
+
+func TestScanArtifact_PostsScan(t *testing.T) {
+	var got string
+	svc, cfgID := setupHarbor(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Method + " " + r.URL.Path
+		w.WriteHeader(http.StatusAccepted)
+	})
+	if err := svc.ScanArtifact(cfgID, "team", "api", "v1"); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if got != "POST /api/v2.0/projects/team/repositories/api/artifacts/v1/scan" {
+		t.Fatalf("unexpected request %q", got)
+	}
+}
+
+func TestGetVulnerabilityReport_SortsAndCounts(t *testing.T) {
+	svc, cfgID := setupHarbor(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(reportJSON))
+	})
+	report, err := svc.GetVulnerabilityReport(cfgID, "team", "api", "v1")
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	var ids []string
+	for _, v := range report.Vulnerabilities {
+		ids = append(ids, v.ID)
+	}
+	if strings.Join(ids, ",") != "CVE-2024-1,CVE-2024-2,CVE-2024-3" {
+		t.Fatalf("vulnerabilities not sorted by severity: %v", ids)
+	}
+	if report.Summary["Critical"] != 1 || report.Summary["High"] != 1 || report.Summary["Low"] != 1 {
+		t.Fatalf("unexpected summary: %v", report.Summary)
+	}
+	if report.Vulnerabilities[0].FixVersion != "2.32" || len(report.Vulnerabilities[0].Links) != 1 {
+		t.Fatalf("vulnerability fields not mapped: %+v", report.Vulnerabilities[0])
+	}
+}
+
+func TestGetVulnerabilityReport_NotScanned(t *testing.T) {
+	svc, cfgID := setupHarbor(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	})
+	if _, err := svc.GetVulnerabilityReport(cfgID, "team", "api", "v1"); err == nil {
+		t.Fatal("expected error for an artifact without report")
+	}
+}
+
+func TestCheckScanPolicy(t *testing.T) {
+	svc, cfgID := setupHarbor(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(artifactJSON))
+	})
+	decision, err := svc.CheckScanPolicy(cfgID, "team", "api", "v1")
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if decision.Allowed || len(decision.Reasons) != 1 || !strings.Contains(decision.Reasons[0], "Critical") {
+		t.Fatalf("default policy should block critical findings: %+v", decision)
+	}
+
+	svc.SetScanPolicy(model.ScanPolicy{MaxCritical: 1, MaxHigh: 1})
+	decision, _ = svc.CheckScanPolicy(cfgID, "team", "api", "v1")
+	if decision.Allowed || !strings.Contains(strings.Join(decision.Reasons, ";"), "High") {
+		t.Fatalf("high limit should block: %+v", decision)
+	}
+}
+
+func TestEvaluateScanPolicy_Unscanned(t *testing.T) {
+	if d := evaluateScanPolicy(DefaultScanPolicy, nil); d.Allowed {
+		t.Fatal("unscanned artifact should be blocked when a scan is required")
+	}
+	if d := evaluateScanPolicy(DefaultScanPolicy, &model.ScanOverview{Status: "Running"}); d.Allowed || !strings.Contains(d.Reasons[0], "Running") {
+		t.Fatalf("running scan should be blocked: %+v", d)
+	}
+	if d := evaluateScanPolicy(model.ScanPolicy{MaxCritical: 0, MaxHigh: -1}, nil); !d.Allowed {
+		t.Fatal("unscanned artifact should pass when no scan is required")
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"devops-platform/internal/modules/k8s/model"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RunningImages 返回所有已注册集群中 Pod 正在使用的镜像（镜像引用及 imageID）。
+// 任一集群无法访问时返回错误，调用方不能据此认为镜像未被使用。
+func (s *K8sService) RunningImages() ([]string, error) {
+	if err := s.ensureReady(); err != nil {
+		return nil, err
+	}
+	var clusters []model.Cluster
+	for page := 1; ; page++ {
+		items, total, err := s.clusterService.List(page, 100, "", "")
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, items...)
+		if len(items) == 0 || int64(len(clusters)) >= total {
+			break
+		}
+	}
+
+	seen := map[string]bool{}
+	var images []string
+	add := func(image string) {
+		if image != "" && !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+	for i := range clusters {
+		cluster := &clusters[i]
+		client, err := s.clientFactory.GetClient(cluster)
+		if err != nil {
+			return nil, fmt.Errorf("集群 %s 连接失败: %w", cluster.Name, err)
+		}
+		pods, err := client.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			s.clientFactory.RemoveClient(cluster.Name)
+			return nil, fmt.Errorf("集群 %s 获取 Pod 失败: %w", cluster.Name, err)
+		}
+		for _, pod := range pods.Items {
+			for _, c := range pod.Spec.InitContainers {
+				add(c.Image)
+			}
+			for _, c := range pod.Spec.Containers {
+				add(c.Image)
+			}
+			for _, st := range pod.Status.InitContainerStatuses {
+				add(st.ImageID)
+			}
+			for _, st := range pod.Status.ContainerStatuses {
+				add(st.ImageID)
+			}
+		}
+	}
+	return images, nil
+}
@@ -100,6 +100,25 @@ func TestLogSourceConnection(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "connection successful"})
 }
 
+// ListLogFields godoc
+// @Summary 获取日志字段
+// @Description 获取日志源中可用于检索的字段（Loki 为标签）
+// @Tags 日志管理
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "日志源ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /log/sources/{id}/fields [get]
+func ListLogFields(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	fields, err := logSvc.Fields(uint(id))
+	if err != nil {
+		writeObservableError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": fields})
+}
+
 // SearchLogs godoc
 // @Summary 检索日志
 // @Description 按条件分页检索日志
@@ -156,6 +175,38 @@ func ExportLogs(c *gin.Context) {
 	}
 }
 
+// AggregateLogs godoc
+// @Summary 日志聚合统计
+// @Description 按条件统计日志数量直方图、错误率趋势和指定字段的 Top N
+// @Tags 日志管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.AggregateRequest true "统计条件"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /log/aggregate [post]
+func AggregateLogs(c *gin.Context) {
+	var req model.AggregateRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.SourceID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+		return
+	}
+	resp, err := logSvc.Aggregate(req)
+	if err != nil {
+		writeObservableError(c, queryErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": resp})
+}
+
+// queryErrorStatus tells rejected query parameters apart from backend failures.
+func queryErrorStatus(err error) int {
+	if obserr.Details(err)["code"] == "INVALID_PARAM" {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 func writeObservableError(c *gin.Context, status int, err error) {
 	details := obserr.Details(err)
 	code, _ := details["code"].(string)
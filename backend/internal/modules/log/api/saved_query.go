@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"devops-platform/internal/modules/log/model"
+	"devops-platform/internal/pkg/obserr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSavedQueries godoc
+// @Summary 获取已保存的日志查询
+// @Description 返回当前用户自己的查询和租户内共享的查询
+// @Tags 日志管理
+// @Produce json
+// @Security BearerAuth
+// @Param keyword query string false "名称关键词"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /log/queries [get]
+func ListSavedQueries(c *gin.Context) {
+	items, err := logSvc.ListSavedQueries(c.GetUint("tenantID"), c.GetUint("userID"), c.Query("keyword"))
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": items, "total": len(items)})
+}
+
+// GetSavedQuery godoc
+// @Summary 获取已保存的日志查询详情
+// @Description 返回查询及按当前时间换算后的检索条件
+// @Tags 日志管理
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "查询ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /log/queries/{id} [get]
+func GetSavedQuery(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	view, err := logSvc.GetSavedQuery(c.GetUint("tenantID"), c.GetUint("userID"), uint(id))
+	if err != nil {
+		writeObservableError(c, savedQueryErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": view})
+}
+
+// GetSharedQuery godoc
+// @Summary 打开日志查询分享链接
+// @Description 根据分享令牌还原查询，租户内用户均可打开
+// @Tags 日志管理
+// @Produce json
+// @Security BearerAuth
+// @Param token path string true "分享令牌"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /log/queries/shared/{token} [get]
+func GetSharedQuery(c *gin.Context) {
+	view, err := logSvc.SharedQuery(c.GetUint("tenantID"), c.Param("token"))
+	if err != nil {
+		writeObservableError(c, savedQueryErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": view})
+}
+
+// SaveSavedQuery godoc
+// @Summary 保存日志查询
+// @Description 创建查询，或由所有者更新查询
+// @Tags 日志管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int false "查询ID（更新时）"
+// @Param request body model.SavedQuery true "查询信息"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /log/queries [post]
+func SaveSavedQuery(c *gin.Context) {
+	var item model.SavedQuery
+	if err := c.ShouldBindJSON(&item); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+		return
+	}
+	item.ID = 0
+	if id, err := strconv.ParseUint(c.Param("id"), 10, 64); err == nil {
+		item.ID = uint(id)
+	}
+	if err := logSvc.SaveSavedQuery(c.GetUint("tenantID"), c.GetUint("userID"), c.GetString("username"), &item); err != nil {
+		writeObservableError(c, savedQueryErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": item})
+}
+
+// DeleteSavedQuery godoc
+// @Summary 删除日志查询
+// @Description 由所有者删除查询，分享链接随之失效
+// @Tags 日志管理
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "查询ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /log/queries/{id} [delete]
+func DeleteSavedQuery(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err := logSvc.DeleteSavedQuery(c.GetUint("tenantID"), c.GetUint("userID"), uint(id)); err != nil {
+		writeObservableError(c, savedQueryErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "deleted"})
+}
+
+func savedQueryErrorStatus(err error) int {
+	switch obserr.Details(err)["code"] {
+	case "SAVED_QUERY_NOT_FOUND":
+		return http.StatusNotFound
+	case "SAVED_QUERY_FORBIDDEN":
+		return http.StatusForbidden
+	}
+	return queryErrorStatus(err)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	cmdbterminal "devops-platform/internal/modules/cmdb/terminal"
+	"devops-platform/internal/modules/log/model"
+	"devops-platform/internal/pkg/obserr"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// tailWriteWait bounds a single push; a client that stops reading for longer
+// is dropped and can reconnect from the last timestamp it got.
+const tailWriteWait = 30 * time.Second
+
+var tailUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 64 << 10,
+	CheckOrigin:     cmdbterminal.CheckOrigin,
+}
+
+// TailLogs godoc
+// @Summary 实时跟踪日志
+// @Description 持续推送符合条件的新日志，请求升级时使用 WebSocket，否则使用 SSE
+// @Tags 日志管理
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param sourceId query int true "日志源ID"
+// @Param keywords query string false "关键词，逗号分隔"
+// @Param level query string false "日志级别"
+// @Param service query string false "服务名"
+// @Param host query string false "主机"
+// @Param since query string false "起始时间(RFC3339)，默认当前时间"
+// @Success 200 {string} string "日志事件流"
+// @Router /log/tail [get]
+func TailLogs(c *gin.Context) {
+	sourceID, _ := strconv.ParseUint(c.Query("sourceId"), 10, 64)
+	if sourceID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "sourceId is required"})
+		return
+	}
+	req := model.SearchRequest{
+		SourceID:  uint(sourceID),
+		Level:     c.Query("level"),
+		Service:   c.Query("service"),
+		Host:      c.Query("host"),
+		StartTime: c.Query("since"),
+	}
+	for _, kw := range strings.Split(c.Query("keywords"), ",") {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			req.Keywords = append(req.Keywords, kw)
+		}
+	}
+	// An SSE event id is "<timestamp>#<n>" for the nth entry at that timestamp,
+	// so a reconnect resumes right after the last entry the client got.
+	skip := 0
+	if lastID := c.GetHeader("Last-Event-ID"); lastID != "" {
+		var seq string
+		req.StartTime, seq, _ = strings.Cut(lastID, "#")
+		skip, _ = strconv.Atoi(seq)
+	}
+	stream := func(ctx context.Context, send func(*model.LogEntry, int) error) error {
+		return logSvc.TailLogs(ctx, req, skip, send)
+	}
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		tailLogsWS(c, stream)
+		return
+	}
+	tailLogsSSE(c, stream)
+}
+
+func tailLogsWS(c *gin.Context, stream func(context.Context, func(*model.LogEntry, int) error) error) {
+	conn, err := tailUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// The client only sends control frames; reading them notices when it leaves.
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(message cmdbterminal.WSMessage) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(tailWriteWait))
+		return conn.WriteJSON(message)
+	}
+	err = stream(ctx, func(entry *model.LogEntry, _ int) error {
+		return write(cmdbterminal.WSMessage{Operation: "log", Data: entry})
+	})
+	if err != nil && !cmdbterminal.IsNormalClose(err) {
+		_ = write(cmdbterminal.WSMessage{Operation: "error", Data: obserr.Details(err)["message"]})
+		_ = conn.WriteControl(websocket.CloseMessage, cmdbterminal.EncodeClosedMessage("log tail failed"), time.Now().Add(2*time.Second))
+	}
+}
+
+func tailLogsSSE(c *gin.Context, stream func(context.Context, func(*model.LogEntry, int) error) error) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+	rc := http.NewResponseController(c.Writer)
+
+	writeEvent := func(event, id string, data interface{}) error {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		_ = rc.SetWriteDeadline(time.Now().Add(tailWriteWait))
+		if id != "" {
+			if _, err := fmt.Fprintf(c.Writer, "id: %s\n", id); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+	err := stream(c.Request.Context(), func(entry *model.LogEntry, seq int) error {
+		return writeEvent("log", fmt.Sprintf("%s#%d", entry.Timestamp, seq), entry)
+	})
+	if err != nil && c.Request.Context().Err() == nil {
+		_ = writeEvent("error", "", gin.H{"message": obserr.Details(err)["message"]})
+	}
+}
+
+// LogContext godoc
+// @Summary 查看日志上下文
+// @Description 返回同一服务、主机上某条日志前后的若干条日志
+// @Tags 日志管理
+// @Produce json
+// @Security BearerAuth
+// @Param sourceId query int true "日志源ID"
+// @Param timestamp query string true "日志时间(RFC3339)"
+// @Param service query string false "服务名"
+// @Param host query string false "主机"
+// @Param message query string false "日志内容，用于区分同一时刻的多条日志"
+// @Param before query int false "之前的条数，默认20，最多90"
+// @Param after query int false "之后的条数，默认20，最多90"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /log/context [get]
+func LogContext(c *gin.Context) {
+	var req model.ContextRequest
+	if err := c.ShouldBindQuery(&req); err != nil || req.SourceID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+		return
+	}
+	resp, err := logSvc.Context(req)
+	if err != nil {
+		writeObservableError(c, queryErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": resp})
+}
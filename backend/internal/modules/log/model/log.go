@@ -6,7 +6,15 @@ import (
 	"gorm.io/gorm"
 )
 
-// LogSource holds connection info for a log backend
+// Log source types.
+const (
+	SourceElasticsearch = "elasticsearch"
+	SourceOpenSearch    = "opensearch"
+	SourceLoki          = "loki"
+)
+
+// LogSource holds connection info for a log backend. For Loki, IndexPattern
+// is the base stream selector, e.g. {namespace="prod"}.
 type LogSource struct {
 	ID           uint           `gorm:"primaryKey" json:"id"`
 	Name         string         `gorm:"size:128;not null" json:"name"`
@@ -31,6 +39,7 @@ type SearchRequest struct {
 	EndTime   string   `json:"endTime"`
 	Level     string   `json:"level"`   // ERROR, WARN, INFO, DEBUG
 	Service   string   `json:"service"` // source service name
+	Host      string   `json:"host"`
 	Page      int      `json:"page"`
 	PageSize  int      `json:"pageSize"`
 	SortOrder string   `json:"sortOrder"` // asc, desc
@@ -54,3 +63,68 @@ type SearchResponse struct {
 	PageSize   int        `json:"pageSize"`
 	TotalPages int        `json:"totalPages"`
 }
+
+// LogField is a field (Elasticsearch, OpenSearch) or label (Loki) found on
+// log entries.
+type LogField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ContextRequest asks for the lines around one entry of a stream, the stream
+// being the entry's service and host.
+type ContextRequest struct {
+	SourceID  uint   `form:"sourceId" json:"sourceId"`
+	Timestamp string `form:"timestamp" json:"timestamp"`
+	Service   string `form:"service" json:"service"`
+	Host      string `form:"host" json:"host"`
+	// Message tells the entry apart from others logged at the same instant.
+	Message string `form:"message" json:"message"`
+	Before  int    `form:"before" json:"before"`
+	After   int    `form:"after" json:"after"`
+}
+
+// ContextResponse lists the surrounding lines oldest first. Anchor is nil when
+// the entry itself was not found.
+type ContextResponse struct {
+	Before []LogEntry `json:"before"`
+	Anchor *LogEntry  `json:"anchor"`
+	After  []LogEntry `json:"after"`
+}
+
+// AggregateRequest asks for statistics over the entries a search matches.
+// Paging and sort fields of the embedded search are ignored.
+type AggregateRequest struct {
+	SearchRequest
+	// Interval is the histogram bucket width, e.g. "1m" or "1h"; empty picks
+	// one that splits the time range into about 60 buckets.
+	Interval string `json:"interval"`
+	// TopField is the field counted for the top-N list: service, host, level
+	// or any other field (label, for Loki) of the source.
+	TopField string `json:"topField"`
+	TopSize  int    `json:"topSize"`
+}
+
+// HistogramBucket counts the entries in one interval starting at Time.
+type HistogramBucket struct {
+	Time      string  `json:"time"`
+	Count     int64   `json:"count"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+}
+
+// TermCount is one value of the top-N list.
+type TermCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// AggregateResponse holds the histogram, oldest bucket first, and the most
+// frequent values of TopField.
+type AggregateResponse struct {
+	Interval  int64             `json:"interval"` // bucket width in seconds
+	Total     int64             `json:"total"`
+	Histogram []HistogramBucket `json:"histogram"`
+	TopField  string            `json:"topField"`
+	Top       []TermCount       `json:"top"`
+}
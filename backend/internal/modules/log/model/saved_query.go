@@ -0,0 +1,62 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Saved query visibility: private queries are seen by their owner only,
+// tenant queries by everyone in the tenant. Only the owner edits either.
+const (
+	VisibilityPrivate = "private"
+	VisibilityTenant  = "tenant"
+)
+
+// SavedQuery is a named search. With a TimeRange such as "15m" or "7d" the
+// query always covers that span up to now; without one it keeps the absolute
+// StartTime and EndTime of Query. ShareToken identifies the query in share
+// links, which open it for anyone in the tenant.
+type SavedQuery struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	TenantID    uint           `gorm:"index;not null" json:"tenantId"`
+	Name        string         `gorm:"size:128;not null" json:"name"`
+	Description string         `gorm:"size:512" json:"description"`
+	SourceID    uint           `gorm:"index;not null" json:"sourceId"`
+	Query       SearchRequest  `gorm:"type:text;serializer:json" json:"query"`
+	TimeRange   string         `gorm:"size:16" json:"timeRange"`
+	Visibility  string         `gorm:"size:16;not null;default:'private'" json:"visibility"`
+	OwnerID     uint           `gorm:"index" json:"ownerId"`
+	OwnerName   string         `gorm:"size:128" json:"ownerName"`
+	ShareToken  string         `gorm:"size:64;uniqueIndex" json:"shareToken"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (SavedQuery) TableName() string { return "log_saved_queries" }
+
+// SavedQueryView is a saved query together with the search it stands for
+// right now, relative time range resolved.
+type SavedQueryView struct {
+	SavedQuery
+	Search SearchRequest `json:"search"`
+}
+
+// ParseTimeRange parses a relative time range: a positive Go duration ("15m",
+// "1.5h") or a whole number of days ("7d"), which time.ParseDuration rejects.
+func ParseTimeRange(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid time range: %q", s)
+	}
+	return d, nil
+}
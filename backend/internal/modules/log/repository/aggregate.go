@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/log/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// Histogram sizing: an automatic interval aims at autoBuckets buckets and an
+// explicit one may not produce more than maxBuckets.
+const (
+	autoBuckets = 60
+	maxBuckets  = 1000
+)
+
+// autoIntervals are the bucket widths an automatic interval picks from.
+var autoIntervals = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 10 * time.Minute, 30 * time.Minute,
+	time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// errorLevels are the levels counted as errors for the error rate.
+var errorLevels = []string{"ERROR", "FATAL"}
+
+func isErrorLevel(level string) bool {
+	for _, l := range errorLevels {
+		if strings.EqualFold(level, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// histogramRange is the resolved time range of an aggregation. Buckets start
+// at multiples of the interval, so Start is the range start rounded down.
+type histogramRange struct {
+	Start    time.Time
+	End      time.Time
+	Interval time.Duration
+}
+
+func resolveHistogram(req model.AggregateRequest) (histogramRange, error) {
+	start, end, err := searchTimeRange(req.StartTime, req.EndTime)
+	if err != nil {
+		return histogramRange{}, err
+	}
+	span := end.Sub(start)
+	var interval time.Duration
+	if req.Interval != "" {
+		interval, err = time.ParseDuration(req.Interval)
+		if err != nil || interval < time.Second {
+			return histogramRange{}, obserr.New("INVALID_PARAM", op, fmt.Sprintf("invalid interval %q, use e.g. 30s, 5m or 1h", req.Interval))
+		}
+		if span/interval >= maxBuckets {
+			return histogramRange{}, obserr.New("INVALID_PARAM", op, fmt.Sprintf("interval %s yields more than %d buckets", interval, maxBuckets))
+		}
+	} else {
+		interval = autoIntervals[len(autoIntervals)-1]
+		for _, d := range autoIntervals {
+			if span/d <= autoBuckets {
+				interval = d
+				break
+			}
+		}
+	}
+	return histogramRange{Start: start.Truncate(interval), End: end, Interval: interval}, nil
+}
+
+// emptyBuckets lists one zero bucket per interval of the range.
+func (h histogramRange) emptyBuckets() []model.HistogramBucket {
+	var buckets []model.HistogramBucket
+	for t := h.Start; t.Before(h.End); t = t.Add(h.Interval) {
+		buckets = append(buckets, model.HistogramBucket{Time: t.UTC().Format(time.RFC3339)})
+	}
+	return buckets
+}
+
+// finishAggregate fills in the error rates and the total.
+func finishAggregate(resp *model.AggregateResponse) *model.AggregateResponse {
+	var total int64
+	for i := range resp.Histogram {
+		b := &resp.Histogram[i]
+		if b.Count > 0 {
+			b.ErrorRate = float64(b.Errors) / float64(b.Count)
+		}
+		total += b.Count
+	}
+	if resp.Total == 0 {
+		resp.Total = total
+	}
+	if resp.Histogram == nil {
+		resp.Histogram = []model.HistogramBucket{}
+	}
+	if resp.Top == nil {
+		resp.Top = []model.TermCount{}
+	}
+	return resp
+}
@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/log/model"
+)
+
+type recordedRequest struct {
+	method string
+	path   string
+	query  string
+	body   string
+}
+
+// fixtureServer replays recorded responses from testdata, keyed by
+// "METHOD /path", and records the requests it receives.
+func fixtureServer(t *testing.T, fixtures map[string]string) (*model.LogSource, *[]recordedRequest) {
+	t.Helper()
+	var requests []recordedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, recordedRequest{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery, body: string(body)})
+		if u, p, _ := r.BasicAuth(); u != "reader" || p != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		name, ok := fixtures[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatalf("read fixture %s: %v", name, err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return &model.LogSource{Endpoint: srv.URL, Username: "reader", Password: "secret", IndexPattern: "app-logs-*"}, &requests
+}
+
+func decodeBody(t *testing.T, body string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &m); err != nil {
+		t.Fatalf("request body is not JSON: %v", err)
+	}
+	return m
+}
+
+var testRequest = model.SearchRequest{
+	Keywords:  []string{"timeout"},
+	Level:     "error",
+	Service:   "order-api",
+	StartTime: "2026-10-18T09:00:00Z",
+	EndTime:   "2026-10-18T10:00:00Z",
+	Page:      1,
+	PageSize:  20,
+}
+
+func TestElasticsearchAdapter_Search(t *testing.T) {
+	source, requests := fixtureServer(t, map[string]string{"POST /app-logs-*/_search": "elasticsearch_search.json"})
+	source.Type = model.SourceElasticsearch
+	resp, err := (&ElasticsearchAdapter{httpClient: http.DefaultClient}).Search(source, testRequest)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if resp.Total != 42 || resp.TotalPages != 3 || len(resp.Entries) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if e := resp.Entries[0]; e.Service != "order-api" || e.Level != "ERROR" || e.TraceID != "4bf92f3577b34da6" {
+		t.Fatalf("entry not mapped: %+v", e)
+	}
+	body := (*requests)[0].body
+	for _, want := range []string{`"level.keyword":"ERROR"`, `"service.keyword":"order-api"`, `"gte":"2026-10-18T09:00:00Z"`, `"message":"*timeout*"`, `"order":"desc"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("query misses %s: %s", want, body)
+		}
+	}
+	if _, ok := decodeBody(t, body)["track_total_hits"]; ok {
+		t.Error("elasticsearch query should keep the default total tracking")
+	}
+}
+
+func TestElasticsearchAdapter_Fields(t *testing.T) {
+	source, _ := fixtureServer(t, map[string]string{"GET /app-logs-*/_mapping": "elasticsearch_mapping.json"})
+	fields, err := (&ElasticsearchAdapter{httpClient: http.DefaultClient}).Fields(source)
+	if err != nil {
+		t.Fatalf("fields: %v", err)
+	}
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.Name+":"+f.Type)
+	}
+	want := "@timestamp:date,kubernetes.namespace:keyword,kubernetes.pod.name:keyword,level:text,message:text,service:keyword"
+	if strings.Join(names, ",") != want {
+		t.Fatalf("fields = %v, want %s", names, want)
+	}
+}
+
+func TestOpenSearchAdapter_Search(t *testing.T) {
+	source, requests := fixtureServer(t, map[string]string{"POST /app-logs-*/_search": "opensearch_search.json"})
+	req := testRequest
+	req.SortOrder = "asc"
+	resp, err := (&OpenSearchAdapter{httpClient: http.DefaultClient}).Search(source, req)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if resp.Total != 12034 || len(resp.Entries) != 1 || resp.Entries[0].Host != "billing-7d9f" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	body := decodeBody(t, (*requests)[0].body)
+	if body["track_total_hits"] != true {
+		t.Errorf("opensearch query should track exact totals: %v", body)
+	}
+	if !strings.Contains((*requests)[0].body, `"order":"asc"`) {
+		t.Errorf("sort order not applied: %s", (*requests)[0].body)
+	}
+}
+
+func TestOpenSearchAdapter_HealthCheck(t *testing.T) {
+	source, _ := fixtureServer(t, map[string]string{"GET /": "opensearch_root.json"})
+	if err := (&OpenSearchAdapter{httpClient: http.DefaultClient}).HealthCheck(source); err != nil {
+		t.Fatalf("health check: %v", err)
+	}
+	es, _ := fixtureServer(t, map[string]string{"GET /": "elasticsearch_root.json"})
+	if err := (&OpenSearchAdapter{httpClient: http.DefaultClient}).HealthCheck(es); err == nil {
+		t.Fatal("an Elasticsearch endpoint must not pass as OpenSearch")
+	}
+}
+
+func TestLokiAdapter_Search(t *testing.T) {
+	source, requests := fixtureServer(t, map[string]string{"GET /loki/api/v1/query_range": "loki_query_range.json"})
+	source.IndexPattern = `{namespace="prod"}`
+	req := testRequest
+	req.Keywords = []string{"timeout", "a.b"}
+	req.PageSize = 2
+	resp, err := (&LokiAdapter{httpClient: http.DefaultClient}).Search(source, req)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+
+	q := (*requests)[0]
+	params, _ := url.ParseQuery(q.query)
+	wantQuery := `{namespace="prod"} | service_name="order-api" or service="order-api" or app="order-api" or job="order-api" |~ "(?i)(timeout|a\\.b)" | level=~"(?i)error"`
+	if params.Get("query") != wantQuery {
+		t.Errorf("logql = %s, want %s", params.Get("query"), wantQuery)
+	}
+	start, _ := time.Parse(time.RFC3339, "2026-10-18T09:00:00Z")
+	if params.Get("start") != strconv.FormatInt(start.UnixNano(), 10) || params.Get("limit") != "2" || params.Get("direction") != "backward" {
+		t.Errorf("unexpected range params: %v", params)
+	}
+
+	// Two streams merge newest first and the page keeps the first two lines.
+	if len(resp.Entries) != 2 || resp.Entries[0].Host != "order-api-5c7d9-x2k4p" || resp.Entries[1].Host != "order-api-5c7d9-q8m2z" {
+		t.Fatalf("streams not merged by time: %+v", resp.Entries)
+	}
+	if e := resp.Entries[0]; e.Level != "ERROR" || e.Service != "order-api" || e.Timestamp != "2026-10-18T09:15:02.114Z" {
+		t.Fatalf("entry not mapped: %+v", e)
+	}
+}
+
+func TestLokiAdapter_FieldsAndHealth(t *testing.T) {
+	source, _ := fixtureServer(t, map[string]string{
+		"GET /loki/api/v1/labels": "loki_labels.json",
+		"GET /ready":              "loki_ready.txt",
+	})
+	a := &LokiAdapter{httpClient: http.DefaultClient}
+	fields, err := a.Fields(source)
+	if err != nil {
+		t.Fatalf("fields: %v", err)
+	}
+	if len(fields) != 4 || fields[0].Name != "level" || fields[0].Type != "label" {
+		t.Fatalf("unexpected fields: %+v", fields)
+	}
+	if err := a.HealthCheck(source); err != nil {
+		t.Fatalf("health check: %v", err)
+	}
+	source.Password = "wrong"
+	if err := a.HealthCheck(source); err == nil {
+		t.Fatal("health check should fail on rejected credentials")
+	}
+}
+
+func TestBuildLogQL_Defaults(t *testing.T) {
+	got := buildLogQL(&model.LogSource{IndexPattern: "app-logs-*"}, model.SearchRequest{})
+	if got != lokiDefaultSelector {
+		t.Fatalf("got %s", got)
+	}
+	got = buildLogQL(&model.LogSource{}, model.SearchRequest{Service: `we"ird`})
+	if got != lokiDefaultSelector+` | service_name="we\"ird" or service="we\"ird" or app="we\"ird" or job="we\"ird"` {
+		t.Fatalf("got %s", got)
+	}
+	got = buildLogQL(&model.LogSource{}, model.SearchRequest{Host: "node-1"})
+	if got != lokiDefaultSelector+` | host="node-1" or hostname="node-1" or pod="node-1" or instance="node-1"` {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestElasticsearchAdapter_Aggregate(t *testing.T) {
+	source, requests := fixtureServer(t, map[string]string{"POST /app-logs-*/_search": "elasticsearch_aggregate.json"})
+	req := model.AggregateRequest{SearchRequest: testRequest, Interval: "30m", TopField: "service", TopSize: 5}
+	resp, err := (&ElasticsearchAdapter{httpClient: http.DefaultClient}).Aggregate(source, req)
+	if err != nil {
+		t.Fatalf("aggregate: %v", err)
+	}
+	if resp.Interval != 1800 || resp.Total != 30 || len(resp.Histogram) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if b := resp.Histogram[0]; b.Time != "2026-10-18T09:00:00Z" || b.Count != 20 || b.Errors != 5 || b.ErrorRate != 0.25 {
+		t.Fatalf("bucket not mapped: %+v", b)
+	}
+	if len(resp.Top) != 2 || resp.Top[0] != (model.TermCount{Value: "order-api", Count: 21}) {
+		t.Fatalf("top not mapped: %+v", resp.Top)
+	}
+	body := (*requests)[0].body
+	for _, want := range []string{`"size":0`, `"fixed_interval":"1800s"`, `"field":"service.keyword","size":5`, `"level.keyword":["ERROR","FATAL"]`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("query misses %s: %s", want, body)
+		}
+	}
+}
+
+func TestLokiAdapter_Aggregate(t *testing.T) {
+	source, requests := fixtureServer(t, map[string]string{
+		"GET /loki/api/v1/query_range": "loki_matrix.json",
+		"GET /loki/api/v1/query":       "loki_vector.json",
+	})
+	req := model.AggregateRequest{SearchRequest: testRequest, Interval: "30m", TopField: "service", TopSize: 5}
+	req.Keywords, req.Level = nil, ""
+	resp, err := (&LokiAdapter{httpClient: http.DefaultClient}).Aggregate(source, req)
+	if err != nil {
+		t.Fatalf("aggregate: %v", err)
+	}
+	if resp.Total != 20 || len(resp.Histogram) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if b := resp.Histogram[0]; b.Time != "2026-10-18T09:00:00Z" || b.Count != 10 || b.Errors != 3 || b.ErrorRate != 0.3 {
+		t.Fatalf("first bucket: %+v", b)
+	}
+	if b := resp.Histogram[1]; b.Count != 10 || b.Errors != 1 {
+		t.Fatalf("second bucket: %+v", b)
+	}
+	// Series naming the service through different labels merge; unnamed ones drop.
+	want := []model.TermCount{{Value: "order-api", Count: 15}, {Value: "billing", Count: 8}}
+	if len(resp.Top) != 2 || resp.Top[0] != want[0] || resp.Top[1] != want[1] {
+		t.Fatalf("top = %+v", resp.Top)
+	}
+
+	rangeParams, _ := url.ParseQuery((*requests)[0].query)
+	if q := rangeParams.Get("query"); q != `sum by (level, detected_level, severity) (count_over_time({service_name=~".+"} | service_name="order-api" or service="order-api" or app="order-api" or job="order-api" [1800s]))` {
+		t.Errorf("histogram query = %s", q)
+	}
+	if rangeParams.Get("start") != "1792315800000000000" || rangeParams.Get("end") != "1792317600000000000" || rangeParams.Get("step") != "1800" {
+		t.Errorf("unexpected range params: %v", rangeParams)
+	}
+	topParams, _ := url.ParseQuery((*requests)[1].query)
+	if q := topParams.Get("query"); q != `sum by (service_name, service, app, job) (count_over_time({service_name=~".+"} | service_name="order-api" or service="order-api" or app="order-api" or job="order-api" [3600s]))` {
+		t.Errorf("top query = %s", q)
+	}
+}
+
+func TestResolveHistogram(t *testing.T) {
+	h, err := resolveHistogram(model.AggregateRequest{SearchRequest: model.SearchRequest{
+		StartTime: "2026-10-18T09:07:30Z", EndTime: "2026-10-18T15:00:00Z",
+	}})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if h.Interval != 10*time.Minute || h.Start.Format(time.RFC3339) != "2026-10-18T09:00:00Z" {
+		t.Fatalf("got interval %s from %s", h.Interval, h.Start)
+	}
+	for _, interval := range []string{"1ms", "abc", "1s"} {
+		_, err := resolveHistogram(model.AggregateRequest{Interval: interval, SearchRequest: model.SearchRequest{
+			StartTime: "2026-10-18T09:00:00Z", EndTime: "2026-10-18T15:00:00Z",
+		}})
+		if err == nil {
+			t.Errorf("interval %s should be rejected", interval)
+		}
+	}
+}
+
+func TestBackends_Count(t *testing.T) {
+	es, esRequests := fixtureServer(t, map[string]string{"POST /app-logs-*/_count": "elasticsearch_count.json"})
+	n, err := (&ElasticsearchAdapter{httpClient: http.DefaultClient}).Count(es, testRequest)
+	if err != nil || n != 57 {
+		t.Fatalf("elasticsearch count = %d, %v", n, err)
+	}
+	body := decodeBody(t, (*esRequests)[0].body)
+	if _, ok := body["query"]; !ok || len(body) != 1 {
+		t.Errorf("count body should hold only the query: %v", body)
+	}
+
+	loki, lokiRequests := fixtureServer(t, map[string]string{"GET /loki/api/v1/query": "loki_vector.json"})
+	req := testRequest
+	req.Keywords, req.Level = nil, ""
+	n, err = (&LokiAdapter{httpClient: http.DefaultClient}).Count(loki, req)
+	if err != nil || n != 25 {
+		t.Fatalf("loki count = %d, %v", n, err)
+	}
+	params, _ := url.ParseQuery((*lokiRequests)[0].query)
+	if q := params.Get("query"); q != `sum(count_over_time({service_name=~".+"} | service_name="order-api" or service="order-api" or app="order-api" or job="order-api" [3600s]))` {
+		t.Errorf("count query = %s", q)
+	}
+}
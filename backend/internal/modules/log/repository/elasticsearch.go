@@ -0,0 +1,424 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/log/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// --- Elasticsearch Adapter ---
+
+type ElasticsearchAdapter struct {
+	httpClient *http.Client
+}
+
+type esSearchRequest struct {
+	Size  int `json:"size"`
+	From  int `json:"from"`
+	Query struct {
+		Bool struct {
+			Must []interface{} `json:"must"`
+		} `json:"bool"`
+	} `json:"query"`
+	Sort []map[string]interface{} `json:"sort"`
+	// TrackTotalHits asks for an exact total beyond 10000 hits.
+	TrackTotalHits bool                   `json:"track_total_hits,omitempty"`
+	Aggs           map[string]interface{} `json:"aggs,omitempty"`
+}
+
+func (a *ElasticsearchAdapter) Search(source *model.LogSource, req model.SearchRequest) (*model.SearchResponse, error) {
+	req = normalizePage(req)
+	esReq := buildDSLQuery(req)
+	resp, err := dslRequest(a.httpClient, source, "POST", "/"+indexPattern(source)+"/_search", esReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return parseDSLResponse(resp.Body, req.Page, req.PageSize)
+}
+
+func (a *ElasticsearchAdapter) Aggregate(source *model.LogSource, req model.AggregateRequest) (*model.AggregateResponse, error) {
+	return dslAggregate(a.httpClient, source, req, false)
+}
+
+func (a *ElasticsearchAdapter) Count(source *model.LogSource, req model.SearchRequest) (int64, error) {
+	return dslCount(a.httpClient, source, req)
+}
+
+func (a *ElasticsearchAdapter) HealthCheck(source *model.LogSource) error {
+	resp, err := dslRequest(a.httpClient, source, "GET", "/_cluster/health", nil)
+	if err != nil {
+		return obserr.Wrap("LOG_CONNECT_FAILED", op, "ES health check failed", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (a *ElasticsearchAdapter) Fields(source *model.LogSource) ([]model.LogField, error) {
+	return dslFields(a.httpClient, source)
+}
+
+func normalizePage(req model.SearchRequest) model.SearchRequest {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+	return req
+}
+
+func indexPattern(source *model.LogSource) string {
+	if source.IndexPattern == "" {
+		return "app-logs-*"
+	}
+	return source.IndexPattern
+}
+
+func sortOrder(req model.SearchRequest) string {
+	if strings.EqualFold(req.SortOrder, "asc") {
+		return "asc"
+	}
+	return "desc"
+}
+
+// buildDSLQuery translates a search into the query DSL shared by
+// Elasticsearch and OpenSearch.
+func buildDSLQuery(req model.SearchRequest) esSearchRequest {
+	esReq := esSearchRequest{
+		Size: req.PageSize,
+		From: (req.Page - 1) * req.PageSize,
+	}
+	esReq.Sort = []map[string]interface{}{
+		{"@timestamp": map[string]string{"order": sortOrder(req)}},
+	}
+
+	// Build query filters
+	var must []interface{}
+
+	// Time range
+	if req.StartTime != "" || req.EndTime != "" {
+		timeRange := map[string]interface{}{}
+		if req.StartTime != "" {
+			timeRange["gte"] = req.StartTime
+		}
+		if req.EndTime != "" {
+			timeRange["lte"] = req.EndTime
+		}
+		must = append(must, map[string]interface{}{
+			"range": map[string]interface{}{"@timestamp": timeRange},
+		})
+	}
+
+	// Keywords (search across message field)
+	if len(req.Keywords) > 0 {
+		var should []interface{}
+		for _, kw := range req.Keywords {
+			should = append(should, map[string]interface{}{
+				"match": map[string]string{"message": kw},
+			})
+			should = append(should, map[string]interface{}{
+				"wildcard": map[string]string{"message": fmt.Sprintf("*%s*", kw)},
+			})
+		}
+		must = append(must, map[string]interface{}{
+			"bool": map[string]interface{}{"should": should, "minimum_should_match": 1},
+		})
+	}
+
+	// Level filter
+	if req.Level != "" {
+		must = append(must, map[string]interface{}{
+			"term": map[string]string{"level.keyword": strings.ToUpper(req.Level)},
+		})
+	}
+
+	// Service filter
+	if req.Service != "" {
+		must = append(must, map[string]interface{}{
+			"term": map[string]string{"service.keyword": req.Service},
+		})
+	}
+
+	// Host filter
+	if req.Host != "" {
+		must = append(must, map[string]interface{}{
+			"term": map[string]string{"host.keyword": req.Host},
+		})
+	}
+
+	if len(must) > 0 {
+		esReq.Query.Bool.Must = must
+	} else {
+		// Match all if no filters
+		esReq.Query.Bool.Must = []interface{}{map[string]interface{}{"match_all": map[string]interface{}{}}}
+	}
+	return esReq
+}
+
+// dslRequest sends a JSON request to an Elasticsearch compatible endpoint and
+// returns the response when its status is below 400.
+func dslRequest(client *http.Client, source *model.LogSource, method, path string, payload interface{}) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, obserr.Wrap("LOG_SEARCH_FAILED", op, "failed to marshal query", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	u := strings.TrimRight(source.Endpoint, "/") + path
+	httpReq, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, obserr.Wrap("LOG_SEARCH_FAILED", op, "failed to build request", err)
+	}
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if source.Username != "" {
+		httpReq.SetBasicAuth(source.Username, source.Password)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, obserr.Wrap("LOG_SEARCH_FAILED", op, fmt.Sprintf("%s request failed", source.Type), err)
+	}
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, obserr.New("LOG_SEARCH_FAILED", op, fmt.Sprintf("%s returned %d: %s", source.Type, resp.StatusCode, string(respBody)))
+	}
+	return resp, nil
+}
+
+// dslTotal accepts both hits.total forms: {"value": n} and a bare number.
+type dslTotal struct {
+	Value int64 `json:"value"`
+}
+
+func (t *dslTotal) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '{' {
+		return json.Unmarshal(data, &t.Value)
+	}
+	var obj struct {
+		Value int64 `json:"value"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	t.Value = obj.Value
+	return nil
+}
+
+func parseDSLResponse(body io.Reader, page, pageSize int) (*model.SearchResponse, error) {
+	var raw struct {
+		Hits struct {
+			Total dslTotal `json:"total"`
+			Hits  []struct {
+				Source struct {
+					Timestamp string `json:"@timestamp"`
+					Level     string `json:"level"`
+					Service   string `json:"service"`
+					Message   string `json:"message"`
+					Host      string `json:"host"`
+					TraceID   string `json:"traceId"`
+				} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return nil, obserr.Wrap("LOG_PARSE_FAILED", op, "failed to parse search response", err)
+	}
+
+	var entries []model.LogEntry
+	for _, h := range raw.Hits.Hits {
+		entries = append(entries, model.LogEntry{
+			Timestamp: h.Source.Timestamp,
+			Level:     h.Source.Level,
+			Service:   h.Source.Service,
+			Message:   h.Source.Message,
+			Host:      h.Source.Host,
+			TraceID:   h.Source.TraceID,
+		})
+	}
+	return pagedResponse(entries, raw.Hits.Total.Value, page, pageSize), nil
+}
+
+func pagedResponse(entries []model.LogEntry, total int64, page, pageSize int) *model.SearchResponse {
+	totalPages := int(total) / pageSize
+	if int(total)%pageSize != 0 {
+		totalPages++
+	}
+	return &model.SearchResponse{
+		Entries:    entries,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}
+}
+
+// dslFields reads the index mappings and flattens nested properties into
+// dotted field names.
+func dslFields(client *http.Client, source *model.LogSource) ([]model.LogField, error) {
+	resp, err := dslRequest(client, source, "GET", "/"+indexPattern(source)+"/_mapping", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var raw map[string]struct {
+		Mappings struct {
+			Properties map[string]dslProperty `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, obserr.Wrap("LOG_PARSE_FAILED", op, "failed to parse mapping", err)
+	}
+	types := map[string]string{}
+	for _, index := range raw {
+		collectFields(types, "", index.Mappings.Properties)
+	}
+	fields := make([]model.LogField, 0, len(types))
+	for name, typ := range types {
+		fields = append(fields, model.LogField{Name: name, Type: typ})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields, nil
+}
+
+type dslProperty struct {
+	Type       string                 `json:"type"`
+	Properties map[string]dslProperty `json:"properties"`
+}
+
+func collectFields(types map[string]string, prefix string, props map[string]dslProperty) {
+	for name, p := range props {
+		if len(p.Properties) > 0 {
+			collectFields(types, prefix+name+".", p.Properties)
+			continue
+		}
+		types[prefix+name] = p.Type
+	}
+}
+
+// dslCount uses the _count API, which counts exactly without fetching hits.
+func dslCount(client *http.Client, source *model.LogSource, req model.SearchRequest) (int64, error) {
+	query := buildDSLQuery(req)
+	resp, err := dslRequest(client, source, "POST", "/"+indexPattern(source)+"/_count", map[string]interface{}{"query": query.Query})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	var raw struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return 0, obserr.Wrap("LOG_PARSE_FAILED", op, "failed to parse count response", err)
+	}
+	return raw.Count, nil
+}
+
+// dslKeywordFields are the text fields whose keyword sub-field the filters and
+// terms aggregations use.
+var dslKeywordFields = map[string]bool{"service": true, "host": true, "level": true}
+
+// dslAggregate runs a date histogram, with an error count per bucket, and a
+// terms aggregation on the top field in one size-0 search.
+func dslAggregate(client *http.Client, source *model.LogSource, req model.AggregateRequest, trackTotal bool) (*model.AggregateResponse, error) {
+	h, err := resolveHistogram(req)
+	if err != nil {
+		return nil, err
+	}
+	search := req.SearchRequest
+	search.StartTime = h.Start.UTC().Format(time.RFC3339Nano)
+	search.EndTime = h.End.UTC().Format(time.RFC3339Nano)
+	search.Page, search.PageSize = 1, 1
+	query := buildDSLQuery(search)
+	query.Size, query.From, query.Sort = 0, 0, nil
+	query.TrackTotalHits = trackTotal
+	query.Aggs = map[string]interface{}{
+		"histogram": map[string]interface{}{
+			"date_histogram": map[string]interface{}{
+				"field":           "@timestamp",
+				"fixed_interval":  fmt.Sprintf("%ds", int64(h.Interval/time.Second)),
+				"min_doc_count":   0,
+				"extended_bounds": map[string]int64{"min": h.Start.UnixMilli(), "max": h.End.UnixMilli() - 1},
+			},
+			"aggs": map[string]interface{}{
+				"errors": map[string]interface{}{
+					"filter": map[string]interface{}{"terms": map[string][]string{"level.keyword": errorLevels}},
+				},
+			},
+		},
+	}
+	if req.TopField != "" {
+		field := req.TopField
+		if dslKeywordFields[field] {
+			field += ".keyword"
+		}
+		query.Aggs["top"] = map[string]interface{}{
+			"terms": map[string]interface{}{"field": field, "size": req.TopSize},
+		}
+	}
+
+	resp, err := dslRequest(client, source, "POST", "/"+indexPattern(source)+"/_search", query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var raw struct {
+		Hits struct {
+			Total dslTotal `json:"total"`
+		} `json:"hits"`
+		Aggregations struct {
+			Histogram struct {
+				Buckets []struct {
+					Key      int64 `json:"key"`
+					DocCount int64 `json:"doc_count"`
+					Errors   struct {
+						DocCount int64 `json:"doc_count"`
+					} `json:"errors"`
+				} `json:"buckets"`
+			} `json:"histogram"`
+			Top struct {
+				Buckets []struct {
+					Key      json.RawMessage `json:"key"`
+					DocCount int64           `json:"doc_count"`
+				} `json:"buckets"`
+			} `json:"top"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, obserr.Wrap("LOG_PARSE_FAILED", op, "failed to parse aggregation response", err)
+	}
+
+	out := &model.AggregateResponse{
+		Interval: int64(h.Interval / time.Second),
+		Total:    raw.Hits.Total.Value,
+		TopField: req.TopField,
+	}
+	for _, b := range raw.Aggregations.Histogram.Buckets {
+		out.Histogram = append(out.Histogram, model.HistogramBucket{
+			Time:   time.UnixMilli(b.Key).UTC().Format(time.RFC3339),
+			Count:  b.DocCount,
+			Errors: b.Errors.DocCount,
+		})
+	}
+	for _, b := range raw.Aggregations.Top.Buckets {
+		// Keys of numeric and boolean fields are not strings.
+		var value string
+		if err := json.Unmarshal(b.Key, &value); err != nil {
+			value = string(b.Key)
+		}
+		out.Top = append(out.Top, model.TermCount{Value: value, Count: b.DocCount})
+	}
+	return finishAggregate(out), nil
+}
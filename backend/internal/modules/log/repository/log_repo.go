@@ -1,12 +1,8 @@
 package repository
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"time"
 
 	"devops-platform/internal/modules/log/model"
@@ -17,29 +13,53 @@ import (
 
 const op = "log/repository"
 
-// LogSourceAdapter abstracts a log backend
-type LogSourceAdapter interface {
+// LogBackend abstracts a log store. Each backend translates SearchRequest
+// into its own query language.
+type LogBackend interface {
 	Search(source *model.LogSource, req model.SearchRequest) (*model.SearchResponse, error)
+	// Aggregate counts the entries a search matches per time bucket and per
+	// value of a field.
+	Aggregate(source *model.LogSource, req model.AggregateRequest) (*model.AggregateResponse, error)
+	// Count returns how many entries a search matches in its time range.
+	Count(source *model.LogSource, req model.SearchRequest) (int64, error)
 	HealthCheck(source *model.LogSource) error
+	// Fields lists the fields (or labels) that log entries of the source carry.
+	Fields(source *model.LogSource) ([]model.LogField, error)
 }
 
-// LogRepo manages log sources and delegates to adapters
+// LogRepo manages log sources and delegates to backends
 type LogRepo struct {
 	db         *gorm.DB
 	httpClient *http.Client
-	adapters   map[string]LogSourceAdapter
+	backends   map[string]LogBackend
 }
 
 func NewLogRepo(db *gorm.DB) *LogRepo {
 	r := &LogRepo{
 		db:         db,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
-		adapters:   make(map[string]LogSourceAdapter),
+		backends:   make(map[string]LogBackend),
 	}
-	r.adapters["elasticsearch"] = &ElasticsearchAdapter{httpClient: r.httpClient}
+	r.backends[model.SourceElasticsearch] = &ElasticsearchAdapter{httpClient: r.httpClient}
+	r.backends[model.SourceOpenSearch] = &OpenSearchAdapter{httpClient: r.httpClient}
+	r.backends[model.SourceLoki] = &LokiAdapter{httpClient: r.httpClient}
 	return r
 }
 
+// Supports reports whether a backend is registered for the source type.
+func (r *LogRepo) Supports(sourceType string) bool {
+	_, ok := r.backends[sourceType]
+	return ok
+}
+
+func (r *LogRepo) backend(src *model.LogSource) (LogBackend, error) {
+	b, ok := r.backends[src.Type]
+	if !ok {
+		return nil, obserr.New("LOG_UNSUPPORTED_TYPE", op, fmt.Sprintf("unsupported log source type: %s", src.Type))
+	}
+	return b, nil
+}
+
 // --- Source CRUD ---
 
 func (r *LogRepo) ListSources(page, pageSize int) ([]model.LogSource, int64, error) {
@@ -75,219 +95,73 @@ func (r *LogRepo) DeleteSource(id uint) error {
 	return nil
 }
 
-// --- Search (delegates to adapter) ---
+// --- Search (delegates to backend) ---
 
 func (r *LogRepo) Search(sourceID uint, req model.SearchRequest) (*model.SearchResponse, error) {
 	src, err := r.GetSource(sourceID)
 	if err != nil {
 		return nil, obserr.Wrap("LOG_SOURCE_NOT_FOUND", op, "log source not found", err)
 	}
-	adapter, ok := r.adapters[src.Type]
-	if !ok {
-		return nil, obserr.New("LOG_UNSUPPORTED_TYPE", op, fmt.Sprintf("unsupported log source type: %s", src.Type))
-	}
-	return adapter.Search(src, req)
-}
-
-// --- Health check ---
-
-func (r *LogRepo) TestConnection(id uint) error {
-	src, err := r.GetSource(id)
+	b, err := r.backend(src)
 	if err != nil {
-		return obserr.Wrap("LOG_SOURCE_NOT_FOUND", op, "log source not found", err)
+		return nil, err
 	}
-	adapter, ok := r.adapters[src.Type]
-	if !ok {
-		return obserr.New("LOG_UNSUPPORTED_TYPE", op, fmt.Sprintf("unsupported type: %s", src.Type))
-	}
-	return adapter.HealthCheck(src)
+	return b.Search(src, req)
 }
 
-// --- Elasticsearch Adapter ---
-
-type ElasticsearchAdapter struct {
-	httpClient *http.Client
-}
-
-type esSearchRequest struct {
-	Size  int  `json:"size"`
-	From  int  `json:"from"`
-	Query struct {
-		Bool struct {
-			Must []interface{} `json:"must"`
-		} `json:"bool"`
-	} `json:"query"`
-	Sort []map[string]interface{} `json:"sort"`
-}
-
-func (a *ElasticsearchAdapter) Search(source *model.LogSource, req model.SearchRequest) (*model.SearchResponse, error) {
-	if req.Page <= 0 {
-		req.Page = 1
-	}
-	if req.PageSize <= 0 || req.PageSize > 100 {
-		req.PageSize = 20
-	}
-
-	esReq := esSearchRequest{
-		Size: req.PageSize,
-		From: (req.Page - 1) * req.PageSize,
-	}
-	esReq.Sort = []map[string]interface{}{
-		{"@timestamp": map[string]string{"order": "desc"}},
-	}
-
-	// Build query filters
-	var must []interface{}
-
-	// Time range
-	if req.StartTime != "" || req.EndTime != "" {
-		timeRange := map[string]interface{}{}
-		if req.StartTime != "" {
-			timeRange["gte"] = req.StartTime
-		}
-		if req.EndTime != "" {
-			timeRange["lte"] = req.EndTime
-		}
-		must = append(must, map[string]interface{}{
-			"range": map[string]interface{}{"@timestamp": timeRange},
-		})
-	}
-
-	// Keywords (search across message field)
-	if len(req.Keywords) > 0 {
-		var should []interface{}
-		for _, kw := range req.Keywords {
-			should = append(should, map[string]interface{}{
-				"match": map[string]string{"message": kw},
-			})
-			should = append(should, map[string]interface{}{
-				"wildcard": map[string]string{"message": fmt.Sprintf("*%s*", kw)},
-			})
-		}
-		must = append(must, map[string]interface{}{
-			"bool": map[string]interface{}{"should": should, "minimum_should_match": 1},
-		})
-	}
-
-	// Level filter
-	if req.Level != "" {
-		must = append(must, map[string]interface{}{
-			"term": map[string]string{"level.keyword": strings.ToUpper(req.Level)},
-		})
-	}
-
-	// Service filter
-	if req.Service != "" {
-		must = append(must, map[string]interface{}{
-			"term": map[string]string{"service.keyword": req.Service},
-		})
-	}
-
-	if len(must) > 0 {
-		esReq.Query.Bool.Must = must
-	} else {
-		// Match all if no filters
-		esReq.Query.Bool.Must = []interface{}{map[string]interface{}{"match_all": map[string]interface{}{}}}
-	}
-
-	body, err := json.Marshal(esReq)
+func (r *LogRepo) Aggregate(sourceID uint, req model.AggregateRequest) (*model.AggregateResponse, error) {
+	src, err := r.GetSource(sourceID)
 	if err != nil {
-		return nil, obserr.Wrap("LOG_SEARCH_FAILED", op, "failed to marshal ES query", err)
-	}
-
-	index := source.IndexPattern
-	if index == "" {
-		index = "app-logs-*"
+		return nil, obserr.Wrap("LOG_SOURCE_NOT_FOUND", op, "log source not found", err)
 	}
-	u := strings.TrimRight(source.Endpoint, "/") + "/" + index + "/_search"
-
-	httpReq, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	b, err := r.backend(src)
 	if err != nil {
-		return nil, obserr.Wrap("LOG_SEARCH_FAILED", op, "failed to build ES request", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	if source.Username != "" {
-		httpReq.SetBasicAuth(source.Username, source.Password)
+		return nil, err
 	}
+	return b.Aggregate(src, req)
+}
 
-	resp, err := a.httpClient.Do(httpReq)
+func (r *LogRepo) Count(sourceID uint, req model.SearchRequest) (int64, error) {
+	src, err := r.GetSource(sourceID)
 	if err != nil {
-		return nil, obserr.Wrap("LOG_SEARCH_FAILED", op, "ES search request failed", err)
+		return 0, obserr.Wrap("LOG_SOURCE_NOT_FOUND", op, "log source not found", err)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, obserr.New("LOG_SEARCH_FAILED", op, fmt.Sprintf("ES returned %d: %s", resp.StatusCode, string(respBody)))
+	b, err := r.backend(src)
+	if err != nil {
+		return 0, err
 	}
-
-	return a.parseESResponse(resp.Body, req.Page, req.PageSize)
+	return b.Count(src, req)
 }
 
-func (a *ElasticsearchAdapter) HealthCheck(source *model.LogSource) error {
-	u := strings.TrimRight(source.Endpoint, "/") + "/_cluster/health"
-	req, err := http.NewRequest("GET", u, nil)
+// --- Field discovery ---
+
+func (r *LogRepo) Fields(sourceID uint) ([]model.LogField, error) {
+	src, err := r.GetSource(sourceID)
 	if err != nil {
-		return obserr.New("LOG_CONNECT_FAILED", op, "failed to build health check request")
-	}
-	if source.Username != "" {
-		req.SetBasicAuth(source.Username, source.Password)
+		return nil, obserr.Wrap("LOG_SOURCE_NOT_FOUND", op, "log source not found", err)
 	}
-	resp, err := a.httpClient.Do(req)
+	b, err := r.backend(src)
 	if err != nil {
-		return obserr.Wrap("LOG_CONNECT_FAILED", op, "ES health check failed", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		return obserr.New("LOG_CONNECT_FAILED", op, fmt.Sprintf("ES returned %d", resp.StatusCode))
+		return nil, err
 	}
-	return nil
+	return b.Fields(src)
 }
 
-func (a *ElasticsearchAdapter) parseESResponse(body io.Reader, page, pageSize int) (*model.SearchResponse, error) {
-	var raw struct {
-		Hits struct {
-			Total struct {
-				Value    int64  `json:"value"`
-				Relation string `json:"relation"`
-			} `json:"total"`
-			Hits []struct {
-				Source struct {
-					Timestamp string `json:"@timestamp"`
-					Level     string `json:"level"`
-					Service   string `json:"service"`
-					Message   string `json:"message"`
-					Host      string `json:"host"`
-					TraceID   string `json:"traceId"`
-				} `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
-	}
-	if err := json.NewDecoder(body).Decode(&raw); err != nil {
-		return nil, obserr.Wrap("LOG_PARSE_FAILED", op, "failed to parse ES response", err)
-	}
+// --- Health check ---
 
-	var entries []model.LogEntry
-	for _, h := range raw.Hits.Hits {
-		entries = append(entries, model.LogEntry{
-			Timestamp: h.Source.Timestamp,
-			Level:     h.Source.Level,
-			Service:   h.Source.Service,
-			Message:   h.Source.Message,
-			Host:      h.Source.Host,
-			TraceID:   h.Source.TraceID,
-		})
+func (r *LogRepo) TestConnection(id uint) error {
+	src, err := r.GetSource(id)
+	if err != nil {
+		return obserr.Wrap("LOG_SOURCE_NOT_FOUND", op, "log source not found", err)
 	}
+	return r.HealthCheck(src)
+}
 
-	total := raw.Hits.Total.Value
-	totalPages := int(total) / pageSize
-	if int(total)%pageSize != 0 {
-		totalPages++
+// HealthCheck checks a source that may not be saved yet.
+func (r *LogRepo) HealthCheck(src *model.LogSource) error {
+	b, err := r.backend(src)
+	if err != nil {
+		return err
 	}
-	return &model.SearchResponse{
-		Entries:    entries,
-		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
-	}, nil
+	return b.HealthCheck(src)
 }
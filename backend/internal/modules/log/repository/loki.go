@@ -0,0 +1,404 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/log/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// --- Loki Adapter ---
+
+// lokiMaxEntries matches Loki's default max_entries_limit_per_query.
+const lokiMaxEntries = 5000
+
+// Labels that carry the fields of a LogEntry, by preference.
+var (
+	lokiServiceLabels = []string{"service_name", "service", "app", "job"}
+	lokiHostLabels    = []string{"host", "hostname", "pod", "instance"}
+	lokiLevelLabels   = []string{"level", "detected_level", "severity"}
+)
+
+// lokiLabelName matches the label names LogQL accepts in a grouping.
+var lokiLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// lokiDefaultSelector matches every stream Loki tagged with a service.
+const lokiDefaultSelector = `{service_name=~".+"}`
+
+// LokiAdapter queries Loki's query_range API with LogQL. Loki has no offset
+// and no hit count, so a page is cut from the newest (or oldest)
+// page*pageSize lines and Total counts the lines fetched, at most
+// lokiMaxEntries.
+type LokiAdapter struct {
+	httpClient *http.Client
+}
+
+func (a *LokiAdapter) Search(source *model.LogSource, req model.SearchRequest) (*model.SearchResponse, error) {
+	req = normalizePage(req)
+	start, end, err := searchTimeRange(req.StartTime, req.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	limit := req.Page * req.PageSize
+	if limit > lokiMaxEntries {
+		limit = lokiMaxEntries
+	}
+	direction := "backward"
+	if sortOrder(req) == "asc" {
+		direction = "forward"
+	}
+	params := url.Values{}
+	params.Set("query", buildLogQL(source, req))
+	params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("direction", direction)
+
+	var raw struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Stream map[string]string `json:"stream"`
+				Values [][2]string       `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := a.get(source, "/loki/api/v1/query_range?"+params.Encode(), &raw); err != nil {
+		return nil, err
+	}
+	if raw.Data.ResultType != "" && raw.Data.ResultType != "streams" {
+		return nil, obserr.New("LOG_PARSE_FAILED", op, fmt.Sprintf("unexpected Loki result type %s", raw.Data.ResultType))
+	}
+
+	type line struct {
+		ns    int64
+		entry model.LogEntry
+	}
+	var lines []line
+	for _, stream := range raw.Data.Result {
+		for _, v := range stream.Values {
+			ns, _ := strconv.ParseInt(v[0], 10, 64)
+			lines = append(lines, line{ns: ns, entry: lokiEntry(stream.Stream, ns, v[1])})
+		}
+	}
+	// Streams come back one after another; merge them into one timeline.
+	sort.SliceStable(lines, func(i, j int) bool {
+		if direction == "forward" {
+			return lines[i].ns < lines[j].ns
+		}
+		return lines[i].ns > lines[j].ns
+	})
+	if len(lines) > limit {
+		lines = lines[:limit]
+	}
+	var entries []model.LogEntry
+	for i := (req.Page - 1) * req.PageSize; i < len(lines); i++ {
+		entries = append(entries, lines[i].entry)
+	}
+	return pagedResponse(entries, int64(len(lines)), req.Page, req.PageSize), nil
+}
+
+func (a *LokiAdapter) HealthCheck(source *model.LogSource) error {
+	if err := a.get(source, "/ready", nil); err != nil {
+		return obserr.Wrap("LOG_CONNECT_FAILED", op, "Loki health check failed", err)
+	}
+	return nil
+}
+
+// Fields lists the labels of streams seen in the last six hours.
+func (a *LokiAdapter) Fields(source *model.LogSource) ([]model.LogField, error) {
+	end := time.Now()
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(end.Add(-6*time.Hour).UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	var raw struct {
+		Data []string `json:"data"`
+	}
+	if err := a.get(source, "/loki/api/v1/labels?"+params.Encode(), &raw); err != nil {
+		return nil, err
+	}
+	sort.Strings(raw.Data)
+	fields := make([]model.LogField, 0, len(raw.Data))
+	for _, name := range raw.Data {
+		fields = append(fields, model.LogField{Name: name, Type: "label"})
+	}
+	return fields, nil
+}
+
+func (a *LokiAdapter) get(source *model.LogSource, path string, result interface{}) error {
+	u := strings.TrimRight(source.Endpoint, "/") + path
+	httpReq, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return obserr.Wrap("LOG_SEARCH_FAILED", op, "failed to build Loki request", err)
+	}
+	if source.Username != "" {
+		httpReq.SetBasicAuth(source.Username, source.Password)
+	}
+	resp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return obserr.Wrap("LOG_SEARCH_FAILED", op, "Loki request failed", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return obserr.New("LOG_SEARCH_FAILED", op, fmt.Sprintf("Loki returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody))))
+	}
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return obserr.Wrap("LOG_PARSE_FAILED", op, "failed to parse Loki response", err)
+		}
+	}
+	return nil
+}
+
+// buildLogQL translates a search into LogQL:
+//   - the source's IndexPattern, when it is a stream selector, narrows the streams,
+//   - Service matches any of the labels lokiEntry reads the service from, as
+//     a label filter since a stream selector cannot match one of several labels,
+//   - Keywords become one case-insensitive line filter matching any of them,
+//   - Level becomes a case-insensitive filter on the level label, which
+//     works for stream labels and for labels a parser stage extracted,
+//   - Host matches any of the labels lokiEntry reads the host from.
+func buildLogQL(source *model.LogSource, req model.SearchRequest) string {
+	selector := lokiDefaultSelector
+	if p := strings.TrimSpace(source.IndexPattern); strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+		selector = p
+	}
+	query := selector
+	if req.Service != "" {
+		query += " | " + anyLabelEquals(lokiServiceLabels, req.Service)
+	}
+	var keywords []string
+	for _, kw := range req.Keywords {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			keywords = append(keywords, regexp.QuoteMeta(kw))
+		}
+	}
+	if len(keywords) > 0 {
+		query += " |~ " + strconv.Quote("(?i)("+strings.Join(keywords, "|")+")")
+	}
+	if req.Level != "" {
+		query += " | level=~" + strconv.Quote("(?i)"+regexp.QuoteMeta(req.Level))
+	}
+	if req.Host != "" {
+		query += " | " + anyLabelEquals(lokiHostLabels, req.Host)
+	}
+	return query
+}
+
+// anyLabelEquals is a label filter matching lines whose value is in any of
+// the labels.
+func anyLabelEquals(labels []string, value string) string {
+	matchers := make([]string, 0, len(labels))
+	for _, label := range labels {
+		matchers = append(matchers, label+"="+strconv.Quote(value))
+	}
+	return strings.Join(matchers, " or ")
+}
+
+// searchTimeRange parses a request's time range, defaulting to the last hour.
+func searchTimeRange(startStr, endStr string) (time.Time, time.Time, error) {
+	end := time.Now()
+	if endStr != "" {
+		t, err := parseLogTime(endStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		end = t
+	}
+	start := end.Add(-time.Hour)
+	if startStr != "" {
+		t, err := parseLogTime(startStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		start = t
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, obserr.New("INVALID_PARAM", op, "startTime must be before endTime")
+	}
+	return start, end, nil
+}
+
+// parseLogTime accepts RFC 3339, "2006-01-02 15:04:05" (local time) and Unix
+// milliseconds.
+func parseLogTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local); err == nil {
+		return t, nil
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Time{}, obserr.New("INVALID_PARAM", op, fmt.Sprintf("invalid time %q", s))
+}
+
+// lokiEntry maps a log line and its stream labels onto a LogEntry.
+func lokiEntry(labels map[string]string, ns int64, line string) model.LogEntry {
+	return model.LogEntry{
+		Timestamp: time.Unix(0, ns).UTC().Format(time.RFC3339Nano),
+		Level:     strings.ToUpper(firstLabel(labels, lokiLevelLabels)),
+		Service:   firstLabel(labels, lokiServiceLabels),
+		Message:   line,
+		Host:      firstLabel(labels, lokiHostLabels),
+		TraceID:   firstLabel(labels, []string{"trace_id", "traceId"}),
+	}
+}
+
+func firstLabel(labels map[string]string, names []string) string {
+	for _, n := range names {
+		if v := labels[n]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Aggregate runs LogQL metric queries: count_over_time summed by level over
+// each interval for the histogram, and over the whole range, summed by the
+// labels of the top field, for the top-N list. A bucket counts the lines
+// after its start up to and including its end.
+func (a *LokiAdapter) Aggregate(source *model.LogSource, req model.AggregateRequest) (*model.AggregateResponse, error) {
+	h, err := resolveHistogram(req)
+	if err != nil {
+		return nil, err
+	}
+	var topLabels []string
+	if req.TopField != "" {
+		if topLabels, err = lokiFieldLabels(req.TopField); err != nil {
+			return nil, err
+		}
+	}
+	selector := buildLogQL(source, req.SearchRequest)
+	step := int64(h.Interval / time.Second)
+	out := &model.AggregateResponse{Interval: step, Histogram: h.emptyBuckets(), TopField: req.TopField}
+
+	params := url.Values{}
+	params.Set("query", fmt.Sprintf("sum by (%s) (count_over_time(%s [%ds]))", strings.Join(lokiLevelLabels, ", "), selector, step))
+	params.Set("start", strconv.FormatInt(h.Start.Add(h.Interval).UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(h.Start.Add(h.Interval*time.Duration(len(out.Histogram))).UnixNano(), 10))
+	params.Set("step", strconv.FormatInt(step, 10))
+	var matrix lokiMetricResponse
+	if err := a.get(source, "/loki/api/v1/query_range?"+params.Encode(), &matrix); err != nil {
+		return nil, err
+	}
+	for _, series := range matrix.Data.Result {
+		isError := isErrorLevel(firstLabel(series.Metric, lokiLevelLabels))
+		for _, v := range series.Values {
+			at, count := lokiSample(v)
+			i := int(at.Sub(h.Start)/h.Interval) - 1
+			if i < 0 || i >= len(out.Histogram) {
+				continue
+			}
+			out.Histogram[i].Count += count
+			if isError {
+				out.Histogram[i].Errors += count
+			}
+		}
+	}
+
+	if topLabels != nil {
+		rangeSecs := int64((h.End.Sub(h.Start) + time.Second - 1) / time.Second)
+		params = url.Values{}
+		params.Set("query", fmt.Sprintf("sum by (%s) (count_over_time(%s [%ds]))", strings.Join(topLabels, ", "), selector, rangeSecs))
+		params.Set("time", strconv.FormatInt(h.End.UnixNano(), 10))
+		var vector lokiMetricResponse
+		if err := a.get(source, "/loki/api/v1/query?"+params.Encode(), &vector); err != nil {
+			return nil, err
+		}
+		counts := map[string]int64{}
+		for _, series := range vector.Data.Result {
+			if value := firstLabel(series.Metric, topLabels); value != "" {
+				_, count := lokiSample(series.Value)
+				counts[value] += count
+			}
+		}
+		for value, count := range counts {
+			out.Top = append(out.Top, model.TermCount{Value: value, Count: count})
+		}
+		sort.Slice(out.Top, func(i, j int) bool {
+			if out.Top[i].Count != out.Top[j].Count {
+				return out.Top[i].Count > out.Top[j].Count
+			}
+			return out.Top[i].Value < out.Top[j].Value
+		})
+		if req.TopSize > 0 && len(out.Top) > req.TopSize {
+			out.Top = out.Top[:req.TopSize]
+		}
+	}
+	return finishAggregate(out), nil
+}
+
+// Count sums count_over_time over the whole time range in one instant query.
+func (a *LokiAdapter) Count(source *model.LogSource, req model.SearchRequest) (int64, error) {
+	start, end, err := searchTimeRange(req.StartTime, req.EndTime)
+	if err != nil {
+		return 0, err
+	}
+	rangeSecs := int64((end.Sub(start) + time.Second - 1) / time.Second)
+	params := url.Values{}
+	params.Set("query", fmt.Sprintf("sum(count_over_time(%s [%ds]))", buildLogQL(source, req), rangeSecs))
+	params.Set("time", strconv.FormatInt(end.UnixNano(), 10))
+	var vector lokiMetricResponse
+	if err := a.get(source, "/loki/api/v1/query?"+params.Encode(), &vector); err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, series := range vector.Data.Result {
+		_, count := lokiSample(series.Value)
+		total += count
+	}
+	return total, nil
+}
+
+// lokiMetricResponse is the body of a metric query: a matrix (Values) from
+// query_range or a vector (Value) from an instant query.
+type lokiMetricResponse struct {
+	Data struct {
+		Result []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][]interface{}   `json:"values"`
+			Value  []interface{}     `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// lokiSample decodes a [<unix seconds>, "<value>"] sample.
+func lokiSample(v []interface{}) (time.Time, int64) {
+	if len(v) != 2 {
+		return time.Time{}, 0
+	}
+	secs, _ := v[0].(float64)
+	str, _ := v[1].(string)
+	value, _ := strconv.ParseFloat(str, 64)
+	return time.UnixMilli(int64(math.Round(secs * 1000))), int64(value)
+}
+
+// lokiFieldLabels maps a field onto the labels that may carry it.
+func lokiFieldLabels(field string) ([]string, error) {
+	switch field {
+	case "service":
+		return lokiServiceLabels, nil
+	case "host":
+		return lokiHostLabels, nil
+	case "level":
+		return lokiLevelLabels, nil
+	}
+	if !lokiLabelName.MatchString(field) {
+		return nil, obserr.New("INVALID_PARAM", op, fmt.Sprintf("%q is not a Loki label name", field))
+	}
+	return []string{field}, nil
+}
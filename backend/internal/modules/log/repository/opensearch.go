@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"devops-platform/internal/modules/log/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// --- OpenSearch Adapter ---
+
+// OpenSearchAdapter speaks the Elasticsearch query DSL OpenSearch kept from
+// its fork. It asks for exact totals, accepts the bare-number total of
+// rest_total_hits_as_int clients, and checks on health that the endpoint
+// really is OpenSearch.
+type OpenSearchAdapter struct {
+	httpClient *http.Client
+}
+
+func (a *OpenSearchAdapter) Search(source *model.LogSource, req model.SearchRequest) (*model.SearchResponse, error) {
+	req = normalizePage(req)
+	query := buildDSLQuery(req)
+	query.TrackTotalHits = true
+	resp, err := dslRequest(a.httpClient, source, "POST", "/"+indexPattern(source)+"/_search", query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return parseDSLResponse(resp.Body, req.Page, req.PageSize)
+}
+
+func (a *OpenSearchAdapter) Aggregate(source *model.LogSource, req model.AggregateRequest) (*model.AggregateResponse, error) {
+	return dslAggregate(a.httpClient, source, req, true)
+}
+
+func (a *OpenSearchAdapter) Count(source *model.LogSource, req model.SearchRequest) (int64, error) {
+	return dslCount(a.httpClient, source, req)
+}
+
+func (a *OpenSearchAdapter) HealthCheck(source *model.LogSource) error {
+	resp, err := dslRequest(a.httpClient, source, "GET", "/", nil)
+	if err != nil {
+		return obserr.Wrap("LOG_CONNECT_FAILED", op, "OpenSearch health check failed", err)
+	}
+	defer resp.Body.Close()
+	var info struct {
+		Version struct {
+			Distribution string `json:"distribution"`
+			Number       string `json:"number"`
+		} `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return obserr.Wrap("LOG_CONNECT_FAILED", op, "failed to parse OpenSearch info", err)
+	}
+	if info.Version.Distribution != "opensearch" {
+		return obserr.New("LOG_CONNECT_FAILED", op,
+			fmt.Sprintf("endpoint is not OpenSearch (version %s), use type elasticsearch", info.Version.Number))
+	}
+	return nil
+}
+
+func (a *OpenSearchAdapter) Fields(source *model.LogSource) ([]model.LogField, error) {
+	return dslFields(a.httpClient, source)
+}
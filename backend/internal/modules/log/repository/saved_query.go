@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+
+	"devops-platform/internal/modules/log/model"
+	"devops-platform/internal/pkg/obserr"
+
+	"gorm.io/gorm"
+)
+
+// --- Saved queries ---
+
+// ListSavedQueries returns the user's own queries and the ones shared with
+// the tenant, newest first.
+func (r *LogRepo) ListSavedQueries(tenantID, userID uint, keyword string) ([]model.SavedQuery, error) {
+	var items []model.SavedQuery
+	q := r.db.Where("tenant_id = ? AND (owner_id = ? OR visibility = ?)", tenantID, userID, model.VisibilityTenant)
+	if keyword != "" {
+		q = q.Where("name LIKE ?", "%"+keyword+"%")
+	}
+	if err := q.Order("updated_at DESC").Find(&items).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list saved queries failed", err)
+	}
+	return items, nil
+}
+
+func (r *LogRepo) GetSavedQuery(tenantID, id uint) (*model.SavedQuery, error) {
+	return r.findSavedQuery(r.db.Where("tenant_id = ? AND id = ?", tenantID, id))
+}
+
+func (r *LogRepo) GetSavedQueryByToken(tenantID uint, token string) (*model.SavedQuery, error) {
+	return r.findSavedQuery(r.db.Where("tenant_id = ? AND share_token = ?", tenantID, token))
+}
+
+func (r *LogRepo) findSavedQuery(q *gorm.DB) (*model.SavedQuery, error) {
+	var item model.SavedQuery
+	if err := q.First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, obserr.New("SAVED_QUERY_NOT_FOUND", op, "saved query not found")
+		}
+		return nil, obserr.Wrap("DB_ERROR", op, "get saved query failed", err)
+	}
+	return &item, nil
+}
+
+func (r *LogRepo) SaveSavedQuery(item *model.SavedQuery) error {
+	if err := r.db.Save(item).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "save saved query failed", err)
+	}
+	return nil
+}
+
+func (r *LogRepo) DeleteSavedQuery(tenantID, id uint) error {
+	if err := r.db.Where("tenant_id = ?", tenantID).Delete(&model.SavedQuery{}, id).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "delete saved query failed", err)
+	}
+	return nil
+}
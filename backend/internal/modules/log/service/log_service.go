@@ -1,6 +1,9 @@
 package service
 
 import (
+	"fmt"
+	"time"
+
 	"devops-platform/internal/modules/log/model"
 	"devops-platform/internal/modules/log/repository"
 	"devops-platform/internal/pkg/obserr"
@@ -12,10 +15,12 @@ const op = "log/service"
 
 type LogService struct {
 	repo *repository.LogRepo
+	// tailPoll is how often a live tail asks the backend for new entries.
+	tailPoll time.Duration
 }
 
 func NewLogService(db *gorm.DB) *LogService {
-	return &LogService{repo: repository.NewLogRepo(db)}
+	return &LogService{repo: repository.NewLogRepo(db), tailPoll: 2 * time.Second}
 }
 
 func (s *LogService) ListSources(page, pageSize int) ([]model.LogSource, int64, error) {
@@ -39,7 +44,13 @@ func (s *LogService) SaveSource(src *model.LogSource) error {
 	if src.Name == "" {
 		return obserr.New("INVALID_PARAM", op, "name is required")
 	}
-	if err := s.repo.TestConnection(src.ID); err != nil {
+	if src.Type == "" {
+		src.Type = model.SourceElasticsearch
+	}
+	if !s.repo.Supports(src.Type) {
+		return obserr.New("INVALID_PARAM", op, fmt.Sprintf("unsupported log source type: %s", src.Type))
+	}
+	if err := s.repo.HealthCheck(src); err != nil {
 		src.Status = "error"
 	} else {
 		src.Status = "connected"
@@ -55,6 +66,11 @@ func (s *LogService) TestConnection(id uint) error {
 	return s.repo.TestConnection(id)
 }
 
+// Fields lists the fields the source's log entries carry, for building queries.
+func (s *LogService) Fields(id uint) ([]model.LogField, error) {
+	return s.repo.Fields(id)
+}
+
 func (s *LogService) Search(req model.SearchRequest) (*model.SearchResponse, error) {
 	if req.Page <= 0 {
 		req.Page = 1
@@ -64,3 +80,29 @@ func (s *LogService) Search(req model.SearchRequest) (*model.SearchResponse, err
 	}
 	return s.repo.Search(req.SourceID, req)
 }
+
+// Top-N list sizes for Aggregate.
+const (
+	defaultTopSize = 10
+	maxTopSize     = 50
+)
+
+// Aggregate returns the count histogram, error rate per bucket and the top
+// values of req.TopField (service when empty) for the entries req matches.
+func (s *LogService) Aggregate(req model.AggregateRequest) (*model.AggregateResponse, error) {
+	if req.TopField == "" {
+		req.TopField = "service"
+	}
+	if req.TopSize <= 0 {
+		req.TopSize = defaultTopSize
+	}
+	if req.TopSize > maxTopSize {
+		req.TopSize = maxTopSize
+	}
+	return s.repo.Aggregate(req.SourceID, req)
+}
+
+// Count returns how many entries req matches between its start and end time.
+func (s *LogService) Count(req model.SearchRequest) (int64, error) {
+	return s.repo.Count(req.SourceID, req)
+}
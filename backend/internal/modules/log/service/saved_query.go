@@ -0,0 +1,138 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/log/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// ListSavedQueries returns the user's queries and those shared with the tenant.
+func (s *LogService) ListSavedQueries(tenantID, userID uint, keyword string) ([]model.SavedQuery, error) {
+	return s.repo.ListSavedQueries(tenantID, userID, strings.TrimSpace(keyword))
+}
+
+// GetSavedQuery returns a query the user may see, with its search resolved.
+// Private queries of other users are reported as not found.
+func (s *LogService) GetSavedQuery(tenantID, userID, id uint) (*model.SavedQueryView, error) {
+	item, err := s.repo.GetSavedQuery(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if item.OwnerID != userID && item.Visibility != model.VisibilityTenant {
+		return nil, obserr.New("SAVED_QUERY_NOT_FOUND", op, "saved query not found")
+	}
+	return viewSavedQuery(item, time.Now()), nil
+}
+
+// SharedQuery opens a share link: the token stands in for the visibility
+// check, within the tenant.
+func (s *LogService) SharedQuery(tenantID uint, token string) (*model.SavedQueryView, error) {
+	if token == "" {
+		return nil, obserr.New("SAVED_QUERY_NOT_FOUND", op, "saved query not found")
+	}
+	item, err := s.repo.GetSavedQueryByToken(tenantID, token)
+	if err != nil {
+		return nil, err
+	}
+	return viewSavedQuery(item, time.Now()), nil
+}
+
+// SaveSavedQuery creates a query owned by the user, or updates one of theirs.
+func (s *LogService) SaveSavedQuery(tenantID, userID uint, username string, item *model.SavedQuery) error {
+	item.Name = strings.TrimSpace(item.Name)
+	if item.Name == "" {
+		return obserr.New("INVALID_PARAM", op, "name is required")
+	}
+	if item.SourceID == 0 {
+		item.SourceID = item.Query.SourceID
+	}
+	if _, err := s.repo.GetSource(item.SourceID); err != nil {
+		return obserr.Wrap("INVALID_PARAM", op, "log source not found", err)
+	}
+	item.Query.SourceID = item.SourceID
+	item.TimeRange = strings.TrimSpace(item.TimeRange)
+	if item.TimeRange != "" {
+		if _, err := parseTimeRange(item.TimeRange); err != nil {
+			return err
+		}
+	}
+	switch item.Visibility {
+	case "":
+		item.Visibility = model.VisibilityPrivate
+	case model.VisibilityPrivate, model.VisibilityTenant:
+	default:
+		return obserr.New("INVALID_PARAM", op, "visibility must be private or tenant")
+	}
+
+	item.TenantID = tenantID
+	if item.ID == 0 {
+		token, err := newShareToken()
+		if err != nil {
+			return obserr.Wrap("SAVED_QUERY_SAVE_FAILED", op, "failed to generate share token", err)
+		}
+		item.OwnerID, item.OwnerName, item.ShareToken = userID, username, token
+		return s.repo.SaveSavedQuery(item)
+	}
+	existing, err := s.ownSavedQuery(tenantID, userID, item.ID)
+	if err != nil {
+		return err
+	}
+	item.OwnerID, item.OwnerName = existing.OwnerID, existing.OwnerName
+	item.ShareToken, item.CreatedAt = existing.ShareToken, existing.CreatedAt
+	return s.repo.SaveSavedQuery(item)
+}
+
+// DeleteSavedQuery removes one of the user's queries; its share link stops
+// working with it.
+func (s *LogService) DeleteSavedQuery(tenantID, userID, id uint) error {
+	if _, err := s.ownSavedQuery(tenantID, userID, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteSavedQuery(tenantID, id)
+}
+
+func (s *LogService) ownSavedQuery(tenantID, userID, id uint) (*model.SavedQuery, error) {
+	item, err := s.repo.GetSavedQuery(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if item.OwnerID != userID {
+		return nil, obserr.New("SAVED_QUERY_FORBIDDEN", op, "only the owner may change a saved query")
+	}
+	return item, nil
+}
+
+// viewSavedQuery resolves the query's relative time range against now.
+func viewSavedQuery(item *model.SavedQuery, now time.Time) *model.SavedQueryView {
+	search := item.Query
+	search.SourceID = item.SourceID
+	if d, err := parseTimeRange(item.TimeRange); err == nil && d > 0 {
+		search.StartTime = now.Add(-d).UTC().Format(time.RFC3339)
+		search.EndTime = now.UTC().Format(time.RFC3339)
+	}
+	return &model.SavedQueryView{SavedQuery: *item, Search: search}
+}
+
+// parseTimeRange is model.ParseTimeRange with an empty range allowed, as 0.
+func parseTimeRange(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := model.ParseTimeRange(s)
+	if err != nil {
+		return 0, obserr.New("INVALID_PARAM", op, "timeRange must be a duration such as 15m, 6h or 7d")
+	}
+	return d, nil
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/log/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+func TestSavedQuery_VisibilityAndOwnership(t *testing.T) {
+	svc, sourceID := setupLog(t, &fakeES{})
+	const tenant, alice, bob = 1, 10, 20
+
+	private := &model.SavedQuery{Name: "oom", SourceID: sourceID, Query: model.SearchRequest{Keywords: []string{"OutOfMemoryError"}}, TimeRange: "1h"}
+	if err := svc.SaveSavedQuery(tenant, alice, "alice", private); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if private.Visibility != model.VisibilityPrivate || private.OwnerName != "alice" || len(private.ShareToken) != 32 {
+		t.Fatalf("defaults not applied: %+v", private)
+	}
+	shared := &model.SavedQuery{Name: "errors", Query: model.SearchRequest{SourceID: sourceID, Level: "error"}, Visibility: model.VisibilityTenant}
+	if err := svc.SaveSavedQuery(tenant, alice, "alice", shared); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	items, _ := svc.ListSavedQueries(tenant, bob, "")
+	if len(items) != 1 || items[0].ID != shared.ID {
+		t.Fatalf("bob should list only the shared query, got %+v", items)
+	}
+	if _, err := svc.GetSavedQuery(tenant, bob, private.ID); obserr.Details(err)["code"] != "SAVED_QUERY_NOT_FOUND" {
+		t.Fatalf("bob must not open alice's private query, got %v", err)
+	}
+	if _, err := svc.GetSavedQuery(tenant+1, alice, shared.ID); err == nil {
+		t.Fatal("queries must not cross tenants")
+	}
+
+	// The share link opens the private query for anyone in the tenant.
+	view, err := svc.SharedQuery(tenant, private.ShareToken)
+	if err != nil || view.ID != private.ID {
+		t.Fatalf("share link: %+v, %v", view, err)
+	}
+	if _, err := svc.SharedQuery(tenant+1, private.ShareToken); err == nil {
+		t.Fatal("share links must not cross tenants")
+	}
+
+	edit := &model.SavedQuery{ID: shared.ID, Name: "hijack", SourceID: sourceID}
+	if err := svc.SaveSavedQuery(tenant, bob, "bob", edit); obserr.Details(err)["code"] != "SAVED_QUERY_FORBIDDEN" {
+		t.Fatalf("bob must not edit alice's query, got %v", err)
+	}
+	if err := svc.DeleteSavedQuery(tenant, bob, shared.ID); err == nil {
+		t.Fatal("bob must not delete alice's query")
+	}
+	edit = &model.SavedQuery{ID: shared.ID, Name: "errors (prod)", SourceID: sourceID, Visibility: model.VisibilityTenant}
+	if err := svc.SaveSavedQuery(tenant, alice, "alice", edit); err != nil {
+		t.Fatalf("owner update: %v", err)
+	}
+	if edit.ShareToken != shared.ShareToken || edit.OwnerID != alice {
+		t.Fatalf("update must keep owner and share link: %+v", edit)
+	}
+	if err := svc.DeleteSavedQuery(tenant, alice, shared.ID); err != nil {
+		t.Fatalf("owner delete: %v", err)
+	}
+}
+
+func TestSavedQuery_Validation(t *testing.T) {
+	svc, sourceID := setupLog(t, &fakeES{})
+	cases := []*model.SavedQuery{
+		{SourceID: sourceID},
+		{Name: "no-source"},
+		{Name: "range", SourceID: sourceID, TimeRange: "yesterday"},
+		{Name: "fractional-days", SourceID: sourceID, TimeRange: "1.5d"},
+		{Name: "visibility", SourceID: sourceID, Visibility: "public"},
+	}
+	for _, q := range cases {
+		if err := svc.SaveSavedQuery(1, 1, "u", q); err == nil {
+			t.Errorf("query %q should be rejected", q.Name)
+		}
+	}
+}
+
+func TestViewSavedQuery_ResolvesRelativeRange(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	q := &model.SavedQuery{SourceID: 3, TimeRange: "7d", Query: model.SearchRequest{Service: "api", StartTime: "2020-01-01T00:00:00Z"}}
+	search := viewSavedQuery(q, now).Search
+	if search.SourceID != 3 || search.Service != "api" || search.StartTime != "2026-10-11T10:00:00Z" || search.EndTime != "2026-10-18T10:00:00Z" {
+		t.Fatalf("unexpected search: %+v", search)
+	}
+	q.TimeRange = ""
+	if search := viewSavedQuery(q, now).Search; search.StartTime != "2020-01-01T00:00:00Z" {
+		t.Fatalf("absolute range must be kept: %+v", search)
+	}
+}
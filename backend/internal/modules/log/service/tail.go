@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"devops-platform/internal/modules/log/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// tailBatch is how many entries one poll of a live tail reads.
+const tailBatch = 100
+
+// Context line limits: defaultContextLines when the request names none, and
+// maxContextLines on each side at most, so that a side, the anchor and
+// contextSlack fit in one 100-entry search page.
+const (
+	defaultContextLines = 20
+	maxContextLines     = 90
+	// contextSlack is read on top of the requested lines, since entries logged
+	// at the anchor's instant are moved out of the "before" side.
+	contextSlack = 9
+)
+
+// TailLogs follows a source for new entries matching req's filters and passes
+// them to send oldest first, from req.StartTime or from now, until ctx is done
+// (returning nil) or the backend fails. Each poll starts at the timestamp of
+// the last entry sent, so entries sharing that timestamp are remembered and not
+// sent twice. When more than a batch share it, the following pages at that
+// timestamp are read. send blocks while the client is behind and the backend
+// is not polled again until it returns.
+//
+// send also gets the entry's position among those at its timestamp, counting
+// from 1. A client resuming after the nth entry at req.StartTime passes n as
+// skip, and the first n entries there are not sent again.
+func (s *LogService) TailLogs(ctx context.Context, req model.SearchRequest, skip int, send func(entry *model.LogEntry, seq int) error) error {
+	cursor := req.StartTime
+	if cursor == "" {
+		cursor = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	seen := map[string]bool{}
+	sent := 0
+	page := 1
+	for ctx.Err() == nil {
+		start := cursor
+		q := req
+		q.StartTime, q.EndTime = cursor, ""
+		q.SortOrder = "asc"
+		q.Page, q.PageSize = page, tailBatch
+		resp, err := s.repo.Search(req.SourceID, q)
+		if err != nil {
+			return err
+		}
+		for i := range resp.Entries {
+			e := &resp.Entries[i]
+			key := entryKey(e)
+			if seen[key] {
+				continue
+			}
+			if e.Timestamp != cursor {
+				cursor = e.Timestamp
+				seen = map[string]bool{}
+				sent, skip = 0, 0
+			}
+			seen[key] = true
+			if sent++; sent <= skip {
+				continue
+			}
+			if err := send(e, sent); err != nil {
+				return err
+			}
+		}
+		// A page still at the cursor's timestamp is followed by the next one
+		// there; a cursor that moved is read from its first page.
+		if cursor != start {
+			page = 1
+		} else if len(resp.Entries) == tailBatch {
+			page++
+		}
+		// A full batch means more are waiting; read on right away.
+		if len(resp.Entries) == tailBatch {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(s.tailPoll):
+		}
+	}
+	return nil
+}
+
+func entryKey(e *model.LogEntry) string {
+	return e.Timestamp + "\x00" + e.Host + "\x00" + e.Service + "\x00" + e.Message
+}
+
+// Context returns up to req.Before entries logged before the anchor entry and
+// req.After entries logged after it, on the same service and host.
+func (s *LogService) Context(req model.ContextRequest) (*model.ContextResponse, error) {
+	anchorTime, err := time.Parse(time.RFC3339Nano, req.Timestamp)
+	if err != nil {
+		return nil, obserr.New("INVALID_PARAM", op, "timestamp must be an RFC 3339 time")
+	}
+	if req.Service == "" && req.Host == "" {
+		return nil, obserr.New("INVALID_PARAM", op, "service or host is required to identify the stream")
+	}
+	before := clampContext(req.Before)
+	after := clampContext(req.After)
+	base := model.SearchRequest{SourceID: req.SourceID, Service: req.Service, Host: req.Host, Page: 1}
+	sameInstant := func(e *model.LogEntry) bool {
+		t, err := time.Parse(time.RFC3339Nano, e.Timestamp)
+		if err != nil {
+			return e.Timestamp == req.Timestamp
+		}
+		return t.Equal(anchorTime)
+	}
+
+	// Entries from the anchor's instant on, oldest first. The anchor and the
+	// entries sharing its instant lead the list.
+	q := base
+	q.StartTime, q.SortOrder, q.PageSize = req.Timestamp, "asc", after+1+contextSlack
+	later, err := s.repo.Search(req.SourceID, q)
+	if err != nil {
+		return nil, err
+	}
+	resp := &model.ContextResponse{Before: []model.LogEntry{}, After: []model.LogEntry{}}
+	var siblings []model.LogEntry
+	for i := range later.Entries {
+		e := later.Entries[i]
+		switch {
+		case resp.Anchor == nil && sameInstant(&e) && (req.Message == "" || e.Message == req.Message):
+			resp.Anchor = &e
+		case resp.Anchor == nil && sameInstant(&e):
+			siblings = append(siblings, e)
+		default:
+			resp.After = append(resp.After, e)
+		}
+	}
+	if resp.Anchor == nil {
+		resp.After = append(siblings, resp.After...)
+		siblings = nil
+	}
+	if len(resp.After) > after {
+		resp.After = resp.After[:after]
+	}
+
+	q = base
+	q.EndTime, q.SortOrder, q.PageSize = req.Timestamp, "desc", before+contextSlack
+	earlier, err := s.repo.Search(req.SourceID, q)
+	if err != nil {
+		return nil, err
+	}
+	// Newest first; the anchor's instant is already covered above.
+	for i := len(earlier.Entries) - 1; i >= 0; i-- {
+		if e := earlier.Entries[i]; !sameInstant(&e) {
+			resp.Before = append(resp.Before, e)
+		}
+	}
+	resp.Before = append(resp.Before, siblings...)
+	if len(resp.Before) > before {
+		resp.Before = resp.Before[len(resp.Before)-before:]
+	}
+	return resp, nil
+}
+
+func clampContext(n int) int {
+	if n <= 0 {
+		return defaultContextLines
+	}
+	if n > maxContextLines {
+		return maxContextLines
+	}
+	return n
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/log/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeES serves _search over an in-memory log, honouring the time range, the
+// sort order and the page (from and size) of the query.
+type fakeES struct {
+	mu      sync.Mutex
+	entries []model.LogEntry
+}
+
+func (f *fakeES) add(entries ...model.LogEntry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = append(f.entries, entries...)
+}
+
+func (f *fakeES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var q struct {
+		Size  int `json:"size"`
+		From  int `json:"from"`
+		Query struct {
+			Bool struct {
+				Must []map[string]json.RawMessage `json:"must"`
+			} `json:"bool"`
+		} `json:"query"`
+		Sort []map[string]map[string]string `json:"sort"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var gte, lte string
+	for _, m := range q.Query.Bool.Must {
+		var rng map[string]map[string]string
+		if raw, ok := m["range"]; ok && json.Unmarshal(raw, &rng) == nil {
+			gte, lte = rng["@timestamp"]["gte"], rng["@timestamp"]["lte"]
+		}
+	}
+	f.mu.Lock()
+	var hits []model.LogEntry
+	for _, e := range f.entries {
+		if (gte == "" || e.Timestamp >= gte) && (lte == "" || e.Timestamp <= lte) {
+			hits = append(hits, e)
+		}
+	}
+	f.mu.Unlock()
+	desc := q.Sort[0]["@timestamp"]["order"] == "desc"
+	sort.SliceStable(hits, func(i, j int) bool {
+		if desc {
+			return hits[i].Timestamp > hits[j].Timestamp
+		}
+		return hits[i].Timestamp < hits[j].Timestamp
+	})
+	total := len(hits)
+	hits = hits[min(q.From, len(hits)):]
+	if len(hits) > q.Size {
+		hits = hits[:q.Size]
+	}
+	type source struct {
+		Timestamp string `json:"@timestamp"`
+		Service   string `json:"service"`
+		Host      string `json:"host"`
+		Message   string `json:"message"`
+	}
+	type hit struct {
+		Source source `json:"_source"`
+	}
+	resp := struct {
+		Hits struct {
+			Total int   `json:"total"`
+			Hits  []hit `json:"hits"`
+		} `json:"hits"`
+	}{}
+	resp.Hits.Total = total
+	for _, e := range hits {
+		resp.Hits.Hits = append(resp.Hits.Hits, hit{Source: source{e.Timestamp, e.Service, e.Host, e.Message}})
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func setupLog(t *testing.T, es *fakeES) (*LogService, uint) {
+	t.Helper()
+	srv := httptest.NewServer(es)
+	t.Cleanup(srv.Close)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db failed: %v", err)
+	}
+	if err := db.AutoMigrate(&model.LogSource{}, &model.SavedQuery{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	src := model.LogSource{Name: "es", Type: model.SourceElasticsearch, Endpoint: srv.URL}
+	db.Create(&src)
+	svc := NewLogService(db)
+	svc.tailPoll = time.Millisecond
+	return svc, src.ID
+}
+
+func line(ts, msg string) model.LogEntry {
+	return model.LogEntry{Timestamp: "2026-10-18T10:00:" + ts + "Z", Service: "order-api", Host: "node-1", Message: msg}
+}
+
+func TestTailLogs_FollowsNewEntriesOnce(t *testing.T) {
+	es := &fakeES{}
+	es.add(line("00", "old"), line("01", "a"), line("01", "b"))
+	svc, sourceID := setupLog(t, es)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got []string
+	err := svc.TailLogs(ctx, model.SearchRequest{SourceID: sourceID, StartTime: "2026-10-18T10:00:01Z"}, 0, func(e *model.LogEntry, _ int) error {
+		got = append(got, e.Message)
+		switch len(got) {
+		case 2:
+			// Logged at the cursor's instant after the first poll read it.
+			es.add(line("01", "c"), line("02", "d"))
+		case 4:
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if want := "a,b,c,d"; strings.Join(got, ",") != want {
+		t.Fatalf("got %s, want %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestTailLogs_PagesThroughEntriesSharingATimestamp(t *testing.T) {
+	es := &fakeES{}
+	for i := 0; i < 2*tailBatch+50; i++ {
+		es.add(line("01", fmt.Sprintf("burst-%d", i)))
+	}
+	es.add(line("02", "after"))
+	svc, sourceID := setupLog(t, es)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got := map[string]int{}
+	err := svc.TailLogs(ctx, model.SearchRequest{SourceID: sourceID, StartTime: "2026-10-18T10:00:01Z"}, 0, func(e *model.LogEntry, _ int) error {
+		got[e.Message]++
+		if e.Message == "after" {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if len(got) != 2*tailBatch+51 {
+		t.Fatalf("expected every entry sent, got %d distinct", len(got))
+	}
+	for msg, n := range got {
+		if n != 1 {
+			t.Fatalf("%s sent %d times", msg, n)
+		}
+	}
+}
+
+func TestTailLogs_ResumesAfterLastEventID(t *testing.T) {
+	es := &fakeES{}
+	es.add(line("01", "a"), line("01", "b"), line("01", "c"), line("02", "d"))
+	svc, sourceID := setupLog(t, es)
+
+	// The client got a and b, the entries at positions 1 and 2 of :01.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got []string
+	err := svc.TailLogs(ctx, model.SearchRequest{SourceID: sourceID, StartTime: "2026-10-18T10:00:01Z"}, 2, func(e *model.LogEntry, seq int) error {
+		got = append(got, fmt.Sprintf("%s#%d", e.Message, seq))
+		if e.Message == "d" {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if want := "c#3,d#1"; strings.Join(got, ",") != want {
+		t.Fatalf("got %s, want %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestTailLogs_StopsOnSendError(t *testing.T) {
+	es := &fakeES{}
+	es.add(line("01", "a"))
+	svc, sourceID := setupLog(t, es)
+	gone := errors.New("client gone")
+	err := svc.TailLogs(context.Background(), model.SearchRequest{SourceID: sourceID, StartTime: "2026-10-18T10:00:00Z"}, 0, func(*model.LogEntry, int) error {
+		return gone
+	})
+	if !errors.Is(err, gone) {
+		t.Fatalf("err = %v, want the send error", err)
+	}
+}
+
+func TestContext_ReturnsLinesAroundEntry(t *testing.T) {
+	es := &fakeES{}
+	es.add(line("01", "b1"), line("02", "b2"), line("03", "b3"),
+		line("04", "sibling"), line("04", "anchor"), line("04", "after-same"),
+		line("05", "a1"), line("06", "a2"), line("07", "a3"))
+	svc, sourceID := setupLog(t, es)
+
+	resp, err := svc.Context(model.ContextRequest{
+		SourceID: sourceID, Timestamp: "2026-10-18T10:00:04Z", Service: "order-api", Host: "node-1",
+		Message: "anchor", Before: 2, After: 2,
+	})
+	if err != nil {
+		t.Fatalf("context: %v", err)
+	}
+	if resp.Anchor == nil || resp.Anchor.Message != "anchor" {
+		t.Fatalf("anchor = %+v", resp.Anchor)
+	}
+	if got := messages(resp.Before); got != "b3,sibling" {
+		t.Errorf("before = %s", got)
+	}
+	if got := messages(resp.After); got != "after-same,a1" {
+		t.Errorf("after = %s", got)
+	}
+}
+
+func TestContext_Validation(t *testing.T) {
+	svc, sourceID := setupLog(t, &fakeES{})
+	if _, err := svc.Context(model.ContextRequest{SourceID: sourceID, Timestamp: "yesterday", Service: "x"}); err == nil {
+		t.Error("an unparsable timestamp must be rejected")
+	}
+	if _, err := svc.Context(model.ContextRequest{SourceID: sourceID, Timestamp: "2026-10-18T10:00:04Z"}); err == nil {
+		t.Error("a request without service or host must be rejected")
+	}
+}
+
+func messages(entries []model.LogEntry) string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.Message)
+	}
+	return strings.Join(out, ",")
+}
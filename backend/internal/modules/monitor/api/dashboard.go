@@ -0,0 +1,207 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"devops-platform/internal/middleware"
+	"devops-platform/internal/modules/monitor/model"
+	"devops-platform/internal/modules/monitor/service"
+	"devops-platform/internal/pkg/obserr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// variableParamPrefix marks query parameters carrying dashboard variable
+// values, e.g. var-cluster=prod
+const variableParamPrefix = "var-"
+
+// SetDashboardLogAggregator enables log panels on dashboards
+func SetDashboardLogAggregator(logs service.LogAggregator) {
+	monitorSvc.SetLogAggregator(logs)
+}
+
+// SetDashboardVariableSources sets where dashboard variables get their options
+func SetDashboardVariableSources(sources service.VariableSources) {
+	monitorSvc.SetVariableSources(sources)
+}
+
+// ListDashboards GET /api/v1/monitor/dashboards
+func ListDashboards(c *gin.Context) {
+	items, err := monitorSvc.ListDashboards(c.GetUint("tenantID"), c.Query("keyword"))
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": items, "total": len(items)})
+}
+
+// GetDashboard GET /api/v1/monitor/dashboards/:id
+func GetDashboard(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+	item, err := monitorSvc.GetDashboard(c.GetUint("tenantID"), uint(id))
+	if err != nil {
+		writeObservableError(c, dashboardErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": item})
+}
+
+// SaveDashboard POST/PUT /api/v1/monitor/dashboards
+func SaveDashboard(c *gin.Context) {
+	var item model.Dashboard
+	if err := c.ShouldBindJSON(&item); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request: " + err.Error()})
+		return
+	}
+	item.ID = 0
+	if id, err := strconv.ParseUint(c.Param("id"), 10, 64); err == nil {
+		item.ID = uint(id)
+	}
+	canQueryLogs, err := canQueryLogs(c)
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := monitorSvc.SaveDashboard(c.GetUint("tenantID"), c.GetUint("userID"), c.GetString("username"), &item, canQueryLogs); err != nil {
+		writeObservableError(c, dashboardErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": item})
+}
+
+// DeleteDashboard DELETE /api/v1/monitor/dashboards/:id
+func DeleteDashboard(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+	if err := monitorSvc.DeleteDashboard(c.GetUint("tenantID"), uint(id)); err != nil {
+		writeObservableError(c, dashboardErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "deleted"})
+}
+
+// ExportDashboard GET /api/v1/monitor/dashboards/:id/export
+// Responds with the bare export JSON as a file, ready for import.
+func ExportDashboard(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+	data, err := monitorSvc.ExportDashboard(c.GetUint("tenantID"), uint(id))
+	if err != nil {
+		writeObservableError(c, dashboardErrorStatus(err), err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=dashboard-%d.json", id))
+	c.JSON(http.StatusOK, data)
+}
+
+// ImportDashboard POST /api/v1/monitor/dashboards/import
+// The body is an exported dashboard; configId optionally rebinds Prometheus panels.
+func ImportDashboard(c *gin.Context) {
+	var data model.DashboardExport
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid dashboard json: " + err.Error()})
+		return
+	}
+	configID, _ := strconv.ParseUint(c.DefaultQuery("configId", "0"), 10, 64)
+	canQueryLogs, err := canQueryLogs(c)
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	item, err := monitorSvc.ImportDashboard(c.GetUint("tenantID"), c.GetUint("userID"), c.GetString("username"), &data, uint(configID), canQueryLogs)
+	if err != nil {
+		writeObservableError(c, dashboardErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": item})
+}
+
+// ListDashboardVariableOptions GET /api/v1/monitor/dashboards/:id/variables/:name/options
+func ListDashboardVariableOptions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+	options, err := monitorSvc.VariableOptions(c.GetUint("tenantID"), uint(id), c.Param("name"), variableValues(c))
+	if err != nil {
+		writeObservableError(c, dashboardErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": options, "total": len(options)})
+}
+
+// QueryDashboardPanel GET /api/v1/monitor/dashboards/:id/panels/:panelId/data
+func QueryDashboardPanel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+	panelID, err := strconv.Atoi(c.Param("panelId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid panel id"})
+		return
+	}
+	req := model.PanelDataRequest{
+		Vars:      variableValues(c),
+		StartTime: c.Query("startTime"),
+		EndTime:   c.Query("endTime"),
+		Step:      c.Query("step"),
+	}
+	canQueryLogs, err := canQueryLogs(c)
+	if err != nil {
+		writeObservableError(c, http.StatusInternalServerError, err)
+		return
+	}
+	data, err := monitorSvc.PanelData(c.GetUint("tenantID"), c.GetUint("userID"), uint(id), panelID, req, canQueryLogs)
+	if err != nil {
+		writeObservableError(c, dashboardErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": data})
+}
+
+// variableValues collects the var-<name> query parameters
+func variableValues(c *gin.Context) map[string]string {
+	vars := map[string]string{}
+	for key, values := range c.Request.URL.Query() {
+		if name, ok := strings.CutPrefix(key, variableParamPrefix); ok && len(values) > 0 {
+			vars[name] = values[0]
+		}
+	}
+	return vars
+}
+
+// canQueryLogs reports whether the caller holds the log query permission that
+// log panels require, the same one the log search routes check.
+func canQueryLogs(c *gin.Context) (bool, error) {
+	allowed, err := middleware.HasPermission(c, "log", "list")
+	if err != nil {
+		return false, obserr.Wrap("PERMISSION_CHECK_FAILED", "monitor/api", "failed to check log permission", err)
+	}
+	return allowed, nil
+}
+
+func dashboardErrorStatus(err error) int {
+	switch obserr.Details(err)["code"] {
+	case "DASHBOARD_NOT_FOUND":
+		return http.StatusNotFound
+	case "DASHBOARD_LOG_FORBIDDEN":
+		return http.StatusForbidden
+	}
+	return metricErrorStatus(err)
+}
@@ -142,6 +142,93 @@ func QueryAgentStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"code": 200, "data": result})
 }
 
+// QueryMetrics GET /api/v1/monitor/query
+func QueryMetrics(c *gin.Context) {
+	var req model.MetricQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "configId and query are required"})
+		return
+	}
+	result, err := monitorSvc.Query(req)
+	if err != nil {
+		writeObservableError(c, metricErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": result})
+}
+
+// QueryMetricsRange GET /api/v1/monitor/query_range
+func QueryMetricsRange(c *gin.Context) {
+	var req model.MetricQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "configId and query are required"})
+		return
+	}
+	result, err := monitorSvc.QueryRange(req)
+	if err != nil {
+		writeObservableError(c, metricErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": result})
+}
+
+// ListMetricLabels GET /api/v1/monitor/labels
+func ListMetricLabels(c *gin.Context) {
+	var req model.MetricDiscoveryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+		return
+	}
+	names, truncated, err := monitorSvc.Labels(req)
+	if err != nil {
+		writeObservableError(c, metricErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": names, "total": len(names), "truncated": truncated})
+}
+
+// ListMetricLabelValues GET /api/v1/monitor/label/:name/values
+func ListMetricLabelValues(c *gin.Context) {
+	var req model.MetricDiscoveryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+		return
+	}
+	values, truncated, err := monitorSvc.LabelValues(c.Param("name"), req)
+	if err != nil {
+		writeObservableError(c, metricErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": values, "total": len(values), "truncated": truncated})
+}
+
+// ListMetricSeries GET /api/v1/monitor/series
+func ListMetricSeries(c *gin.Context) {
+	var req model.MetricDiscoveryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+		return
+	}
+	series, truncated, err := monitorSvc.Series(req)
+	if err != nil {
+		writeObservableError(c, metricErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": series, "total": len(series), "truncated": truncated})
+}
+
+// metricErrorStatus maps rejected parameters to 400, an unknown Prometheus
+// config to 404 and failures to 500.
+func metricErrorStatus(err error) int {
+	switch obserr.Details(err)["code"] {
+	case "INVALID_PARAM":
+		return http.StatusBadRequest
+	case "PROMETHEUS_CONFIG_NOT_FOUND":
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
 func writeObservableError(c *gin.Context, status int, err error) {
 	details := obserr.Details(err)
 	c.JSON(status, gin.H{"code": 500, "message": details["message"], "error": details})
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"time"
+
+	logModel "devops-platform/internal/modules/log/model"
+
+	"gorm.io/gorm"
+)
+
+// Panel types
+const (
+	PanelTimeseries = "timeseries"
+	PanelStat       = "stat"
+	PanelTable      = "table"
+	PanelGauge      = "gauge"
+)
+
+// Panel data sources
+const (
+	DatasourcePrometheus = "prometheus"
+	DatasourceLog        = "log"
+)
+
+// Dashboard variable types. Cluster, namespace and host group options come
+// from the k8s and cmdb modules; custom variables list their own options.
+const (
+	VariableCluster   = "cluster"
+	VariableNamespace = "namespace"
+	VariableHostGroup = "hostgroup"
+	VariableCustom    = "custom"
+)
+
+// DashboardSchemaVersion is written into exported dashboards
+const DashboardSchemaVersion = 1
+
+// Dashboard is a tenant's board of panels laid out in rows. TimeRange such as
+// "1h" or "7d" is the span shown by default, up to now.
+type Dashboard struct {
+	ID          uint                `gorm:"primaryKey" json:"id"`
+	TenantID    uint                `gorm:"index;not null" json:"tenantId"`
+	Name        string              `gorm:"size:128;not null" json:"name"`
+	Description string              `gorm:"size:512" json:"description"`
+	TimeRange   string              `gorm:"size:16" json:"timeRange"`
+	Refresh     string              `gorm:"size:16" json:"refresh"`
+	Variables   []DashboardVariable `gorm:"type:text;serializer:json" json:"variables"`
+	Rows        []DashboardRow      `gorm:"type:text;serializer:json" json:"rows"`
+	OwnerID     uint                `gorm:"index" json:"ownerId"`
+	OwnerName   string              `gorm:"size:128" json:"ownerName"`
+	CreatedAt   time.Time           `json:"createdAt"`
+	UpdatedAt   time.Time           `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt      `gorm:"index" json:"-"`
+}
+
+func (Dashboard) TableName() string { return "monitor_dashboards" }
+
+// DashboardVariable is referenced in panel queries as $name or ${name}.
+// A namespace variable lists the namespaces of the cluster chosen in the
+// variable named by Cluster.
+type DashboardVariable struct {
+	Name    string   `json:"name"`
+	Label   string   `json:"label"`
+	Type    string   `json:"type"`
+	Cluster string   `json:"cluster,omitempty"`
+	Options []string `json:"options,omitempty"`
+	Default string   `json:"default"`
+}
+
+// DashboardRow groups panels under a title
+type DashboardRow struct {
+	Title     string  `json:"title"`
+	Collapsed bool    `json:"collapsed"`
+	Panels    []Panel `json:"panels"`
+}
+
+// Panel shows either a PromQL query on a Prometheus config or a log
+// aggregation, given inline or by a saved log query shared with the tenant.
+// Width is in grid columns out of 24.
+type Panel struct {
+	ID           int                        `json:"id"`
+	Title        string                     `json:"title"`
+	Type         string                     `json:"type"`
+	Width        int                        `json:"width"`
+	Height       int                        `json:"height"`
+	Datasource   string                     `json:"datasource"`
+	ConfigID     uint                       `json:"configId,omitempty"`
+	Query        string                     `json:"query,omitempty"`
+	Legend       string                     `json:"legend,omitempty"`
+	LogQuery     *logModel.AggregateRequest `json:"logQuery,omitempty"`
+	SavedQueryID uint                       `json:"savedQueryId,omitempty"`
+	Unit         string                     `json:"unit,omitempty"`
+	Min          *float64                   `json:"min,omitempty"`
+	Max          *float64                   `json:"max,omitempty"`
+	Thresholds   []float64                  `json:"thresholds,omitempty"`
+}
+
+// DashboardExport is the portable JSON form of a dashboard, without tenant,
+// owner or IDs other than panel IDs.
+type DashboardExport struct {
+	SchemaVersion int                 `json:"schemaVersion"`
+	Name          string              `json:"name"`
+	Description   string              `json:"description"`
+	TimeRange     string              `json:"timeRange"`
+	Refresh       string              `json:"refresh"`
+	Variables     []DashboardVariable `json:"variables"`
+	Rows          []DashboardRow      `json:"rows"`
+}
+
+// PanelDataRequest asks for one panel's data. Vars holds the chosen variable
+// values; missing ones take the variable's default. Without a time range the
+// dashboard's TimeRange up to now is used.
+type PanelDataRequest struct {
+	Vars      map[string]string `json:"vars"`
+	StartTime string            `json:"startTime"`
+	EndTime   string            `json:"endTime"`
+	Step      string            `json:"step"`
+}
+
+// PanelData is a panel's data: Metrics for Prometheus panels, Logs for log
+// panels. Query is the PromQL actually run, variables substituted.
+type PanelData struct {
+	PanelID    int                         `json:"panelId"`
+	Type       string                      `json:"type"`
+	Datasource string                      `json:"datasource"`
+	Query      string                      `json:"query,omitempty"`
+	Metrics    *MetricQueryResponse        `json:"metrics,omitempty"`
+	Logs       *logModel.AggregateResponse `json:"logs,omitempty"`
+}
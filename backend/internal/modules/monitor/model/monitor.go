@@ -22,12 +22,26 @@ type PrometheusConfig struct {
 
 func (PrometheusConfig) TableName() string { return "monitor_prometheus_configs" }
 
-// MetricQueryRequest is the request for querying metrics
+// MetricQueryRequest is the request for querying metrics. Time, StartTime and
+// EndTime take RFC3339 or unix seconds; Step takes a duration or seconds.
 type MetricQueryRequest struct {
-	Query     string `json:"query" binding:"required"`
-	StartTime string `json:"startTime"`
-	EndTime   string `json:"endTime"`
-	Step      string `json:"step"`
+	ConfigID  uint   `form:"configId" json:"configId"`
+	Query     string `form:"query" json:"query" binding:"required"`
+	Time      string `form:"time" json:"time"`
+	StartTime string `form:"startTime" json:"startTime"`
+	EndTime   string `form:"endTime" json:"endTime"`
+	Step      string `form:"step" json:"step"`
+	Limit     int    `form:"limit" json:"limit"`
+}
+
+// MetricDiscoveryRequest narrows label and series discovery to the series
+// matching any of Match within the time range.
+type MetricDiscoveryRequest struct {
+	ConfigID  uint     `form:"configId" json:"configId"`
+	Match     []string `form:"match[]" json:"match"`
+	StartTime string   `form:"startTime" json:"startTime"`
+	EndTime   string   `form:"endTime" json:"endTime"`
+	Limit     int      `form:"limit" json:"limit"`
 }
 
 // MetricResult holds a single metric data point
@@ -42,10 +56,13 @@ type MetricSeries struct {
 	Values []MetricResult    `json:"values"`
 }
 
-// MetricQueryResponse is the response for a metric query
+// MetricQueryResponse is the response for a metric query. Step is the step a
+// range query ran with; Truncated reports series dropped over the limit.
 type MetricQueryResponse struct {
 	ResultType string         `json:"resultType"`
 	Results    []MetricSeries `json:"results"`
+	Step       string         `json:"step,omitempty"`
+	Truncated  bool           `json:"truncated,omitempty"`
 }
 
 // HostMetricRequest is for querying specific host metrics
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"errors"
+
+	"devops-platform/internal/modules/monitor/model"
+	"devops-platform/internal/pkg/obserr"
+
+	"gorm.io/gorm"
+)
+
+// --- Dashboards ---
+
+// ListDashboards returns the tenant's dashboards, most recently updated first
+func (r *MonitorRepo) ListDashboards(tenantID uint, keyword string) ([]model.Dashboard, error) {
+	var items []model.Dashboard
+	q := r.db.Where("tenant_id = ?", tenantID)
+	if keyword != "" {
+		q = q.Where("name LIKE ?", "%"+keyword+"%")
+	}
+	if err := q.Order("updated_at DESC").Find(&items).Error; err != nil {
+		return nil, obserr.Wrap("DB_ERROR", op, "list dashboards failed", err)
+	}
+	return items, nil
+}
+
+// GetDashboard retrieves one of the tenant's dashboards
+func (r *MonitorRepo) GetDashboard(tenantID, id uint) (*model.Dashboard, error) {
+	var item model.Dashboard
+	if err := r.db.Where("tenant_id = ? AND id = ?", tenantID, id).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, obserr.New("DASHBOARD_NOT_FOUND", op, "dashboard not found")
+		}
+		return nil, obserr.Wrap("DB_ERROR", op, "get dashboard failed", err)
+	}
+	return &item, nil
+}
+
+// SaveDashboard creates or updates a dashboard
+func (r *MonitorRepo) SaveDashboard(item *model.Dashboard) error {
+	if err := r.db.Save(item).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "save dashboard failed", err)
+	}
+	return nil
+}
+
+// DeleteDashboard soft-deletes one of the tenant's dashboards
+func (r *MonitorRepo) DeleteDashboard(tenantID, id uint) error {
+	if err := r.db.Where("tenant_id = ?", tenantID).Delete(&model.Dashboard{}, id).Error; err != nil {
+		return obserr.Wrap("DB_ERROR", op, "delete dashboard failed", err)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"devops-platform/internal/modules/monitor/model"
@@ -103,13 +104,26 @@ func (r *MonitorRepo) QueryInstant(configID uint, promQL string) (*model.MetricQ
 	return r.executeQuery(cfg, promQL, "query", "")
 }
 
+// QueryInstantAt performs an instant PromQL query evaluated at ts, bounded by the config's timeout
+func (r *MonitorRepo) QueryInstantAt(configID uint, promQL, ts string) (*model.MetricQueryResponse, error) {
+	cfg, err := r.GetConfig(configID)
+	if err != nil {
+		return nil, obserr.Wrap("PROMETHEUS_CONFIG_NOT_FOUND", op, "config not found", err)
+	}
+	params := timeoutParams(cfg)
+	if ts != "" {
+		params.Set("time", ts)
+	}
+	return r.executeQuery(cfg, promQL, "query", params.Encode())
+}
+
 // QueryRange performs a range PromQL query against the config's Prometheus
 func (r *MonitorRepo) QueryRange(configID uint, promQL, start, end, step string) (*model.MetricQueryResponse, error) {
 	cfg, err := r.GetConfig(configID)
 	if err != nil {
 		return nil, obserr.Wrap("PROMETHEUS_CONFIG_NOT_FOUND", op, "config not found", err)
 	}
-	params := url.Values{}
+	params := timeoutParams(cfg)
 	params.Set("start", start)
 	params.Set("end", end)
 	params.Set("step", step)
@@ -140,6 +154,101 @@ func (r *MonitorRepo) executeQuery(cfg *model.PrometheusConfig, promQL, promEndp
 	return r.parsePrometheusResponse(resp.Body)
 }
 
+// --- Label discovery ---
+
+// Labels returns the label names of series matching any of matches in [start, end]
+func (r *MonitorRepo) Labels(configID uint, matches []string, start, end string, limit int) ([]string, error) {
+	var names []string
+	if err := r.getData(configID, "labels", discoveryParams(matches, start, end, limit), &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// LabelValues returns the values of a label on series matching any of matches in [start, end]
+func (r *MonitorRepo) LabelValues(configID uint, name string, matches []string, start, end string, limit int) ([]string, error) {
+	var values []string
+	path := "label/" + url.PathEscape(name) + "/values"
+	if err := r.getData(configID, path, discoveryParams(matches, start, end, limit), &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Series returns the label sets of series matching any of matches in [start, end]
+func (r *MonitorRepo) Series(configID uint, matches []string, start, end string, limit int) ([]map[string]string, error) {
+	var series []map[string]string
+	if err := r.getData(configID, "series", discoveryParams(matches, start, end, limit), &series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func discoveryParams(matches []string, start, end string, limit int) url.Values {
+	params := url.Values{}
+	for _, m := range matches {
+		params.Add("match[]", m)
+	}
+	if start != "" {
+		params.Set("start", start)
+	}
+	if end != "" {
+		params.Set("end", end)
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	return params
+}
+
+// getData calls a Prometheus metadata endpoint and decodes its data field
+func (r *MonitorRepo) getData(configID uint, path string, params url.Values, data interface{}) error {
+	cfg, err := r.GetConfig(configID)
+	if err != nil {
+		return obserr.Wrap("PROMETHEUS_CONFIG_NOT_FOUND", op, "config not found", err)
+	}
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/%s?%s", cfg.Endpoint, path, params.Encode()), nil)
+	if err != nil {
+		return obserr.Wrap("PROMETHEUS_QUERY_FAILED", op, "failed to build prometheus request", err)
+	}
+	if cfg.Username != "" {
+		req.SetBasicAuth(cfg.Username, cfg.Password)
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return obserr.Wrap("PROMETHEUS_QUERY_FAILED", op, "prometheus request failed", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return obserr.New("PROMETHEUS_QUERY_FAILED", op, fmt.Sprintf("prometheus returned %d: %s", resp.StatusCode, string(body)))
+	}
+	var raw struct {
+		Status string          `json:"status"`
+		Data   json.RawMessage `json:"data"`
+		Error  string          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return obserr.Wrap("PROMETHEUS_QUERY_FAILED", op, "failed to parse prometheus response", err)
+	}
+	if raw.Status == "error" {
+		return obserr.New("PROMETHEUS_QUERY_FAILED", op, raw.Error)
+	}
+	if err := json.Unmarshal(raw.Data, data); err != nil {
+		return obserr.Wrap("PROMETHEUS_QUERY_FAILED", op, "failed to parse prometheus response", err)
+	}
+	return nil
+}
+
+// timeoutParams passes the config's timeout on so Prometheus aborts slow queries itself
+func timeoutParams(cfg *model.PrometheusConfig) url.Values {
+	params := url.Values{}
+	if cfg.TimeoutSeconds > 0 {
+		params.Set("timeout", fmt.Sprintf("%ds", cfg.TimeoutSeconds))
+	}
+	return params
+}
+
 // parsePrometheusResponse parses the Prometheus HTTP API JSON response
 func (r *MonitorRepo) parsePrometheusResponse(body io.Reader) (*model.MetricQueryResponse, error) {
 	var raw struct {
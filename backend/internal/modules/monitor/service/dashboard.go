@@ -0,0 +1,463 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	logModel "devops-platform/internal/modules/log/model"
+	"devops-platform/internal/modules/monitor/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+const defaultDashboardRange = "1h"
+
+// variableRef matches $name and ${name} in panel queries
+var variableRef = regexp.MustCompile(`\$\{(\w+)\}|\$(\w+)`)
+
+// promStringEscaper keeps substituted values inside PromQL string literals
+var promStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// LogAggregator runs the aggregations behind log panels.
+// log/service.LogService satisfies it.
+type LogAggregator interface {
+	Aggregate(req logModel.AggregateRequest) (*logModel.AggregateResponse, error)
+	GetSavedQuery(tenantID, userID, id uint) (*logModel.SavedQueryView, error)
+}
+
+// VariableSources list the options of cluster, namespace and host group
+// variables within a tenant. A nil source leaves its variable type without
+// options.
+type VariableSources struct {
+	Clusters   func(tenantID uint) ([]string, error)
+	Namespaces func(tenantID uint, cluster string) ([]string, error)
+	HostGroups func(tenantID uint) ([]string, error)
+}
+
+// SetLogAggregator enables log panels
+func (s *MonitorService) SetLogAggregator(logs LogAggregator) {
+	s.logs = logs
+}
+
+// SetVariableSources sets where cluster, namespace and host group variables get their options
+func (s *MonitorService) SetVariableSources(sources VariableSources) {
+	s.sources = sources
+}
+
+// --- Dashboards ---
+
+// ListDashboards returns the tenant's dashboards
+func (s *MonitorService) ListDashboards(tenantID uint, keyword string) ([]model.Dashboard, error) {
+	return s.repo.ListDashboards(tenantID, strings.TrimSpace(keyword))
+}
+
+// GetDashboard returns one of the tenant's dashboards
+func (s *MonitorService) GetDashboard(tenantID, id uint) (*model.Dashboard, error) {
+	return s.repo.GetDashboard(tenantID, id)
+}
+
+// SaveDashboard validates and creates or updates a dashboard. The creator
+// stays its owner; panels without an ID get the next free one. Dashboards
+// with log panels can only be saved by users allowed to query logs.
+func (s *MonitorService) SaveDashboard(tenantID, userID uint, username string, item *model.Dashboard, canQueryLogs bool) error {
+	if err := s.validateDashboard(tenantID, userID, item, canQueryLogs); err != nil {
+		return err
+	}
+	item.TenantID = tenantID
+	if item.ID == 0 {
+		item.OwnerID, item.OwnerName = userID, username
+		return s.repo.SaveDashboard(item)
+	}
+	existing, err := s.repo.GetDashboard(tenantID, item.ID)
+	if err != nil {
+		return err
+	}
+	item.OwnerID, item.OwnerName, item.CreatedAt = existing.OwnerID, existing.OwnerName, existing.CreatedAt
+	return s.repo.SaveDashboard(item)
+}
+
+// DeleteDashboard removes one of the tenant's dashboards
+func (s *MonitorService) DeleteDashboard(tenantID, id uint) error {
+	if _, err := s.repo.GetDashboard(tenantID, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteDashboard(tenantID, id)
+}
+
+// ExportDashboard returns a dashboard in its portable JSON form
+func (s *MonitorService) ExportDashboard(tenantID, id uint) (*model.DashboardExport, error) {
+	d, err := s.repo.GetDashboard(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &model.DashboardExport{
+		SchemaVersion: model.DashboardSchemaVersion,
+		Name:          d.Name,
+		Description:   d.Description,
+		TimeRange:     d.TimeRange,
+		Refresh:       d.Refresh,
+		Variables:     d.Variables,
+		Rows:          d.Rows,
+	}, nil
+}
+
+// ImportDashboard creates a dashboard from an export. A non-zero configID
+// points every Prometheus panel at that config, for exports taken where the
+// config IDs differ.
+func (s *MonitorService) ImportDashboard(tenantID, userID uint, username string, data *model.DashboardExport, configID uint, canQueryLogs bool) (*model.Dashboard, error) {
+	if data.SchemaVersion > model.DashboardSchemaVersion {
+		return nil, obserr.New("INVALID_PARAM", op, fmt.Sprintf("unsupported dashboard schema version: %d", data.SchemaVersion))
+	}
+	item := &model.Dashboard{
+		Name:        data.Name,
+		Description: data.Description,
+		TimeRange:   data.TimeRange,
+		Refresh:     data.Refresh,
+		Variables:   data.Variables,
+		Rows:        data.Rows,
+	}
+	if configID != 0 {
+		for i := range item.Rows {
+			for j := range item.Rows[i].Panels {
+				if p := &item.Rows[i].Panels[j]; p.Datasource != model.DatasourceLog {
+					p.ConfigID = configID
+				}
+			}
+		}
+	}
+	if err := s.SaveDashboard(tenantID, userID, username, item, canQueryLogs); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *MonitorService) validateDashboard(tenantID, userID uint, d *model.Dashboard, canQueryLogs bool) error {
+	d.Name = strings.TrimSpace(d.Name)
+	if d.Name == "" {
+		return obserr.New("INVALID_PARAM", op, "name is required")
+	}
+	if d.TimeRange == "" {
+		d.TimeRange = defaultDashboardRange
+	}
+	if span, err := logModel.ParseTimeRange(d.TimeRange); err != nil || span > maxQueryRange {
+		return obserr.New("INVALID_PARAM", op, "timeRange must be a duration such as 1h or 7d, at most 31d")
+	}
+	if d.Refresh != "" {
+		if r, err := time.ParseDuration(d.Refresh); err != nil || r < 5*time.Second {
+			return obserr.New("INVALID_PARAM", op, "refresh must be a duration of at least 5s")
+		}
+	}
+
+	types := make(map[string]string, len(d.Variables))
+	for _, v := range d.Variables {
+		if !labelNamePattern.MatchString(v.Name) {
+			return obserr.New("INVALID_PARAM", op, fmt.Sprintf("invalid variable name: %q", v.Name))
+		}
+		if _, dup := types[v.Name]; dup {
+			return obserr.New("INVALID_PARAM", op, fmt.Sprintf("duplicate variable: %s", v.Name))
+		}
+		types[v.Name] = v.Type
+	}
+	for i := range d.Variables {
+		v := &d.Variables[i]
+		switch v.Type {
+		case model.VariableCluster, model.VariableHostGroup:
+			v.Cluster, v.Options = "", nil
+		case model.VariableNamespace:
+			if types[v.Cluster] != model.VariableCluster {
+				return obserr.New("INVALID_PARAM", op, fmt.Sprintf("namespace variable %s must name a cluster variable", v.Name))
+			}
+			v.Options = nil
+		case model.VariableCustom:
+			if len(v.Options) == 0 {
+				return obserr.New("INVALID_PARAM", op, fmt.Sprintf("custom variable %s needs options", v.Name))
+			}
+			v.Cluster = ""
+		default:
+			return obserr.New("INVALID_PARAM", op, fmt.Sprintf("unsupported variable type: %s", v.Type))
+		}
+	}
+
+	nextID, seen := 1, map[int]bool{}
+	for _, row := range d.Rows {
+		for _, p := range row.Panels {
+			if p.ID != 0 && seen[p.ID] {
+				return obserr.New("INVALID_PARAM", op, fmt.Sprintf("duplicate panel id: %d", p.ID))
+			}
+			seen[p.ID] = true
+			nextID = max(nextID, p.ID+1)
+		}
+	}
+	for i := range d.Rows {
+		for j := range d.Rows[i].Panels {
+			p := &d.Rows[i].Panels[j]
+			if p.ID == 0 {
+				p.ID = nextID
+				nextID++
+			}
+			if err := s.validatePanel(tenantID, userID, p, canQueryLogs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *MonitorService) validatePanel(tenantID, userID uint, p *model.Panel, canQueryLogs bool) error {
+	switch p.Type {
+	case model.PanelTimeseries, model.PanelStat, model.PanelTable, model.PanelGauge:
+	default:
+		return obserr.New("INVALID_PARAM", op, fmt.Sprintf("panel %d: unsupported type: %s", p.ID, p.Type))
+	}
+	if p.Width <= 0 || p.Width > 24 {
+		p.Width = 12
+	}
+	if p.Height <= 0 {
+		p.Height = 8
+	}
+
+	switch p.Datasource {
+	case "", model.DatasourcePrometheus:
+		p.Datasource = model.DatasourcePrometheus
+		if strings.TrimSpace(p.Query) == "" {
+			return obserr.New("INVALID_PARAM", op, fmt.Sprintf("panel %d: query is required", p.ID))
+		}
+		if _, err := s.repo.GetConfig(p.ConfigID); err != nil {
+			return obserr.Wrap("INVALID_PARAM", op, fmt.Sprintf("panel %d: prometheus config not found", p.ID), err)
+		}
+		p.LogQuery, p.SavedQueryID = nil, 0
+	case model.DatasourceLog:
+		if s.logs == nil {
+			return obserr.New("INVALID_PARAM", op, "log panels are not enabled")
+		}
+		if !canQueryLogs {
+			return errLogPanelForbidden(p.ID)
+		}
+		if p.SavedQueryID != 0 {
+			view, err := s.logs.GetSavedQuery(tenantID, userID, p.SavedQueryID)
+			if err != nil {
+				return obserr.Wrap("INVALID_PARAM", op, fmt.Sprintf("panel %d: saved log query not found", p.ID), err)
+			}
+			if view.Visibility != logModel.VisibilityTenant {
+				return obserr.New("INVALID_PARAM", op, fmt.Sprintf("panel %d: saved log query must be shared with the tenant", p.ID))
+			}
+		} else if p.LogQuery == nil || p.LogQuery.SourceID == 0 {
+			return obserr.New("INVALID_PARAM", op, fmt.Sprintf("panel %d: log source or saved query is required", p.ID))
+		}
+		p.ConfigID, p.Query = 0, ""
+	default:
+		return obserr.New("INVALID_PARAM", op, fmt.Sprintf("panel %d: unsupported datasource: %s", p.ID, p.Datasource))
+	}
+	return nil
+}
+
+// --- Dashboard rendering ---
+
+// VariableOptions lists the values a dashboard variable can take. vars holds
+// the values already chosen, which namespace variables need for their cluster.
+func (s *MonitorService) VariableOptions(tenantID, id uint, name string, vars map[string]string) ([]string, error) {
+	d, err := s.repo.GetDashboard(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range d.Variables {
+		if v.Name != name {
+			continue
+		}
+		values, err := s.checkVariables(tenantID, d, vars)
+		if err != nil {
+			return nil, err
+		}
+		return s.variableOptions(tenantID, v, values)
+	}
+	return nil, obserr.New("DASHBOARD_NOT_FOUND", op, fmt.Sprintf("variable not found: %s", name))
+}
+
+func (s *MonitorService) variableOptions(tenantID uint, v model.DashboardVariable, values map[string]string) ([]string, error) {
+	var source func() ([]string, error)
+	switch v.Type {
+	case model.VariableCustom:
+		return v.Options, nil
+	case model.VariableCluster:
+		if s.sources.Clusters != nil {
+			source = func() ([]string, error) { return s.sources.Clusters(tenantID) }
+		}
+	case model.VariableHostGroup:
+		if s.sources.HostGroups != nil {
+			source = func() ([]string, error) { return s.sources.HostGroups(tenantID) }
+		}
+	case model.VariableNamespace:
+		cluster := values[v.Cluster]
+		if cluster == "" {
+			return nil, obserr.New("INVALID_PARAM", op, fmt.Sprintf("choose %s first", v.Cluster))
+		}
+		if s.sources.Namespaces != nil {
+			source = func() ([]string, error) { return s.sources.Namespaces(tenantID, cluster) }
+		}
+	}
+	if source == nil {
+		return []string{}, nil
+	}
+	options, err := source()
+	if err != nil {
+		return nil, obserr.Wrap("DASHBOARD_VARIABLE_FAILED", op, fmt.Sprintf("failed to list options of %s", v.Name), err)
+	}
+	return options, nil
+}
+
+// checkVariables resolves the variable values, rejecting chosen values that
+// are not among the variable's options. Defaults were set by the dashboard's
+// editors and are taken as they are.
+func (s *MonitorService) checkVariables(tenantID uint, d *model.Dashboard, chosen map[string]string) (map[string]string, error) {
+	values := resolveVariables(d, chosen)
+	for _, v := range d.Variables {
+		value := chosen[v.Name]
+		if value == "" || value == v.Default {
+			continue
+		}
+		options, err := s.variableOptions(tenantID, v, values)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(options, value) {
+			return nil, obserr.New("INVALID_PARAM", op, fmt.Sprintf("invalid value for variable %s: %q", v.Name, value))
+		}
+	}
+	return values, nil
+}
+
+// PanelData runs one panel's query with the dashboard variables substituted.
+// Timeseries panels query the whole range; the others show the value at its
+// end. Log panels with a saved query follow later edits of that query, and
+// are only served to users allowed to query logs.
+func (s *MonitorService) PanelData(tenantID, userID, id uint, panelID int, req model.PanelDataRequest, canQueryLogs bool) (*model.PanelData, error) {
+	d, err := s.repo.GetDashboard(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	panel := findPanel(d, panelID)
+	if panel == nil {
+		return nil, obserr.New("DASHBOARD_NOT_FOUND", op, fmt.Sprintf("panel not found: %d", panelID))
+	}
+	span, err := logModel.ParseTimeRange(d.TimeRange)
+	if err != nil {
+		span = defaultQueryRange
+	}
+	start, end, err := resolveRange(req.StartTime, req.EndTime, time.Now(), span)
+	if err != nil {
+		return nil, err
+	}
+	if panel.Datasource == model.DatasourceLog && !canQueryLogs {
+		return nil, errLogPanelForbidden(panel.ID)
+	}
+	vars, err := s.checkVariables(tenantID, d, req.Vars)
+	if err != nil {
+		return nil, err
+	}
+	data := &model.PanelData{PanelID: panel.ID, Type: panel.Type, Datasource: panel.Datasource}
+
+	if panel.Datasource == model.DatasourceLog {
+		logReq, err := s.panelLogRequest(tenantID, userID, panel, vars)
+		if err != nil {
+			return nil, err
+		}
+		logReq.StartTime = start.UTC().Format(time.RFC3339)
+		logReq.EndTime = end.UTC().Format(time.RFC3339)
+		if data.Logs, err = s.logs.Aggregate(logReq); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	data.Query = substituteVariables(panel.Query, vars, promStringEscaper.Replace)
+	query := model.MetricQueryRequest{ConfigID: panel.ConfigID, Query: data.Query}
+	if panel.Type == model.PanelTimeseries {
+		query.StartTime, query.EndTime, query.Step = formatPromTime(start), formatPromTime(end), req.Step
+		data.Metrics, err = s.QueryRange(query)
+	} else {
+		query.Time = formatPromTime(end)
+		data.Metrics, err = s.Query(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// panelLogRequest builds a log panel's aggregation: the saved query's source
+// and filters if it has one, then the panel's own settings. Saved queries on
+// dashboards are shared with the tenant, so every viewer may resolve them.
+func (s *MonitorService) panelLogRequest(tenantID, userID uint, p *model.Panel, vars map[string]string) (logModel.AggregateRequest, error) {
+	var req logModel.AggregateRequest
+	if s.logs == nil {
+		return req, obserr.New("INVALID_PARAM", op, "log panels are not enabled")
+	}
+	if p.LogQuery != nil {
+		req = *p.LogQuery
+	}
+	if p.SavedQueryID != 0 {
+		view, err := s.logs.GetSavedQuery(tenantID, userID, p.SavedQueryID)
+		if err != nil {
+			return req, err
+		}
+		req.SearchRequest = view.Query
+		req.SourceID = view.SourceID
+	}
+	same := func(v string) string { return v }
+	req.Service = substituteVariables(req.Service, vars, same)
+	req.Host = substituteVariables(req.Host, vars, same)
+	req.Level = substituteVariables(req.Level, vars, same)
+	keywords := make([]string, 0, len(req.Keywords))
+	for _, k := range req.Keywords {
+		keywords = append(keywords, substituteVariables(k, vars, same))
+	}
+	req.Keywords = keywords
+	req.Page, req.PageSize = 0, 0
+	return req, nil
+}
+
+func errLogPanelForbidden(panelID int) error {
+	return obserr.New("DASHBOARD_LOG_FORBIDDEN", op, fmt.Sprintf("panel %d: log query permission is required", panelID))
+}
+
+func findPanel(d *model.Dashboard, id int) *model.Panel {
+	for i := range d.Rows {
+		for j := range d.Rows[i].Panels {
+			if d.Rows[i].Panels[j].ID == id {
+				return &d.Rows[i].Panels[j]
+			}
+		}
+	}
+	return nil
+}
+
+// resolveVariables picks each variable's chosen value, else its default, else
+// a custom variable's first option. Values for unknown names are dropped.
+func resolveVariables(d *model.Dashboard, chosen map[string]string) map[string]string {
+	values := make(map[string]string, len(d.Variables))
+	for _, v := range d.Variables {
+		value := chosen[v.Name]
+		if value == "" {
+			value = v.Default
+		}
+		if value == "" && v.Type == model.VariableCustom && len(v.Options) > 0 {
+			value = v.Options[0]
+		}
+		values[v.Name] = value
+	}
+	return values
+}
+
+// substituteVariables replaces $name and ${name} of known variables with
+// their escaped values and leaves other references, such as $1 in
+// label_replace, untouched.
+func substituteVariables(s string, values map[string]string, escape func(string) string) string {
+	return variableRef.ReplaceAllStringFunc(s, func(ref string) string {
+		if value, ok := values[strings.Trim(ref, "${}")]; ok {
+			return escape(value)
+		}
+		return ref
+	})
+}
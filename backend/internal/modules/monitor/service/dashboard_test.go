@@ -0,0 +1,305 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	logModel "devops-platform/internal/modules/log/model"
+	"devops-platform/internal/modules/monitor/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+const dashboardTenant = 7
+
+type fakeLogAggregator struct {
+	requests []logModel.AggregateRequest
+	saved    map[uint]logModel.SavedQuery
+}
+
+func (f *fakeLogAggregator) Aggregate(req logModel.AggregateRequest) (*logModel.AggregateResponse, error) {
+	f.requests = append(f.requests, req)
+	return &logModel.AggregateResponse{Total: 42}, nil
+}
+
+func (f *fakeLogAggregator) GetSavedQuery(tenantID, userID, id uint) (*logModel.SavedQueryView, error) {
+	q, ok := f.saved[id]
+	if !ok || q.TenantID != tenantID || (q.OwnerID != userID && q.Visibility != logModel.VisibilityTenant) {
+		return nil, errors.New("saved query not found")
+	}
+	return &logModel.SavedQueryView{SavedQuery: q}, nil
+}
+
+func setupDashboardService(t *testing.T, endpoint string) (*MonitorService, uint, *fakeLogAggregator) {
+	svc, configID := setupQueryService(t, endpoint)
+	if err := svc.db.AutoMigrate(&model.Dashboard{}); err != nil {
+		t.Fatalf("failed to migrate dashboards: %v", err)
+	}
+	logs := &fakeLogAggregator{saved: map[uint]logModel.SavedQuery{
+		5: {ID: 5, TenantID: dashboardTenant, OwnerID: 1, SourceID: 9, Visibility: logModel.VisibilityTenant,
+			Query: logModel.SearchRequest{Level: "ERROR", Service: "api"}},
+		6: {ID: 6, TenantID: dashboardTenant, OwnerID: 1, SourceID: 9, Visibility: logModel.VisibilityPrivate},
+	}}
+	svc.SetLogAggregator(logs)
+	return svc, configID, logs
+}
+
+func newTestDashboard(configID uint) *model.Dashboard {
+	return &model.Dashboard{
+		Name: "Cluster overview",
+		Variables: []model.DashboardVariable{
+			{Name: "cluster", Type: model.VariableCluster, Default: "prod"},
+			{Name: "namespace", Type: model.VariableNamespace, Cluster: "cluster"},
+			{Name: "quantile", Type: model.VariableCustom, Options: []string{"0.99", "0.5"}},
+		},
+		Rows: []model.DashboardRow{
+			{Title: "Workloads", Panels: []model.Panel{
+				{ID: 3, Title: "CPU", Type: model.PanelTimeseries, ConfigID: configID,
+					Query: `sum by (pod) (rate(container_cpu_usage_seconds_total{cluster="$cluster",namespace="${namespace}"}[5m]))`},
+				{Title: "Pods", Type: model.PanelStat, ConfigID: configID, Query: `count(kube_pod_info{cluster="$cluster"})`},
+			}},
+			{Title: "Logs", Panels: []model.Panel{
+				{Title: "API errors", Type: model.PanelTimeseries, Datasource: model.DatasourceLog, SavedQueryID: 5,
+					LogQuery: &logModel.AggregateRequest{Interval: "1m", TopField: "host"}},
+				{Title: "Namespace logs", Type: model.PanelTable, Datasource: model.DatasourceLog,
+					LogQuery: &logModel.AggregateRequest{SearchRequest: logModel.SearchRequest{SourceID: 2, Service: "$namespace"}}},
+			}},
+		},
+	}
+}
+
+func TestMonitorServiceSaveDashboard_DefaultsAndPanelIDs(t *testing.T) {
+	svc, configID, _ := setupDashboardService(t, "http://127.0.0.1:19999")
+	d := newTestDashboard(configID)
+	if err := svc.SaveDashboard(dashboardTenant, 1, "alice", d, true); err != nil {
+		t.Fatalf("save dashboard: %v", err)
+	}
+	var ids []int
+	for _, row := range d.Rows {
+		for _, p := range row.Panels {
+			ids = append(ids, p.ID)
+		}
+	}
+	if !reflect.DeepEqual(ids, []int{3, 4, 5, 6}) {
+		t.Fatalf("unexpected panel ids: %v", ids)
+	}
+	if d.TimeRange != "1h" || d.OwnerName != "alice" || d.Rows[0].Panels[1].Datasource != model.DatasourcePrometheus || d.Rows[0].Panels[0].Width != 12 {
+		t.Fatalf("defaults not applied: %+v", d)
+	}
+
+	// Another user edits; the owner stays.
+	d.Name = "Renamed"
+	if err := svc.SaveDashboard(dashboardTenant, 2, "bob", d, true); err != nil {
+		t.Fatalf("update dashboard: %v", err)
+	}
+	got, err := svc.GetDashboard(dashboardTenant, d.ID)
+	if err != nil || got.Name != "Renamed" || got.OwnerName != "alice" || len(got.Rows[1].Panels) != 2 {
+		t.Fatalf("unexpected stored dashboard: %+v %v", got, err)
+	}
+	if _, err := svc.GetDashboard(dashboardTenant+1, d.ID); obserr.Details(err)["code"] != "DASHBOARD_NOT_FOUND" {
+		t.Fatalf("dashboards must not cross tenants, got %v", err)
+	}
+}
+
+func TestMonitorServiceSaveDashboard_Validation(t *testing.T) {
+	svc, configID, _ := setupDashboardService(t, "http://127.0.0.1:19999")
+	cases := map[string]func(d *model.Dashboard){
+		"no name":                  func(d *model.Dashboard) { d.Name = " " },
+		"range too long":           func(d *model.Dashboard) { d.TimeRange = "90d" },
+		"refresh too short":        func(d *model.Dashboard) { d.Refresh = "1s" },
+		"bad variable name":        func(d *model.Dashboard) { d.Variables[0].Name = "my-cluster" },
+		"orphan namespace":         func(d *model.Dashboard) { d.Variables[1].Cluster = "quantile" },
+		"empty custom":             func(d *model.Dashboard) { d.Variables[2].Options = nil },
+		"unknown panel type":       func(d *model.Dashboard) { d.Rows[0].Panels[0].Type = "heatmap" },
+		"missing config":           func(d *model.Dashboard) { d.Rows[0].Panels[0].ConfigID = 99 },
+		"empty query":              func(d *model.Dashboard) { d.Rows[0].Panels[0].Query = "" },
+		"duplicate panel id":       func(d *model.Dashboard) { d.Rows[1].Panels[0].ID = 3 },
+		"private saved query":      func(d *model.Dashboard) { d.Rows[1].Panels[0].SavedQueryID = 6 },
+		"unknown saved query":      func(d *model.Dashboard) { d.Rows[1].Panels[0].SavedQueryID = 8 },
+		"log panel without source": func(d *model.Dashboard) { d.Rows[1].Panels[1].LogQuery.SourceID = 0 },
+	}
+	for name, mutate := range cases {
+		d := newTestDashboard(configID)
+		mutate(d)
+		if err := svc.SaveDashboard(dashboardTenant, 1, "alice", d, true); err == nil {
+			t.Errorf("%s: expected the dashboard to be rejected", name)
+		}
+	}
+}
+
+func TestMonitorServiceDashboard_ExportImport(t *testing.T) {
+	svc, configID, _ := setupDashboardService(t, "http://127.0.0.1:19999")
+	d := newTestDashboard(configID)
+	if err := svc.SaveDashboard(dashboardTenant, 1, "alice", d, true); err != nil {
+		t.Fatalf("save dashboard: %v", err)
+	}
+	exported, err := svc.ExportDashboard(dashboardTenant, d.ID)
+	if err != nil || exported.SchemaVersion != model.DashboardSchemaVersion || len(exported.Rows) != 2 {
+		t.Fatalf("unexpected export: %+v %v", exported, err)
+	}
+
+	// Importing where the exported config ID does not exist needs a rebind.
+	other := &model.PrometheusConfig{Name: "other", Endpoint: "http://127.0.0.1:19998"}
+	svc.db.Create(other)
+	for i := range exported.Rows[0].Panels {
+		exported.Rows[0].Panels[i].ConfigID = 99
+	}
+	if _, err := svc.ImportDashboard(dashboardTenant, 1, "alice", exported, 0, true); err == nil {
+		t.Fatal("expected import with unknown config to fail")
+	}
+	imported, err := svc.ImportDashboard(dashboardTenant, 1, "alice", exported, other.ID, true)
+	if err != nil {
+		t.Fatalf("import dashboard: %v", err)
+	}
+	if imported.ID == d.ID || imported.Rows[0].Panels[0].ConfigID != other.ID || imported.Rows[1].Panels[0].ConfigID != 0 {
+		t.Fatalf("unexpected imported dashboard: %+v", imported)
+	}
+
+	exported.SchemaVersion = model.DashboardSchemaVersion + 1
+	if _, err := svc.ImportDashboard(dashboardTenant, 1, "alice", exported, other.ID, true); err == nil {
+		t.Fatal("expected newer schema version to be rejected")
+	}
+}
+
+func TestMonitorServicePanelData(t *testing.T) {
+	srv, requests := fakePrometheus(t, map[string]string{
+		"/api/v1/query_range": `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"pod":"a"},"values":[[1760781600,"0.5"]]}]}}`,
+		"/api/v1/query":       `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1760781600,"12"]}]}}`,
+	})
+	svc, configID, logs := setupDashboardService(t, srv.URL)
+	svc.SetVariableSources(VariableSources{
+		Namespaces: func(tenantID uint, cluster string) ([]string, error) { return []string{"default", `kube"system`}, nil },
+	})
+	d := newTestDashboard(configID)
+	d.TimeRange = "6h"
+	if err := svc.SaveDashboard(dashboardTenant, 1, "alice", d, true); err != nil {
+		t.Fatalf("save dashboard: %v", err)
+	}
+	req := model.PanelDataRequest{Vars: map[string]string{"namespace": `kube"system`}, EndTime: "2025-10-18T10:00:00Z"}
+
+	data, err := svc.PanelData(dashboardTenant, 1, d.ID, 3, req, true)
+	if err != nil {
+		t.Fatalf("timeseries panel: %v", err)
+	}
+	want := `sum by (pod) (rate(container_cpu_usage_seconds_total{cluster="prod",namespace="kube\"system"}[5m]))`
+	if data.Query != want || len(data.Metrics.Results) != 1 {
+		t.Fatalf("unexpected panel data: %+v", data)
+	}
+	form := (*requests)[0].Form
+	if form.Get("query") != want || form.Get("start") != "1760760000" || form.Get("end") != "1760781600" {
+		t.Fatalf("unexpected range query: %v", form)
+	}
+
+	if data, err = svc.PanelData(dashboardTenant, 1, d.ID, 4, req, true); err != nil || data.Metrics.ResultType != "vector" {
+		t.Fatalf("stat panel: %+v %v", data, err)
+	}
+	if form := (*requests)[1].Form; form.Get("time") != "1760781600" {
+		t.Fatalf("stat panels query the end of the range: %v", form)
+	}
+
+	if data, err = svc.PanelData(dashboardTenant, 1, d.ID, 5, req, true); err != nil || data.Logs.Total != 42 {
+		t.Fatalf("saved query log panel: %+v %v", data, err)
+	}
+	got := logs.requests[0]
+	if got.SourceID != 9 || got.Level != "ERROR" || got.Service != "api" || got.TopField != "host" || got.Interval != "1m" ||
+		got.StartTime != "2025-10-18T04:00:00Z" || got.EndTime != "2025-10-18T10:00:00Z" {
+		t.Fatalf("unexpected log aggregation: %+v", got)
+	}
+	if _, err = svc.PanelData(dashboardTenant, 1, d.ID, 6, req, true); err != nil || logs.requests[1].Service != `kube"system` {
+		t.Fatalf("inline log panel: %+v %v", logs.requests, err)
+	}
+
+	if _, err := svc.PanelData(dashboardTenant, 1, d.ID, 99, req, true); obserr.Details(err)["code"] != "DASHBOARD_NOT_FOUND" {
+		t.Fatalf("expected unknown panel to be not found, got %v", err)
+	}
+}
+
+func TestMonitorServiceVariableOptions(t *testing.T) {
+	svc, configID, _ := setupDashboardService(t, "http://127.0.0.1:19999")
+	var namespacesOf string
+	svc.SetVariableSources(VariableSources{
+		Clusters: func(tenantID uint) ([]string, error) { return []string{"prod", "staging"}, nil },
+		Namespaces: func(tenantID uint, cluster string) ([]string, error) {
+			namespacesOf = cluster
+			return []string{"default", "kube-system"}, nil
+		},
+	})
+	d := newTestDashboard(configID)
+	d.Variables = append(d.Variables, model.DashboardVariable{Name: "group", Type: model.VariableHostGroup})
+	if err := svc.SaveDashboard(dashboardTenant, 1, "alice", d, true); err != nil {
+		t.Fatalf("save dashboard: %v", err)
+	}
+
+	if opts, err := svc.VariableOptions(dashboardTenant, d.ID, "cluster", nil); err != nil || len(opts) != 2 {
+		t.Fatalf("cluster options: %v %v", opts, err)
+	}
+	if opts, err := svc.VariableOptions(dashboardTenant, d.ID, "namespace", map[string]string{"cluster": "staging"}); err != nil || len(opts) != 2 || namespacesOf != "staging" {
+		t.Fatalf("namespace options: %v %v (cluster %s)", opts, err, namespacesOf)
+	}
+	if _, err := svc.VariableOptions(dashboardTenant, d.ID, "namespace", nil); err != nil || namespacesOf != "prod" {
+		t.Fatalf("namespace options should follow the cluster default: %v (cluster %s)", err, namespacesOf)
+	}
+	if opts, err := svc.VariableOptions(dashboardTenant, d.ID, "quantile", nil); err != nil || len(opts) != 2 {
+		t.Fatalf("custom options: %v %v", opts, err)
+	}
+	if opts, err := svc.VariableOptions(dashboardTenant, d.ID, "group", nil); err != nil || len(opts) != 0 {
+		t.Fatalf("variables without a source have no options: %v %v", opts, err)
+	}
+	if _, err := svc.VariableOptions(dashboardTenant, d.ID, "missing", nil); err == nil {
+		t.Fatal("expected unknown variable to fail")
+	}
+}
+
+func TestMonitorServiceDashboard_LogPanelsNeedLogPermission(t *testing.T) {
+	svc, configID, logs := setupDashboardService(t, "http://127.0.0.1:19999")
+	d := newTestDashboard(configID)
+	if err := svc.SaveDashboard(dashboardTenant, 1, "alice", d, false); obserr.Details(err)["code"] != "DASHBOARD_LOG_FORBIDDEN" {
+		t.Fatalf("expected saving log panels without log permission to be forbidden, got %v", err)
+	}
+	if err := svc.SaveDashboard(dashboardTenant, 1, "alice", d, true); err != nil {
+		t.Fatalf("save dashboard: %v", err)
+	}
+	exported, _ := svc.ExportDashboard(dashboardTenant, d.ID)
+	if _, err := svc.ImportDashboard(dashboardTenant, 1, "alice", exported, configID, false); obserr.Details(err)["code"] != "DASHBOARD_LOG_FORBIDDEN" {
+		t.Fatalf("expected importing log panels without log permission to be forbidden, got %v", err)
+	}
+	if _, err := svc.PanelData(dashboardTenant, 2, d.ID, 5, model.PanelDataRequest{}, false); obserr.Details(err)["code"] != "DASHBOARD_LOG_FORBIDDEN" {
+		t.Fatalf("expected log panel data without log permission to be forbidden, got %v", err)
+	}
+	if len(logs.requests) != 0 {
+		t.Fatalf("no log aggregation may run without log permission, got %+v", logs.requests)
+	}
+}
+
+func TestMonitorServicePanelData_RejectsUnknownVariableValues(t *testing.T) {
+	srv, requests := fakePrometheus(t, map[string]string{
+		"/api/v1/query": `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+	})
+	svc, configID, _ := setupDashboardService(t, srv.URL)
+	svc.SetVariableSources(VariableSources{
+		Clusters: func(tenantID uint) ([]string, error) { return []string{"prod", "staging"}, nil },
+	})
+	d := newTestDashboard(configID)
+	if err := svc.SaveDashboard(dashboardTenant, 1, "alice", d, true); err != nil {
+		t.Fatalf("save dashboard: %v", err)
+	}
+
+	for _, vars := range []map[string]string{
+		{"cluster": `prod"} or vector(1) or up{x="`},
+		{"quantile": "0.75"},
+	} {
+		_, err := svc.PanelData(dashboardTenant, 1, d.ID, 4, model.PanelDataRequest{Vars: vars}, true)
+		if obserr.Details(err)["code"] != "INVALID_PARAM" {
+			t.Fatalf("expected %v to be rejected, got %v", vars, err)
+		}
+	}
+	if len(*requests) != 0 {
+		t.Fatalf("rejected values must not reach prometheus, got %d requests", len(*requests))
+	}
+	if _, err := svc.PanelData(dashboardTenant, 1, d.ID, 4, model.PanelDataRequest{Vars: map[string]string{"cluster": "staging", "quantile": "0.5"}}, true); err != nil {
+		t.Fatalf("listed options should be accepted: %v", err)
+	}
+	if _, err := svc.VariableOptions(dashboardTenant, d.ID, "namespace", map[string]string{"cluster": "other"}); obserr.Details(err)["code"] != "INVALID_PARAM" {
+		t.Fatalf("namespace options of an unlisted cluster should be rejected, got %v", err)
+	}
+}
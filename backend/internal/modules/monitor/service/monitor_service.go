@@ -14,8 +14,10 @@ const op = "monitor/service"
 
 // MonitorService provides business logic for Prometheus config and metric queries
 type MonitorService struct {
-	repo *repository.MonitorRepo
-	db   *gorm.DB
+	repo    *repository.MonitorRepo
+	db      *gorm.DB
+	logs    LogAggregator
+	sources VariableSources
 }
 
 // NewMonitorService creates a new MonitorService
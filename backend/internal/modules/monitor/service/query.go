@@ -0,0 +1,252 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"devops-platform/internal/modules/monitor/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// Guards applied to ad-hoc queries before they reach Prometheus.
+const (
+	defaultQueryRange     = time.Hour
+	maxQueryRange         = 31 * 24 * time.Hour
+	minQueryStep          = time.Second
+	defaultRangePoints    = 250
+	maxRangePoints        = 11000 // Prometheus rejects range queries above this per series
+	defaultSeriesLimit    = 500
+	maxSeriesLimit        = 5000
+	defaultDiscoveryLimit = 1000
+	maxDiscoveryLimit     = 10000
+)
+
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Query runs an instant PromQL query, at req.Time or now, keeping at most
+// req.Limit series.
+func (s *MonitorService) Query(req model.MetricQueryRequest) (*model.MetricQueryResponse, error) {
+	if err := validateQuery(req); err != nil {
+		return nil, err
+	}
+	ts := ""
+	if req.Time != "" {
+		t, err := parsePromTime(req.Time)
+		if err != nil {
+			return nil, err
+		}
+		ts = formatPromTime(t)
+	}
+	resp, err := s.repo.QueryInstantAt(req.ConfigID, req.Query, ts)
+	if err != nil {
+		return nil, err
+	}
+	limitSeries(resp, req.Limit)
+	return resp, nil
+}
+
+// QueryRange runs a range PromQL query over [StartTime, EndTime], by default
+// the last hour. Without a step one is chosen for about 250 points; a step
+// that would exceed Prometheus's per-series point limit is raised to fit.
+func (s *MonitorService) QueryRange(req model.MetricQueryRequest) (*model.MetricQueryResponse, error) {
+	if err := validateQuery(req); err != nil {
+		return nil, err
+	}
+	start, end, err := resolveRange(req.StartTime, req.EndTime, time.Now(), defaultQueryRange)
+	if err != nil {
+		return nil, err
+	}
+	step, err := resolveStep(req.Step, end.Sub(start))
+	if err != nil {
+		return nil, err
+	}
+	stepParam := strconv.FormatFloat(step.Seconds(), 'f', -1, 64)
+	resp, err := s.repo.QueryRange(req.ConfigID, req.Query, formatPromTime(start), formatPromTime(end), stepParam)
+	if err != nil {
+		return nil, err
+	}
+	resp.Step = step.String()
+	limitSeries(resp, req.Limit)
+	return resp, nil
+}
+
+// Labels lists label names for autocomplete.
+func (s *MonitorService) Labels(req model.MetricDiscoveryRequest) ([]string, bool, error) {
+	start, end, limit, err := resolveDiscovery(req)
+	if err != nil {
+		return nil, false, err
+	}
+	names, err := s.repo.Labels(req.ConfigID, req.Match, start, end, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	names, truncated := limitStrings(names, limit)
+	return names, truncated, nil
+}
+
+// LabelValues lists the values of one label for autocomplete.
+func (s *MonitorService) LabelValues(name string, req model.MetricDiscoveryRequest) ([]string, bool, error) {
+	if !labelNamePattern.MatchString(name) {
+		return nil, false, obserr.New("INVALID_PARAM", op, fmt.Sprintf("invalid label name: %s", name))
+	}
+	start, end, limit, err := resolveDiscovery(req)
+	if err != nil {
+		return nil, false, err
+	}
+	values, err := s.repo.LabelValues(req.ConfigID, name, req.Match, start, end, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	values, truncated := limitStrings(values, limit)
+	return values, truncated, nil
+}
+
+// Series lists the label sets of series matching at least one selector.
+func (s *MonitorService) Series(req model.MetricDiscoveryRequest) ([]map[string]string, bool, error) {
+	if len(req.Match) == 0 {
+		return nil, false, obserr.New("INVALID_PARAM", op, "at least one match[] selector is required")
+	}
+	start, end, limit, err := resolveDiscovery(req)
+	if err != nil {
+		return nil, false, err
+	}
+	series, err := s.repo.Series(req.ConfigID, req.Match, start, end, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(series) > limit {
+		return series[:limit], true, nil
+	}
+	return series, false, nil
+}
+
+func validateQuery(req model.MetricQueryRequest) error {
+	if req.ConfigID == 0 {
+		return obserr.New("INVALID_PARAM", op, "configId is required")
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return obserr.New("INVALID_PARAM", op, "query is required")
+	}
+	return nil
+}
+
+// resolveDiscovery applies the query range guards and the discovery limit.
+func resolveDiscovery(req model.MetricDiscoveryRequest) (string, string, int, error) {
+	if req.ConfigID == 0 {
+		return "", "", 0, obserr.New("INVALID_PARAM", op, "configId is required")
+	}
+	start, end, err := resolveRange(req.StartTime, req.EndTime, time.Now(), defaultQueryRange)
+	if err != nil {
+		return "", "", 0, err
+	}
+	return formatPromTime(start), formatPromTime(end), clampLimit(req.Limit, defaultDiscoveryLimit, maxDiscoveryLimit), nil
+}
+
+// resolveRange defaults the end to now and the start to span before it.
+func resolveRange(startTime, endTime string, now time.Time, span time.Duration) (time.Time, time.Time, error) {
+	end := now
+	if endTime != "" {
+		t, err := parsePromTime(endTime)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		end = t
+	}
+	start := end.Add(-span)
+	if startTime != "" {
+		t, err := parsePromTime(startTime)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		start = t
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, obserr.New("INVALID_PARAM", op, "startTime must be before endTime")
+	}
+	if end.Sub(start) > maxQueryRange {
+		return time.Time{}, time.Time{}, obserr.New("INVALID_PARAM", op, fmt.Sprintf("time range must not exceed %s", maxQueryRange))
+	}
+	return start, end, nil
+}
+
+// resolveStep picks a step for the range, or raises the requested one to the
+// smallest step keeping the series within maxRangePoints.
+func resolveStep(stepParam string, span time.Duration) (time.Duration, error) {
+	floor := ceilSeconds(span / maxRangePoints)
+	if stepParam == "" {
+		return max(ceilSeconds(span/defaultRangePoints), floor, minQueryStep), nil
+	}
+	step, err := parseStep(stepParam)
+	if err != nil {
+		return 0, err
+	}
+	if step < minQueryStep {
+		return 0, obserr.New("INVALID_PARAM", op, fmt.Sprintf("step must be at least %s", minQueryStep))
+	}
+	return max(step, floor), nil
+}
+
+// parsePromTime accepts RFC3339 and unix seconds, as Prometheus does.
+func parsePromTime(s string) (time.Time, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, obserr.New("INVALID_PARAM", op, fmt.Sprintf("invalid time: %s", s))
+	}
+	return t, nil
+}
+
+func formatPromTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', -1, 64)
+}
+
+// parseStep accepts durations ("30s", "5m") and seconds ("15", "0.5").
+func parseStep(s string) (time.Duration, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, obserr.New("INVALID_PARAM", op, fmt.Sprintf("invalid step: %s", s))
+	}
+	return d, nil
+}
+
+func ceilSeconds(d time.Duration) time.Duration {
+	if r := d % time.Second; r != 0 {
+		d += time.Second - r
+	}
+	return d
+}
+
+func clampLimit(limit, def, ceiling int) int {
+	if limit <= 0 {
+		return def
+	}
+	return min(limit, ceiling)
+}
+
+// limitSeries keeps the first series up to the limit and flags the rest as
+// dropped. It runs on the decoded result, so it bounds what the client
+// receives; Prometheus still evaluates and returns every series.
+func limitSeries(resp *model.MetricQueryResponse, limit int) {
+	limit = clampLimit(limit, defaultSeriesLimit, maxSeriesLimit)
+	if len(resp.Results) > limit {
+		resp.Results = resp.Results[:limit]
+		resp.Truncated = true
+	}
+}
+
+func limitStrings(items []string, limit int) ([]string, bool) {
+	if len(items) > limit {
+		return items[:limit], true
+	}
+	return items, false
+}
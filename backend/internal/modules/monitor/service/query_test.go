@@ -0,0 +1,153 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/monitor/model"
+	"devops-platform/internal/pkg/obserr"
+)
+
+// fakePrometheus records the requests it receives and answers them from the
+// canned bodies keyed by path.
+func fakePrometheus(t *testing.T, bodies map[string]string) (*httptest.Server, *[]*http.Request) {
+	var requests []*http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		requests = append(requests, r)
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func setupQueryService(t *testing.T, endpoint string) (*MonitorService, uint) {
+	db := setupTestDB(t)
+	cfg := &model.PrometheusConfig{Name: "prom", Endpoint: endpoint, TimeoutSeconds: 20}
+	db.Create(cfg)
+	return NewMonitorService(db), cfg.ID
+}
+
+func TestMonitorServiceQuery_InstantAtTimeWithLimit(t *testing.T) {
+	srv, requests := fakePrometheus(t, map[string]string{"/api/v1/query": `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"job":"node","instance":"a:9100"},"value":[1760781600,"1"]},
+		{"metric":{"job":"node","instance":"b:9100"},"value":[1760781600,"0"]}]}}`})
+	svc, configID := setupQueryService(t, srv.URL)
+
+	resp, err := svc.Query(model.MetricQueryRequest{ConfigID: configID, Query: "up", Time: "2025-10-18T10:00:00Z", Limit: 1})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(resp.Results) != 1 || !resp.Truncated || resp.Results[0].Metric["instance"] != "a:9100" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	form := (*requests)[0].Form
+	if form.Get("query") != "up" || form.Get("time") != "1760781600" || form.Get("timeout") != "20s" {
+		t.Fatalf("unexpected request params: %v", form)
+	}
+}
+
+func TestMonitorServiceQueryRange_StepGuards(t *testing.T) {
+	srv, requests := fakePrometheus(t, map[string]string{"/api/v1/query_range": `{"status":"success","data":{"resultType":"matrix","result":[
+		{"metric":{"job":"node"},"values":[[1760781600,"1"],[1760781660,"2"]]}]}}`})
+	svc, configID := setupQueryService(t, srv.URL)
+
+	cases := []struct {
+		start, end, step string
+		wantStep         string
+	}{
+		// An hour at the default density of about 250 points.
+		{"2025-10-18T09:00:00Z", "2025-10-18T10:00:00Z", "", "15"},
+		// A requested step is kept when the series stays under the point limit.
+		{"1760778000", "1760781600", "30s", "30"},
+		// Thirty days at 1s would be 2.6M points; the step is raised to fit 11000.
+		{"2025-09-18T10:00:00Z", "2025-10-18T10:00:00Z", "1", "236"},
+	}
+	for i, tc := range cases {
+		resp, err := svc.QueryRange(model.MetricQueryRequest{ConfigID: configID, Query: "up", StartTime: tc.start, EndTime: tc.end, Step: tc.step})
+		if err != nil {
+			t.Fatalf("case %d: query range failed: %v", i, err)
+		}
+		form := (*requests)[i].Form
+		if form.Get("step") != tc.wantStep {
+			t.Errorf("case %d: expected step %s, got %s", i, tc.wantStep, form.Get("step"))
+		}
+		if resp.ResultType != "matrix" || len(resp.Results[0].Values) != 2 || resp.Step == "" {
+			t.Errorf("case %d: unexpected response: %+v", i, resp)
+		}
+	}
+}
+
+func TestMonitorServiceQueryRange_RejectsInvalidRequests(t *testing.T) {
+	svc, configID := setupQueryService(t, "http://127.0.0.1:19999")
+	cases := []model.MetricQueryRequest{
+		{Query: "up"},
+		{ConfigID: configID, Query: " "},
+		{ConfigID: configID, Query: "up", StartTime: "2025-10-18T10:00:00Z", EndTime: "2025-10-18T09:00:00Z"},
+		{ConfigID: configID, Query: "up", StartTime: "2025-01-01T00:00:00Z", EndTime: "2025-10-18T00:00:00Z"},
+		{ConfigID: configID, Query: "up", Step: "100ms"},
+		{ConfigID: configID, Query: "up", Step: "often"},
+		{ConfigID: configID, Query: "up", EndTime: "yesterday"},
+	}
+	for i, req := range cases {
+		_, err := svc.QueryRange(req)
+		if err == nil || obserr.Details(err)["code"] != "INVALID_PARAM" {
+			t.Errorf("case %d: expected INVALID_PARAM, got %v", i, err)
+		}
+	}
+}
+
+func TestMonitorServiceDiscovery(t *testing.T) {
+	srv, requests := fakePrometheus(t, map[string]string{
+		"/api/v1/labels":           `{"status":"success","data":["__name__","instance","job"]}`,
+		"/api/v1/label/job/values": `{"status":"success","data":["node","prometheus"]}`,
+		"/api/v1/series":           `{"status":"success","data":[{"__name__":"up","job":"node"}]}`,
+		"/api/v1/label/bad/values": `{"status":"error","error":"boom"}`,
+	})
+	svc, configID := setupQueryService(t, srv.URL)
+	req := model.MetricDiscoveryRequest{ConfigID: configID, Match: []string{`up{job="node"}`}, Limit: 2}
+
+	names, truncated, err := svc.Labels(req)
+	if err != nil || len(names) != 2 || !truncated {
+		t.Fatalf("unexpected labels: %v %v %v", names, truncated, err)
+	}
+	form := (*requests)[0].Form
+	if form.Get("match[]") != `up{job="node"}` || form.Get("limit") != "3" {
+		t.Fatalf("unexpected labels params: %v", form)
+	}
+	start, _ := parsePromTime(form.Get("start"))
+	end, _ := parsePromTime(form.Get("end"))
+	if end.Sub(start) != time.Hour {
+		t.Fatalf("expected the last hour by default, got %s", end.Sub(start))
+	}
+
+	values, truncated, err := svc.LabelValues("job", req)
+	if err != nil || len(values) != 2 || truncated {
+		t.Fatalf("unexpected values: %v %v %v", values, truncated, err)
+	}
+	series, _, err := svc.Series(req)
+	if err != nil || len(series) != 1 || series[0]["job"] != "node" {
+		t.Fatalf("unexpected series: %v %v", series, err)
+	}
+
+	if _, _, err := svc.LabelValues("bad", req); err == nil || obserr.Details(err)["code"] != "PROMETHEUS_QUERY_FAILED" {
+		t.Fatalf("expected prometheus error, got %v", err)
+	}
+	if _, _, err := svc.LabelValues("job/../../admin", req); err == nil {
+		t.Fatal("expected invalid label name to be rejected")
+	}
+	if _, _, err := svc.Series(model.MetricDiscoveryRequest{ConfigID: configID}); err == nil {
+		t.Fatal("expected series without match[] to be rejected")
+	}
+	if len(*requests) != 4 {
+		t.Fatalf("rejected requests must not reach prometheus, got %d requests", len(*requests))
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"devops-platform/internal/modules/notification/model"
+	"devops-platform/internal/modules/notification/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var notificationService *service.NotificationService
+
+func InitNotificationService(svc *service.NotificationService) {
+	notificationService = svc
+}
+
+// ListTemplates returns the tenant's notification templates.
+func ListTemplates(c *gin.Context) {
+	templates, err := notificationService.ListTemplates(c.GetUint("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": templates})
+}
+
+// SaveTemplate creates a template, or updates the one in the :id path parameter.
+func SaveTemplate(c *gin.Context) {
+	var t model.Template
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+		return
+	}
+	if idParam := c.Param("id"); idParam != "" {
+		id, _ := strconv.ParseUint(idParam, 10, 64)
+		t.ID = uint(id)
+	}
+	saved, err := notificationService.SaveTemplate(c.GetUint("tenantID"), t)
+	if err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": saved})
+}
+
+func DeleteTemplate(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err := notificationService.DeleteTemplate(c.GetUint("tenantID"), uint(id)); err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "deleted"})
+}
+
+// PreviewTemplate renders a saved or draft template against a sample alert,
+// workflow or task payload without sending anything.
+func PreviewTemplate(c *gin.Context) {
+	var req service.PreviewTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+		return
+	}
+	resp, err := notificationService.PreviewTemplate(c.GetUint("tenantID"), req)
+	if err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": resp})
+}
+
+func writeTemplateError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrTemplateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "模板不存在"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+}
+
+// GetOutboxStatus returns the outbox depth and message counts by status.
+func GetOutboxStatus(c *gin.Context) {
+	status, err := notificationService.OutboxStatus(c.GetUint("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": status})
+}
+
+// ListOutbox lists recent outbox messages, e.g. ?status=dead for the dead-letter queue.
+func ListOutbox(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	items, err := notificationService.ListOutbox(c.GetUint("tenantID"), model.OutboxStatus(c.Query("status")), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": items})
+}
+
+// ReplayOutbox requeues dead-lettered messages; an empty id list replays all of them.
+func ReplayOutbox(c *gin.Context) {
+	var req service.ReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request"})
+		return
+	}
+	count, err := notificationService.ReplayDeadLetters(c.GetUint("tenantID"), req.IDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": gin.H{"replayed": count}})
+}
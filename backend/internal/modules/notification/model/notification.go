@@ -13,6 +13,9 @@ const (
 	ChannelDingTalk ChannelType = "dingtalk"
 	ChannelWeCom    ChannelType = "wecom"
 	ChannelEmail    ChannelType = "email"
+	ChannelWebhook  ChannelType = "webhook"
+	ChannelSlack    ChannelType = "slack"
+	ChannelTeams    ChannelType = "teams"
 )
 
 type Template struct {
@@ -55,3 +58,36 @@ type SendLog struct {
 }
 
 func (SendLog) TableName() string { return "notification_logs" }
+
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending"
+	OutboxSending OutboxStatus = "sending"
+	OutboxSent    OutboxStatus = "sent"
+	OutboxDead    OutboxStatus = "dead"
+)
+
+// OutboxMessage is a queued notification. Channels are tried in order on each
+// attempt; failed attempts are retried with backoff until MaxAttempts, after
+// which the message is dead-lettered until replayed.
+type OutboxMessage struct {
+	ID            uint          `gorm:"primaryKey" json:"id"`
+	TenantID      uint          `gorm:"index;not null" json:"tenant_id"`
+	Channels      []ChannelType `gorm:"serializer:json;type:text" json:"channels"`
+	Recipients    []string      `gorm:"serializer:json;type:text" json:"recipients"`
+	Subject       string        `gorm:"size:255" json:"subject"`
+	Body          string        `gorm:"type:text" json:"body"`
+	Status        OutboxStatus  `gorm:"size:16;not null;index:idx_outbox_due,priority:1" json:"status"`
+	Attempts      int           `gorm:"default:0" json:"attempts"`
+	MaxAttempts   int           `gorm:"default:5" json:"max_attempts"`
+	NextAttemptAt time.Time     `gorm:"index:idx_outbox_due,priority:2" json:"next_attempt_at"`
+	LastError     string        `gorm:"size:1024" json:"last_error"`
+	SentChannel   ChannelType   `gorm:"size:32" json:"sent_channel"`
+	SentAt        *time.Time    `json:"sent_at"`
+	Version       int           `gorm:"default:0" json:"-"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+func (OutboxMessage) TableName() string { return "notification_outbox" }
@@ -14,6 +14,7 @@ type NotificationService struct {
 	db        *gorm.DB
 	notifiers map[model.ChannelType]Notifier
 	channels  []model.ChannelConfig
+	outbox    *outbox
 }
 
 func NewNotificationService(db *gorm.DB) *NotificationService {
@@ -27,11 +28,20 @@ func (s *NotificationService) RegisterNotifier(channel model.ChannelType, notifi
 	s.notifiers[channel] = notifier
 }
 
+// Send delivers through one channel. With the outbox enabled the message is
+// queued and delivered by the outbox workers; otherwise it is sent inline.
 func (s *NotificationService) Send(tenantID uint, channel model.ChannelType, recipients []string, subject, body string) error {
 	notifier, ok := s.notifiers[channel]
 	if !ok {
 		return fmt.Errorf("no notifier registered for channel: %s", channel)
 	}
+	if s.outbox != nil {
+		return s.enqueue(tenantID, []model.ChannelType{channel}, recipients, subject, body)
+	}
+	return s.sendNow(tenantID, channel, notifier, recipients, subject, body)
+}
+
+func (s *NotificationService) sendNow(tenantID uint, channel model.ChannelType, notifier Notifier, recipients []string, subject, body string) error {
 	if err := notifier.Send(recipients, subject, body); err != nil {
 		s.logSend(tenantID, channel, strings.Join(recipients, ","), body, false, err.Error())
 		return err
@@ -41,6 +51,7 @@ func (s *NotificationService) Send(tenantID uint, channel model.ChannelType, rec
 }
 
 // SendWithFallback sends via the primary channel, falling back to lower priority channels on failure.
+// With the outbox enabled the whole fallback chain is queued as one message.
 func (s *NotificationService) SendWithFallback(tenantID uint, recipients []string, subject, body string) error {
 	var channels []struct {
 		Channel  model.ChannelType
@@ -56,6 +67,18 @@ func (s *NotificationService) SendWithFallback(tenantID uint, recipients []strin
 		return channels[i].Priority > channels[j].Priority
 	})
 
+	if s.outbox != nil {
+		var chain []model.ChannelType
+		for _, ch := range channels {
+			if _, ok := s.notifiers[ch.Channel]; ok {
+				chain = append(chain, ch.Channel)
+			}
+		}
+		if len(chain) == 0 {
+			return fmt.Errorf("all notification channels failed")
+		}
+		return s.enqueue(tenantID, chain, recipients, subject, body)
+	}
 	for _, ch := range channels {
 		if err := s.Send(tenantID, ch.Channel, recipients, subject, body); err == nil {
 			return nil
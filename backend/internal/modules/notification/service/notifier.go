@@ -2,9 +2,18 @@ package service
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 )
 
 // Notifier is the interface for sending notifications through a specific channel.
@@ -12,52 +21,120 @@ type Notifier interface {
 	Send(recipients []string, subject, body string) error
 }
 
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON posts payload as JSON and returns the response body, failing on non-2xx status.
+func postJSON(name, targetURL string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s payload encode failed: %w", name, err)
+	}
+	return doRequest(name, http.MethodPost, targetURL, nil, data)
+}
+
+func doRequest(name, method, targetURL string, headers map[string]string, data []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, targetURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s webhook failed: %w", name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s webhook failed: %w", name, err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 300 {
+		return respBody, fmt.Errorf("%s returned status %d", name, resp.StatusCode)
+	}
+	return respBody, nil
+}
+
+// checkErrCode reports an error from the {"errcode": n, "errmsg": "..."} or
+// {"code": n, "msg": "..."} envelope used by DingTalk, WeCom and Feishu.
+func checkErrCode(name string, respBody []byte) error {
+	var result struct {
+		ErrCode *int   `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		Code    *int   `json:"code"`
+		Msg     string `json:"msg"`
+	}
+	if len(respBody) == 0 || json.Unmarshal(respBody, &result) != nil {
+		return nil
+	}
+	if result.ErrCode != nil && *result.ErrCode != 0 {
+		return fmt.Errorf("%s returned errcode %d: %s", name, *result.ErrCode, result.ErrMsg)
+	}
+	if result.Code != nil && *result.Code != 0 {
+		return fmt.Errorf("%s returned code %d: %s", name, *result.Code, result.Msg)
+	}
+	return nil
+}
+
 // FeishuNotifier sends notifications via Feishu webhook.
+// When Secret is set, requests are signed for the bot's signature security mode.
 type FeishuNotifier struct {
 	WebhookURL string
+	Secret     string
 }
 
 func NewFeishuNotifier(webhookURL string) *FeishuNotifier {
 	return &FeishuNotifier{WebhookURL: webhookURL}
 }
 
+func NewSignedFeishuNotifier(webhookURL, secret string) *FeishuNotifier {
+	return &FeishuNotifier{WebhookURL: webhookURL, Secret: secret}
+}
+
 func (n *FeishuNotifier) Send(recipients []string, subject, body string) error {
 	msg := map[string]interface{}{
 		"msg_type": "interactive",
 		"card": map[string]interface{}{
 			"header": map[string]interface{}{
-				"title":    map[string]string{"content": subject},
+				"title": map[string]string{"tag": "plain_text", "content": subject},
 			},
 			"elements": []map[string]interface{}{
 				{"tag": "markdown", "content": body},
 			},
 		},
 	}
-	return n.post(msg)
-}
-
-func (n *FeishuNotifier) post(msg interface{}) error {
-	data, _ := json.Marshal(msg)
-	resp, err := http.Post(n.WebhookURL, "application/json", bytes.NewReader(data))
-	if err != nil {
-		return fmt.Errorf("feishu webhook failed: %w", err)
+	if n.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		msg["timestamp"] = timestamp
+		msg["sign"] = FeishuSign(timestamp, n.Secret)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("feishu returned status %d", resp.StatusCode)
+	respBody, err := postJSON("feishu", n.WebhookURL, msg)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkErrCode("feishu", respBody)
+}
+
+// FeishuSign computes the Feishu bot signature: HMAC-SHA256 keyed by
+// "timestamp\nsecret" over an empty message, base64 encoded.
+func FeishuSign(timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
 }
 
 // DingTalkNotifier sends notifications via DingTalk webhook.
+// When Secret is set, requests are signed for the robot's "加签" security mode.
 type DingTalkNotifier struct {
 	WebhookURL string
+	Secret     string
 }
 
 func NewDingTalkNotifier(webhookURL string) *DingTalkNotifier {
 	return &DingTalkNotifier{WebhookURL: webhookURL}
 }
 
+func NewSignedDingTalkNotifier(webhookURL, secret string) *DingTalkNotifier {
+	return &DingTalkNotifier{WebhookURL: webhookURL, Secret: secret}
+}
+
 func (n *DingTalkNotifier) Send(recipients []string, subject, body string) error {
 	msg := map[string]interface{}{
 		"msgtype": "markdown",
@@ -66,16 +143,178 @@ func (n *DingTalkNotifier) Send(recipients []string, subject, body string) error
 			"text":  fmt.Sprintf("## %s\n\n%s", subject, body),
 		},
 	}
-	data, _ := json.Marshal(msg)
-	resp, err := http.Post(n.WebhookURL, "application/json", bytes.NewReader(data))
+	targetURL := n.WebhookURL
+	if n.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		signed, err := appendQuery(targetURL, map[string]string{
+			"timestamp": timestamp,
+			"sign":      DingTalkSign(timestamp, n.Secret),
+		})
+		if err != nil {
+			return fmt.Errorf("dingtalk webhook failed: %w", err)
+		}
+		targetURL = signed
+	}
+	respBody, err := postJSON("dingtalk", targetURL, msg)
 	if err != nil {
-		return fmt.Errorf("dingtalk webhook failed: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("dingtalk returned status %d", resp.StatusCode)
+	return checkErrCode("dingtalk", respBody)
+}
+
+// DingTalkSign computes the DingTalk robot signature: HMAC-SHA256 keyed by
+// the secret over "timestamp\nsecret", base64 encoded. The caller URL-encodes it.
+func DingTalkSign(timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func appendQuery(rawURL string, params map[string]string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
 	}
-	return nil
+	q := u.Query()
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// WeComNotifier sends notifications via a WeCom (企业微信) group robot webhook.
+// Recipients are WeCom user IDs mentioned in the message.
+type WeComNotifier struct {
+	WebhookURL string
+}
+
+func NewWeComNotifier(webhookURL string) *WeComNotifier {
+	return &WeComNotifier{WebhookURL: webhookURL}
+}
+
+func (n *WeComNotifier) Send(recipients []string, subject, body string) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## %s\n\n%s", subject, body))
+	for _, r := range recipients {
+		sb.WriteString(fmt.Sprintf("\n<@%s>", r))
+	}
+	msg := map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"content": sb.String()},
+	}
+	respBody, err := postJSON("wecom", n.WebhookURL, msg)
+	if err != nil {
+		return err
+	}
+	return checkErrCode("wecom", respBody)
+}
+
+// SlackNotifier sends notifications via a Slack incoming webhook.
+type SlackNotifier struct {
+	WebhookURL string
+}
+
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{WebhookURL: webhookURL}
+}
+
+func (n *SlackNotifier) Send(recipients []string, subject, body string) error {
+	msg := map[string]interface{}{
+		"text": subject,
+		"blocks": []map[string]interface{}{
+			{"type": "header", "text": map[string]string{"type": "plain_text", "text": subject}},
+			{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": body}},
+		},
+	}
+	_, err := postJSON("slack", n.WebhookURL, msg)
+	return err
+}
+
+// TeamsNotifier sends notifications via a Microsoft Teams incoming webhook.
+type TeamsNotifier struct {
+	WebhookURL string
+}
+
+func NewTeamsNotifier(webhookURL string) *TeamsNotifier {
+	return &TeamsNotifier{WebhookURL: webhookURL}
+}
+
+func (n *TeamsNotifier) Send(recipients []string, subject, body string) error {
+	msg := map[string]interface{}{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  subject,
+		"title":    subject,
+		"text":     body,
+	}
+	_, err := postJSON("teams", n.WebhookURL, msg)
+	return err
+}
+
+// defaultWebhookBody is used when WebhookNotifier.BodyTemplate is empty.
+const defaultWebhookBody = `{"subject":{{json .Subject}},"body":{{json .Body}},"recipients":{{json .Recipients}}}`
+
+// WebhookPayload is the data available to a generic webhook body template.
+type WebhookPayload struct {
+	Subject    string
+	Body       string
+	Recipients []string
+}
+
+// WebhookNotifier posts to an arbitrary HTTP endpoint. The request body is
+// rendered from BodyTemplate (text/template over WebhookPayload); the "json"
+// template function encodes a value as a JSON literal.
+type WebhookNotifier struct {
+	URL          string
+	Method       string
+	Headers      map[string]string
+	BodyTemplate string
+}
+
+func NewWebhookNotifier(targetURL, method string, headers map[string]string, bodyTemplate string) (*WebhookNotifier, error) {
+	n := &WebhookNotifier{URL: targetURL, Method: method, Headers: headers, BodyTemplate: bodyTemplate}
+	if _, err := n.template(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *WebhookNotifier) template() (*template.Template, error) {
+	text := n.BodyTemplate
+	if strings.TrimSpace(text) == "" {
+		text = defaultWebhookBody
+	}
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("webhook body template invalid: %w", err)
+	}
+	return tmpl, nil
+}
+
+func (n *WebhookNotifier) Send(recipients []string, subject, body string) error {
+	tmpl, err := n.template()
+	if err != nil {
+		return err
+	}
+	if recipients == nil {
+		recipients = []string{}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, WebhookPayload{Subject: subject, Body: body, Recipients: recipients}); err != nil {
+		return fmt.Errorf("webhook body render failed: %w", err)
+	}
+	method := strings.ToUpper(strings.TrimSpace(n.Method))
+	if method == "" {
+		method = http.MethodPost
+	}
+	_, err = doRequest("webhook", method, n.URL, n.Headers, buf.Bytes())
+	return err
 }
 
 // EmailNotifier sends notifications via SMTP.
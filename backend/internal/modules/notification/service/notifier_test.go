@@ -0,0 +1,154 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type capturedRequest struct {
+	method  string
+	query   map[string]string
+	headers http.Header
+	body    map[string]interface{}
+}
+
+// newCaptureServer records the last request and answers with the given status and body.
+func newCaptureServer(t *testing.T, status int, response string) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+	captured := &capturedRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.method = r.Method
+		captured.headers = r.Header.Clone()
+		captured.query = map[string]string{}
+		for k := range r.URL.Query() {
+			captured.query[k] = r.URL.Query().Get(k)
+		}
+		data, _ := io.ReadAll(r.Body)
+		captured.body = map[string]interface{}{}
+		if err := json.Unmarshal(data, &captured.body); err != nil {
+			t.Errorf("request body is not JSON: %s", data)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, captured
+}
+
+func TestFeishuNotifier_SignedRequest(t *testing.T) {
+	srv, captured := newCaptureServer(t, http.StatusOK, `{"code":0,"msg":"success"}`)
+	if err := NewSignedFeishuNotifier(srv.URL, "feishu-secret").Send(nil, "告警", "**内容**"); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	timestamp, _ := captured.body["timestamp"].(string)
+	if timestamp == "" || captured.body["sign"] != FeishuSign(timestamp, "feishu-secret") {
+		t.Fatalf("expected timestamp and sign in body, got %v", captured.body)
+	}
+	if captured.body["msg_type"] != "interactive" {
+		t.Fatalf("unexpected message type: %v", captured.body["msg_type"])
+	}
+
+	// Feishu reports signature failures in the body with HTTP 200.
+	failing, _ := newCaptureServer(t, http.StatusOK, `{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`)
+	if err := NewSignedFeishuNotifier(failing.URL, "wrong").Send(nil, "告警", "内容"); err == nil {
+		t.Fatalf("expected body error code reported")
+	}
+}
+
+func TestSign_KnownValues(t *testing.T) {
+	if got := FeishuSign("1599360473", "demo"); got != "l1N0gAcBjdwBvGm1xMjOF0XSyaLRpR7tuO5dHfhAYc8=" {
+		t.Fatalf("unexpected feishu sign: %s", got)
+	}
+	if got := DingTalkSign("1577262236757", "SECdemo"); got != "sLRlUUvzoOXn5DR/+o+s6tOqX+bWe3JGaZ7JQ7844l8=" {
+		t.Fatalf("unexpected dingtalk sign: %s", got)
+	}
+}
+
+func TestDingTalkNotifier_SignedRequest(t *testing.T) {
+	srv, captured := newCaptureServer(t, http.StatusOK, `{"errcode":0,"errmsg":"ok"}`)
+	if err := NewSignedDingTalkNotifier(srv.URL+"/robot/send?access_token=abc", "SECabc").Send(nil, "告警", "内容"); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if captured.query["access_token"] != "abc" {
+		t.Fatalf("expected access_token preserved, got %v", captured.query)
+	}
+	timestamp := captured.query["timestamp"]
+	if timestamp == "" || captured.query["sign"] != DingTalkSign(timestamp, "SECabc") {
+		t.Fatalf("expected timestamp and sign query params, got %v", captured.query)
+	}
+
+	failing, _ := newCaptureServer(t, http.StatusOK, `{"errcode":310000,"errmsg":"sign not match"}`)
+	if err := NewSignedDingTalkNotifier(failing.URL, "wrong").Send(nil, "告警", "内容"); err == nil {
+		t.Fatalf("expected errcode reported")
+	}
+}
+
+func TestWeComNotifier_MentionsRecipients(t *testing.T) {
+	srv, captured := newCaptureServer(t, http.StatusOK, `{"errcode":0,"errmsg":"ok"}`)
+	if err := NewWeComNotifier(srv.URL).Send([]string{"zhangsan"}, "告警", "内容"); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	markdown, _ := captured.body["markdown"].(map[string]interface{})
+	content, _ := markdown["content"].(string)
+	if captured.body["msgtype"] != "markdown" || !strings.Contains(content, "<@zhangsan>") {
+		t.Fatalf("unexpected wecom payload: %v", captured.body)
+	}
+}
+
+func TestSlackAndTeamsNotifiers(t *testing.T) {
+	slack, slackReq := newCaptureServer(t, http.StatusOK, "ok")
+	if err := NewSlackNotifier(slack.URL).Send(nil, "Deploy failed", "*api* rollout stuck"); err != nil {
+		t.Fatalf("slack send failed: %v", err)
+	}
+	if slackReq.body["text"] != "Deploy failed" {
+		t.Fatalf("unexpected slack payload: %v", slackReq.body)
+	}
+
+	teams, teamsReq := newCaptureServer(t, http.StatusOK, "1")
+	if err := NewTeamsNotifier(teams.URL).Send(nil, "Deploy failed", "api rollout stuck"); err != nil {
+		t.Fatalf("teams send failed: %v", err)
+	}
+	if teamsReq.body["@type"] != "MessageCard" || teamsReq.body["title"] != "Deploy failed" {
+		t.Fatalf("unexpected teams payload: %v", teamsReq.body)
+	}
+
+	down, _ := newCaptureServer(t, http.StatusBadRequest, "invalid_payload")
+	if err := NewSlackNotifier(down.URL).Send(nil, "x", "y"); err == nil {
+		t.Fatalf("expected non-2xx status reported")
+	}
+}
+
+func TestWebhookNotifier_TemplateAndHeaders(t *testing.T) {
+	srv, captured := newCaptureServer(t, http.StatusOK, "")
+	n, err := NewWebhookNotifier(srv.URL, "put", map[string]string{"Authorization": "Bearer token-1"},
+		`{"title":{{json .Subject}},"text":{{json .Body}},"to":{{json .Recipients}},"source":"devops"}`)
+	if err != nil {
+		t.Fatalf("create webhook notifier failed: %v", err)
+	}
+	if err := n.Send([]string{"ops"}, `disk "full"`, "line1\nline2"); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if captured.method != http.MethodPut || captured.headers.Get("Authorization") != "Bearer token-1" {
+		t.Fatalf("unexpected method or headers: %s %v", captured.method, captured.headers)
+	}
+	if captured.body["title"] != `disk "full"` || captured.body["text"] != "line1\nline2" || captured.body["source"] != "devops" {
+		t.Fatalf("unexpected rendered body: %v", captured.body)
+	}
+
+	defaultSrv, defaultReq := newCaptureServer(t, http.StatusOK, "")
+	n, _ = NewWebhookNotifier(defaultSrv.URL, "", nil, "")
+	if err := n.Send(nil, "s", "b"); err != nil {
+		t.Fatalf("send with default template failed: %v", err)
+	}
+	if defaultReq.method != http.MethodPost || defaultReq.body["subject"] != "s" {
+		t.Fatalf("unexpected default webhook request: %s %v", defaultReq.method, defaultReq.body)
+	}
+
+	if _, err := NewWebhookNotifier(srv.URL, "", nil, "{{.Subject"); err == nil {
+		t.Fatalf("expected invalid template rejected")
+	}
+}
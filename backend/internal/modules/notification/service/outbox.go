@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"devops-platform/internal/modules/notification/model"
+	"devops-platform/internal/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// outboxLease is how long a claimed message stays invisible to other workers.
+// A message still "sending" after its lease (e.g. the process died) is claimed again.
+const outboxLease = 2 * time.Minute
+
+// OutboxPolicy configures asynchronous delivery through the outbox.
+type OutboxPolicy struct {
+	Workers     int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	BatchSize   int
+}
+
+type outbox struct {
+	policy OutboxPolicy
+	wake   chan struct{}
+	now    func() time.Time
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+type OutboxStatusResponse struct {
+	Enabled         bool       `json:"enabled"`
+	Workers         int        `json:"workers"`
+	Depth           int64      `json:"depth"`
+	Pending         int64      `json:"pending"`
+	Sending         int64      `json:"sending"`
+	Sent            int64      `json:"sent"`
+	Dead            int64      `json:"dead"`
+	OldestPendingAt *time.Time `json:"oldest_pending_at"`
+}
+
+type ReplayRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+// EnableOutbox makes Send and SendWithFallback queue messages instead of
+// delivering inline. Call StartOutbox to run the workers.
+func (s *NotificationService) EnableOutbox(policy OutboxPolicy) {
+	if policy.Workers <= 0 {
+		policy.Workers = 4
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 5
+	}
+	if policy.BaseBackoff <= 0 {
+		policy.BaseBackoff = 10 * time.Second
+	}
+	if policy.MaxBackoff < policy.BaseBackoff {
+		policy.MaxBackoff = policy.BaseBackoff
+	}
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = 100
+	}
+	s.outbox = &outbox{policy: policy, wake: make(chan struct{}, 1), now: time.Now}
+}
+
+// StartOutbox processes due messages on the given interval, and immediately
+// whenever a message is queued, until StopOutbox is called.
+func (s *NotificationService) StartOutbox(interval time.Duration) {
+	if s.outbox == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.outbox.mu.Lock()
+	s.outbox.cancel = cancel
+	s.outbox.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.outbox.wake:
+			}
+			s.ProcessOutbox()
+		}
+	}()
+}
+
+func (s *NotificationService) StopOutbox() {
+	if s.outbox == nil {
+		return
+	}
+	s.outbox.mu.Lock()
+	defer s.outbox.mu.Unlock()
+	if s.outbox.cancel != nil {
+		s.outbox.cancel()
+		s.outbox.cancel = nil
+	}
+}
+
+func (s *NotificationService) enqueue(tenantID uint, channels []model.ChannelType, recipients []string, subject, body string) error {
+	msg := &model.OutboxMessage{
+		TenantID:      tenantID,
+		Channels:      channels,
+		Recipients:    recipients,
+		Subject:       subject,
+		Body:          body,
+		Status:        model.OutboxPending,
+		MaxAttempts:   s.outbox.policy.MaxAttempts,
+		NextAttemptAt: s.outbox.now(),
+	}
+	if err := s.db.Create(msg).Error; err != nil {
+		return fmt.Errorf("enqueue notification failed: %w", err)
+	}
+	s.outbox.notify()
+	return nil
+}
+
+func (o *outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// ProcessOutbox claims due messages and delivers them on the worker pool.
+// It returns the number of messages attempted.
+func (s *NotificationService) ProcessOutbox() int {
+	if s.outbox == nil {
+		return 0
+	}
+	now := s.outbox.now()
+	var due []model.OutboxMessage
+	if err := s.db.Where("status IN ? AND next_attempt_at <= ?",
+		[]model.OutboxStatus{model.OutboxPending, model.OutboxSending}, now).
+		Order("next_attempt_at").Limit(s.outbox.policy.BatchSize).Find(&due).Error; err != nil {
+		if logger.Log != nil {
+			logger.Log.Warn("加载通知发件箱失败", zap.Error(err))
+		}
+		return 0
+	}
+
+	jobs := make(chan model.OutboxMessage)
+	var wg sync.WaitGroup
+	for i := 0; i < s.outbox.policy.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range jobs {
+				s.deliver(msg)
+			}
+		}()
+	}
+	claimed := 0
+	for _, msg := range due {
+		if !s.claim(&msg, now) {
+			continue
+		}
+		claimed++
+		jobs <- msg
+	}
+	close(jobs)
+	wg.Wait()
+	return claimed
+}
+
+// claim marks a message as sending. The version check makes the claim
+// exclusive when several instances poll the same table.
+func (s *NotificationService) claim(msg *model.OutboxMessage, now time.Time) bool {
+	result := s.db.Model(&model.OutboxMessage{}).
+		Where("id = ? AND version = ?", msg.ID, msg.Version).
+		Updates(map[string]interface{}{
+			"status":          model.OutboxSending,
+			"next_attempt_at": now.Add(outboxLease),
+			"version":         msg.Version + 1,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	msg.Status = model.OutboxSending
+	msg.Version++
+	return true
+}
+
+// deliver tries the message's channels in order. On failure the message is
+// rescheduled with exponential backoff, or dead-lettered after MaxAttempts.
+func (s *NotificationService) deliver(msg model.OutboxMessage) {
+	var errs []string
+	for _, ch := range msg.Channels {
+		notifier, ok := s.notifiers[ch]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s: no notifier registered", ch))
+			continue
+		}
+		if err := s.sendNow(msg.TenantID, ch, notifier, msg.Recipients, msg.Subject, msg.Body); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", ch, err))
+			continue
+		}
+		now := s.outbox.now()
+		s.finish(msg, map[string]interface{}{
+			"status":       model.OutboxSent,
+			"attempts":     msg.Attempts + 1,
+			"sent_channel": ch,
+			"sent_at":      &now,
+			"last_error":   "",
+		})
+		return
+	}
+
+	attempts := msg.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":   attempts,
+		"last_error": truncateError(strings.Join(errs, "; ")),
+	}
+	if attempts >= msg.MaxAttempts {
+		updates["status"] = model.OutboxDead
+	} else {
+		updates["status"] = model.OutboxPending
+		updates["next_attempt_at"] = s.outbox.now().Add(s.outbox.backoff(attempts))
+	}
+	s.finish(msg, updates)
+}
+
+func (s *NotificationService) finish(msg model.OutboxMessage, updates map[string]interface{}) {
+	s.db.Model(&model.OutboxMessage{}).Where("id = ? AND version = ?", msg.ID, msg.Version).Updates(updates)
+}
+
+// backoff returns BaseBackoff doubled for every attempt after the first, capped at MaxBackoff.
+func (o *outbox) backoff(attempts int) time.Duration {
+	d := o.policy.BaseBackoff
+	for i := 1; i < attempts && d < o.policy.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > o.policy.MaxBackoff {
+		d = o.policy.MaxBackoff
+	}
+	return d
+}
+
+func truncateError(msg string) string {
+	if len(msg) > 1024 {
+		return msg[:1024]
+	}
+	return msg
+}
+
+// ReplayDeadLetters requeues the tenant's dead-lettered messages with a fresh
+// attempt budget. An empty id list replays all of them.
+func (s *NotificationService) ReplayDeadLetters(tenantID uint, ids []uint) (int64, error) {
+	if s.outbox == nil {
+		return 0, fmt.Errorf("notification outbox is not enabled")
+	}
+	query := s.db.Model(&model.OutboxMessage{}).Where("tenant_id = ? AND status = ?", tenantID, model.OutboxDead)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	result := query.Updates(map[string]interface{}{
+		"status":          model.OutboxPending,
+		"attempts":        0,
+		"next_attempt_at": s.outbox.now(),
+	})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		s.outbox.notify()
+	}
+	return result.RowsAffected, nil
+}
+
+// ListOutbox returns the tenant's most recent outbox messages, optionally filtered by status.
+func (s *NotificationService) ListOutbox(tenantID uint, status model.OutboxStatus, limit int) ([]model.OutboxMessage, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	query := s.db.Where("tenant_id = ?", tenantID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var items []model.OutboxMessage
+	err := query.Order("id DESC").Limit(limit).Find(&items).Error
+	return items, err
+}
+
+// OutboxStatus reports the tenant's outbox depth and message counts by status.
+func (s *NotificationService) OutboxStatus(tenantID uint) (OutboxStatusResponse, error) {
+	resp := OutboxStatusResponse{Enabled: s.outbox != nil}
+	if s.outbox != nil {
+		resp.Workers = s.outbox.policy.Workers
+	}
+	var counts []struct {
+		Status model.OutboxStatus
+		Count  int64
+	}
+	if err := s.db.Model(&model.OutboxMessage{}).Select("status, count(*) AS count").
+		Where("tenant_id = ?", tenantID).Group("status").Scan(&counts).Error; err != nil {
+		return OutboxStatusResponse{}, err
+	}
+	for _, c := range counts {
+		switch c.Status {
+		case model.OutboxPending:
+			resp.Pending = c.Count
+		case model.OutboxSending:
+			resp.Sending = c.Count
+		case model.OutboxSent:
+			resp.Sent = c.Count
+		case model.OutboxDead:
+			resp.Dead = c.Count
+		}
+	}
+	resp.Depth = resp.Pending + resp.Sending
+	if resp.Depth > 0 {
+		var oldest model.OutboxMessage
+		if s.db.Where("tenant_id = ? AND status IN ?", tenantID,
+			[]model.OutboxStatus{model.OutboxPending, model.OutboxSending}).
+			Order("created_at").Limit(1).Find(&oldest).RowsAffected > 0 {
+			resp.OldestPendingAt = &oldest.CreatedAt
+		}
+	}
+	return resp, nil
+}
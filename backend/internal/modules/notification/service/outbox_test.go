@@ -0,0 +1,158 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/notification/model"
+)
+
+func newOutboxService(t *testing.T, maxAttempts int) (*NotificationService, *time.Time) {
+	t.Helper()
+	ns := NewNotificationService(setupNotificationDB(t))
+	if err := ns.db.AutoMigrate(&model.OutboxMessage{}); err != nil {
+		t.Fatalf("migrate outbox failed: %v", err)
+	}
+	ns.EnableOutbox(OutboxPolicy{Workers: 2, MaxAttempts: maxAttempts, BaseBackoff: 10 * time.Second, MaxBackoff: 25 * time.Second})
+	now := time.Date(2024, 5, 20, 10, 0, 0, 0, time.UTC)
+	ns.outbox.now = func() time.Time { return now }
+	return ns, &now
+}
+
+func loadOutbox(t *testing.T, ns *NotificationService, id uint) model.OutboxMessage {
+	t.Helper()
+	var msg model.OutboxMessage
+	if err := ns.db.First(&msg, id).Error; err != nil {
+		t.Fatalf("load outbox message failed: %v", err)
+	}
+	return msg
+}
+
+func TestOutbox_SendIsQueuedAndDelivered(t *testing.T) {
+	ns, _ := newOutboxService(t, 3)
+	notifier := &recordingNotifier{}
+	ns.RegisterNotifier(model.ChannelDingTalk, notifier)
+
+	if err := ns.Send(1, model.ChannelDingTalk, []string{"ops"}, "告警", "内容"); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if notifier.subject != "" {
+		t.Fatalf("expected delivery deferred to the outbox worker")
+	}
+	if err := ns.Send(1, model.ChannelFeishu, nil, "x", "y"); err == nil {
+		t.Fatalf("expected unregistered channel rejected up front")
+	}
+	status, _ := ns.OutboxStatus(1)
+	if status.Depth != 1 || status.Pending != 1 || status.OldestPendingAt == nil {
+		t.Fatalf("unexpected status before delivery: %+v", status)
+	}
+
+	if n := ns.ProcessOutbox(); n != 1 {
+		t.Fatalf("expected one message processed, got %d", n)
+	}
+	if notifier.subject != "告警" {
+		t.Fatalf("expected message delivered, got %q", notifier.subject)
+	}
+	msg := loadOutbox(t, ns, 1)
+	if msg.Status != model.OutboxSent || msg.Attempts != 1 || msg.SentChannel != model.ChannelDingTalk || msg.SentAt == nil {
+		t.Fatalf("unexpected sent message: %+v", msg)
+	}
+	status, _ = ns.OutboxStatus(1)
+	if status.Depth != 0 || status.Sent != 1 {
+		t.Fatalf("unexpected status after delivery: %+v", status)
+	}
+}
+
+func TestOutbox_BackoffDeadLetterAndReplay(t *testing.T) {
+	ns, now := newOutboxService(t, 3)
+	notifier := &recordingNotifier{err: errors.New("dingtalk timeout")}
+	ns.RegisterNotifier(model.ChannelDingTalk, notifier)
+	if err := ns.Send(1, model.ChannelDingTalk, nil, "告警", "内容"); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	ns.ProcessOutbox()
+	msg := loadOutbox(t, ns, 1)
+	if msg.Status != model.OutboxPending || msg.Attempts != 1 || !msg.NextAttemptAt.Equal(now.Add(10*time.Second)) {
+		t.Fatalf("expected retry after base backoff, got %+v", msg)
+	}
+	if n := ns.ProcessOutbox(); n != 0 {
+		t.Fatalf("expected message not retried before its backoff, got %d", n)
+	}
+
+	*now = now.Add(10 * time.Second)
+	ns.ProcessOutbox()
+	msg = loadOutbox(t, ns, 1)
+	if msg.Attempts != 2 || !msg.NextAttemptAt.Equal(now.Add(20*time.Second)) {
+		t.Fatalf("expected doubled backoff, got %+v", msg)
+	}
+
+	*now = now.Add(20 * time.Second)
+	ns.ProcessOutbox()
+	msg = loadOutbox(t, ns, 1)
+	if msg.Status != model.OutboxDead || msg.Attempts != 3 || msg.LastError == "" {
+		t.Fatalf("expected dead-lettered after max attempts, got %+v", msg)
+	}
+
+	*now = now.Add(time.Hour)
+	if n := ns.ProcessOutbox(); n != 0 {
+		t.Fatalf("expected dead letters left alone, got %d", n)
+	}
+	if n, _ := ns.ReplayDeadLetters(2, nil); n != 0 {
+		t.Fatalf("expected replay scoped to tenant, got %d", n)
+	}
+	notifier.err = nil
+	if n, err := ns.ReplayDeadLetters(1, []uint{1}); err != nil || n != 1 {
+		t.Fatalf("replay failed: %d, %v", n, err)
+	}
+	ns.ProcessOutbox()
+	if msg = loadOutbox(t, ns, 1); msg.Status != model.OutboxSent || msg.Attempts != 1 {
+		t.Fatalf("expected replayed message delivered, got %+v", msg)
+	}
+}
+
+func TestOutbox_FallbackChainAndStaleClaim(t *testing.T) {
+	ns, now := newOutboxService(t, 5)
+	feishu := &recordingNotifier{err: errors.New("feishu down")}
+	wecom := &recordingNotifier{}
+	ns.RegisterNotifier(model.ChannelFeishu, feishu)
+	ns.RegisterNotifier(model.ChannelWeCom, wecom)
+	ns.db.Create(&[]model.ChannelConfig{
+		{TenantID: 1, Channel: model.ChannelFeishu, Enabled: true, Priority: 10},
+		{TenantID: 1, Channel: model.ChannelWeCom, Enabled: true, Priority: 1},
+	})
+
+	if err := ns.SendWithFallback(1, nil, "升级", "内容"); err != nil {
+		t.Fatalf("send with fallback failed: %v", err)
+	}
+	// Simulate a worker that claimed the message and died mid-send.
+	var msg model.OutboxMessage
+	ns.db.First(&msg)
+	if !ns.claim(&msg, *now) {
+		t.Fatalf("claim failed")
+	}
+	if n := ns.ProcessOutbox(); n != 0 {
+		t.Fatalf("expected claimed message hidden during its lease, got %d", n)
+	}
+
+	*now = now.Add(outboxLease)
+	ns.ProcessOutbox()
+	msg = loadOutbox(t, ns, msg.ID)
+	if feishu.subject != "升级" || wecom.subject != "升级" {
+		t.Fatalf("expected feishu tried before falling back to wecom")
+	}
+	if msg.Status != model.OutboxSent || msg.SentChannel != model.ChannelWeCom {
+		t.Fatalf("unexpected message after fallback: %+v", msg)
+	}
+}
+
+func TestOutbox_ProcessReportsNothingWhenLoadFails(t *testing.T) {
+	ns, _ := newOutboxService(t, 3)
+	if err := ns.db.Migrator().DropTable(&model.OutboxMessage{}); err != nil {
+		t.Fatalf("drop outbox failed: %v", err)
+	}
+	if n := ns.ProcessOutbox(); n != 0 {
+		t.Fatalf("expected nothing processed when the outbox cannot be read, got %d", n)
+	}
+}
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"devops-platform/internal/modules/notification/model"
+)
+
+// ErrTemplateNotFound is returned when no template matches the requested name or ID.
+var ErrTemplateNotFound = errors.New("notification template not found")
+
+const defaultDateLayout = "2006-01-02 15:04:05"
+
+// templateFuncs is the function set available to notification templates.
+// It only formats values; nothing here touches the network, files or environment.
+var templateFuncs = template.FuncMap{
+	"date":     formatDate,
+	"truncate": truncate,
+	"label":    label,
+	"join":     join,
+	"default":  defaultValue,
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"trim":     strings.TrimSpace,
+}
+
+// formatDate formats a time.Time, RFC3339 string or unix seconds with a Go layout.
+// An empty layout uses "2006-01-02 15:04:05"; zero or unparsable values render as "".
+func formatDate(layout string, v interface{}) string {
+	if layout == "" {
+		layout = defaultDateLayout
+	}
+	var t time.Time
+	switch val := v.(type) {
+	case time.Time:
+		t = val
+	case *time.Time:
+		if val == nil {
+			return ""
+		}
+		t = *val
+	case string:
+		parsed, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			return val
+		}
+		t = parsed
+	case int:
+		t = time.Unix(int64(val), 0)
+	case int64:
+		t = time.Unix(val, 0)
+	case float64:
+		t = time.Unix(int64(val), 0)
+	default:
+		return ""
+	}
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// truncate shortens a value to n runes, marking the cut with "...".
+func truncate(n int, v interface{}) string {
+	s := toString(v)
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
+
+// label looks up a key in a label map, returning "" when absent.
+func label(labels interface{}, key string) string {
+	switch m := labels.(type) {
+	case map[string]string:
+		return m[key]
+	case map[string]interface{}:
+		return toString(m[key])
+	}
+	return ""
+}
+
+// join joins a slice of values with sep.
+func join(sep string, v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return toString(v)
+	}
+	parts := make([]string, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		parts = append(parts, toString(rv.Index(i).Interface()))
+	}
+	return strings.Join(parts, sep)
+}
+
+// defaultValue returns def when v is nil or its type's zero value.
+func defaultValue(def, v interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		if rv.Len() == 0 {
+			return def
+		}
+	default:
+		if rv.IsZero() {
+			return def
+		}
+	}
+	return v
+}
+
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// RenderTemplate renders the template's subject and body against data.
+func RenderTemplate(tpl model.Template, data interface{}) (string, string, error) {
+	subject, err := renderText("subject", tpl.Subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := renderText("body", tpl.Body, data)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+// ValidateTemplate reports whether the template's subject and body parse.
+func ValidateTemplate(tpl model.Template) error {
+	if _, err := parseText("subject", tpl.Subject); err != nil {
+		return err
+	}
+	_, err := parseText("body", tpl.Body)
+	return err
+}
+
+func parseText(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template %s invalid: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func renderText(name, text string, data interface{}) (string, error) {
+	tmpl, err := parseText(name, text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("template %s render failed: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// SendTemplate renders the tenant's templates named templateName against data and
+// sends the result to recipients. A name may have one template per channel; they
+// are tried in channel priority order until one send succeeds. Disabled channels
+// are skipped. With the outbox enabled, the first candidate is queued and retried
+// there.
+func (s *NotificationService) SendTemplate(tenantID uint, templateName string, recipients []string, data interface{}) error {
+	var templates []model.Template
+	if err := s.db.Where("tenant_id = ? AND name = ?", tenantID, templateName).Find(&templates).Error; err != nil {
+		return err
+	}
+	if len(templates) == 0 {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, templateName)
+	}
+
+	var configs []model.ChannelConfig
+	s.db.Where("tenant_id = ?", tenantID).Find(&configs)
+	byChannel := make(map[model.ChannelType]model.ChannelConfig, len(configs))
+	for _, c := range configs {
+		byChannel[c.Channel] = c
+	}
+	candidates := templates[:0]
+	for _, tpl := range templates {
+		if cfg, ok := byChannel[tpl.Channel]; ok && !cfg.Enabled {
+			continue
+		}
+		candidates = append(candidates, tpl)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return byChannel[candidates[i].Channel].Priority > byChannel[candidates[j].Channel].Priority
+	})
+
+	var errs []string
+	for _, tpl := range candidates {
+		subject, body, err := RenderTemplate(tpl, data)
+		if err == nil {
+			err = s.Send(tenantID, tpl.Channel, recipients, subject, body)
+		}
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", tpl.Channel, err))
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no enabled channel for template %s", templateName)
+	}
+	return fmt.Errorf("send template %s failed: %s", templateName, strings.Join(errs, "; "))
+}
+
+// Sample payload kinds for template preview.
+const (
+	SampleAlert      = "alert"
+	SampleAlertGroup = "alert_group"
+	SampleWorkflow   = "workflow"
+	SampleTask       = "task"
+)
+
+// SampleData returns an example payload of the given kind, keyed in snake_case
+// like the payloads callers pass to SendTemplate.
+func SampleData(kind string) (map[string]interface{}, bool) {
+	startedAt := time.Date(2024, 5, 20, 10, 30, 0, 0, time.Local)
+	switch kind {
+	case SampleAlert, "":
+		return map[string]interface{}{
+			"status":      "firing",
+			"rule_name":   "NodeCPUHigh",
+			"severity":    "critical",
+			"cluster":     "prod",
+			"expr":        "node_cpu_usage > 0.9",
+			"description": "node-01 CPU 使用率持续 5 分钟高于 90%，当前值 92.5%",
+			"labels": map[string]string{
+				"alertname": "NodeCPUHigh",
+				"cluster":   "prod",
+				"instance":  "node-01:9100",
+				"severity":  "critical",
+			},
+		}, true
+	case SampleAlertGroup:
+		return map[string]interface{}{
+			"status":       "firing",
+			"count":        2,
+			"group_labels": map[string]string{"alertname": "NodeCPUHigh"},
+			"alerts": []map[string]interface{}{
+				{
+					"status":    "firing",
+					"rule_name": "NodeCPUHigh",
+					"severity":  "critical",
+					"summary":   "node-01 CPU 使用率超过 90%",
+					"labels":    map[string]string{"alertname": "NodeCPUHigh", "instance": "node-01:9100"},
+				},
+				{
+					"status":    "firing",
+					"rule_name": "NodeCPUHigh",
+					"severity":  "critical",
+					"summary":   "node-02 CPU 使用率超过 90%",
+					"labels":    map[string]string{"alertname": "NodeCPUHigh", "instance": "node-02:9100"},
+				},
+			},
+		}, true
+	case SampleWorkflow:
+		return map[string]interface{}{
+			"order_id":        1024,
+			"title":           "生产环境发布 user-service v1.8.0",
+			"type":            "deploy",
+			"status":          "pending",
+			"submitted_by":    "zhangsan",
+			"current_level":   1,
+			"approval_levels": 2,
+			"description":     "修复登录超时问题",
+			"created_at":      startedAt,
+		}, true
+	case SampleTask:
+		return map[string]interface{}{
+			"task_id":      12,
+			"task_name":    "清理日志",
+			"type":         "shell",
+			"execution_id": 3301,
+			"status":       "success",
+			"targets":      []string{"10.0.0.11", "10.0.0.12"},
+			"started_at":   startedAt,
+			"finished_at":  startedAt.Add(42 * time.Second),
+			"duration_ms":  42000,
+			"result":       "removed 1.2G of rotated logs",
+		}, true
+	}
+	return nil, false
+}
+
+// PreviewTemplateRequest renders either a saved template (TemplateID) or an
+// unsaved subject/body against a sample payload or caller-supplied data.
+type PreviewTemplateRequest struct {
+	TemplateID uint                   `json:"template_id"`
+	Subject    string                 `json:"subject"`
+	Body       string                 `json:"body"`
+	Sample     string                 `json:"sample"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+type PreviewTemplateResponse struct {
+	Subject string                 `json:"subject"`
+	Body    string                 `json:"body"`
+	Data    map[string]interface{} `json:"data"`
+}
+
+func (s *NotificationService) PreviewTemplate(tenantID uint, req PreviewTemplateRequest) (PreviewTemplateResponse, error) {
+	tpl := model.Template{Subject: req.Subject, Body: req.Body}
+	if req.TemplateID > 0 {
+		found, err := s.GetTemplate(tenantID, req.TemplateID)
+		if err != nil {
+			return PreviewTemplateResponse{}, err
+		}
+		tpl = found
+	}
+	data := req.Data
+	if data == nil {
+		sample, ok := SampleData(req.Sample)
+		if !ok {
+			return PreviewTemplateResponse{}, fmt.Errorf("unknown sample type: %s", req.Sample)
+		}
+		data = sample
+	}
+	subject, body, err := RenderTemplate(tpl, data)
+	if err != nil {
+		return PreviewTemplateResponse{}, err
+	}
+	return PreviewTemplateResponse{Subject: subject, Body: body, Data: data}, nil
+}
+
+func (s *NotificationService) ListTemplates(tenantID uint) ([]model.Template, error) {
+	var templates []model.Template
+	err := s.db.Where("tenant_id = ?", tenantID).Order("name, channel").Find(&templates).Error
+	return templates, err
+}
+
+func (s *NotificationService) GetTemplate(tenantID, id uint) (model.Template, error) {
+	var tpl model.Template
+	result := s.db.Where("tenant_id = ? AND id = ?", tenantID, id).Limit(1).Find(&tpl)
+	if result.Error != nil {
+		return model.Template{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return model.Template{}, ErrTemplateNotFound
+	}
+	return tpl, nil
+}
+
+// SaveTemplate creates or updates a template after checking that it parses.
+// Name plus channel is unique within a tenant.
+func (s *NotificationService) SaveTemplate(tenantID uint, tpl model.Template) (model.Template, error) {
+	tpl.TenantID = tenantID
+	tpl.Name = strings.TrimSpace(tpl.Name)
+	if tpl.Name == "" || tpl.Channel == "" || strings.TrimSpace(tpl.Body) == "" {
+		return model.Template{}, errors.New("模板名称、渠道和内容不能为空")
+	}
+	if err := ValidateTemplate(tpl); err != nil {
+		return model.Template{}, err
+	}
+	var count int64
+	s.db.Model(&model.Template{}).
+		Where("tenant_id = ? AND name = ? AND channel = ? AND id <> ?", tenantID, tpl.Name, tpl.Channel, tpl.ID).
+		Count(&count)
+	if count > 0 {
+		return model.Template{}, fmt.Errorf("渠道 %s 已存在同名模板 %s", tpl.Channel, tpl.Name)
+	}
+	if tpl.ID > 0 {
+		existing, err := s.GetTemplate(tenantID, tpl.ID)
+		if err != nil {
+			return model.Template{}, err
+		}
+		tpl.CreatedAt = existing.CreatedAt
+	}
+	if err := s.db.Save(&tpl).Error; err != nil {
+		return model.Template{}, err
+	}
+	return tpl, nil
+}
+
+func (s *NotificationService) DeleteTemplate(tenantID, id uint) error {
+	result := s.db.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&model.Template{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
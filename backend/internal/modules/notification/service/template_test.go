@@ -0,0 +1,145 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"devops-platform/internal/modules/notification/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupNotificationDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db failed: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Template{}, &model.ChannelConfig{}, &model.SendLog{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	return db
+}
+
+type recordingNotifier struct {
+	subject, body string
+	err           error
+}
+
+func (n *recordingNotifier) Send(recipients []string, subject, body string) error {
+	n.subject, n.body = subject, body
+	return n.err
+}
+
+func TestRenderTemplate_Functions(t *testing.T) {
+	data := map[string]interface{}{
+		"rule_name": "NodeCPUHigh",
+		"severity":  "critical",
+		"summary":   "节点 CPU 使用率持续超过阈值",
+		"starts_at": time.Date(2024, 5, 20, 10, 30, 0, 0, time.UTC),
+		"labels":    map[string]string{"cluster": "prod"},
+		"targets":   []string{"a", "b"},
+	}
+	tpl := model.Template{
+		Subject: `[{{upper .severity}}] {{.rule_name}}`,
+		Body:    `{{date "01-02 15:04" .starts_at}}|{{label .labels "cluster"}}|{{truncate 6 .summary}}|{{join "," .targets}}|{{default "-" .owner}}`,
+	}
+	subject, body, err := RenderTemplate(tpl, data)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if subject != "[CRITICAL] NodeCPUHigh" {
+		t.Fatalf("unexpected subject: %s", subject)
+	}
+	if body != "05-20 10:30|prod|节点 CPU...|a,b|-" {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	if _, _, err := RenderTemplate(model.Template{Body: `{{env "HOME"}}`}, data); err == nil {
+		t.Fatalf("expected functions outside the safe set rejected")
+	}
+}
+
+func TestSendTemplate_ChannelPriorityAndFallback(t *testing.T) {
+	db := setupNotificationDB(t)
+	ns := NewNotificationService(db)
+	feishu := &recordingNotifier{err: errors.New("feishu down")}
+	dingtalk := &recordingNotifier{}
+	wecom := &recordingNotifier{}
+	ns.RegisterNotifier(model.ChannelFeishu, feishu)
+	ns.RegisterNotifier(model.ChannelDingTalk, dingtalk)
+	ns.RegisterNotifier(model.ChannelWeCom, wecom)
+
+	db.Create(&[]model.ChannelConfig{
+		{TenantID: 1, Channel: model.ChannelFeishu, Enabled: true, Priority: 10},
+		{TenantID: 1, Channel: model.ChannelDingTalk, Enabled: true, Priority: 5},
+		{TenantID: 1, Channel: model.ChannelWeCom, Enabled: true, Priority: 1},
+	})
+	db.Create(&[]model.Template{
+		{TenantID: 1, Name: "alert", Channel: model.ChannelWeCom, Subject: "wecom {{.rule_name}}", Body: "w"},
+		{TenantID: 1, Name: "alert", Channel: model.ChannelDingTalk, Subject: "dingtalk {{.rule_name}}", Body: "d"},
+		{TenantID: 1, Name: "alert", Channel: model.ChannelFeishu, Subject: "feishu {{.rule_name}}", Body: "f"},
+	})
+
+	if err := ns.SendTemplate(1, "alert", nil, map[string]interface{}{"rule_name": "DiskFull"}); err != nil {
+		t.Fatalf("send template failed: %v", err)
+	}
+	if feishu.subject != "feishu DiskFull" {
+		t.Fatalf("expected highest priority channel tried first, got %q", feishu.subject)
+	}
+	if dingtalk.subject != "dingtalk DiskFull" || wecom.subject != "" {
+		t.Fatalf("expected fallback to stop at dingtalk, got dingtalk=%q wecom=%q", dingtalk.subject, wecom.subject)
+	}
+
+	if err := ns.SendTemplate(1, "missing", nil, nil); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if err := ns.SendTemplate(2, "alert", nil, nil); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected templates scoped by tenant, got %v", err)
+	}
+}
+
+func TestPreviewTemplate_Samples(t *testing.T) {
+	ns := NewNotificationService(setupNotificationDB(t))
+	for _, sample := range []string{SampleAlert, SampleAlertGroup, SampleWorkflow, SampleTask} {
+		if _, ok := SampleData(sample); !ok {
+			t.Fatalf("missing sample %s", sample)
+		}
+	}
+
+	resp, err := ns.PreviewTemplate(1, PreviewTemplateRequest{
+		Subject: "审批: {{.title}}",
+		Body:    "{{.submitted_by}} 提交于 {{date \"\" .created_at}}",
+		Sample:  SampleWorkflow,
+	})
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	if !strings.HasPrefix(resp.Subject, "审批: 生产环境发布") || !strings.HasPrefix(resp.Body, "zhangsan 提交于 2024-05-20") {
+		t.Fatalf("unexpected preview: %+v", resp)
+	}
+
+	saved, err := ns.SaveTemplate(1, model.Template{Name: "task-done", Channel: model.ChannelFeishu, Subject: "{{.task_name}}", Body: "{{.status}}"})
+	if err != nil {
+		t.Fatalf("save template failed: %v", err)
+	}
+	resp, err = ns.PreviewTemplate(1, PreviewTemplateRequest{TemplateID: saved.ID, Sample: SampleTask})
+	if err != nil || resp.Subject != "清理日志" || resp.Body != "success" {
+		t.Fatalf("unexpected saved template preview: %+v, %v", resp, err)
+	}
+	if _, err := ns.PreviewTemplate(2, PreviewTemplateRequest{TemplateID: saved.ID}); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected other tenant's template hidden, got %v", err)
+	}
+	if _, err := ns.PreviewTemplate(1, PreviewTemplateRequest{Body: "x", Sample: "deploy"}); err == nil {
+		t.Fatalf("expected unknown sample rejected")
+	}
+	if _, err := ns.SaveTemplate(1, model.Template{Name: "bad", Channel: model.ChannelFeishu, Body: "{{.x"}); err == nil {
+		t.Fatalf("expected unparsable template rejected")
+	}
+	if _, err := ns.SaveTemplate(1, model.Template{Name: "task-done", Channel: model.ChannelFeishu, Body: "dup"}); err == nil {
+		t.Fatalf("expected duplicate name and channel rejected")
+	}
+}
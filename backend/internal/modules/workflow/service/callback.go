@@ -123,3 +123,22 @@ func (h *NotificationCallback) Handle(order *model.ChangeOrder) error {
 	}
 	return h.SendNotification(order.TenantID, payload.Channel, payload.Recipients, payload.Subject, payload.Body)
 }
+
+// PromotionCallback handles callback for the "harbor" module: it runs the
+// image promotion the order was created for.
+type PromotionCallback struct {
+	ExecutePromotion func(promotionID uint, tenantID uint) error
+}
+
+func (h *PromotionCallback) Handle(order *model.ChangeOrder) error {
+	var payload struct {
+		PromotionID uint `json:"promotionId"`
+	}
+	if err := json.Unmarshal([]byte(order.CallbackPayload), &payload); err != nil {
+		return fmt.Errorf("invalid promotion callback payload: %w", err)
+	}
+	if payload.PromotionID == 0 {
+		return fmt.Errorf("promotion callback payload missing promotionId")
+	}
+	return h.ExecutePromotion(payload.PromotionID, order.TenantID)
+}
@@ -1,6 +1,7 @@
 package workflow
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -45,6 +46,30 @@ func (s *WorkflowService) CreateOrder(tenantID, userID uint, title, desc, orderT
 	return order, nil
 }
 
+// CreateCallbackOrder creates an order whose final approval runs the callback
+// registered for module with the JSON encoded payload.
+func (s *WorkflowService) CreateCallbackOrder(tenantID, userID uint, title, desc, orderType string, approvalLevels int, module, action string, payload interface{}) (*model.ChangeOrder, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode callback payload: %w", err)
+	}
+	order, err := s.CreateOrder(tenantID, userID, title, desc, orderType, approvalLevels)
+	if err != nil {
+		return nil, err
+	}
+	order.CallbackModule = module
+	order.CallbackAction = action
+	order.CallbackPayload = string(data)
+	if err := s.db.Model(order).Updates(map[string]interface{}{
+		"callback_module":  module,
+		"callback_action":  action,
+		"callback_payload": order.CallbackPayload,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
 func (s *WorkflowService) SubmitForReview(orderID, tenantID uint) error {
 	order, err := s.getOrder(orderID, tenantID)
 	if err != nil {
@@ -15,12 +15,32 @@ func registerAlert(r *gin.RouterGroup) {
 	{
 		g.GET("/rules", listPermission, alertAPI.ListAlertRules)
 		g.GET("/history", listPermission, alertAPI.ListAlertHistory)
+		g.POST("/history/ack", updatePermission, middleware.SetAuditOperation("告警确认"), alertAPI.AckAlertHistory)
+		g.POST("/history/resolve", updatePermission, middleware.SetAuditOperation("告警手动恢复"), alertAPI.ResolveAlertHistory)
+		g.POST("/rule/upsert", createPermission, middleware.SetAuditOperation("告警规则配置"), alertAPI.UpsertAlertRule)
 		g.POST("/rule/toggle", updatePermission, middleware.SetAuditOperation("告警规则启停"), alertAPI.ToggleAlertRule)
 		g.GET("/silences", listPermission, alertAPI.ListAlertSilences)
 		g.POST("/silence/upsert", createPermission, middleware.SetAuditOperation("告警静默配置"), alertAPI.UpsertAlertSilence)
+		g.POST("/silence/preview", listPermission, alertAPI.PreviewAlertSilence)
+		g.GET("/inhibitions", listPermission, alertAPI.ListAlertInhibitRules)
+		g.POST("/inhibition/upsert", createPermission, middleware.SetAuditOperation("告警抑制规则配置"), alertAPI.UpsertAlertInhibitRule)
 		g.GET("/channels", listPermission, alertAPI.ListAlertChannels)
 		g.POST("/channel/upsert", createPermission, middleware.SetAuditOperation("告警通知渠道配置"), alertAPI.UpsertAlertChannel)
+		g.GET("/oncall/schedules", listPermission, alertAPI.ListOnCallSchedules)
+		g.GET("/oncall/current", listPermission, alertAPI.GetCurrentOnCall)
+		g.POST("/oncall/schedule/upsert", createPermission, middleware.SetAuditOperation("值班表配置"), alertAPI.UpsertOnCallSchedule)
+		g.GET("/escalation/policies", listPermission, alertAPI.ListEscalationPolicies)
+		g.POST("/escalation/policy/upsert", createPermission, middleware.SetAuditOperation("告警升级策略配置"), alertAPI.UpsertEscalationPolicy)
 		g.GET("/config", listPermission, alertAPI.GetAlertmanagerConfig)
 		g.POST("/config/upsert", updatePermission, middleware.SetAuditOperation("Alertmanager 配置更新"), alertAPI.SaveAlertmanagerConfig)
 	}
 }
+
+// registerAlertWebhook 注册 Alertmanager webhook 接收端点。
+// Alertmanager 无法携带会话，使用 UnifiedAuth 以支持 "Authorization: ApiKey <key>"；
+// 写入告警历史并触发通知，API Key 所属用户需具备告警创建权限。
+func registerAlertWebhook(r *gin.RouterGroup) {
+	g := r.Group("/alert")
+	g.POST("/webhook/alertmanager", middleware.UnifiedAuth(), middleware.RequirePermission("alert", "create"),
+		middleware.SetAuditOperation("Alertmanager 告警接收"), alertAPI.ReceiveAlertmanagerWebhook)
+}
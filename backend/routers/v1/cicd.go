@@ -12,26 +12,84 @@ func registerCICD(r *gin.RouterGroup) {
 	queryPermission := middleware.RequirePermission("cicd", "list")
 	updatePermission := middleware.RequirePermission("cicd", "update")
 
-	// Jenkins config
-	g.GET("/jenkins", queryPermission, cicdAPI.ListJenkinsConfigs)
+	// CI provider configs (Jenkins / GitLab / GitHub Actions)
+	g.GET("/providers", queryPermission, cicdAPI.ListProviderConfigs)
+	g.POST("/providers", updatePermission,
+		middleware.SetAuditOperation("CI 配置保存"),
+		cicdAPI.SaveProviderConfig)
+	g.PUT("/providers/:id", updatePermission,
+		middleware.SetAuditOperation("CI 配置更新"),
+		cicdAPI.SaveProviderConfig)
+	g.DELETE("/providers/:id", updatePermission,
+		middleware.SetAuditOperation("CI 配置删除"),
+		cicdAPI.DeleteProviderConfig)
+	g.POST("/providers/test", queryPermission, cicdAPI.TestProviderConnection)
+
+	// Jobs & builds
+	g.GET("/providers/:configId/jobs", queryPermission, cicdAPI.ListJobs)
+	g.GET("/providers/:configId/job-params", queryPermission, cicdAPI.GetJobParameters)
+	g.POST("/providers/:configId/build", updatePermission,
+		middleware.SetAuditOperation("触发 CI 构建"),
+		cicdAPI.TriggerBuild)
+	g.GET("/providers/:configId/builds", queryPermission, cicdAPI.ListBuilds)
+	g.GET("/providers/:configId/build-log", queryPermission, cicdAPI.GetBuildLog)
+	g.GET("/providers/:configId/build-log/stream", queryPermission, cicdAPI.StreamBuildLog)
+
+	// Jenkins 旧路由：配置 ID 迁移时保持不变，兼容现有前端
+	g.GET("/jenkins", queryPermission, cicdAPI.ListProviderConfigs)
 	g.POST("/jenkins", updatePermission,
 		middleware.SetAuditOperation("Jenkins 配置保存"),
-		cicdAPI.SaveJenkinsConfig)
+		cicdAPI.SaveProviderConfig)
 	g.PUT("/jenkins/:id", updatePermission,
 		middleware.SetAuditOperation("Jenkins 配置更新"),
-		cicdAPI.SaveJenkinsConfig)
+		cicdAPI.SaveProviderConfig)
 	g.DELETE("/jenkins/:id", updatePermission,
 		middleware.SetAuditOperation("Jenkins 配置删除"),
-		cicdAPI.DeleteJenkinsConfig)
-	g.POST("/jenkins/test", queryPermission, cicdAPI.TestJenkinsConnection)
-
-	// Jobs & builds
+		cicdAPI.DeleteProviderConfig)
+	g.POST("/jenkins/test", queryPermission, cicdAPI.TestProviderConnection)
 	g.GET("/jenkins/:configId/jobs", queryPermission, cicdAPI.ListJobs)
+	g.GET("/jenkins/:configId/job-params", queryPermission, cicdAPI.GetJobParameters)
 	g.POST("/jenkins/:configId/build", updatePermission,
 		middleware.SetAuditOperation("触发 Jenkins 构建"),
 		cicdAPI.TriggerBuild)
 	g.GET("/jenkins/:configId/builds", queryPermission, cicdAPI.ListBuilds)
 	g.GET("/jenkins/:configId/build-log", queryPermission, cicdAPI.GetBuildLog)
+	g.GET("/jenkins/:configId/build-log/stream", queryPermission, cicdAPI.StreamBuildLog)
+
+	// Pipeline runs
+	g.GET("/runs", queryPermission, cicdAPI.ListRuns)
+	g.GET("/runs/:id", queryPermission, cicdAPI.GetRun)
+	g.POST("/runs/sync", updatePermission,
+		middleware.SetAuditOperation("同步流水线运行记录"),
+		cicdAPI.SyncRuns)
+
+	// Analytics
+	g.GET("/pipelines/:id/stats", queryPermission, cicdAPI.GetPipelineStats)
+	g.GET("/analytics/report", queryPermission, cicdAPI.GetHealthReport)
+
+	// Multi-stage pipeline specs & executions
+	g.GET("/specs", queryPermission, cicdAPI.ListPipelineSpecs)
+	g.GET("/specs/:id", queryPermission, cicdAPI.GetPipelineSpec)
+	g.POST("/specs", updatePermission,
+		middleware.SetAuditOperation("创建多阶段流水线"),
+		cicdAPI.SavePipelineSpec)
+	g.PUT("/specs/:id", updatePermission,
+		middleware.SetAuditOperation("更新多阶段流水线"),
+		cicdAPI.SavePipelineSpec)
+	g.DELETE("/specs/:id", updatePermission,
+		middleware.SetAuditOperation("删除多阶段流水线"),
+		cicdAPI.DeletePipelineSpec)
+	g.POST("/specs/:id/run", updatePermission,
+		middleware.SetAuditOperation("执行多阶段流水线"),
+		cicdAPI.RunPipelineSpec)
+	g.GET("/executions", queryPermission, cicdAPI.ListPipelineExecutions)
+	g.GET("/executions/:id", queryPermission, cicdAPI.GetPipelineExecution)
+	g.POST("/executions/:id/resume", updatePermission,
+		middleware.SetAuditOperation("恢复流水线执行"),
+		cicdAPI.ResumePipelineExecution)
+	g.POST("/executions/:id/cancel", updatePermission,
+		middleware.SetAuditOperation("取消流水线执行"),
+		cicdAPI.CancelPipelineExecution)
 
 	// Pipelines
 	g.GET("/pipelines", queryPermission, cicdAPI.ListPipelines)
@@ -34,4 +34,37 @@ func registerHarbor(r *gin.RouterGroup) {
 	g.DELETE("/projects/:projectName/repos/:repoName/artifacts", updatePermission,
 		middleware.SetAuditOperation("删除 Harbor artifact"),
 		harborAPI.DeleteArtifact)
+	g.GET("/projects/:projectName/repos/:repoName/artifacts/:reference", queryPermission, harborAPI.GetArtifact)
+
+	// Vulnerability scanning
+	g.POST("/projects/:projectName/repos/:repoName/artifacts/:reference/scan", updatePermission,
+		middleware.SetAuditOperation("触发 Harbor 漏洞扫描"),
+		harborAPI.ScanArtifact)
+	g.GET("/projects/:projectName/repos/:repoName/artifacts/:reference/vulnerabilities", queryPermission, harborAPI.GetVulnerabilityReport)
+	g.GET("/projects/:projectName/repos/:repoName/artifacts/:reference/policy-check", queryPermission, harborAPI.CheckScanPolicy)
+
+	// Retention policies
+	g.GET("/retention-policies", queryPermission, harborAPI.ListRetentionPolicies)
+	g.GET("/retention-policies/:id", queryPermission, harborAPI.GetRetentionPolicy)
+	g.POST("/retention-policies", updatePermission,
+		middleware.SetAuditOperation("创建 Harbor 镜像保留策略"),
+		harborAPI.SaveRetentionPolicy)
+	g.PUT("/retention-policies/:id", updatePermission,
+		middleware.SetAuditOperation("更新 Harbor 镜像保留策略"),
+		harborAPI.SaveRetentionPolicy)
+	g.DELETE("/retention-policies/:id", updatePermission,
+		middleware.SetAuditOperation("删除 Harbor 镜像保留策略"),
+		harborAPI.DeleteRetentionPolicy)
+	g.POST("/retention-policies/:id/dry-run", queryPermission, harborAPI.DryRunRetentionPolicy)
+	g.POST("/retention-policies/:id/execute", updatePermission,
+		middleware.SetAuditOperation("执行 Harbor 镜像保留策略"),
+		harborAPI.ExecuteRetentionPolicy)
+	g.GET("/retention-policies/:id/runs", queryPermission, harborAPI.ListRetentionRuns)
+
+	// Promotions between projects and registries
+	g.GET("/promotions", queryPermission, harborAPI.ListPromotions)
+	g.GET("/promotions/:id", queryPermission, harborAPI.GetPromotion)
+	g.POST("/promotions", updatePermission,
+		middleware.SetAuditOperation("Harbor 镜像晋级"),
+		harborAPI.CreatePromotion)
 }
@@ -22,8 +22,26 @@ func registerLog(r *gin.RouterGroup) {
 		middleware.SetAuditOperation("日志源删除"),
 		logAPI.DeleteLogSource)
 	g.POST("/sources/:id/test", queryPermission, logAPI.TestLogSourceConnection)
+	g.GET("/sources/:id/fields", queryPermission, logAPI.ListLogFields)
 
 	// Search & Export
 	g.POST("/search", queryPermission, logAPI.SearchLogs)
 	g.POST("/export", queryPermission, logAPI.ExportLogs)
+	g.POST("/aggregate", queryPermission, logAPI.AggregateLogs)
+	g.GET("/tail", queryPermission, logAPI.TailLogs)
+	g.GET("/context", queryPermission, logAPI.LogContext)
+
+	// Saved queries: any log reader keeps their own and opens shared ones
+	g.GET("/queries", queryPermission, logAPI.ListSavedQueries)
+	g.GET("/queries/:id", queryPermission, logAPI.GetSavedQuery)
+	g.GET("/queries/shared/:token", queryPermission, logAPI.GetSharedQuery)
+	g.POST("/queries", queryPermission,
+		middleware.SetAuditOperation("日志查询保存"),
+		logAPI.SaveSavedQuery)
+	g.PUT("/queries/:id", queryPermission,
+		middleware.SetAuditOperation("日志查询保存"),
+		logAPI.SaveSavedQuery)
+	g.DELETE("/queries/:id", queryPermission,
+		middleware.SetAuditOperation("日志查询删除"),
+		logAPI.DeleteSavedQuery)
 }
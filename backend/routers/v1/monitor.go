@@ -30,6 +30,32 @@ func registerMonitor(r *gin.RouterGroup) {
 	g.GET("/host/metrics", queryPermission, monitorAPI.QueryHostMetrics)
 	g.GET("/host/ports", queryPermission, monitorAPI.QueryPortStatus)
 
+	// PromQL queries and label discovery
+	g.GET("/query", queryPermission, monitorAPI.QueryMetrics)
+	g.GET("/query_range", queryPermission, monitorAPI.QueryMetricsRange)
+	g.GET("/labels", queryPermission, monitorAPI.ListMetricLabels)
+	g.GET("/label/:name/values", queryPermission, monitorAPI.ListMetricLabelValues)
+	g.GET("/series", queryPermission, monitorAPI.ListMetricSeries)
+
+	// Dashboards
+	g.GET("/dashboards", queryPermission, monitorAPI.ListDashboards)
+	g.GET("/dashboards/:id", queryPermission, monitorAPI.GetDashboard)
+	g.GET("/dashboards/:id/export", queryPermission, monitorAPI.ExportDashboard)
+	g.GET("/dashboards/:id/variables/:name/options", queryPermission, monitorAPI.ListDashboardVariableOptions)
+	g.GET("/dashboards/:id/panels/:panelId/data", queryPermission, monitorAPI.QueryDashboardPanel)
+	g.POST("/dashboards", updatePermission,
+		middleware.SetAuditOperation("监控大盘保存"),
+		monitorAPI.SaveDashboard)
+	g.POST("/dashboards/import", updatePermission,
+		middleware.SetAuditOperation("监控大盘导入"),
+		monitorAPI.ImportDashboard)
+	g.PUT("/dashboards/:id", updatePermission,
+		middleware.SetAuditOperation("监控大盘更新"),
+		monitorAPI.SaveDashboard)
+	g.DELETE("/dashboards/:id", updatePermission,
+		middleware.SetAuditOperation("监控大盘删除"),
+		monitorAPI.DeleteDashboard)
+
 	// Agent management
 	g.GET("/agent/status", queryPermission, monitorAPI.QueryAgentStatus)
 }
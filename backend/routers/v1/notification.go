@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"devops-platform/internal/middleware"
+	notificationAPI "devops-platform/internal/modules/notification/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+func registerNotificationRoutes(r *gin.RouterGroup) {
+	g := r.Group("/notification")
+	queryPermission := middleware.RequirePermission("notification", "list")
+	updatePermission := middleware.RequirePermission("notification", "update")
+
+	// Templates
+	g.GET("/templates", queryPermission, notificationAPI.ListTemplates)
+	g.POST("/templates", updatePermission,
+		middleware.SetAuditOperation("创建通知模板"),
+		notificationAPI.SaveTemplate)
+	g.PUT("/templates/:id", updatePermission,
+		middleware.SetAuditOperation("更新通知模板"),
+		notificationAPI.SaveTemplate)
+	g.DELETE("/templates/:id", updatePermission,
+		middleware.SetAuditOperation("删除通知模板"),
+		notificationAPI.DeleteTemplate)
+	g.POST("/templates/preview", queryPermission, notificationAPI.PreviewTemplate)
+
+	// Outbox
+	g.GET("/outbox", queryPermission, notificationAPI.ListOutbox)
+	g.GET("/outbox/status", queryPermission, notificationAPI.GetOutboxStatus)
+	g.POST("/outbox/replay", updatePermission,
+		middleware.SetAuditOperation("重放死信通知"),
+		notificationAPI.ReplayOutbox)
+}
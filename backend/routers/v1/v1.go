@@ -17,6 +17,7 @@ func Register(r *gin.Engine) {
 	// 旧业务路由（K8s / CMDB 等暂保留）
 	registerCluster(auth)
 	registerAlert(auth)
+	registerAlertWebhook(apiV1)
 	registerLog(auth)
 	registerMonitor(auth)
 	registerHarbor(auth)
@@ -33,6 +34,7 @@ func Register(r *gin.Engine) {
 	registerTaskRoutes(auth)
 	registerWorkflowRoutes(auth)
 	registerToolRoutes(auth)
+	registerNotificationRoutes(auth)
 	registerSqlAuditRoutes(auth)
 	registerKnowledgeBase(auth)
 }